@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ysicing/nexus/pkg/backup"
+	"github.com/ysicing/nexus/pkg/common"
+	"github.com/ysicing/nexus/pkg/database"
+)
+
+const backupUsage = `Usage:
+  nexus backup export -output <file> [-passphrase-file <file>]
+  nexus backup import -input <file> [-mode merge|replace] [-dry-run] [-passphrase-file <file>]
+
+The database is selected by DATABASE_DSN. The passphrase is read from
+-passphrase-file, or from NEXUS_BACKUP_PASSPHRASE when no file is given.
+`
+
+// runBackupCommand 处理 backup 子命令，返回进程退出码
+func runBackupCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, backupUsage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "export":
+		err = runBackupExport(args[1:])
+	case "import":
+		err = runBackupImport(args[1:])
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(os.Stdout, backupUsage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown backup command: %s\n\n%s", args[0], backupUsage)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "backup %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func runBackupExport(args []string) error {
+	fs := flag.NewFlagSet("backup export", flag.ContinueOnError)
+	output := fs.String("output", "", "file to write the backup to")
+	passphraseFile := fs.String("passphrase-file", "", "file containing the backup passphrase")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output == "" {
+		return fmt.Errorf("-output is required")
+	}
+
+	passphrase, err := readBackupPassphrase(*passphraseFile)
+	if err != nil {
+		return err
+	}
+
+	db, err := openBackupDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	doc, err := backup.Export(db.GetDB(), passphrase)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	// 备份中的敏感信息虽已加密，仍只允许当前用户读取
+	if err := os.WriteFile(*output, data, 0600); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "exported %d clusters to %s\n", len(doc.Clusters), *output)
+	return nil
+}
+
+func runBackupImport(args []string) error {
+	fs := flag.NewFlagSet("backup import", flag.ContinueOnError)
+	input := fs.String("input", "", "backup file to import")
+	mode := fs.String("mode", string(backup.ModeMerge), "import mode: merge or replace")
+	dryRun := fs.Bool("dry-run", false, "only report the changes without writing to the database")
+	passphraseFile := fs.String("passphrase-file", "", "file containing the backup passphrase")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *input == "" {
+		return fmt.Errorf("-input is required")
+	}
+
+	passphrase, err := readBackupPassphrase(*passphraseFile)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(*input)
+	if err != nil {
+		return err
+	}
+	var doc backup.Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("invalid backup file: %w", err)
+	}
+
+	db, err := openBackupDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	report, err := backup.Import(db.GetDB(), &doc, backup.ImportOptions{
+		Passphrase: passphrase,
+		Mode:       backup.Mode(*mode),
+		DryRun:     *dryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stdout, string(out))
+	if !report.DryRun {
+		fmt.Fprintln(os.Stderr, "restart running nexus servers to load the imported clusters")
+	}
+	return nil
+}
+
+// openBackupDatabase 打开 DATABASE_DSN 指定的数据库并执行迁移
+func openBackupDatabase() (*database.Database, error) {
+	// 数据库中的凭据使用 ENCRYPTION_KEY 加密，必须与服务端保持一致
+	common.LoadEnvs()
+	if err := common.RequireEncryptionKey(); err != nil {
+		return nil, err
+	}
+
+	db, err := database.NewDatabase(database.GetDefaultConfig())
+	if err != nil {
+		return nil, err
+	}
+	if err := db.MigrateDatabase(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// readBackupPassphrase 从文件或 NEXUS_BACKUP_PASSPHRASE 环境变量读取备份口令
+func readBackupPassphrase(file string) (string, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	if passphrase := os.Getenv("NEXUS_BACKUP_PASSPHRASE"); passphrase != "" {
+		return passphrase, nil
+	}
+	return "", fmt.Errorf("passphrase is required, use -passphrase-file or NEXUS_BACKUP_PASSPHRASE")
+}
@@ -16,15 +16,22 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/ysicing/nexus/pkg/auth"
+	"github.com/ysicing/nexus/pkg/backup"
 	"github.com/ysicing/nexus/pkg/cluster"
 	"github.com/ysicing/nexus/pkg/common"
 	"github.com/ysicing/nexus/pkg/database"
 	"github.com/ysicing/nexus/pkg/handlers"
 	"github.com/ysicing/nexus/pkg/handlers/resources"
+	"github.com/ysicing/nexus/pkg/history"
 	"github.com/ysicing/nexus/pkg/kube"
 	"github.com/ysicing/nexus/pkg/middleware"
+	"github.com/ysicing/nexus/pkg/models"
+	"github.com/ysicing/nexus/pkg/preferences"
 	"github.com/ysicing/nexus/pkg/prometheus"
+	"github.com/ysicing/nexus/pkg/settings"
+	"github.com/ysicing/nexus/pkg/tunnel"
 	"github.com/ysicing/nexus/pkg/utils"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/klog/v2"
 )
 
@@ -38,10 +45,14 @@ type ClusterManager interface {
 	GetDefaultCluster() (*cluster.ClusterInfo, error)
 	GetCluster(clusterID string) (*cluster.ClusterInfo, error)
 	ListClusters() []*cluster.ClusterInfo
+	ListClustersBySelector(selector labels.Selector) ([]*cluster.ClusterInfo, error)
 	AddCluster(name, description, kubeconfigContent string, labels map[string]string) (*cluster.ClusterInfo, error)
 	RemoveCluster(clusterID string) error
 	SetDefaultCluster(clusterID string) error
 	UpdateClusterLabels(clusterID string, labels map[string]string) error
+	UpdateClusterKubeconfig(clusterID, kubeconfigContent string) (*cluster.ClusterInfo, error)
+	AddClusterWithTunnel(name, description, kubeconfigContent string, labels map[string]string, tunnelConfig *tunnel.Config) (*cluster.ClusterInfo, error)
+	UpdateClusterTunnel(clusterID string, tunnelConfig *tunnel.Config) (*cluster.ClusterInfo, error)
 }
 
 func setupStatic(r *gin.Engine) {
@@ -64,7 +75,7 @@ func setupStatic(r *gin.Engine) {
 		}
 
 		htmlContent := string(content)
-		if common.EnableAnalytics {
+		if common.Settings().EnableAnalytics {
 			// Inject analytics if enabled
 			htmlContent = utils.InjectAnalytics(string(content))
 		}
@@ -75,7 +86,7 @@ func setupStatic(r *gin.Engine) {
 	})
 }
 
-func setupAPIRouter(r *gin.Engine, k8sClient *kube.K8sClient, promClient *prometheus.Client, clusterManager ClusterManager) {
+func setupAPIRouter(r *gin.Engine, k8sClient *kube.K8sClient, promClient *prometheus.Client, clusterManager ClusterManager, settingsManager *settings.Manager) {
 	r.GET("/healthz", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"status": "ok",
@@ -95,12 +106,18 @@ func setupAPIRouter(r *gin.Engine, k8sClient *kube.K8sClient, promClient *promet
 		authGroup.GET("/user", authHandler.RequireAuth(), authHandler.GetUser)
 	}
 
+	clusterManagerHandler := cluster.NewHandlerWithInterface(clusterManager)
+
+	// Kubernetes API 代理同时接受代理 kubeconfig 中的令牌
+	proxyAPI := r.Group("/api/v1")
+	proxyAPI.Use(clusterManagerHandler.ProxyAuth(authHandler.RequireAuth()), middleware.ReadonlyMiddleware(), middleware.Audit())
+	clusterManagerHandler.RegisterProxyRoutes(proxyAPI)
+
 	// API routes group (protected)
 	api := r.Group("/api/v1")
-	api.Use(authHandler.RequireAuth(), middleware.ReadonlyMiddleware())
+	api.Use(authHandler.RequireAuth(), middleware.ReadonlyMiddleware(), middleware.Audit())
 	{
 		// 注册集群管理路由（支持所有类型的集群管理器）
-		clusterManagerHandler := cluster.NewHandlerWithInterface(clusterManager)
 		clusterManagerHandler.RegisterRoutes(api)
 
 		// 根据实际的集群管理器类型来注册其他路由
@@ -136,12 +153,30 @@ func setupAPIRouter(r *gin.Engine, k8sClient *kube.K8sClient, promClient *promet
 				clusterAPI.POST("/resources/apply", resourceApplyHandler.ApplyResource)
 
 				// 注册资源路由，使用集群中间件
-				resources.RegisterRoutesWithCluster(clusterAPI, mgr)
+				resources.RegisterRoutesWithCluster(clusterAPI)
 			}
 		case *cluster.ManagerWithDB:
 			// 数据库集群管理器 - 创建一个简化的集群处理器
 			klog.Info("Using database cluster manager with full API support")
 
+			// 管理员路由
+			adminAPI := api.Group("/admin")
+			adminAPI.Use(middleware.RequireAdmin())
+			{
+				backupHandler := backup.NewHandler(mgr.Database().GetDB(), mgr)
+				backupHandler.RegisterRoutes(adminAPI)
+
+				if settingsManager != nil {
+					settingsHandler := settings.NewHandler(settingsManager)
+					settingsHandler.RegisterRoutes(adminAPI)
+				}
+			}
+
+			// 用户个人设置
+			prefStore := preferences.NewStore(mgr.Database().GetDB())
+			preferencesHandler := preferences.NewHandler(prefStore, clusterManager)
+			preferencesHandler.RegisterRoutes(api)
+
 			// 创建一个简化的集群中间件（不依赖具体的 Manager 类型）
 			clusterMiddleware := func(c *gin.Context) {
 				clusterID := c.Query("cluster")
@@ -161,8 +196,16 @@ func setupAPIRouter(r *gin.Engine, k8sClient *kube.K8sClient, promClient *promet
 						return
 					}
 				} else {
-					// 使用默认集群
-					clusterInfo, err = clusterManager.GetDefaultCluster()
+					// 优先使用当前用户的默认集群，不存在时使用全局默认集群
+					if preferred := prefStore.DefaultCluster(middleware.CurrentUser(c)); preferred != "" {
+						if clusterInfo, err = clusterManager.GetCluster(preferred); err != nil {
+							klog.Warningf("User default cluster %s not available: %v", preferred, err)
+							clusterInfo = nil
+						}
+					}
+					if clusterInfo == nil {
+						clusterInfo, err = clusterManager.GetDefaultCluster()
+					}
 					if err != nil {
 						klog.Warningf("Failed to get default cluster: %v", err)
 						c.Set("k8sClient", nil)
@@ -171,6 +214,7 @@ func setupAPIRouter(r *gin.Engine, k8sClient *kube.K8sClient, promClient *promet
 					}
 				}
 
+				c.Set("clusterID", clusterInfo.ID)
 				if clusterInfo.Client == nil {
 					klog.Warningf("Cluster client not available for cluster: %s", clusterInfo.Name)
 					c.Set("k8sClient", nil)
@@ -203,11 +247,19 @@ func setupAPIRouter(r *gin.Engine, k8sClient *kube.K8sClient, promClient *promet
 				searchHandler := handlers.NewSearchHandler(k8sClient)
 				clusterAPI.GET("/search", searchHandler.GlobalSearch)
 
+				// 通过 Nexus 修改对象前保存历史版本
+				recorder := history.NewRecorder(models.NewRevisionRepository(mgr.Database().GetDB()))
+				historyHandler := history.NewHandler(recorder)
+				historyHandler.RegisterRoutes(clusterAPI)
+
 				resourceApplyHandler := handlers.NewResourceApplyHandler(k8sClient)
+				resourceApplyHandler.Recorder = recorder
 				clusterAPI.POST("/resources/apply", resourceApplyHandler.ApplyResource)
 
-				// TODO: 注册资源路由 - 需要适配支持接口的版本
-				// resources.RegisterRoutesWithCluster(clusterAPI, mgr)
+				// 注册资源路由，使用集群中间件
+				resourceAPI := clusterAPI.Group("")
+				resourceAPI.Use(recorder.Middleware(resourceAPI.BasePath()))
+				resources.RegisterRoutesWithCluster(resourceAPI)
 			}
 		default:
 			klog.Errorf("Unknown cluster manager type: %T", clusterManager)
@@ -215,10 +267,35 @@ func setupAPIRouter(r *gin.Engine, k8sClient *kube.K8sClient, promClient *promet
 	}
 }
 
-func setupWebhookRouter(r *gin.Engine, k8sClient *kube.K8sClient) {
-	webhookGroup := r.Group("/api/v1/webhooks", gin.BasicAuth(gin.Accounts{
-		common.WebhookUsername: common.WebhookPassword,
-	}))
+func setupWebhookRouter(r *gin.Engine, k8sClient *kube.K8sClient, clusterManager ClusterManager) {
+	// Webhook 按请求解析集群，默认集群变更或使用数据库存储时都作用于正确的集群
+	webhookCluster := func(c *gin.Context) {
+		clusterID := c.Query("cluster")
+		if clusterID == "" {
+			clusterID = c.GetHeader("X-Cluster-ID")
+		}
+
+		var clusterInfo *cluster.ClusterInfo
+		var err error
+		if clusterID != "" {
+			clusterInfo, err = clusterManager.GetCluster(clusterID)
+		} else {
+			clusterInfo, err = clusterManager.GetDefaultCluster()
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if clusterInfo.Client == nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Cluster client not available for cluster " + clusterInfo.ID})
+			return
+		}
+		c.Set("clusterID", clusterInfo.ID)
+		c.Set("k8sClient", clusterInfo.Client)
+		c.Next()
+	}
+
+	webhookGroup := r.Group("/api/v1/webhooks", middleware.WebhookAuth(), webhookCluster)
 	{
 		webhookHandler := handlers.NewWebhookHandler(k8sClient)
 		webhookGroup.POST("/events", webhookHandler.HandleWebhook)
@@ -226,6 +303,16 @@ func setupWebhookRouter(r *gin.Engine, k8sClient *kube.K8sClient) {
 }
 
 func main() {
+	// 子命令在启动服务之前处理
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "backup":
+			os.Exit(runBackupCommand(os.Args[2:]))
+		case "migrate":
+			os.Exit(runMigrateCommand(os.Args[2:]))
+		}
+	}
+
 	klog.InitFlags(nil)
 	flag.Parse()
 	go func() {
@@ -235,16 +322,24 @@ func main() {
 	common.LoadEnvs()
 	gin.SetMode(gin.DebugMode)
 	r := gin.New()
+	// 只信任 TRUSTED_PROXIES 中反向代理转发的客户端地址，未配置时不信任任何转发头
+	if err := r.SetTrustedProxies(common.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 	r.Use(gin.Recovery())
 	r.Use(middleware.Logger())
 	r.Use(middleware.CORS())
 
 	// 初始化数据库（如果配置了 DATABASE_DSN）
 	var clusterManager ClusterManager
+	var settingsManager *settings.Manager
 
 	if databaseDSN := os.Getenv("DATABASE_DSN"); databaseDSN != "" {
 		// 使用数据库集成的集群管理器
 		klog.Info("Using database-integrated cluster manager")
+		if err := common.RequireEncryptionKey(); err != nil {
+			log.Fatal(err)
+		}
 		dbConfig := database.GetDefaultConfig()
 		db, err := database.NewDatabase(dbConfig)
 		if err != nil {
@@ -257,6 +352,26 @@ func main() {
 			log.Fatalf("Failed to migrate database: %v", err)
 		}
 
+		// 多个副本共享数据库时使用同一个 JWT 签名密钥，否则在一个副本登录后访问其他副本会失效
+		if os.Getenv("JWT_SECRET") == "" {
+			secret, err := models.GetOrCreateSystemSecret(db.GetDB(), "jwt-secret", func() string {
+				return utils.RandomString(32)
+			})
+			if err != nil {
+				log.Fatalf("Failed to load shared JWT secret: %v", err)
+			}
+			common.JwtSecret = secret
+			klog.Info("Using the JWT secret shared through the database")
+		}
+
+		// 数据库中的运行时设置覆盖环境变量提供的默认值
+		settingsManager = settings.NewManager(db.GetDB())
+		if err := settingsManager.Load(); err != nil {
+			log.Fatalf("Failed to load settings: %v", err)
+		}
+		go settingsManager.Run()
+		defer settingsManager.Stop()
+
 		clusterManager = cluster.NewManagerWithDB(db)
 	} else {
 		// 使用传统的内存集群管理器
@@ -290,10 +405,11 @@ func main() {
 	// 这里保留一个默认客户端用于向后兼容
 	var promClient *prometheus.Client
 	if defaultCluster != nil && defaultCluster.PrometheusEnabled && defaultCluster.PrometheusURL != "" {
-		promClient, err = prometheus.NewClientWithAuth(
+		promClient, err = prometheus.NewClientWithTransport(
 			defaultCluster.PrometheusURL,
 			defaultCluster.PrometheusUsername,
 			defaultCluster.PrometheusPassword,
+			defaultCluster.HTTPTransport(),
 		)
 		if err != nil {
 			klog.Errorf("Failed to create Prometheus client for default cluster: %v", err)
@@ -306,8 +422,8 @@ func main() {
 	}
 
 	// Setup router
-	setupAPIRouter(r, k8sClient, promClient, clusterManager)
-	setupWebhookRouter(r, k8sClient)
+	setupAPIRouter(r, k8sClient, promClient, clusterManager, settingsManager)
+	setupWebhookRouter(r, k8sClient, clusterManager)
 	setupStatic(r)
 
 	srv := &http.Server{
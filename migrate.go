@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ysicing/nexus/pkg/common"
+	"github.com/ysicing/nexus/pkg/database"
+)
+
+const migrateUsage = `Usage:
+  nexus migrate status
+  nexus migrate up
+
+The database is selected by DATABASE_DSN. The server applies pending
+migrations on startup; "up" applies them without starting the server.
+`
+
+// runMigrateCommand 处理 migrate 子命令，返回进程退出码
+func runMigrateCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "status":
+		err = runMigrateStatus(args[1:])
+	case "up":
+		err = runMigrateUp(args[1:])
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(os.Stdout, migrateUsage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate command: %s\n\n%s", args[0], migrateUsage)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func runMigrateStatus(args []string) error {
+	fs := flag.NewFlagSet("migrate status", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := database.NewDatabase(database.GetDefaultConfig())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	statuses, err := db.MigrationStatus()
+	if err != nil {
+		return err
+	}
+
+	pending, problems := 0, 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		state, appliedAt := "pending", "-"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		switch {
+		case status.Unknown:
+			state = "unknown"
+			problems++
+		case status.ChecksumMismatch:
+			state = "checksum mismatch"
+			problems++
+		case status.Applied:
+			state = "applied"
+		default:
+			pending++
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if problems > 0 {
+		return fmt.Errorf("%d migrations do not match this version", problems)
+	}
+	fmt.Fprintf(os.Stderr, "%d pending migrations\n", pending)
+	return nil
+}
+
+func runMigrateUp(args []string) error {
+	fs := flag.NewFlagSet("migrate up", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// 部分迁移需要使用 ENCRYPTION_KEY 加密已有数据
+	common.LoadEnvs()
+	if err := common.RequireEncryptionKey(); err != nil {
+		return err
+	}
+
+	db, err := database.NewDatabase(database.GetDefaultConfig())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.MigrateDatabase()
+}
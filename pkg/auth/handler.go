@@ -21,7 +21,7 @@ func NewAuthHandler() *AuthHandler {
 
 func (h *AuthHandler) GetProviders(c *gin.Context) {
 	providers := []string{}
-	if common.OAuthEnabled {
+	if common.Settings().OAuthEnabled {
 		providers = h.manager.GetAvailableProviders()
 	}
 	if common.PasswordLoginEnabled {
@@ -194,7 +194,7 @@ func (h *AuthHandler) RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var tokenString string
 
-		if !common.OAuthEnabled && !common.PasswordLoginEnabled {
+		if !common.Settings().OAuthEnabled && !common.PasswordLoginEnabled {
 			c.Set("user", gin.H{
 				"id":         "anonymous",
 				"username":   "anonymous",
@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -318,38 +319,69 @@ func (g *GenericProvider) GetUserInfo(accessToken string) (*User, error) {
 }
 
 type OAuthManager struct {
+	mu        sync.RWMutex
 	providers map[string]OAuthProvider
-	jwtSecret string
+	// configured is the OAUTH_PROVIDERS value the providers were built from
+	configured string
+	jwtSecret  string
 }
 
 func NewOAuthManager() *OAuthManager {
 	manager := &OAuthManager{
-		providers: make(map[string]OAuthProvider),
 		jwtSecret: common.JwtSecret,
 	}
+	manager.loadProviders(common.Settings().OAuthProviders)
+	return manager
+}
+
+// loadProviders registers the providers enabled by environment variables and
+// the configured provider list.
+func (om *OAuthManager) loadProviders(configured string) {
+	providers := make(map[string]OAuthProvider)
 
 	// Register providers based on environment variables
 	if os.Getenv("GITHUB_CLIENT_ID") != "" {
-		manager.providers["github"] = NewGitHubProvider()
+		providers["github"] = NewGitHubProvider()
 	}
 
 	// Register custom providers
-	customProviders := strings.SplitSeq(common.OAuthProviders, ",")
+	customProviders := strings.SplitSeq(configured, ",")
 	for providerName := range customProviders {
 		providerName = strings.TrimSpace(providerName)
 		if providerName != "" && providerName != "github" {
 			provider := NewGenericProvider(providerName)
 			if provider.Config.ClientID != "" {
-				manager.providers[providerName] = provider
+				providers[providerName] = provider
 			}
 		}
 	}
 
-	return manager
+	om.mu.Lock()
+	om.providers = providers
+	om.configured = configured
+	om.mu.Unlock()
+}
+
+// currentProviders returns the registered providers, reloading them when the
+// provider list has been changed through the settings API.
+func (om *OAuthManager) currentProviders() map[string]OAuthProvider {
+	configured := common.Settings().OAuthProviders
+
+	om.mu.RLock()
+	providers, stale := om.providers, om.configured != configured
+	om.mu.RUnlock()
+	if !stale {
+		return providers
+	}
+
+	om.loadProviders(configured)
+	om.mu.RLock()
+	defer om.mu.RUnlock()
+	return om.providers
 }
 
 func (om *OAuthManager) GetProvider(name string) (OAuthProvider, error) {
-	provider, exists := om.providers[name]
+	provider, exists := om.currentProviders()[name]
 	if !exists {
 		return nil, fmt.Errorf("provider %s not found", name)
 	}
@@ -358,7 +390,7 @@ func (om *OAuthManager) GetProvider(name string) (OAuthProvider, error) {
 
 func (om *OAuthManager) GetAvailableProviders() []string {
 	var providers []string
-	for name := range om.providers {
+	for name := range om.currentProviders() {
 		providers = append(providers, name)
 	}
 	return providers
@@ -465,7 +497,7 @@ func (om *OAuthManager) RefreshJWT(tokenString string) (string, error) {
 }
 
 func CheckPermissions(user *User) bool {
-	allowUsers := common.OAuthAllowUsers
+	allowUsers := common.Settings().OAuthAllowUsers
 	if allowUsers == "" {
 		return false
 	}
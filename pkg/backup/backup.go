@@ -0,0 +1,230 @@
+package backup
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/ysicing/nexus/pkg/models"
+	"github.com/ysicing/nexus/pkg/utils"
+	"golang.org/x/crypto/scrypt"
+	"gorm.io/gorm"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// FormatVersion 备份文件格式版本，版本 2 增加了运行时设置
+	FormatVersion = 2
+
+	// minPassphraseLength 备份口令的最小长度
+	minPassphraseLength = 8
+	// passphraseCheck 用于在导入前校验口令是否正确的明文
+	passphraseCheck = "nexus-backup"
+)
+
+// Document 注册表备份文件
+//
+// 集群的 kubeconfig、Prometheus 密码、连接方式和运行时设置等敏感信息使用口令派生的密钥加密，
+// 与服务端的 ENCRYPTION_KEY 无关，因此可以导入到其他 Nexus 实例。
+type Document struct {
+	Version    int        `json:"version"`
+	ExportedAt time.Time  `json:"exportedAt"`
+	Encryption Encryption `json:"encryption"`
+	Clusters   []Cluster  `json:"clusters"`
+	Settings   []Setting  `json:"settings"`
+}
+
+// Encryption 备份文件的加密参数
+type Encryption struct {
+	Algorithm string `json:"algorithm"`
+	KDF       string `json:"kdf"`
+	Salt      string `json:"salt"`
+	// Check 口令校验值
+	Check string `json:"check"`
+}
+
+// Cluster 备份中的集群
+type Cluster struct {
+	ID             string            `json:"id"`
+	Name           string            `json:"name"`
+	Description    string            `json:"description,omitempty"`
+	Server         string            `json:"server"`
+	Context        string            `json:"context,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	IsDefault      bool              `json:"isDefault"`
+	IsInCluster    bool              `json:"isInCluster"`
+	KubeconfigPath string            `json:"kubeconfigPath,omitempty"`
+	// Kubeconfig 加密后的 kubeconfig 内容
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+	// Tunnel 加密后的连接方式配置
+	Tunnel     string      `json:"tunnel,omitempty"`
+	Prometheus *Prometheus `json:"prometheus,omitempty"`
+	CreatedAt  time.Time   `json:"createdAt"`
+}
+
+// Prometheus 备份中的 Prometheus 配置
+type Prometheus struct {
+	URL      string `json:"url"`
+	Username string `json:"username,omitempty"`
+	// Password 加密后的密码
+	Password string `json:"password,omitempty"`
+	Enabled  bool   `json:"enabled"`
+}
+
+// cipher 使用口令派生的密钥加解密备份中的敏感信息
+type cipher struct {
+	key string
+}
+
+// newCipher 根据口令和盐派生密钥
+func newCipher(passphrase string, salt []byte) (*cipher, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	return &cipher{key: hex.EncodeToString(key)}, nil
+}
+
+func (c *cipher) encrypt(plaintext string) (string, error) {
+	return utils.EncryptString(c.key, plaintext)
+}
+
+func (c *cipher) decrypt(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	if !utils.IsEncrypted(value) {
+		return "", fmt.Errorf("value is not encrypted")
+	}
+	return utils.DecryptString(c.key, value)
+}
+
+// Export 导出数据库中的所有集群和运行时设置
+func Export(db *gorm.DB, passphrase string) (*Document, error) {
+	if len(passphrase) < minPassphraseLength {
+		return nil, fmt.Errorf("passphrase must be at least %d characters", minPassphraseLength)
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	c, err := newCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	check, err := c.encrypt(passphraseCheck)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters, err := models.NewClusterRepository(db).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clusters: %w", err)
+	}
+
+	doc := &Document{
+		Version:    FormatVersion,
+		ExportedAt: time.Now().UTC(),
+		Encryption: Encryption{
+			Algorithm: "AES-256-GCM",
+			KDF:       "scrypt",
+			Salt:      base64.StdEncoding.EncodeToString(salt),
+			Check:     check,
+		},
+		Clusters: make([]Cluster, 0, len(clusters)),
+	}
+
+	for _, model := range clusters {
+		cluster, err := exportCluster(c, model)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export cluster %s: %w", model.ID, err)
+		}
+		doc.Clusters = append(doc.Clusters, *cluster)
+	}
+
+	if doc.Settings, err = exportSettings(db, c); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// exportCluster 将数据库模型转换为备份中的集群
+func exportCluster(c *cipher, model *models.ClusterModel) (*Cluster, error) {
+	cluster := &Cluster{
+		ID:             model.ID,
+		Name:           model.Name,
+		Description:    model.Description,
+		Server:         model.Server,
+		Context:        model.Context,
+		IsDefault:      model.IsDefault,
+		IsInCluster:    model.IsInCluster,
+		Labels:         model.Labels,
+		KubeconfigPath: model.KubeconfigPath,
+		CreatedAt:      model.CreatedAt,
+	}
+
+	// 从 kubeconfig 文件加载的集群没有保存内容，导出时一并带上，使备份可以独立恢复
+	kubeconfig := string(model.KubeconfigContent)
+	if kubeconfig == "" && model.KubeconfigPath != "" && model.Context != "" {
+		content, err := kubeconfigFromFile(model.KubeconfigPath, model.Context)
+		if err != nil {
+			klog.Warningf("Failed to embed kubeconfig of cluster %s from %s: %v", model.ID, model.KubeconfigPath, err)
+		}
+		kubeconfig = content
+	}
+
+	var err error
+	if cluster.Kubeconfig, err = c.encrypt(kubeconfig); err != nil {
+		return nil, err
+	}
+	if cluster.Tunnel, err = c.encrypt(string(model.TunnelConfig)); err != nil {
+		return nil, err
+	}
+
+	if model.PrometheusURL != "" {
+		password, err := c.encrypt(string(model.PrometheusPassword))
+		if err != nil {
+			return nil, err
+		}
+		cluster.Prometheus = &Prometheus{
+			URL:      model.PrometheusURL,
+			Username: model.PrometheusUsername,
+			Password: password,
+			Enabled:  model.PrometheusEnabled,
+		}
+	}
+
+	return cluster, nil
+}
+
+// kubeconfigFromFile 从 kubeconfig 文件中提取指定上下文，生成独立的 kubeconfig
+func kubeconfigFromFile(path, contextName string) (string, error) {
+	config, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		return "", err
+	}
+	if _, exists := config.Contexts[contextName]; !exists {
+		return "", fmt.Errorf("context %s not found", contextName)
+	}
+
+	config.CurrentContext = contextName
+	if err := clientcmdapi.MinifyConfig(config); err != nil {
+		return "", err
+	}
+	// 证书等以文件路径引用的内容需要内联，否则换一台机器无法使用
+	if err := clientcmdapi.FlattenConfig(config); err != nil {
+		return "", err
+	}
+
+	content, err := clientcmd.Write(*config)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
@@ -0,0 +1,106 @@
+package backup
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ysicing/nexus/pkg/middleware"
+	"gorm.io/gorm"
+	"k8s.io/klog/v2"
+)
+
+// Reloader 在导入备份后重新加载集群
+type Reloader interface {
+	ReloadClusters(clusterIDs []string)
+}
+
+// Handler 备份处理器
+type Handler struct {
+	db       *gorm.DB
+	reloader Reloader
+}
+
+// NewHandler 创建备份处理器
+func NewHandler(db *gorm.DB, reloader Reloader) *Handler {
+	return &Handler{
+		db:       db,
+		reloader: reloader,
+	}
+}
+
+// ExportRequest 导出请求
+type ExportRequest struct {
+	Passphrase string `json:"passphrase" binding:"required"`
+}
+
+// ImportRequest 导入请求
+type ImportRequest struct {
+	Passphrase string    `json:"passphrase" binding:"required"`
+	Mode       Mode      `json:"mode"`
+	DryRun     bool      `json:"dryRun"`
+	Backup     *Document `json:"backup" binding:"required"`
+}
+
+// Export 导出注册表备份
+func (h *Handler) Export(c *gin.Context) {
+	var req ExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	doc, err := Export(h.db, req.Passphrase)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	klog.Infof("Registry backup exported by %s: %d clusters, %d settings",
+		middleware.CurrentUser(c), len(doc.Clusters), len(doc.Settings))
+
+	filename := fmt.Sprintf("nexus-backup-%s.json", doc.ExportedAt.Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.JSON(http.StatusOK, doc)
+}
+
+// Import 导入注册表备份
+func (h *Handler) Import(c *gin.Context) {
+	var req ImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now()
+	report, err := Import(h.db, req.Backup, ImportOptions{
+		Passphrase: req.Passphrase,
+		Mode:       req.Mode,
+		DryRun:     req.DryRun,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !report.DryRun && h.reloader != nil {
+		h.reloader.ReloadClusters(report.Changed())
+	}
+
+	klog.Infof("Registry backup imported by %s: mode=%s dryRun=%t created=%d updated=%d deleted=%d settings=%d in %s",
+		middleware.CurrentUser(c), report.Mode, report.DryRun,
+		len(report.Created), len(report.Updated), len(report.Deleted),
+		len(report.Settings), time.Since(start))
+
+	c.JSON(http.StatusOK, report)
+}
+
+// RegisterRoutes 注册备份路由，调用方负责管理员权限校验
+func (h *Handler) RegisterRoutes(group *gin.RouterGroup) {
+	backupGroup := group.Group("/backup")
+	{
+		backupGroup.POST("/export", h.Export)
+		backupGroup.POST("/import", h.Import)
+	}
+}
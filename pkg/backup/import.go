@@ -0,0 +1,300 @@
+package backup
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/ysicing/nexus/pkg/models"
+	"gorm.io/gorm"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Mode 导入模式
+type Mode string
+
+const (
+	// ModeMerge 按 ID 新增或覆盖备份中的集群，保留备份中没有的集群
+	ModeMerge Mode = "merge"
+	// ModeReplace 使数据库与备份完全一致，删除备份中没有的集群
+	ModeReplace Mode = "replace"
+)
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	Passphrase string
+	Mode       Mode
+	// DryRun 只生成导入报告，不写入数据库
+	DryRun bool
+}
+
+// Report 导入报告
+type Report struct {
+	Mode    Mode     `json:"mode"`
+	DryRun  bool     `json:"dryRun"`
+	Created []string `json:"created"`
+	Updated []string `json:"updated"`
+	Deleted []string `json:"deleted"`
+	// Default 导入后的默认集群
+	Default string `json:"default,omitempty"`
+	// Settings 导入的运行时设置，DeletedSettings 为 replace 模式删除的设置
+	Settings        []string `json:"settings"`
+	DeletedSettings []string `json:"deletedSettings"`
+	Warnings        []string `json:"warnings,omitempty"`
+}
+
+// Changed 返回导入过程中新增、更新或删除的集群 ID
+func (r *Report) Changed() []string {
+	ids := make([]string, 0, len(r.Created)+len(r.Updated)+len(r.Deleted))
+	ids = append(ids, r.Created...)
+	ids = append(ids, r.Updated...)
+	ids = append(ids, r.Deleted...)
+	return ids
+}
+
+// Import 将备份导入数据库，所有修改在同一个事务中完成
+func Import(db *gorm.DB, doc *Document, opts ImportOptions) (*Report, error) {
+	if opts.Mode == "" {
+		opts.Mode = ModeMerge
+	}
+	if opts.Mode != ModeMerge && opts.Mode != ModeReplace {
+		return nil, fmt.Errorf("unsupported import mode: %s", opts.Mode)
+	}
+
+	decoded, err := decode(doc, opts.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Mode:     opts.Mode,
+		DryRun:   opts.DryRun,
+		Created:  []string{},
+		Updated:  []string{},
+		Deleted:  []string{},
+		Warnings: decoded.warnings,
+
+		Settings:        []string{},
+		DeletedSettings: []string{},
+	}
+
+	// dry-run 同样在事务中执行，最后回滚，保证报告与实际导入的结果一致
+	errDryRun := errors.New("dry run")
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := apply(tx, decoded.clusters, opts.Mode, report); err != nil {
+			return err
+		}
+		// 版本 1 的备份不包含设置，不能按 replace 模式清空已有的设置
+		if doc.Version >= 2 {
+			if err := applySettings(tx, decoded.settings, opts.Mode, report); err != nil {
+				return err
+			}
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, fmt.Errorf("failed to import backup: %w", err)
+	}
+
+	return report, nil
+}
+
+// apply 在事务中写入集群
+func apply(tx *gorm.DB, clusters []*models.ClusterModel, mode Mode, report *Report) error {
+	repo := models.NewClusterRepository(tx)
+
+	existing, err := repo.GetAll()
+	if err != nil {
+		return err
+	}
+	existingByID := make(map[string]*models.ClusterModel, len(existing))
+	for _, model := range existing {
+		existingByID[model.ID] = model
+	}
+
+	imported := make(map[string]bool, len(clusters))
+	backupDefault := ""
+	for _, model := range clusters {
+		imported[model.ID] = true
+		if model.IsDefault {
+			backupDefault = model.ID
+		}
+
+		// 默认集群统一在最后处理，这里先保留数据库中已有的状态
+		if old, exists := existingByID[model.ID]; exists {
+			model.IsDefault = old.IsDefault
+			model.Status = old.Status
+			model.Version = old.Version
+			model.LastCheck = old.LastCheck
+			report.Updated = append(report.Updated, model.ID)
+		} else {
+			model.IsDefault = false
+			report.Created = append(report.Created, model.ID)
+		}
+
+		if err := repo.Save(model); err != nil {
+			return fmt.Errorf("failed to save cluster %s: %w", model.ID, err)
+		}
+	}
+
+	if mode == ModeReplace {
+		for _, model := range existing {
+			if imported[model.ID] {
+				continue
+			}
+			if err := repo.Delete(model.ID); err != nil {
+				return fmt.Errorf("failed to delete cluster %s: %w", model.ID, err)
+			}
+			report.Deleted = append(report.Deleted, model.ID)
+		}
+	}
+
+	// replace 模式使用备份中的默认集群；merge 模式只在没有默认集群时使用
+	current, err := repo.GetDefault()
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	switch {
+	case backupDefault != "" && (mode == ModeReplace || current == nil):
+		if err := repo.SetDefault(backupDefault); err != nil {
+			return fmt.Errorf("failed to set default cluster: %w", err)
+		}
+		report.Default = backupDefault
+	case current != nil:
+		report.Default = current.ID
+	}
+
+	sort.Strings(report.Created)
+	sort.Strings(report.Updated)
+	sort.Strings(report.Deleted)
+	return nil
+}
+
+// decoded 解密后的备份内容
+type decoded struct {
+	clusters []*models.ClusterModel
+	settings []*models.SettingModel
+	warnings []string
+}
+
+// decode 校验口令并解密备份，任何一个集群或设置无法解密都会导致整个导入失败
+func decode(doc *Document, passphrase string) (*decoded, error) {
+	if doc == nil {
+		return nil, fmt.Errorf("backup is empty")
+	}
+	if doc.Version < 1 || doc.Version > FormatVersion {
+		return nil, fmt.Errorf("unsupported backup version: %d", doc.Version)
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(doc.Encryption.Salt)
+	if err != nil || len(salt) == 0 {
+		return nil, fmt.Errorf("invalid backup salt")
+	}
+	c, err := newCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if check, err := c.decrypt(doc.Encryption.Check); err != nil || check != passphraseCheck {
+		return nil, fmt.Errorf("incorrect passphrase")
+	}
+
+	clusters, warnings, err := decodeClusters(c, doc.Clusters)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := decodeSettings(c, doc.Settings)
+	if err != nil {
+		return nil, err
+	}
+	return &decoded{clusters: clusters, settings: settings, warnings: warnings}, nil
+}
+
+// decodeClusters 解密备份中的集群
+func decodeClusters(c *cipher, backupClusters []Cluster) ([]*models.ClusterModel, []string, error) {
+	var warnings []string
+	seen := make(map[string]bool, len(backupClusters))
+	clusters := make([]*models.ClusterModel, 0, len(backupClusters))
+	for _, cluster := range backupClusters {
+		if cluster.ID == "" || cluster.Name == "" {
+			return nil, nil, fmt.Errorf("cluster id and name are required")
+		}
+		if seen[cluster.ID] {
+			return nil, nil, fmt.Errorf("duplicate cluster id in backup: %s", cluster.ID)
+		}
+		seen[cluster.ID] = true
+
+		model, warning, err := importCluster(c, cluster)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decode cluster %s: %w", cluster.ID, err)
+		}
+		if warning != "" {
+			warnings = append(warnings, warning)
+		}
+		clusters = append(clusters, model)
+	}
+
+	return clusters, warnings, nil
+}
+
+// importCluster 将备份中的集群转换为数据库模型
+func importCluster(c *cipher, cluster Cluster) (*models.ClusterModel, string, error) {
+	kubeconfig, err := c.decrypt(cluster.Kubeconfig)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+	tunnelConfig, err := c.decrypt(cluster.Tunnel)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid tunnel config: %w", err)
+	}
+	if errs := metav1validation.ValidateLabels(cluster.Labels, field.NewPath("labels")); len(errs) > 0 {
+		return nil, "", errs.ToAggregate()
+	}
+
+	model := &models.ClusterModel{
+		ID:                cluster.ID,
+		Name:              cluster.Name,
+		Description:       cluster.Description,
+		Server:            cluster.Server,
+		Status:            "unknown",
+		Context:           cluster.Context,
+		IsDefault:         cluster.IsDefault,
+		IsInCluster:       cluster.IsInCluster,
+		KubeconfigPath:    cluster.KubeconfigPath,
+		KubeconfigContent: models.EncryptedString(kubeconfig),
+		TunnelConfig:      models.EncryptedString(tunnelConfig),
+		Labels:            cluster.Labels,
+		CreatedAt:         cluster.CreatedAt,
+	}
+
+	if cluster.Prometheus != nil {
+		password, err := c.decrypt(cluster.Prometheus.Password)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid prometheus password: %w", err)
+		}
+		model.PrometheusURL = cluster.Prometheus.URL
+		model.PrometheusUsername = cluster.Prometheus.Username
+		model.PrometheusPassword = models.EncryptedString(password)
+		model.PrometheusEnabled = cluster.Prometheus.Enabled
+	}
+
+	// 导入到其他机器时原 kubeconfig 文件可能不存在，改为使用备份中的内容
+	warning := ""
+	if model.KubeconfigPath != "" {
+		if _, err := os.Stat(model.KubeconfigPath); err != nil {
+			if kubeconfig == "" {
+				warning = fmt.Sprintf("cluster %s: kubeconfig file %s not found and backup has no kubeconfig content", model.ID, model.KubeconfigPath)
+			} else {
+				warning = fmt.Sprintf("cluster %s: kubeconfig file %s not found, using kubeconfig from backup", model.ID, model.KubeconfigPath)
+			}
+			model.KubeconfigPath = ""
+		}
+	}
+
+	return model, warning, nil
+}
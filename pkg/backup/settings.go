@@ -0,0 +1,104 @@
+package backup
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ysicing/nexus/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Setting 备份中的运行时设置
+type Setting struct {
+	Name string `json:"name"`
+	// Value 加密后的设置值，设置中包含 Webhook 密码等敏感信息
+	Value     string    `json:"value"`
+	UpdatedBy string    `json:"updatedBy,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// exportSettings 导出运行时设置，设置值使用备份口令重新加密
+func exportSettings(db *gorm.DB, c *cipher) ([]Setting, error) {
+	rows, err := models.NewSettingRepository(db).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list settings: %w", err)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Name < rows[j].Name })
+
+	settings := make([]Setting, 0, len(rows))
+	for _, row := range rows {
+		value, err := c.encrypt(string(row.Value))
+		if err != nil {
+			return nil, err
+		}
+		settings = append(settings, Setting{
+			Name:      row.Name,
+			Value:     value,
+			UpdatedBy: row.UpdatedBy,
+			UpdatedAt: row.UpdatedAt,
+		})
+	}
+	return settings, nil
+}
+
+// decodeSettings 解密备份中的运行时设置
+func decodeSettings(c *cipher, settings []Setting) ([]*models.SettingModel, error) {
+	seen := make(map[string]bool, len(settings))
+	rows := make([]*models.SettingModel, 0, len(settings))
+	for _, setting := range settings {
+		if setting.Name == "" {
+			return nil, fmt.Errorf("setting name is required")
+		}
+		if seen[setting.Name] {
+			return nil, fmt.Errorf("duplicate setting in backup: %s", setting.Name)
+		}
+		seen[setting.Name] = true
+
+		value, err := c.decrypt(setting.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode setting %s: %w", setting.Name, err)
+		}
+		rows = append(rows, &models.SettingModel{
+			Name:      setting.Name,
+			Value:     models.EncryptedString(value),
+			UpdatedBy: setting.UpdatedBy,
+			UpdatedAt: setting.UpdatedAt,
+		})
+	}
+	return rows, nil
+}
+
+// applySettings 在事务中写入运行时设置，replace 模式删除备份中没有的设置
+func applySettings(tx *gorm.DB, settings []*models.SettingModel, mode Mode, report *Report) error {
+	names := make([]string, 0, len(settings))
+	for _, setting := range settings {
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(setting).Error; err != nil {
+			return fmt.Errorf("failed to save setting %s: %w", setting.Name, err)
+		}
+		names = append(names, setting.Name)
+	}
+	report.Settings = append(report.Settings, names...)
+
+	if mode == ModeReplace {
+		var stale []string
+		query := tx.Model(&models.SettingModel{})
+		if len(names) > 0 {
+			query = query.Where("name NOT IN ?", names)
+		}
+		if err := query.Pluck("name", &stale).Error; err != nil {
+			return err
+		}
+		if len(stale) > 0 {
+			if err := tx.Where("name IN ?", stale).Delete(&models.SettingModel{}).Error; err != nil {
+				return fmt.Errorf("failed to delete settings: %w", err)
+			}
+		}
+		report.DeletedSettings = append(report.DeletedSettings, stale...)
+	}
+
+	sort.Strings(report.Settings)
+	sort.Strings(report.DeletedSettings)
+	return nil
+}
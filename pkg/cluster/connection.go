@@ -0,0 +1,145 @@
+package cluster
+
+import (
+	"fmt"
+	"net/url"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// connectionContextName 由连接参数生成的 kubeconfig 使用的上下文名称
+const connectionContextName = "nexus"
+
+// ClusterConnection 不依赖 kubeconfig 的集群连接参数
+type ClusterConnection struct {
+	// Server API Server 地址，例如 https://10.0.0.1:6443
+	Server string `json:"server" binding:"required"`
+	// Token Bearer Token，例如 ServiceAccount Token
+	Token string `json:"token"`
+	// ClientCertificate 客户端证书（PEM）
+	ClientCertificate string `json:"clientCertificate"`
+	// ClientKey 客户端私钥（PEM）
+	ClientKey string `json:"clientKey"`
+	// CertificateAuthority CA 证书（PEM）
+	CertificateAuthority string `json:"certificateAuthority"`
+	// InsecureSkipTLSVerify 跳过服务端证书校验
+	InsecureSkipTLSVerify bool `json:"insecureSkipTLSVerify"`
+	// TLSServerName 校验服务端证书时使用的名称
+	TLSServerName string `json:"tlsServerName"`
+	// ProxyURL 访问 API Server 使用的代理地址
+	ProxyURL string `json:"proxyUrl"`
+}
+
+// Validate 校验连接参数
+func (c *ClusterConnection) Validate() error {
+	server, err := url.Parse(c.Server)
+	if err != nil || server.Host == "" {
+		return fmt.Errorf("invalid server url: %s", c.Server)
+	}
+
+	hasCert := c.ClientCertificate != "" || c.ClientKey != ""
+	if c.Token == "" && !hasCert {
+		return fmt.Errorf("either token or client certificate/key is required")
+	}
+	if c.Token != "" && hasCert {
+		return fmt.Errorf("token and client certificate/key are mutually exclusive")
+	}
+	if hasCert && (c.ClientCertificate == "" || c.ClientKey == "") {
+		return fmt.Errorf("client certificate and client key must be provided together")
+	}
+	if c.InsecureSkipTLSVerify && c.CertificateAuthority != "" {
+		return fmt.Errorf("certificate authority cannot be used with insecureSkipTLSVerify")
+	}
+
+	if c.ProxyURL != "" {
+		proxy, err := url.Parse(c.ProxyURL)
+		if err != nil || proxy.Host == "" {
+			return fmt.Errorf("invalid proxy url: %s", c.ProxyURL)
+		}
+	}
+	return nil
+}
+
+// BuildKubeconfig 根据连接参数生成 kubeconfig 内容
+//
+// 生成的 kubeconfig 与用户上传的内容走同一套存储和加载流程，凭据随之加密保存。
+func (c *ClusterConnection) BuildKubeconfig() (string, error) {
+	if err := c.Validate(); err != nil {
+		return "", err
+	}
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters[connectionContextName] = &clientcmdapi.Cluster{
+		Server:                   c.Server,
+		CertificateAuthorityData: []byte(c.CertificateAuthority),
+		InsecureSkipTLSVerify:    c.InsecureSkipTLSVerify,
+		TLSServerName:            c.TLSServerName,
+		ProxyURL:                 c.ProxyURL,
+	}
+	config.AuthInfos[connectionContextName] = &clientcmdapi.AuthInfo{
+		Token:                 c.Token,
+		ClientCertificateData: []byte(c.ClientCertificate),
+		ClientKeyData:         []byte(c.ClientKey),
+	}
+	config.Contexts[connectionContextName] = &clientcmdapi.Context{
+		Cluster:  connectionContextName,
+		AuthInfo: connectionContextName,
+	}
+	config.CurrentContext = connectionContextName
+
+	// 提前构建一次 REST 配置，尽早暴露证书格式等错误
+	if _, err := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}).ClientConfig(); err != nil {
+		return "", fmt.Errorf("invalid connection: %w", err)
+	}
+
+	content, err := clientcmd.Write(*config)
+	if err != nil {
+		return "", fmt.Errorf("failed to build kubeconfig: %w", err)
+	}
+	return string(content), nil
+}
+
+// restConfigFromKubeconfig 解析 kubeconfig 内容，返回当前上下文的 REST 配置
+func restConfigFromKubeconfig(kubeconfigContent string) (*rest.Config, string, error) {
+	config, err := clientcmd.Load([]byte(kubeconfigContent))
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+
+	// 使用当前上下文
+	currentContext := config.CurrentContext
+	if currentContext == "" {
+		// 如果没有当前上下文，使用第一个可用的
+		for contextName := range config.Contexts {
+			currentContext = contextName
+			break
+		}
+	}
+
+	if currentContext == "" {
+		return nil, "", fmt.Errorf("no valid context found in kubeconfig")
+	}
+
+	clientConfig := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{
+		CurrentContext: currentContext,
+	})
+
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create client config: %w", err)
+	}
+	return restConfig, currentContext, nil
+}
+
+// checkConnectionUpdatable 只有通过 API 添加的集群才能通过 API 修改连接配置
+func checkConnectionUpdatable(cluster *ClusterInfo) error {
+	if cluster.ID == "in-cluster" {
+		return fmt.Errorf("cannot update connection of in-cluster configuration")
+	}
+	if cluster.KubeconfigPath != "" {
+		return fmt.Errorf("cluster %s is loaded from %s, update the kubeconfig file instead", cluster.ID, cluster.KubeconfigPath)
+	}
+	return nil
+}
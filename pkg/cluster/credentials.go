@@ -0,0 +1,221 @@
+package cluster
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"k8s.io/client-go/rest"
+)
+
+// DefaultCredentialWarningDays 凭证剩余有效期低于该天数时，集群健康状态变为 warning
+const DefaultCredentialWarningDays = 30
+
+// CredentialType 集群凭证类型
+type CredentialType string
+
+const (
+	CredentialTypeClientCertificate    CredentialType = "client-certificate"
+	CredentialTypeCertificateAuthority CredentialType = "certificate-authority"
+	CredentialTypeToken                CredentialType = "token"
+)
+
+// CredentialExpiry 单个集群凭证的过期信息
+type CredentialExpiry struct {
+	Type         CredentialType `json:"type"`
+	Subject      string         `json:"subject,omitempty"`
+	ExpiresAt    time.Time      `json:"expiresAt"`
+	DaysToExpiry int            `json:"daysToExpiry"`
+}
+
+// Expired 凭证是否已经过期
+func (e CredentialExpiry) Expired() bool {
+	return e.DaysToExpiry < 0
+}
+
+// InspectCredentials 解析 rest.Config 中客户端证书、CA 证书和 JWT 令牌的过期时间
+//
+// 无法解析或不包含过期时间的凭证（例如 exec 插件、无 exp 的令牌）会被忽略。
+func InspectCredentials(config *rest.Config) []CredentialExpiry {
+	if config == nil {
+		return nil
+	}
+
+	now := time.Now()
+	var result []CredentialExpiry
+
+	if certData := readCredential(config.CertData, config.CertFile); len(certData) > 0 {
+		if certs := parseCertificates(certData); len(certs) > 0 {
+			// 证书链中第一个证书是客户端证书
+			result = append(result, newCredentialExpiry(CredentialTypeClientCertificate, certs[0].Subject.CommonName, certs[0].NotAfter, now))
+		}
+	}
+
+	if caData := readCredential(config.CAData, config.CAFile); len(caData) > 0 {
+		if certs := parseCertificates(caData); len(certs) > 0 {
+			// CA 包中可能有多个证书，以最早过期的为准
+			earliest := certs[0]
+			for _, cert := range certs[1:] {
+				if cert.NotAfter.Before(earliest.NotAfter) {
+					earliest = cert
+				}
+			}
+			result = append(result, newCredentialExpiry(CredentialTypeCertificateAuthority, earliest.Subject.CommonName, earliest.NotAfter, now))
+		}
+	}
+
+	token := config.BearerToken
+	if token == "" && config.BearerTokenFile != "" {
+		if data, err := os.ReadFile(config.BearerTokenFile); err == nil {
+			token = strings.TrimSpace(string(data))
+		}
+	}
+	if token != "" {
+		if subject, expiresAt, ok := parseTokenExpiry(token); ok {
+			result = append(result, newCredentialExpiry(CredentialTypeToken, subject, expiresAt, now))
+		}
+	}
+
+	return result
+}
+
+// setConfig 设置集群的 REST 配置，并重新解析凭证的过期时间
+func (c *ClusterInfo) setConfig(config *rest.Config) {
+	c.Config = config
+	c.credentials = InspectCredentials(config)
+}
+
+// Credentials 返回集群凭证的过期信息，剩余天数按当前时间计算
+//
+// 过期时间在设置 REST 配置时解析并缓存；令牌来自文件时（例如集群内的
+// ServiceAccount 令牌）文件会被定期轮换，每次重新读取。
+func (c *ClusterInfo) Credentials() []CredentialExpiry {
+	if c.Config != nil && c.Config.BearerToken == "" && c.Config.BearerTokenFile != "" {
+		return InspectCredentials(c.Config)
+	}
+	if len(c.credentials) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	result := make([]CredentialExpiry, 0, len(c.credentials))
+	for _, cred := range c.credentials {
+		result = append(result, newCredentialExpiry(cred.Type, cred.Subject, cred.ExpiresAt, now))
+	}
+	return result
+}
+
+// EarliestExpiry 返回最早过期的凭证
+func EarliestExpiry(credentials []CredentialExpiry) (CredentialExpiry, bool) {
+	if len(credentials) == 0 {
+		return CredentialExpiry{}, false
+	}
+
+	earliest := credentials[0]
+	for _, cred := range credentials[1:] {
+		if cred.ExpiresAt.Before(earliest.ExpiresAt) {
+			earliest = cred
+		}
+	}
+	return earliest, true
+}
+
+// ExpiringCluster 凭证即将过期的集群
+type ExpiringCluster struct {
+	ID          string             `json:"id"`
+	Name        string             `json:"name"`
+	Status      ClusterStatus      `json:"status"`
+	Credentials []CredentialExpiry `json:"credentials"`
+}
+
+// FindExpiringClusters 找出凭证在 days 天内过期（包括已过期）的集群，按剩余天数升序排列
+func FindExpiringClusters(clusters []*ClusterInfo, days int) []ExpiringCluster {
+	result := make([]ExpiringCluster, 0)
+	for _, cluster := range clusters {
+		var expiring []CredentialExpiry
+		for _, cred := range cluster.Credentials() {
+			if cred.DaysToExpiry <= days {
+				expiring = append(expiring, cred)
+			}
+		}
+		if len(expiring) == 0 {
+			continue
+		}
+		sort.Slice(expiring, func(i, j int) bool {
+			return expiring[i].ExpiresAt.Before(expiring[j].ExpiresAt)
+		})
+		result = append(result, ExpiringCluster{
+			ID:          cluster.ID,
+			Name:        cluster.Name,
+			Status:      cluster.Status,
+			Credentials: expiring,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Credentials[0].ExpiresAt.Before(result[j].Credentials[0].ExpiresAt)
+	})
+	return result
+}
+
+func newCredentialExpiry(credType CredentialType, subject string, expiresAt, now time.Time) CredentialExpiry {
+	return CredentialExpiry{
+		Type:         credType,
+		Subject:      subject,
+		ExpiresAt:    expiresAt,
+		DaysToExpiry: int(math.Floor(expiresAt.Sub(now).Hours() / 24)),
+	}
+}
+
+// readCredential 优先使用内联数据，否则读取文件
+func readCredential(data []byte, file string) []byte {
+	if len(data) > 0 {
+		return data
+	}
+	if file == "" {
+		return nil
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	return content
+}
+
+// parseCertificates 解析 PEM 编码的证书
+func parseCertificates(data []byte) []*x509.Certificate {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+		certs = append(certs, cert)
+	}
+	return certs
+}
+
+// parseTokenExpiry 从 JWT 令牌中读取 sub 和 exp，不校验签名
+func parseTokenExpiry(token string) (string, time.Time, bool) {
+	claims := jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &claims); err != nil {
+		return "", time.Time{}, false
+	}
+	if claims.ExpiresAt == nil {
+		return "", time.Time{}, false
+	}
+	return claims.Subject, claims.ExpiresAt.Time, true
+}
@@ -0,0 +1,238 @@
+package cluster
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"k8s.io/client-go/rest"
+)
+
+// newTestCertificate 生成在 notAfter 过期的自签名证书，返回 PEM 编码
+func newTestCertificate(t *testing.T, commonName string, notAfter time.Time) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// newTestToken 生成 JWT 令牌，expiresAt 为零值时不包含 exp
+func newTestToken(t *testing.T, subject string, expiresAt time.Time) string {
+	t.Helper()
+	claims := jwt.RegisteredClaims{Subject: subject}
+	if !expiresAt.IsZero() {
+		claims.ExpiresAt = jwt.NewNumericDate(expiresAt)
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestInspectCredentials(t *testing.T) {
+	now := time.Now()
+	in10Days := now.Add(10*24*time.Hour + time.Hour)
+	in100Days := now.Add(100*24*time.Hour + time.Hour)
+	expired := now.Add(-2*24*time.Hour + time.Hour)
+
+	caBundle := append(newTestCertificate(t, "root-ca", in100Days), newTestCertificate(t, "old-ca", in10Days)...)
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte(newTestToken(t, "system:serviceaccount:nexus:reader", in10Days)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(t.TempDir(), "client.crt")
+	if err := os.WriteFile(certFile, newTestCertificate(t, "file-user", in100Days), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	type expiry struct {
+		Type    CredentialType
+		Subject string
+		Days    int
+	}
+	tests := []struct {
+		name   string
+		config *rest.Config
+		want   []expiry
+	}{
+		{name: "没有配置", config: nil},
+		{name: "没有凭证", config: &rest.Config{Host: "https://example.com"}},
+		{
+			name:   "客户端证书",
+			config: &rest.Config{TLSClientConfig: rest.TLSClientConfig{CertData: newTestCertificate(t, "admin", in10Days)}},
+			want:   []expiry{{CredentialTypeClientCertificate, "admin", 10}},
+		},
+		{
+			name:   "客户端证书文件",
+			config: &rest.Config{TLSClientConfig: rest.TLSClientConfig{CertFile: certFile}},
+			want:   []expiry{{CredentialTypeClientCertificate, "file-user", 100}},
+		},
+		{
+			name:   "已过期的客户端证书",
+			config: &rest.Config{TLSClientConfig: rest.TLSClientConfig{CertData: newTestCertificate(t, "admin", expired)}},
+			want:   []expiry{{CredentialTypeClientCertificate, "admin", -2}},
+		},
+		{
+			name:   "CA 包以最早过期的证书为准",
+			config: &rest.Config{TLSClientConfig: rest.TLSClientConfig{CAData: caBundle}},
+			want:   []expiry{{CredentialTypeCertificateAuthority, "old-ca", 10}},
+		},
+		{
+			name:   "JWT 令牌",
+			config: &rest.Config{BearerToken: newTestToken(t, "alice", in100Days)},
+			want:   []expiry{{CredentialTypeToken, "alice", 100}},
+		},
+		{
+			name:   "令牌文件",
+			config: &rest.Config{BearerTokenFile: tokenFile},
+			want:   []expiry{{CredentialTypeToken, "system:serviceaccount:nexus:reader", 10}},
+		},
+		{name: "没有 exp 的令牌", config: &rest.Config{BearerToken: newTestToken(t, "alice", time.Time{})}},
+		{name: "不是 JWT 的令牌", config: &rest.Config{BearerToken: "abcdef.0123456789abcdef"}},
+		{name: "无法解析的证书", config: &rest.Config{TLSClientConfig: rest.TLSClientConfig{CertData: []byte("not a certificate")}}},
+		{name: "不存在的证书文件", config: &rest.Config{TLSClientConfig: rest.TLSClientConfig{CertFile: filepath.Join(t.TempDir(), "missing.crt")}}},
+		{
+			name: "多种凭证",
+			config: &rest.Config{
+				BearerToken:     newTestToken(t, "bob", expired),
+				TLSClientConfig: rest.TLSClientConfig{CertData: newTestCertificate(t, "admin", in100Days), CAData: newTestCertificate(t, "ca", in10Days)},
+			},
+			want: []expiry{
+				{CredentialTypeClientCertificate, "admin", 100},
+				{CredentialTypeCertificateAuthority, "ca", 10},
+				{CredentialTypeToken, "bob", -2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []expiry
+			for _, cred := range InspectCredentials(tt.config) {
+				got = append(got, expiry{cred.Type, cred.Subject, cred.DaysToExpiry})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("InspectCredentials() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEarliestExpiry(t *testing.T) {
+	now := time.Now()
+	credentials := []CredentialExpiry{
+		newCredentialExpiry(CredentialTypeClientCertificate, "admin", now.Add(30*24*time.Hour), now),
+		newCredentialExpiry(CredentialTypeToken, "alice", now.Add(-24*time.Hour), now),
+		newCredentialExpiry(CredentialTypeCertificateAuthority, "ca", now.Add(365*24*time.Hour), now),
+	}
+
+	earliest, ok := EarliestExpiry(credentials)
+	if !ok || earliest.Type != CredentialTypeToken || !earliest.Expired() {
+		t.Errorf("EarliestExpiry() = %+v, %v", earliest, ok)
+	}
+	if _, ok := EarliestExpiry(nil); ok {
+		t.Error("没有凭证时 EarliestExpiry 应返回 false")
+	}
+}
+
+func TestClusterCredentialsCache(t *testing.T) {
+	now := time.Now()
+	cluster := &ClusterInfo{}
+	cluster.setConfig(&rest.Config{BearerToken: newTestToken(t, "alice", now.Add(10*24*time.Hour+time.Hour))})
+
+	// 缓存的过期时间不依赖 Config，剩余天数按读取时的时间计算
+	cluster.Config.BearerToken = ""
+	cluster.credentials[0].ExpiresAt = now.Add(3*24*time.Hour + time.Hour)
+	credentials := cluster.Credentials()
+	if len(credentials) != 1 || credentials[0].DaysToExpiry != 3 {
+		t.Fatalf("Credentials() = %+v, want 3 days to expiry", credentials)
+	}
+
+	// 令牌文件会被轮换，每次重新读取
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte(newTestToken(t, "reader", now.Add(time.Hour))), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cluster.setConfig(&rest.Config{BearerTokenFile: tokenFile})
+	if err := os.WriteFile(tokenFile, []byte(newTestToken(t, "reader", now.Add(90*24*time.Hour+time.Hour))), 0600); err != nil {
+		t.Fatal(err)
+	}
+	credentials = cluster.Credentials()
+	if len(credentials) != 1 || credentials[0].DaysToExpiry != 90 {
+		t.Fatalf("Credentials() = %+v, want the rotated token with 90 days to expiry", credentials)
+	}
+
+	cluster.setConfig(nil)
+	if credentials := cluster.Credentials(); credentials != nil {
+		t.Errorf("没有配置时 Credentials() = %+v", credentials)
+	}
+}
+
+func TestFindExpiringClusters(t *testing.T) {
+	now := time.Now()
+	newCluster := func(id string, days ...int) *ClusterInfo {
+		cluster := &ClusterInfo{ID: id, Name: id}
+		for _, d := range days {
+			cluster.credentials = append(cluster.credentials, CredentialExpiry{
+				Type:      CredentialTypeClientCertificate,
+				ExpiresAt: now.Add(time.Duration(d)*24*time.Hour + time.Hour),
+			})
+		}
+		return cluster
+	}
+	clusters := []*ClusterInfo{
+		newCluster("later", 20),
+		newCluster("safe", 200),
+		newCluster("expired", -5, 300),
+		newCluster("soon", 3),
+		newCluster("none"),
+	}
+
+	tests := []struct {
+		days int
+		want []string
+	}{
+		{days: 0, want: []string{"expired"}},
+		{days: 7, want: []string{"expired", "soon"}},
+		{days: 30, want: []string{"expired", "soon", "later"}},
+		{days: 365, want: []string{"expired", "soon", "later", "safe"}},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, cluster := range FindExpiringClusters(clusters, tt.days) {
+			got = append(got, cluster.ID)
+			for _, cred := range cluster.Credentials {
+				if cred.DaysToExpiry > tt.days {
+					t.Errorf("days=%d: 集群 %s 包含 %d 天后过期的凭证", tt.days, cluster.ID, cred.DaysToExpiry)
+				}
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FindExpiringClusters(%d) = %v, want %v", tt.days, got, tt.want)
+		}
+	}
+}
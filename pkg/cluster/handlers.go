@@ -1,10 +1,16 @@
 package cluster
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"github.com/ysicing/nexus/pkg/common"
+	"github.com/ysicing/nexus/pkg/middleware"
+	"github.com/ysicing/nexus/pkg/tunnel"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/klog/v2"
 )
 
@@ -12,15 +18,21 @@ import (
 type ClusterManagerInterface interface {
 	GetCluster(clusterID string) (*ClusterInfo, error)
 	ListClusters() []*ClusterInfo
+	ListClustersBySelector(selector labels.Selector) ([]*ClusterInfo, error)
 	AddCluster(name, description, kubeconfigContent string, labels map[string]string) (*ClusterInfo, error)
 	RemoveCluster(clusterID string) error
 	SetDefaultCluster(clusterID string) error
 	UpdateClusterLabels(clusterID string, labels map[string]string) error
+	UpdateClusterKubeconfig(clusterID, kubeconfigContent string) (*ClusterInfo, error)
+	AddClusterWithTunnel(name, description, kubeconfigContent string, labels map[string]string, tunnelConfig *tunnel.Config) (*ClusterInfo, error)
+	UpdateClusterTunnel(clusterID string, tunnelConfig *tunnel.Config) (*ClusterInfo, error)
 }
 
 // Handler 集群管理处理器
 type Handler struct {
-	manager ClusterManagerInterface
+	manager   ClusterManagerInterface
+	proxies   proxyCache
+	overviews overviewCache
 }
 
 // NewHandler 创建新的集群处理器
@@ -37,28 +49,24 @@ func NewHandlerWithInterface(manager ClusterManagerInterface) *Handler {
 	}
 }
 
-// ListClusters 列出所有集群
+// ListClusters 列出所有集群，labelSelector 参数按标签过滤
 func (h *Handler) ListClusters(c *gin.Context) {
-	clusters := h.manager.ListClusters()
+	selector, err := parseLabelSelector(c.Query("labelSelector"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	clusters, err := h.manager.ListClustersBySelector(selector)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 转换为响应格式，排除敏感信息
 	response := make([]map[string]interface{}, 0, len(clusters))
 	for _, cluster := range clusters {
-		clusterData := map[string]interface{}{
-			"id":          cluster.ID,
-			"name":        cluster.Name,
-			"description": cluster.Description,
-			"server":      cluster.Server,
-			"version":     cluster.Version,
-			"status":      cluster.Status,
-			"context":     cluster.Context,
-			"labels":      cluster.Labels,
-			"createdAt":   cluster.CreatedAt,
-			"updatedAt":   cluster.UpdatedAt,
-			"lastCheck":   cluster.LastCheck,
-			"isDefault":   cluster.IsDefault,
-		}
-		response = append(response, clusterData)
+		response = append(response, clusterResponse(cluster))
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -67,16 +75,8 @@ func (h *Handler) ListClusters(c *gin.Context) {
 	})
 }
 
-// GetCluster 获取指定集群信息
-func (h *Handler) GetCluster(c *gin.Context) {
-	clusterID := c.Param("id")
-
-	cluster, err := h.manager.GetCluster(clusterID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		return
-	}
-
+// clusterResponse 将集群信息转换为响应格式，排除敏感信息
+func clusterResponse(cluster *ClusterInfo) map[string]interface{} {
 	response := map[string]interface{}{
 		"id":          cluster.ID,
 		"name":        cluster.Name,
@@ -90,17 +90,72 @@ func (h *Handler) GetCluster(c *gin.Context) {
 		"updatedAt":   cluster.UpdatedAt,
 		"lastCheck":   cluster.LastCheck,
 		"isDefault":   cluster.IsDefault,
+
+		"connectionType": cluster.ConnectionType(),
+	}
+
+	// 连接方式，不返回私钥等敏感信息
+	if summary := cluster.Tunnel.Summary(); summary != nil {
+		response["tunnel"] = summary
+	}
+
+	// 凭证过期信息
+	credentials := cluster.Credentials()
+	if len(credentials) > 0 {
+		response["credentials"] = credentials
+	}
+	if earliest, ok := EarliestExpiry(credentials); ok {
+		response["credentialDaysToExpiry"] = earliest.DaysToExpiry
 	}
 
+	return response
+}
+
+// GetCluster 获取指定集群信息
+func (h *Handler) GetCluster(c *gin.Context) {
+	clusterID := c.Param("id")
+
+	cluster, err := h.manager.GetCluster(clusterID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := clusterResponse(cluster)
+
 	c.JSON(http.StatusOK, response)
 }
 
 // AddClusterRequest 添加集群请求
 type AddClusterRequest struct {
-	Name              string            `json:"name" binding:"required"`
-	Description       string            `json:"description"`
-	KubeconfigContent string            `json:"kubeconfigContent" binding:"required"`
-	Labels            map[string]string `json:"labels"`
+	Name              string `json:"name" binding:"required"`
+	Description       string `json:"description"`
+	KubeconfigContent string `json:"kubeconfigContent"`
+	// Connection 不提供 kubeconfig 时使用的连接参数，与 KubeconfigContent 二选一
+	Connection *ClusterConnection `json:"connection"`
+	Labels     map[string]string  `json:"labels"`
+	// Tunnel 连接方式，不设置时直接连接
+	Tunnel *tunnel.Config `json:"tunnel"`
+}
+
+// UpdateClusterConnectionRequest 更新集群连接配置请求
+type UpdateClusterConnectionRequest struct {
+	KubeconfigContent string             `json:"kubeconfigContent"`
+	Connection        *ClusterConnection `json:"connection"`
+}
+
+// resolveKubeconfig 返回请求中的 kubeconfig 内容，结构化连接参数会被转换为 kubeconfig
+func resolveKubeconfig(kubeconfigContent string, connection *ClusterConnection) (string, error) {
+	switch {
+	case kubeconfigContent != "" && connection != nil:
+		return "", fmt.Errorf("kubeconfigContent and connection are mutually exclusive")
+	case connection != nil:
+		return connection.BuildKubeconfig()
+	case kubeconfigContent != "":
+		return kubeconfigContent, nil
+	default:
+		return "", fmt.Errorf("either kubeconfigContent or connection is required")
+	}
 }
 
 // AddCluster 添加新集群
@@ -111,27 +166,35 @@ func (h *Handler) AddCluster(c *gin.Context) {
 		return
 	}
 
-	cluster, err := h.manager.AddCluster(req.Name, req.Description, req.KubeconfigContent, req.Labels)
+	kubeconfigContent, err := resolveKubeconfig(req.KubeconfigContent, req.Connection)
 	if err != nil {
-		klog.Errorf("Failed to add cluster: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	response := map[string]interface{}{
-		"id":          cluster.ID,
-		"name":        cluster.Name,
-		"description": cluster.Description,
-		"server":      cluster.Server,
-		"version":     cluster.Version,
-		"status":      cluster.Status,
-		"context":     cluster.Context,
-		"labels":      cluster.Labels,
-		"createdAt":   cluster.CreatedAt,
-		"updatedAt":   cluster.UpdatedAt,
-		"isDefault":   cluster.IsDefault,
+	if err := req.Tunnel.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Connection != nil && req.Connection.ProxyURL != "" && !req.Tunnel.IsDirect() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "connection proxyUrl cannot be combined with a " + string(req.Tunnel.Type) + " tunnel"})
+		return
+	}
+
+	if err := validateLabels(req.Labels); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
+	cluster, err := h.manager.AddClusterWithTunnel(req.Name, req.Description, kubeconfigContent, req.Labels, req.Tunnel)
+	if err != nil {
+		klog.Errorf("Failed to add cluster: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := clusterResponse(cluster)
+
 	c.JSON(http.StatusCreated, response)
 }
 
@@ -169,6 +232,62 @@ func (h *Handler) SetDefaultCluster(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Default cluster set successfully"})
 }
 
+// UpdateClusterConnection 更新集群连接配置
+func (h *Handler) UpdateClusterConnection(c *gin.Context) {
+	clusterID := c.Param("id")
+
+	var req UpdateClusterConnectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	kubeconfigContent, err := resolveKubeconfig(req.KubeconfigContent, req.Connection)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.manager.GetCluster(clusterID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	cluster, err := h.manager.UpdateClusterKubeconfig(clusterID, kubeconfigContent)
+	if err != nil {
+		klog.Errorf("Failed to update cluster connection: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, clusterResponse(cluster))
+}
+
+// UpdateClusterTunnel 更新集群连接方式（直连、HTTP/SOCKS5 代理或 SSH 跳板机）
+func (h *Handler) UpdateClusterTunnel(c *gin.Context) {
+	clusterID := c.Param("id")
+
+	var req tunnel.Config
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.manager.GetCluster(clusterID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	cluster, err := h.manager.UpdateClusterTunnel(clusterID, &req)
+	if err != nil {
+		klog.Errorf("Failed to update cluster tunnel: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, clusterResponse(cluster))
+}
+
 // UpdateClusterLabelsRequest 更新集群标签请求
 type UpdateClusterLabelsRequest struct {
 	Labels map[string]string `json:"labels" binding:"required"`
@@ -184,6 +303,11 @@ func (h *Handler) UpdateClusterLabels(c *gin.Context) {
 		return
 	}
 
+	if err := validateLabels(req.Labels); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	err := h.manager.UpdateClusterLabels(clusterID, req.Labels)
 	if err != nil {
 		if err.Error() == "cluster "+clusterID+" not found" {
@@ -214,26 +338,23 @@ func (h *Handler) GetClusterStats(c *gin.Context) {
 
 	ctx := c.Request.Context()
 
-	// 获取节点信息
-	nodes, err := cluster.Client.ClientSet.CoreV1().Nodes().List(ctx,
-		metav1.ListOptions{})
-	if err != nil {
+	// 获取节点信息，从 informer 缓存中读取
+	var nodes corev1.NodeList
+	if err := cluster.Client.Client.List(ctx, &nodes); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get nodes: " + err.Error()})
 		return
 	}
 
 	// 获取Pod信息
-	pods, err := cluster.Client.ClientSet.CoreV1().Pods("").List(ctx,
-		metav1.ListOptions{})
-	if err != nil {
+	var pods corev1.PodList
+	if err := cluster.Client.Client.List(ctx, &pods); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get pods: " + err.Error()})
 		return
 	}
 
 	// 获取命名空间信息
-	namespaces, err := cluster.Client.ClientSet.CoreV1().Namespaces().List(ctx,
-		metav1.ListOptions{})
-	if err != nil {
+	var namespaces corev1.NamespaceList
+	if err := cluster.Client.Client.List(ctx, &namespaces); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get namespaces: " + err.Error()})
 		return
 	}
@@ -274,16 +395,55 @@ func (h *Handler) GetClusterStats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+// ListExpiringCredentials 列出凭证在 N 天内过期的集群
+func (h *Handler) ListExpiringCredentials(c *gin.Context) {
+	days := common.ClusterCredentialWarningDays
+	if value := c.Query("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid days parameter"})
+			return
+		}
+		days = parsed
+	}
+
+	clusters := FindExpiringClusters(h.manager.ListClusters(), days)
+
+	c.JSON(http.StatusOK, gin.H{
+		"clusters": clusters,
+		"total":    len(clusters),
+		"days":     days,
+	})
+}
+
 // RegisterRoutes 注册路由
 func (h *Handler) RegisterRoutes(group *gin.RouterGroup) {
 	clusterGroup := group.Group("/clusters")
 	{
 		clusterGroup.GET("", h.ListClusters)
 		clusterGroup.POST("", h.AddCluster)
+		clusterGroup.GET("/expiring-credentials", h.ListExpiringCredentials)
+		clusterGroup.GET("/overview", h.GetFleetOverview)
+		clusterGroup.GET("/trash", h.ListDeletedClusters)
+		clusterGroup.POST("/trash/:id/restore", h.RestoreCluster)
+		clusterGroup.DELETE("/trash/:id", middleware.RequireAdmin(), h.PurgeCluster)
 		clusterGroup.GET("/:id", h.GetCluster)
 		clusterGroup.DELETE("/:id", h.RemoveCluster)
 		clusterGroup.PUT("/:id/default", h.SetDefaultCluster)
 		clusterGroup.PUT("/:id/labels", h.UpdateClusterLabels)
+		clusterGroup.PUT("/:id/connection", h.UpdateClusterConnection)
+		clusterGroup.PUT("/:id/tunnel", h.UpdateClusterTunnel)
 		clusterGroup.GET("/:id/stats", h.GetClusterStats)
+		clusterGroup.POST("/:id/kubeconfig", h.GetProxyKubeconfig)
+		clusterGroup.GET("/:id/proxy-tokens", h.ListProxyTokens)
+		clusterGroup.DELETE("/:id/proxy-tokens/:tokenId", h.RevokeProxyToken)
 	}
 }
+
+// RegisterProxyRoutes 注册 Kubernetes API 代理路由
+//
+// 代理路由除 Nexus 登录令牌外还接受代理 kubeconfig 中的令牌，需要注册在使用 ProxyAuth 认证的路由组上。
+// 非管理员通过代理访问时模拟为对应的 Kubernetes 用户。
+func (h *Handler) RegisterProxyRoutes(group *gin.RouterGroup) {
+	group.Any("/clusters/:id/proxy/*path", h.ProxyCluster)
+}
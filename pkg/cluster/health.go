@@ -9,21 +9,30 @@ import (
 	"k8s.io/klog/v2"
 )
 
+// HealthTarget 健康检查器所需的集群管理器能力
+type HealthTarget interface {
+	ListClusters() []*ClusterInfo
+	UpdateClusterHealth(clusterID string, status ClusterStatus, checkedAt time.Time)
+}
+
 // HealthChecker 集群健康检查器
 type HealthChecker struct {
-	manager  *Manager
-	interval time.Duration
-	stopCh   chan struct{}
-	running  bool
-	mu       sync.Mutex
+	manager     HealthTarget
+	interval    time.Duration
+	warningDays int
+	stopCh      chan struct{}
+	running     bool
+	active      func() bool // 为 nil 或返回 true 时才执行检查
+	mu          sync.Mutex
 }
 
 // NewHealthChecker 创建新的健康检查器
-func NewHealthChecker(manager *Manager) *HealthChecker {
+func NewHealthChecker(manager HealthTarget) *HealthChecker {
 	return &HealthChecker{
-		manager:  manager,
-		interval: 30 * time.Second, // 默认30秒检查一次
-		stopCh:   make(chan struct{}),
+		manager:     manager,
+		interval:    30 * time.Second, // 默认30秒检查一次
+		warningDays: DefaultCredentialWarningDays,
+		stopCh:      make(chan struct{}),
 	}
 }
 
@@ -69,8 +78,23 @@ func (h *HealthChecker) Stop() {
 	close(h.stopCh)
 }
 
+// SetActive 设置判断是否执行检查的函数，多副本部署时只有主副本执行健康检查
+func (h *HealthChecker) SetActive(active func() bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.active = active
+}
+
 // checkAllClusters 检查所有集群的健康状态
 func (h *HealthChecker) checkAllClusters() {
+	h.mu.Lock()
+	active := h.active
+	h.mu.Unlock()
+	if active != nil && !active() {
+		return
+	}
+
 	clusters := h.manager.ListClusters()
 
 	var wg sync.WaitGroup
@@ -87,25 +111,35 @@ func (h *HealthChecker) checkAllClusters() {
 
 // checkClusterHealth 检查单个集群的健康状态
 func (h *HealthChecker) checkClusterHealth(cluster *ClusterInfo) {
+	status := h.probeCluster(cluster)
+
+	// 集群可用但凭证即将过期时提前告警
+	if status == ClusterStatusHealthy {
+		if earliest, ok := EarliestExpiry(cluster.Credentials()); ok && earliest.DaysToExpiry < h.getWarningDays() {
+			klog.V(2).Infof("Cluster %s %s expires in %d days", cluster.Name, earliest.Type, earliest.DaysToExpiry)
+			status = ClusterStatusWarning
+		}
+	}
+
+	h.manager.UpdateClusterHealth(cluster.ID, status, time.Now())
+}
+
+// probeCluster 探测集群连通性和节点状态
+func (h *HealthChecker) probeCluster(cluster *ClusterInfo) ClusterStatus {
 	if cluster.Client == nil {
-		h.updateClusterStatus(cluster, ClusterStatusUnreachable)
-		return
+		return ClusterStatusUnreachable
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	// 尝试获取集群版本信息来测试连接
-	_, err := cluster.Client.ClientSet.Discovery().ServerVersion()
-
-	h.manager.mu.Lock()
-	cluster.LastCheck = time.Now()
-	h.manager.mu.Unlock()
-
-	if err != nil {
+	if _, err := cluster.Client.ClientSet.Discovery().ServerVersion(); err != nil {
 		klog.V(4).Infof("Health check failed for cluster %s: %v", cluster.Name, err)
-		h.updateClusterStatus(cluster, ClusterStatusUnreachable)
-		return
+		if earliest, ok := EarliestExpiry(cluster.Credentials()); ok && earliest.Expired() {
+			klog.Warningf("Cluster %s is unreachable and its %s expired at %s", cluster.Name, earliest.Type, earliest.ExpiresAt.Format(time.RFC3339))
+		}
+		return ClusterStatusUnreachable
 	}
 
 	// 检查节点状态
@@ -113,41 +147,29 @@ func (h *HealthChecker) checkClusterHealth(cluster *ClusterInfo) {
 		metav1.ListOptions{})
 	if err != nil {
 		klog.V(4).Infof("Failed to list nodes for cluster %s: %v", cluster.Name, err)
-		h.updateClusterStatus(cluster, ClusterStatusUnhealthy)
-		return
+		return ClusterStatusUnhealthy
 	}
 
 	// 检查是否有Ready的节点
-	hasReadyNode := false
 	for _, node := range nodes.Items {
 		for _, condition := range node.Status.Conditions {
 			if condition.Type == "Ready" && condition.Status == "True" {
-				hasReadyNode = true
-				break
+				return ClusterStatusHealthy
 			}
 		}
-		if hasReadyNode {
-			break
-		}
 	}
 
-	if hasReadyNode {
-		h.updateClusterStatus(cluster, ClusterStatusHealthy)
-	} else {
-		h.updateClusterStatus(cluster, ClusterStatusUnhealthy)
-	}
+	return ClusterStatusUnhealthy
 }
 
-// updateClusterStatus 更新集群状态
-func (h *HealthChecker) updateClusterStatus(cluster *ClusterInfo, status ClusterStatus) {
-	h.manager.mu.Lock()
-	defer h.manager.mu.Unlock()
-
+// applyClusterHealth 更新集群状态和检查时间，调用方需持有管理器的写锁
+func applyClusterHealth(cluster *ClusterInfo, status ClusterStatus, checkedAt time.Time) {
+	cluster.LastCheck = checkedAt
 	if cluster.Status != status {
 		klog.V(2).Infof("Cluster %s status changed from %s to %s",
 			cluster.Name, cluster.Status, status)
 		cluster.Status = status
-		cluster.UpdatedAt = time.Now()
+		cluster.UpdatedAt = checkedAt
 	}
 }
 
@@ -158,3 +180,18 @@ func (h *HealthChecker) SetInterval(interval time.Duration) {
 
 	h.interval = interval
 }
+
+// SetCredentialWarningDays 设置凭证过期告警阈值（天）
+func (h *HealthChecker) SetCredentialWarningDays(days int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.warningDays = days
+}
+
+func (h *HealthChecker) getWarningDays() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.warningDays
+}
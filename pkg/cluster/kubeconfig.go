@@ -0,0 +1,238 @@
+package cluster
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/homedir"
+	"k8s.io/klog/v2"
+)
+
+// kubeconfigContext kubeconfig 文件中一个上下文对应的集群
+type kubeconfigContext struct {
+	clusterID   string
+	name        string
+	description string
+	server      string
+	context     string
+	config      *rest.Config
+	fingerprint string
+}
+
+// kubeconfigSyncPlan 某个 kubeconfig 文件与内存中集群之间的差异
+type kubeconfigSyncPlan struct {
+	// add 新增的上下文
+	add []kubeconfigContext
+	// update 内容发生变化、需要重建客户端的上下文
+	update []kubeconfigContext
+	// adopt 已存在但尚未记录来源（例如从数据库加载）的上下文，只需补充来源信息
+	adopt []kubeconfigContext
+	// remove 文件中已不存在的集群 ID
+	remove []string
+}
+
+// empty 是否没有任何变化
+func (p kubeconfigSyncPlan) empty() bool {
+	return len(p.add) == 0 && len(p.update) == 0 && len(p.adopt) == 0 && len(p.remove) == 0
+}
+
+// kubeconfigPaths 返回 KUBECONFIG 指定的路径列表，未设置时返回默认路径
+func kubeconfigPaths() []string {
+	if envKubeconfig := os.Getenv("KUBECONFIG"); envKubeconfig != "" {
+		var paths []string
+		for _, path := range filepath.SplitList(envKubeconfig) {
+			if path != "" {
+				paths = append(paths, path)
+			}
+		}
+		return paths
+	}
+
+	if home := homedir.HomeDir(); home != "" {
+		return []string{filepath.Join(home, ".kube", "config")}
+	}
+	return nil
+}
+
+// kubeconfigDirs 返回需要扫描和监听的 kubeconfig 目录
+func kubeconfigDirs() []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, path := range kubeconfigPaths() {
+		dir := filepath.Dir(path)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// listKubeconfigFiles 列出目录下的候选 kubeconfig 文件
+func listKubeconfigFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		// 跳过 ConfigMap 挂载产生的 ..data 等内部文件
+		if strings.HasPrefix(entry.Name(), "..") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		// 跟随符号链接判断是否是普通文件
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, path)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// loadKubeconfigContexts 解析 kubeconfig 文件中的所有上下文
+func loadKubeconfigContexts(configPath string) ([]kubeconfigContext, error) {
+	config, err := clientcmd.LoadFromFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+
+	contexts := make([]kubeconfigContext, 0, len(config.Contexts))
+	for contextName, context := range config.Contexts {
+		clusterName := context.Cluster
+		cluster, exists := config.Clusters[clusterName]
+		if !exists {
+			continue
+		}
+
+		// 构建REST配置
+		clientConfig := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{
+			CurrentContext: contextName,
+		})
+
+		restConfig, err := clientConfig.ClientConfig()
+		if err != nil {
+			klog.Warningf("Failed to create client config for context %s: %v", contextName, err)
+			continue
+		}
+
+		fingerprint, err := json.Marshal([]interface{}{context, cluster, config.AuthInfos[context.AuthInfo]})
+		if err != nil {
+			klog.Warningf("Failed to fingerprint context %s: %v", contextName, err)
+			continue
+		}
+		sum := sha256.Sum256(fingerprint)
+
+		contexts = append(contexts, kubeconfigContext{
+			clusterID:   fmt.Sprintf("kubeconfig-%s", contextName),
+			name:        fmt.Sprintf("%s (%s)", clusterName, contextName),
+			description: fmt.Sprintf("从 %s 加载的集群", filepath.Base(configPath)),
+			server:      cluster.Server,
+			context:     contextName,
+			config:      restConfig,
+			fingerprint: hex.EncodeToString(sum[:]),
+		})
+	}
+
+	sort.Slice(contexts, func(i, j int) bool {
+		return contexts[i].clusterID < contexts[j].clusterID
+	})
+	return contexts, nil
+}
+
+// readKubeconfigFile 读取 kubeconfig 文件用于同步
+//
+// 文件不存在时返回空列表，表示该文件的集群需要全部移除；
+// 文件解析失败（例如正在写入）时返回 false，此时应保持现状。
+func readKubeconfigFile(configPath string) ([]kubeconfigContext, bool) {
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return nil, true
+	}
+
+	contexts, err := loadKubeconfigContexts(configPath)
+	if err != nil {
+		klog.Warningf("Failed to load kubeconfig %s: %v", configPath, err)
+		return nil, false
+	}
+	return contexts, true
+}
+
+// planKubeconfigSync 计算 kubeconfig 文件与现有集群之间的差异
+//
+// 只有来自该文件的集群会被更新或移除，通过 API、数据库或集群内配置添加的集群不受影响。
+func planKubeconfigSync(configPath string, clusters map[string]*ClusterInfo, contexts []kubeconfigContext) kubeconfigSyncPlan {
+	var plan kubeconfigSyncPlan
+
+	desired := make(map[string]bool, len(contexts))
+	for _, ctx := range contexts {
+		desired[ctx.clusterID] = true
+
+		existing, exists := clusters[ctx.clusterID]
+		switch {
+		case !exists:
+			plan.add = append(plan.add, ctx)
+		case existing.KubeconfigPath != "" && existing.KubeconfigPath != configPath:
+			// 同名上下文已由其他文件提供，以先加载的为准
+			klog.V(4).Infof("Cluster %s is already loaded from %s, skipping %s", ctx.clusterID, existing.KubeconfigPath, configPath)
+		case existing.sourceFingerprint == ctx.fingerprint:
+			// 没有变化
+		case existing.sourceFingerprint == "" && existing.Client != nil:
+			plan.adopt = append(plan.adopt, ctx)
+		default:
+			plan.update = append(plan.update, ctx)
+		}
+	}
+
+	for id, cluster := range clusters {
+		if cluster.KubeconfigPath == configPath && !desired[id] {
+			plan.remove = append(plan.remove, id)
+		}
+	}
+	sort.Strings(plan.remove)
+
+	return plan
+}
+
+// kubeconfigClusterInfo 根据上下文创建集群信息
+func kubeconfigClusterInfo(configPath string, ctx kubeconfigContext) *ClusterInfo {
+	return &ClusterInfo{
+		ID:                ctx.clusterID,
+		Name:              ctx.name,
+		Description:       ctx.description,
+		Server:            ctx.server,
+		Status:            ClusterStatusUnknown,
+		Config:            ctx.config,
+		credentials:       InspectCredentials(ctx.config),
+		Context:           ctx.context,
+		KubeconfigPath:    configPath,
+		sourceFingerprint: ctx.fingerprint,
+	}
+}
+
+// filesFromDir 返回来自指定目录的集群所使用的 kubeconfig 文件
+func filesFromDir(clusters map[string]*ClusterInfo, dir string) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, cluster := range clusters {
+		if cluster.KubeconfigPath == "" || filepath.Dir(cluster.KubeconfigPath) != dir {
+			continue
+		}
+		if !seen[cluster.KubeconfigPath] {
+			seen[cluster.KubeconfigPath] = true
+			files = append(files, cluster.KubeconfigPath)
+		}
+	}
+	return files
+}
@@ -0,0 +1,115 @@
+package cluster
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ysicing/nexus/pkg/kube"
+)
+
+func TestPlanKubeconfigSync(t *testing.T) {
+	const configPath = "/etc/nexus/kubeconfig/a.yaml"
+	const otherPath = "/etc/nexus/kubeconfig/b.yaml"
+
+	kubeContext := func(id, fingerprint string) kubeconfigContext {
+		return kubeconfigContext{clusterID: id, fingerprint: fingerprint}
+	}
+	fromFile := func(path, fingerprint string) *ClusterInfo {
+		return &ClusterInfo{KubeconfigPath: path, sourceFingerprint: fingerprint, Client: &kube.K8sClient{}}
+	}
+
+	tests := []struct {
+		name       string
+		clusters   map[string]*ClusterInfo
+		contexts   []kubeconfigContext
+		wantAdd    []string
+		wantUpdate []string
+		wantAdopt  []string
+		wantRemove []string
+	}{
+		{
+			name:     "新增上下文",
+			clusters: map[string]*ClusterInfo{},
+			contexts: []kubeconfigContext{kubeContext("kubeconfig-a", "f1"), kubeContext("kubeconfig-b", "f2")},
+			wantAdd:  []string{"kubeconfig-a", "kubeconfig-b"},
+		},
+		{
+			name:     "内容没有变化",
+			clusters: map[string]*ClusterInfo{"kubeconfig-a": fromFile(configPath, "f1")},
+			contexts: []kubeconfigContext{kubeContext("kubeconfig-a", "f1")},
+		},
+		{
+			name:       "内容变化需要重建客户端",
+			clusters:   map[string]*ClusterInfo{"kubeconfig-a": fromFile(configPath, "f1")},
+			contexts:   []kubeconfigContext{kubeContext("kubeconfig-a", "f2")},
+			wantUpdate: []string{"kubeconfig-a"},
+		},
+		{
+			name:      "从数据库加载的集群补充来源",
+			clusters:  map[string]*ClusterInfo{"kubeconfig-a": fromFile(configPath, "")},
+			contexts:  []kubeconfigContext{kubeContext("kubeconfig-a", "f1")},
+			wantAdopt: []string{"kubeconfig-a"},
+		},
+		{
+			name:       "没有客户端的集群重建客户端",
+			clusters:   map[string]*ClusterInfo{"kubeconfig-a": {KubeconfigPath: configPath}},
+			contexts:   []kubeconfigContext{kubeContext("kubeconfig-a", "f1")},
+			wantUpdate: []string{"kubeconfig-a"},
+		},
+		{
+			name:     "同名上下文已由其他文件提供",
+			clusters: map[string]*ClusterInfo{"kubeconfig-a": fromFile(otherPath, "f1")},
+			contexts: []kubeconfigContext{kubeContext("kubeconfig-a", "f2")},
+		},
+		{
+			name: "移除文件中已不存在的上下文",
+			clusters: map[string]*ClusterInfo{
+				"kubeconfig-a": fromFile(configPath, "f1"),
+				"kubeconfig-c": fromFile(configPath, "f3"),
+				"kubeconfig-b": fromFile(configPath, "f2"),
+			},
+			contexts:   []kubeconfigContext{kubeContext("kubeconfig-a", "f1")},
+			wantRemove: []string{"kubeconfig-b", "kubeconfig-c"},
+		},
+		{
+			name: "文件被删除时只移除来自该文件的集群",
+			clusters: map[string]*ClusterInfo{
+				"kubeconfig-a": fromFile(configPath, "f1"),
+				"kubeconfig-b": fromFile(otherPath, "f2"),
+				"api-cluster":  {Client: &kube.K8sClient{}},
+				"in-cluster":   {},
+			},
+			wantRemove: []string{"kubeconfig-a"},
+		},
+	}
+
+	ids := func(contexts []kubeconfigContext) []string {
+		var result []string
+		for _, ctx := range contexts {
+			result = append(result, ctx.clusterID)
+		}
+		return result
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := planKubeconfigSync(configPath, tt.clusters, tt.contexts)
+			if got := ids(plan.add); !reflect.DeepEqual(got, tt.wantAdd) {
+				t.Errorf("add = %v, want %v", got, tt.wantAdd)
+			}
+			if got := ids(plan.update); !reflect.DeepEqual(got, tt.wantUpdate) {
+				t.Errorf("update = %v, want %v", got, tt.wantUpdate)
+			}
+			if got := ids(plan.adopt); !reflect.DeepEqual(got, tt.wantAdopt) {
+				t.Errorf("adopt = %v, want %v", got, tt.wantAdopt)
+			}
+			if !reflect.DeepEqual(plan.remove, tt.wantRemove) {
+				t.Errorf("remove = %v, want %v", plan.remove, tt.wantRemove)
+			}
+			wantEmpty := len(tt.wantAdd)+len(tt.wantUpdate)+len(tt.wantAdopt)+len(tt.wantRemove) == 0
+			if plan.empty() != wantEmpty {
+				t.Errorf("empty() = %v, want %v", plan.empty(), wantEmpty)
+			}
+		})
+	}
+}
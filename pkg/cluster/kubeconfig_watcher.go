@@ -0,0 +1,155 @@
+package cluster
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// kubeconfigDebounce 文件变化后等待的时间，合并编辑器或配置管理工具的连续写入
+	kubeconfigDebounce = 500 * time.Millisecond
+	// kubeconfigRetryInterval 目录不存在时重试监听的间隔
+	kubeconfigRetryInterval = 30 * time.Second
+)
+
+// KubeconfigWatcher 监听 kubeconfig 目录变化，并触发对应目录的集群同步
+type KubeconfigWatcher struct {
+	dirs   []string
+	sync   func(dir string)
+	stopCh chan struct{}
+	mu     sync.Mutex
+	// running 是否正在运行
+	running bool
+}
+
+// NewKubeconfigWatcher 创建 kubeconfig 监听器，sync 会在目录内容变化后被调用
+func NewKubeconfigWatcher(dirs []string, sync func(dir string)) *KubeconfigWatcher {
+	return &KubeconfigWatcher{
+		dirs:   dirs,
+		sync:   sync,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Start 启动监听，阻塞直到 Stop 被调用
+func (w *KubeconfigWatcher) Start() {
+	w.mu.Lock()
+	if w.running || len(w.dirs) == 0 {
+		w.mu.Unlock()
+		return
+	}
+	w.running = true
+	w.mu.Unlock()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		klog.Errorf("Failed to create kubeconfig watcher: %v", err)
+		return
+	}
+	defer func() {
+		_ = watcher.Close()
+	}()
+
+	// 尚未成功监听的目录，例如 ~/.kube 还不存在
+	pending := make(map[string]bool)
+	for _, dir := range w.dirs {
+		if err := watcher.Add(dir); err != nil {
+			klog.V(2).Infof("Kubeconfig directory %s is not watchable yet: %v", dir, err)
+			pending[dir] = true
+			continue
+		}
+		klog.Infof("Watching kubeconfig directory %s", dir)
+	}
+
+	retry := time.NewTicker(kubeconfigRetryInterval)
+	defer retry.Stop()
+
+	var debounce *time.Timer
+	var debounceC <-chan time.Time
+	changed := make(map[string]bool)
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			klog.V(4).Infof("Kubeconfig change detected: %s", event)
+			if w.isWatchedDir(event.Name) {
+				// 目录本身被删除或移走，监听随之失效，等待目录重新出现
+				if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+					pending[event.Name] = true
+				}
+				changed[event.Name] = true
+			} else {
+				changed[filepath.Dir(event.Name)] = true
+			}
+			if debounce == nil {
+				debounce = time.NewTimer(kubeconfigDebounce)
+			} else {
+				debounce.Reset(kubeconfigDebounce)
+			}
+			debounceC = debounce.C
+
+		case <-debounceC:
+			debounceC = nil
+			for dir := range changed {
+				w.sync(dir)
+			}
+			changed = make(map[string]bool)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			klog.Warningf("Kubeconfig watcher error: %v", err)
+
+		case <-retry.C:
+			for dir := range pending {
+				if err := watcher.Add(dir); err != nil {
+					continue
+				}
+				klog.Infof("Watching kubeconfig directory %s", dir)
+				delete(pending, dir)
+				w.sync(dir)
+			}
+
+		case <-w.stopCh:
+			if debounce != nil {
+				debounce.Stop()
+			}
+			klog.Info("Stopping kubeconfig watcher")
+			return
+		}
+	}
+}
+
+// isWatchedDir 路径是否是被监听的目录本身
+func (w *KubeconfigWatcher) isWatchedDir(path string) bool {
+	for _, dir := range w.dirs {
+		if filepath.Clean(path) == filepath.Clean(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stop 停止监听
+func (w *KubeconfigWatcher) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.running {
+		return
+	}
+
+	w.running = false
+	close(w.stopCh)
+}
@@ -0,0 +1,41 @@
+package cluster
+
+import (
+	"fmt"
+
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// validateLabels 校验集群标签符合 Kubernetes 标签格式，使其可以通过标签选择器查询
+func validateLabels(set map[string]string) error {
+	if errs := metav1validation.ValidateLabels(set, field.NewPath("labels")); len(errs) > 0 {
+		return errs.ToAggregate()
+	}
+	return nil
+}
+
+// parseLabelSelector 解析 Kubernetes 标签选择器，支持 =、==、!=、in、notin、存在和不存在等语法
+func parseLabelSelector(value string) (labels.Selector, error) {
+	selector, err := labels.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid labelSelector: %w", err)
+	}
+	return selector, nil
+}
+
+// filterClusters 返回标签匹配选择器的集群，用于没有数据库的集群管理器
+func filterClusters(clusters []*ClusterInfo, selector labels.Selector) []*ClusterInfo {
+	if selector.Empty() {
+		return clusters
+	}
+
+	matched := make([]*ClusterInfo, 0, len(clusters))
+	for _, cluster := range clusters {
+		if selector.Matches(labels.Set(cluster.Labels)) {
+			matched = append(matched, cluster)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,85 @@
+package cluster
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+		wantErr  bool
+	}{
+		{name: "空选择器", selector: ""},
+		{name: "等于", selector: "env=prod"},
+		{name: "双等号", selector: "env==prod"},
+		{name: "不等于", selector: "env!=prod"},
+		{name: "in", selector: "region in (beijing,shanghai)"},
+		{name: "notin", selector: "region notin (beijing)"},
+		{name: "存在", selector: "env"},
+		{name: "不存在", selector: "!env"},
+		{name: "多个条件", selector: "env=prod,region in (beijing),!deprecated"},
+		{name: "缺少右括号", selector: "region in (beijing", wantErr: true},
+		{name: "非法的键", selector: "-env=prod", wantErr: true},
+		{name: "非法的值", selector: "env=a b", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := parseLabelSelector(tt.selector)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseLabelSelector(%q) 应该返回错误", tt.selector)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseLabelSelector(%q) 返回错误: %v", tt.selector, err)
+			}
+			if selector.Empty() != (tt.selector == "") {
+				t.Errorf("parseLabelSelector(%q).Empty() = %v", tt.selector, selector.Empty())
+			}
+		})
+	}
+}
+
+func TestFilterClusters(t *testing.T) {
+	clusters := []*ClusterInfo{
+		{ID: "prod-bj", Labels: map[string]string{"env": "prod", "region": "beijing"}},
+		{ID: "prod-sh", Labels: map[string]string{"env": "prod", "region": "shanghai", "tier": "3"}},
+		{ID: "dev", Labels: map[string]string{"env": "dev", "tier": "1"}},
+		{ID: "unlabeled"},
+	}
+
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{selector: "", want: []string{"prod-bj", "prod-sh", "dev", "unlabeled"}},
+		{selector: "env=prod", want: []string{"prod-bj", "prod-sh"}},
+		{selector: "env!=prod", want: []string{"dev", "unlabeled"}},
+		{selector: "region in (beijing,guangzhou)", want: []string{"prod-bj"}},
+		{selector: "region notin (beijing)", want: []string{"prod-sh", "dev", "unlabeled"}},
+		{selector: "region", want: []string{"prod-bj", "prod-sh"}},
+		{selector: "!region", want: []string{"dev", "unlabeled"}},
+		{selector: "tier>2", want: []string{"prod-sh"}},
+		{selector: "env=prod,tier<2", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			selector, err := parseLabelSelector(tt.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0)
+			for _, cluster := range filterClusters(clusters, selector) {
+				got = append(got, cluster.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterClusters(%q) = %v, want %v", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
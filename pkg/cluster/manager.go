@@ -3,14 +3,15 @@ package cluster
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/ysicing/nexus/pkg/common"
 	"github.com/ysicing/nexus/pkg/kube"
+	"github.com/ysicing/nexus/pkg/tunnel"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
 	"k8s.io/klog/v2"
 )
 
@@ -40,6 +41,16 @@ type ClusterInfo struct {
 	PrometheusUsername string `json:"prometheusUsername,omitempty"`
 	PrometheusPassword string `json:"prometheusPassword,omitempty"`
 	PrometheusEnabled  bool   `json:"prometheusEnabled"`
+
+	// Tunnel 连接方式，nil 表示直接连接
+	Tunnel *tunnel.Config `json:"-"`
+
+	// credentials 解析 Config 得到的凭证过期信息，在设置 Config 时计算一次
+	credentials []CredentialExpiry
+	// sourceFingerprint 从 kubeconfig 文件加载时对应上下文内容的摘要，用于检测文件变化
+	sourceFingerprint string
+	// tunnel 当前客户端使用的连接通道
+	tunnel *tunnel.Tunnel
 }
 
 // ClusterStatus 集群状态
@@ -48,16 +59,19 @@ type ClusterStatus string
 const (
 	ClusterStatusHealthy     ClusterStatus = "healthy"
 	ClusterStatusUnhealthy   ClusterStatus = "unhealthy"
+	ClusterStatusWarning     ClusterStatus = "warning"
 	ClusterStatusUnreachable ClusterStatus = "unreachable"
 	ClusterStatusUnknown     ClusterStatus = "unknown"
 )
 
 // Manager 集群管理器
 type Manager struct {
-	clusters      map[string]*ClusterInfo
-	defaultID     string
-	mu            sync.RWMutex
-	healthChecker *HealthChecker
+	clusters          map[string]*ClusterInfo
+	defaultID         string
+	mu                sync.RWMutex
+	healthChecker     *HealthChecker
+	kubeconfigWatcher *KubeconfigWatcher
+	nodeTerminalGC    *NodeTerminalGC
 }
 
 // NewManager 创建新的集群管理器
@@ -66,6 +80,8 @@ func NewManager() *Manager {
 		clusters: make(map[string]*ClusterInfo),
 	}
 	m.healthChecker = NewHealthChecker(m)
+	m.healthChecker.SetCredentialWarningDays(common.ClusterCredentialWarningDays)
+	m.nodeTerminalGC = NewNodeTerminalGC(m)
 	return m
 }
 
@@ -79,6 +95,14 @@ func (m *Manager) Initialize() error {
 	// 启动健康检查
 	go m.healthChecker.Start()
 
+	// 监听 kubeconfig 文件变化
+	if m.kubeconfigWatcher != nil {
+		go m.kubeconfigWatcher.Start()
+	}
+
+	// 清理残留的节点终端 Pod
+	go m.nodeTerminalGC.Start()
+
 	return nil
 }
 
@@ -93,6 +117,7 @@ func (m *Manager) discoverLocalClusters() error {
 			Server:      config.Host,
 			Status:      ClusterStatusUnknown,
 			Config:      config,
+			credentials: InspectCredentials(config),
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
 			IsDefault:   true,
@@ -122,146 +147,145 @@ func (m *Manager) discoverLocalClusters() error {
 
 // discoverKubeconfigClusters 从kubeconfig发现集群
 func (m *Manager) discoverKubeconfigClusters() error {
-	kubeconfigPath := ""
-	if home := homedir.HomeDir(); home != "" {
-		kubeconfigPath = filepath.Join(home, ".kube", "config")
+	dirs := kubeconfigDirs()
+	if len(dirs) == 0 {
+		return fmt.Errorf("could not find kubeconfig file")
 	}
 
-	if envKubeconfig := os.Getenv("KUBECONFIG"); envKubeconfig != "" {
-		kubeconfigPath = envKubeconfig
-	}
+	// 扫描kubeconfig目录下的所有配置文件，之后持续监听变化
+	m.kubeconfigWatcher = NewKubeconfigWatcher(dirs, m.syncKubeconfigDir)
 
-	if kubeconfigPath == "" {
-		return fmt.Errorf("could not find kubeconfig file")
+	for _, dir := range dirs {
+		m.syncKubeconfigDir(dir)
 	}
 
-	// 扫描kubeconfig目录下的所有配置文件
-	configDir := filepath.Dir(kubeconfigPath)
-	files, err := os.ReadDir(configDir)
-	if err != nil {
-		return fmt.Errorf("failed to read kubeconfig directory: %w", err)
+	klog.Infof("Discovered kubeconfig clusters in %v", dirs)
+	return nil
+}
+
+// syncKubeconfigDir 同步目录下所有 kubeconfig 文件，包括已被删除的文件
+func (m *Manager) syncKubeconfigDir(dir string) {
+	files, err := listKubeconfigFiles(dir)
+	if err != nil && !os.IsNotExist(err) {
+		klog.Warningf("Failed to read kubeconfig directory %s: %v", dir, err)
+		return
 	}
 
-	count := 0
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
+	m.mu.RLock()
+	known := filesFromDir(m.clusters, dir)
+	m.mu.RUnlock()
 
-		configPath := filepath.Join(configDir, file.Name())
-		if err := m.loadKubeconfigFile(configPath); err != nil {
-			klog.Warningf("Failed to load kubeconfig %s: %v", configPath, err)
-			continue
-		}
-		count++
+	for _, file := range append(files, known...) {
+		m.syncKubeconfigFile(file)
 	}
-
-	klog.Infof("Discovered %d kubeconfig files", count)
-	return nil
 }
 
-// loadKubeconfigFile 加载单个kubeconfig文件
-func (m *Manager) loadKubeconfigFile(configPath string) error {
-	config, err := clientcmd.LoadFromFile(configPath)
-	if err != nil {
-		return fmt.Errorf("failed to load kubeconfig: %w", err)
+// syncKubeconfigFile 根据 kubeconfig 文件内容新增、更新或移除集群
+func (m *Manager) syncKubeconfigFile(configPath string) {
+	contexts, ok := readKubeconfigFile(configPath)
+	if !ok {
+		return
 	}
 
-	for contextName, context := range config.Contexts {
-		clusterName := context.Cluster
-		cluster, exists := config.Clusters[clusterName]
-		if !exists {
-			continue
-		}
+	m.mu.RLock()
+	plan := planKubeconfigSync(configPath, m.clusters, contexts)
+	m.mu.RUnlock()
 
-		// 构建REST配置
-		clientConfig := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{
-			CurrentContext: contextName,
-		})
+	if plan.empty() {
+		return
+	}
 
-		restConfig, err := clientConfig.ClientConfig()
-		if err != nil {
-			klog.Warningf("Failed to create client config for context %s: %v", contextName, err)
-			continue
+	for _, ctx := range plan.adopt {
+		m.mu.Lock()
+		if cluster, exists := m.clusters[ctx.clusterID]; exists {
+			cluster.KubeconfigPath = configPath
+			cluster.sourceFingerprint = ctx.fingerprint
 		}
+		m.mu.Unlock()
+	}
 
-		clusterID := fmt.Sprintf("kubeconfig-%s", contextName)
-		clusterInfo := &ClusterInfo{
-			ID:          clusterID,
-			Name:        fmt.Sprintf("%s (%s)", clusterName, contextName),
-			Description: fmt.Sprintf("从 %s 加载的集群", filepath.Base(configPath)),
-			Server:      cluster.Server,
-			Status:      ClusterStatusUnknown,
-			Config:      restConfig,
-			Context:     contextName,
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-			IsDefault:   m.defaultID == "",
+	for _, ctx := range append(plan.add, plan.update...) {
+		clusterInfo := kubeconfigClusterInfo(configPath, ctx)
+		clusterInfo.CreatedAt = time.Now()
+		clusterInfo.UpdatedAt = time.Now()
+
+		// 沿用通过 API 设置的连接方式
+		m.mu.RLock()
+		if old, exists := m.clusters[clusterInfo.ID]; exists {
+			clusterInfo.Tunnel = old.Tunnel
 		}
+		m.mu.RUnlock()
 
 		// 尝试创建客户端
-		if client, err := kube.NewK8sClientFromConfig(restConfig); err == nil {
-			clusterInfo.Client = client
-			if version, err := m.getClusterVersion(client); err == nil {
-				clusterInfo.Version = version
-			}
-		} else {
-			klog.Warningf("Failed to create client for cluster %s: %v", clusterInfo.Name, err)
+		client, t, err := newClusterClient(ctx.config, clusterInfo.Tunnel)
+		if err != nil {
 			// 如果无法创建客户端，跳过这个集群
+			klog.Warningf("Failed to create client for cluster %s: %v", clusterInfo.Name, err)
 			continue
 		}
+		clusterInfo.Client = client
+		clusterInfo.tunnel = t
+		if version, err := m.getClusterVersion(client); err == nil {
+			clusterInfo.Version = version
+		}
 
 		m.mu.Lock()
-		m.clusters[clusterID] = clusterInfo
+		old, exists := m.clusters[clusterInfo.ID]
+		if exists {
+			// 保留通过 API 设置的属性
+			clusterInfo.Labels = old.Labels
+			clusterInfo.CreatedAt = old.CreatedAt
+			clusterInfo.IsDefault = old.IsDefault
+		}
+		m.clusters[clusterInfo.ID] = clusterInfo
 		if m.defaultID == "" {
-			m.defaultID = clusterID
+			m.defaultID = clusterInfo.ID
+			clusterInfo.IsDefault = true
 		}
 		m.mu.Unlock()
 
-		klog.Infof("Added cluster: %s", clusterInfo.Name)
-	}
-
-	return nil
-}
-
-// AddCluster 添加新集群
-func (m *Manager) AddCluster(name, description, kubeconfigContent string, labels map[string]string) (*ClusterInfo, error) {
-	config, err := clientcmd.Load([]byte(kubeconfigContent))
-	if err != nil {
-		return nil, fmt.Errorf("invalid kubeconfig: %w", err)
+		if exists {
+			old.disconnect()
+			klog.Infof("Reloaded cluster: %s", clusterInfo.Name)
+		} else {
+			klog.Infof("Added cluster: %s", clusterInfo.Name)
+		}
 	}
 
-	// 使用当前上下文
-	currentContext := config.CurrentContext
-	if currentContext == "" {
-		// 如果没有当前上下文，使用第一个可用的
-		for contextName := range config.Contexts {
-			currentContext = contextName
-			break
+	for _, clusterID := range plan.remove {
+		m.mu.Lock()
+		cluster, exists := m.clusters[clusterID]
+		if exists {
+			m.removeClusterLocked(clusterID)
 		}
-	}
+		m.mu.Unlock()
 
-	if currentContext == "" {
-		return nil, fmt.Errorf("no valid context found in kubeconfig")
+		if exists {
+			cluster.disconnect()
+			klog.Infof("Removed cluster %s: no longer present in %s", cluster.Name, configPath)
+		}
 	}
+}
 
-	// 构建REST配置
-	clientConfig := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{
-		CurrentContext: currentContext,
-	})
+// AddCluster 添加新集群
+func (m *Manager) AddCluster(name, description, kubeconfigContent string, labels map[string]string) (*ClusterInfo, error) {
+	return m.AddClusterWithTunnel(name, description, kubeconfigContent, labels, nil)
+}
 
-	restConfig, err := clientConfig.ClientConfig()
+// AddClusterWithTunnel 添加通过指定连接方式访问的新集群
+func (m *Manager) AddClusterWithTunnel(name, description, kubeconfigContent string, labels map[string]string, tunnelConfig *tunnel.Config) (*ClusterInfo, error) {
+	restConfig, currentContext, err := restConfigFromKubeconfig(kubeconfigContent)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create client config: %w", err)
+		return nil, err
 	}
 
 	// 测试连接
-	client, err := kube.NewK8sClientFromConfig(restConfig)
+	client, t, err := newClusterClient(restConfig, tunnelConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
 	}
 
-	clusterID := fmt.Sprintf("custom-%d", time.Now().Unix())
+	clusterID := newClusterID()
 	clusterInfo := &ClusterInfo{
 		ID:          clusterID,
 		Name:        name,
@@ -269,11 +293,16 @@ func (m *Manager) AddCluster(name, description, kubeconfigContent string, labels
 		Server:      restConfig.Host,
 		Status:      ClusterStatusUnknown,
 		Config:      restConfig,
+		credentials: InspectCredentials(restConfig),
 		Client:      client,
 		Context:     currentContext,
 		Labels:      labels,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
+
+		KubeconfigContent: kubeconfigContent,
+		Tunnel:            tunnelConfig,
+		tunnel:            t,
 	}
 
 	// 获取集群版本
@@ -282,6 +311,11 @@ func (m *Manager) AddCluster(name, description, kubeconfigContent string, labels
 	}
 
 	m.mu.Lock()
+	if existing := findCluster(m.clusters, clusterInfo.Server, currentContext); existing != nil {
+		m.mu.Unlock()
+		clusterInfo.disconnect()
+		return nil, fmt.Errorf("cluster %s already uses server %s and context %s, update its connection instead", existing.ID, existing.Server, currentContext)
+	}
 	m.clusters[clusterID] = clusterInfo
 	if len(m.clusters) == 1 {
 		m.defaultID = clusterID
@@ -293,6 +327,121 @@ func (m *Manager) AddCluster(name, description, kubeconfigContent string, labels
 	return clusterInfo, nil
 }
 
+// newClusterID 生成通过 API 添加的集群 ID，同一秒内添加多个集群也不会重复
+func newClusterID() string {
+	return "custom-" + uuid.NewString()
+}
+
+// findCluster 查找通过 API 添加、API Server 和上下文相同的集群，同一个 kubeconfig 只能添加一次
+func findCluster(clusters map[string]*ClusterInfo, server, contextName string) *ClusterInfo {
+	for _, cluster := range clusters {
+		if cluster.ID == "in-cluster" || cluster.KubeconfigPath != "" {
+			continue
+		}
+		if cluster.Server == server && cluster.Context == contextName {
+			return cluster
+		}
+	}
+	return nil
+}
+
+// UpdateClusterKubeconfig 更新通过 API 添加的集群的连接配置，并重建客户端
+func (m *Manager) UpdateClusterKubeconfig(clusterID, kubeconfigContent string) (*ClusterInfo, error) {
+	m.mu.RLock()
+	cluster, exists := m.clusters[clusterID]
+	m.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("cluster %s not found", clusterID)
+	}
+	if err := checkConnectionUpdatable(cluster); err != nil {
+		return nil, err
+	}
+
+	restConfig, currentContext, err := restConfigFromKubeconfig(kubeconfigContent)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := m.reconnectCluster(clusterID, restConfig, cluster.Tunnel, func(updated *ClusterInfo) {
+		updated.Server = restConfig.Host
+		updated.Context = currentContext
+		updated.KubeconfigContent = kubeconfigContent
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	klog.Infof("Updated connection of cluster: %s", updated.Name)
+	return updated, nil
+}
+
+// UpdateClusterTunnel 更新集群的连接方式，并重建客户端
+func (m *Manager) UpdateClusterTunnel(clusterID string, tunnelConfig *tunnel.Config) (*ClusterInfo, error) {
+	if err := tunnelConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	cluster, exists := m.clusters[clusterID]
+	m.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("cluster %s not found", clusterID)
+	}
+	if clusterID == "in-cluster" {
+		return nil, fmt.Errorf("cannot change connection type of in-cluster configuration")
+	}
+
+	if cluster.Config == nil {
+		return nil, fmt.Errorf("cluster %s config is not available", clusterID)
+	}
+
+	updated, err := m.reconnectCluster(clusterID, cluster.Config, tunnelConfig, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	klog.Infof("Updated connection type of cluster %s: %s", updated.Name, updated.ConnectionType())
+	return updated, nil
+}
+
+// reconnectCluster 使用新的 REST 配置和连接方式重建集群客户端，apply 用于更新其余字段
+func (m *Manager) reconnectCluster(clusterID string, config *rest.Config, tunnelConfig *tunnel.Config, apply func(*ClusterInfo)) (*ClusterInfo, error) {
+	client, t, err := newClusterClient(config, tunnelConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+
+	// 获取集群版本
+	version, _ := m.getClusterVersion(client)
+
+	m.mu.Lock()
+	old, exists := m.clusters[clusterID]
+	if !exists {
+		m.mu.Unlock()
+		client.Stop()
+		t.Close()
+		return nil, fmt.Errorf("cluster %s not found", clusterID)
+	}
+	updated := *old
+	updated.setConfig(config)
+	updated.Client = client
+	updated.Tunnel = tunnelConfig
+	updated.tunnel = t
+	updated.Status = ClusterStatusUnknown
+	updated.UpdatedAt = time.Now()
+	if version != "" {
+		updated.Version = version
+	}
+	if apply != nil {
+		apply(&updated)
+	}
+	m.clusters[clusterID] = &updated
+	m.mu.Unlock()
+
+	old.disconnect()
+	return &updated, nil
+}
+
 // RemoveCluster 移除集群
 func (m *Manager) RemoveCluster(clusterID string) error {
 	m.mu.Lock()
@@ -308,6 +457,15 @@ func (m *Manager) RemoveCluster(clusterID string) error {
 		return fmt.Errorf("cannot remove in-cluster configuration")
 	}
 
+	m.removeClusterLocked(clusterID)
+	cluster.disconnect()
+
+	klog.Infof("Removed cluster: %s", cluster.Name)
+	return nil
+}
+
+// removeClusterLocked 从内存中移除集群，调用方需持有写锁
+func (m *Manager) removeClusterLocked(clusterID string) {
 	delete(m.clusters, clusterID)
 
 	// 如果删除的是默认集群，选择新的默认集群
@@ -319,9 +477,6 @@ func (m *Manager) RemoveCluster(clusterID string) error {
 			break
 		}
 	}
-
-	klog.Infof("Removed cluster: %s", cluster.Name)
-	return nil
 }
 
 // GetCluster 获取指定集群
@@ -362,6 +517,11 @@ func (m *Manager) ListClusters() []*ClusterInfo {
 	return clusters
 }
 
+// ListClustersBySelector 列出标签匹配选择器的集群
+func (m *Manager) ListClustersBySelector(selector labels.Selector) ([]*ClusterInfo, error) {
+	return filterClusters(m.ListClusters(), selector), nil
+}
+
 // SetDefaultCluster 设置默认集群
 func (m *Manager) SetDefaultCluster(clusterID string) error {
 	m.mu.Lock()
@@ -402,6 +562,16 @@ func (m *Manager) UpdateClusterLabels(clusterID string, labels map[string]string
 	return nil
 }
 
+// UpdateClusterHealth 更新集群健康状态
+func (m *Manager) UpdateClusterHealth(clusterID string, status ClusterStatus, checkedAt time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if cluster, exists := m.clusters[clusterID]; exists {
+		applyClusterHealth(cluster, status, checkedAt)
+	}
+}
+
 // getClusterVersion 获取集群版本
 func (m *Manager) getClusterVersion(client *kube.K8sClient) (string, error) {
 	version, err := client.ClientSet.Discovery().ServerVersion()
@@ -417,4 +587,8 @@ func (m *Manager) Stop() {
 	if m.healthChecker != nil {
 		m.healthChecker.Stop()
 	}
+	if m.kubeconfigWatcher != nil {
+		m.kubeconfigWatcher.Stop()
+	}
+	m.nodeTerminalGC.Stop()
 }
@@ -1,52 +1,84 @@
 package cluster
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 	"sync"
 	"time"
 
+	"github.com/ysicing/nexus/pkg/common"
 	"github.com/ysicing/nexus/pkg/database"
 	"github.com/ysicing/nexus/pkg/kube"
+	"github.com/ysicing/nexus/pkg/leader"
 	"github.com/ysicing/nexus/pkg/models"
+	"github.com/ysicing/nexus/pkg/tunnel"
+	"gorm.io/gorm"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
 	"k8s.io/klog/v2"
 )
 
 // ManagerWithDB 带数据库支持的集群管理器
 type ManagerWithDB struct {
-	clusters      map[string]*ClusterInfo
-	defaultID     string
-	mu            sync.RWMutex
-	healthChecker *HealthChecker
-	db            *database.Database
-	repo          models.ClusterRepository
+	clusters          map[string]*ClusterInfo
+	defaultID         string
+	mu                sync.RWMutex
+	healthChecker     *HealthChecker
+	kubeconfigWatcher *KubeconfigWatcher
+	db                *database.Database
+	repo              models.ClusterRepository
+	proxyTokens       models.ProxyTokenRepository
+	elector           *leader.Elector
+	leaderMu          sync.Mutex
+	leaderCancel      context.CancelFunc
+	nodeTerminalGC    *NodeTerminalGC
+	stopCh            chan struct{}
+
+	// pendingWrites 已修改内存、尚未写入数据库的集群，writtenAt 为集群最近一次写入数据库的序号，
+	// 从数据库同步时跳过这些集群，避免用旧的数据库内容覆盖本副本刚做的修改
+	pendingWrites map[string]int
+	writtenAt     map[string]uint64
+	writeSeq      uint64
 }
 
 // NewManagerWithDB 创建带数据库支持的集群管理器
 func NewManagerWithDB(db *database.Database) *ManagerWithDB {
 	m := &ManagerWithDB{
-		clusters: make(map[string]*ClusterInfo),
-		db:       db,
-		repo:     db.GetClusterRepository(),
+		clusters:      make(map[string]*ClusterInfo),
+		db:            db,
+		repo:          db.GetClusterRepository(),
+		proxyTokens:   models.NewProxyTokenRepository(db.GetDB()),
+		stopCh:        make(chan struct{}),
+		pendingWrites: make(map[string]int),
+		writtenAt:     make(map[string]uint64),
 	}
-
-	// 创建一个适配器来兼容 HealthChecker
-	adapter := &Manager{
-		clusters:      m.clusters,
-		defaultID:     m.defaultID,
-		mu:            m.mu,
-		healthChecker: nil, // 避免循环引用
-	}
-	m.healthChecker = NewHealthChecker(adapter)
+	// 多个副本共享同一个数据库时，只有主副本执行健康检查、kubeconfig 扫描和清理任务
+	m.elector = leader.NewElector(models.NewLeaseRepository(db.GetDB()), clusterManagerLease, common.ReplicaID)
+	m.elector.OnStartedLeading(m.startLeading)
+	m.elector.OnStoppedLeading(m.stopLeading)
+
+	m.healthChecker = NewHealthChecker(m)
+	m.healthChecker.SetActive(m.isLeader)
+	m.healthChecker.SetCredentialWarningDays(common.ClusterCredentialWarningDays)
+	m.kubeconfigWatcher = NewKubeconfigWatcher(kubeconfigDirs(), func(dir string) {
+		if m.isLeader() {
+			m.syncKubeconfigDir(context.Background(), dir)
+		}
+	})
+	m.nodeTerminalGC = NewNodeTerminalGC(m)
+	m.nodeTerminalGC.SetActive(m.isLeader)
 
 	return m
 }
 
+// Database 返回集群管理器使用的数据库
+func (m *ManagerWithDB) Database() *database.Database {
+	return m.db
+}
+
 // Initialize 初始化集群管理器，参考 k8m 项目的四步加载机制
 func (m *ManagerWithDB) Initialize() error {
 	klog.Info("开始初始化集群管理器...")
@@ -61,21 +93,79 @@ func (m *ManagerWithDB) Initialize() error {
 		klog.Warningf("注册集群内配置失败: %v", err)
 	}
 
+	// 第三步和第四步（扫描本地 kubeconfig 文件、确保有默认集群）由主副本执行，
+	// 成为主副本时在 startLeading 中完成
+	if !m.elector.TryAcquireOrRenew() {
+		klog.Infof("当前副本 %s 不是主副本，从数据库同步集群配置", m.elector.Identity())
+	}
+	go m.elector.Run()
+
+	// 启动健康检查
+	go m.healthChecker.Start()
+
+	// 监听 kubeconfig 文件变化
+	go m.kubeconfigWatcher.Start()
+
+	// 定期清理回收站
+	go m.runTrashPurger()
+
+	// 清理残留的节点终端 Pod
+	go m.nodeTerminalGC.Start()
+
+	// 同步其他副本对集群配置的修改
+	go m.runRegistrySync()
+
+	klog.Infof("集群管理器初始化完成，共加载 %d 个集群", len(m.clusters))
+	return nil
+}
+
+// startLeading 成为主副本时在单独的协程中执行主副本任务
+//
+// 创建集群客户端需要等待缓存同步，可能超过租约有效期，不能阻塞续约。
+func (m *ManagerWithDB) startLeading() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m.leaderMu.Lock()
+	if m.leaderCancel != nil {
+		m.leaderCancel()
+	}
+	m.leaderCancel = cancel
+	m.leaderMu.Unlock()
+
+	go m.runLeaderTasks(ctx)
+}
+
+// stopLeading 失去主副本身份时取消尚未完成的主副本任务
+func (m *ManagerWithDB) stopLeading() {
+	m.leaderMu.Lock()
+	defer m.leaderMu.Unlock()
+
+	if m.leaderCancel != nil {
+		m.leaderCancel()
+		m.leaderCancel = nil
+	}
+}
+
+// runLeaderTasks 扫描本地 kubeconfig 文件并确保有默认集群，失去主副本身份后停止
+func (m *ManagerWithDB) runLeaderTasks(ctx context.Context) {
 	// 第三步：扫描本地 kubeconfig 文件
-	if err := m.scanClustersInDir(); err != nil {
+	if err := m.scanClustersInDir(ctx); err != nil {
 		klog.Warningf("扫描本地集群配置失败: %v", err)
 	}
+	if ctx.Err() != nil {
+		klog.Info("已失去主副本身份，停止主副本任务")
+		return
+	}
 
 	// 第四步：确保有默认集群
 	if err := m.ensureDefaultCluster(); err != nil {
 		klog.Warningf("设置默认集群失败: %v", err)
 	}
+}
 
-	// 启动健康检查
-	go m.healthChecker.Start()
-
-	klog.Infof("集群管理器初始化完成，共加载 %d 个集群", len(m.clusters))
-	return nil
+// isLeader 当前副本是否为主副本
+func (m *ManagerWithDB) isLeader() bool {
+	return m.elector == nil || m.elector.IsLeader()
 }
 
 // loadClustersFromDB 第一步：从数据库加载已存储的集群
@@ -137,6 +227,7 @@ func (m *ManagerWithDB) registerInCluster() error {
 		Server:      config.Host,
 		Status:      ClusterStatusUnknown,
 		Config:      config,
+		credentials: InspectCredentials(config),
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
@@ -155,6 +246,7 @@ func (m *ManagerWithDB) registerInCluster() error {
 	// 保存到内存
 	m.mu.Lock()
 	m.clusters[clusterID] = clusterInfo
+	m.beginWriteLocked(clusterID)
 	if m.defaultID == "" {
 		m.defaultID = clusterID
 		clusterInfo.IsDefault = true
@@ -165,132 +257,162 @@ func (m *ManagerWithDB) registerInCluster() error {
 	if err := m.saveClusterToDB(clusterInfo, true); err != nil {
 		klog.Warningf("保存集群内配置到数据库失败: %v", err)
 	}
+	m.endWrite(clusterID)
 
 	klog.Info("成功注册集群内配置")
 	return nil
 }
 
 // scanClustersInDir 第三步：扫描本地 kubeconfig 文件
-func (m *ManagerWithDB) scanClustersInDir() error {
+func (m *ManagerWithDB) scanClustersInDir(ctx context.Context) error {
 	klog.Info("正在扫描本地 kubeconfig 文件...")
 
-	kubeconfigPath := ""
-	if home := homedir.HomeDir(); home != "" {
-		kubeconfigPath = filepath.Join(home, ".kube", "config")
-	}
-
-	if envKubeconfig := os.Getenv("KUBECONFIG"); envKubeconfig != "" {
-		kubeconfigPath = envKubeconfig
-	}
-
-	if kubeconfigPath == "" {
+	dirs := kubeconfigDirs()
+	if len(dirs) == 0 {
 		klog.Warning("未找到 kubeconfig 文件路径")
 		return nil
 	}
 
 	// 扫描kubeconfig目录下的所有配置文件
-	configDir := filepath.Dir(kubeconfigPath)
-	files, err := os.ReadDir(configDir)
-	if err != nil {
-		return fmt.Errorf("读取 kubeconfig 目录失败: %w", err)
+	for _, dir := range dirs {
+		m.syncKubeconfigDir(ctx, dir)
 	}
-
-	count := 0
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-
-		configPath := filepath.Join(configDir, file.Name())
-		if err := m.loadKubeconfigFile(configPath); err != nil {
-			klog.Warningf("加载 kubeconfig 文件失败 %s: %v", configPath, err)
-			continue
-		}
-		count++
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	klog.Infof("扫描了 %d 个 kubeconfig 文件", count)
+	klog.Infof("扫描了 kubeconfig 目录: %v", dirs)
 	return nil
 }
 
-// loadKubeconfigFile 加载单个 kubeconfig 文件
-func (m *ManagerWithDB) loadKubeconfigFile(configPath string) error {
-	config, err := clientcmd.LoadFromFile(configPath)
-	if err != nil {
-		return fmt.Errorf("加载 kubeconfig 失败: %w", err)
+// syncKubeconfigDir 同步目录下所有 kubeconfig 文件，包括已被删除的文件
+func (m *ManagerWithDB) syncKubeconfigDir(ctx context.Context, dir string) {
+	files, err := listKubeconfigFiles(dir)
+	if err != nil && !os.IsNotExist(err) {
+		klog.Warningf("读取 kubeconfig 目录失败 %s: %v", dir, err)
+		return
 	}
 
-	for contextName, context := range config.Contexts {
-		clusterName := context.Cluster
-		cluster, exists := config.Clusters[clusterName]
-		if !exists {
-			continue
+	m.mu.RLock()
+	known := filesFromDir(m.clusters, dir)
+	m.mu.RUnlock()
+
+	for _, file := range append(files, known...) {
+		if ctx.Err() != nil {
+			return
 		}
+		m.syncKubeconfigFile(file)
+	}
+}
 
-		clusterID := fmt.Sprintf("kubeconfig-%s", contextName)
+// syncKubeconfigFile 根据 kubeconfig 文件内容新增、更新或移除集群
+func (m *ManagerWithDB) syncKubeconfigFile(configPath string) {
+	contexts, ok := readKubeconfigFile(configPath)
+	if !ok {
+		return
+	}
 
-		// 检查是否已存在
-		m.mu.RLock()
-		_, exists = m.clusters[clusterID]
-		m.mu.RUnlock()
+	m.mu.RLock()
+	plan := planKubeconfigSync(configPath, m.clusters, contexts)
+	m.mu.RUnlock()
+
+	if plan.empty() {
+		return
+	}
 
+	for _, ctx := range plan.adopt {
+		m.mu.Lock()
+		cluster, exists := m.clusters[ctx.clusterID]
 		if exists {
-			klog.V(4).Infof("集群 %s 已存在，跳过", clusterID)
-			continue
+			cluster.KubeconfigPath = configPath
+			cluster.sourceFingerprint = ctx.fingerprint
+			m.beginWriteLocked(cluster.ID)
 		}
+		m.mu.Unlock()
 
-		// 构建REST配置
-		clientConfig := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{
-			CurrentContext: contextName,
-		})
-
-		restConfig, err := clientConfig.ClientConfig()
-		if err != nil {
-			klog.Warningf("创建客户端配置失败 %s: %v", contextName, err)
-			continue
+		if exists {
+			if err := m.saveClusterToDB(cluster, false); err != nil {
+				klog.Warningf("保存集群到数据库失败 %s: %v", cluster.Name, err)
+			}
+			m.endWrite(cluster.ID)
 		}
+	}
 
-		clusterInfo := &ClusterInfo{
-			ID:          clusterID,
-			Name:        fmt.Sprintf("%s (%s)", clusterName, contextName),
-			Description: fmt.Sprintf("从 %s 加载的集群", filepath.Base(configPath)),
-			Server:      cluster.Server,
-			Status:      ClusterStatusUnknown,
-			Config:      restConfig,
-			Context:     contextName,
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
+	for _, ctx := range append(plan.add, plan.update...) {
+		clusterInfo := kubeconfigClusterInfo(configPath, ctx)
+		clusterInfo.CreatedAt = time.Now()
+		clusterInfo.UpdatedAt = time.Now()
+
+		// 沿用通过 API 设置的连接方式
+		m.mu.RLock()
+		if old, exists := m.clusters[clusterInfo.ID]; exists {
+			clusterInfo.Tunnel = old.Tunnel
 		}
+		m.mu.RUnlock()
 
 		// 尝试创建客户端
-		if client, err := kube.NewK8sClientFromConfig(restConfig); err == nil {
-			clusterInfo.Client = client
-			if version, err := m.getClusterVersion(client); err == nil {
-				clusterInfo.Version = version
-			}
-		} else {
+		client, t, err := newClusterClient(ctx.config, clusterInfo.Tunnel)
+		if err != nil {
 			klog.Warningf("创建客户端失败 %s: %v", clusterInfo.Name, err)
 			continue
 		}
+		clusterInfo.Client = client
+		clusterInfo.tunnel = t
+		if version, err := m.getClusterVersion(client); err == nil {
+			clusterInfo.Version = version
+		}
 
 		// 保存到内存
 		m.mu.Lock()
-		m.clusters[clusterID] = clusterInfo
+		old, exists := m.clusters[clusterInfo.ID]
+		if exists {
+			// 保留通过 API 设置的属性
+			clusterInfo.Labels = old.Labels
+			clusterInfo.CreatedAt = old.CreatedAt
+			clusterInfo.IsDefault = old.IsDefault
+			clusterInfo.PrometheusURL = old.PrometheusURL
+			clusterInfo.PrometheusUsername = old.PrometheusUsername
+			clusterInfo.PrometheusPassword = old.PrometheusPassword
+			clusterInfo.PrometheusEnabled = old.PrometheusEnabled
+		}
+		m.clusters[clusterInfo.ID] = clusterInfo
+		m.beginWriteLocked(clusterInfo.ID)
 		if m.defaultID == "" {
-			m.defaultID = clusterID
+			m.defaultID = clusterInfo.ID
 			clusterInfo.IsDefault = true
 		}
 		m.mu.Unlock()
 
+		if exists {
+			old.disconnect()
+		}
+
 		// 保存到数据库
 		if err := m.saveClusterToDB(clusterInfo, false); err != nil {
 			klog.Warningf("保存集群到数据库失败 %s: %v", clusterInfo.Name, err)
 		}
+		m.endWrite(clusterInfo.ID)
 
-		klog.Infof("发现并加载集群: %s", clusterInfo.Name)
+		if exists {
+			klog.Infof("重新加载集群: %s", clusterInfo.Name)
+		} else {
+			klog.Infof("发现并加载集群: %s", clusterInfo.Name)
+		}
 	}
 
-	return nil
+	for _, clusterID := range plan.remove {
+		m.mu.Lock()
+		cluster, exists := m.clusters[clusterID]
+		if exists {
+			m.removeClusterLocked(clusterID)
+		}
+		m.mu.Unlock()
+
+		if exists {
+			cluster.disconnect()
+			klog.Infof("移除集群 %s: %s 中已不存在该上下文", cluster.Name, configPath)
+		}
+	}
 }
 
 // ensureDefaultCluster 第四步：确保有默认集群
@@ -318,6 +440,7 @@ func (m *ManagerWithDB) ensureDefaultCluster() error {
 		if err := m.saveClusterToDB(cluster, cluster.ID == "in-cluster"); err != nil {
 			klog.Warningf("更新默认集群到数据库失败: %v", err)
 		}
+		m.wroteLocked(id)
 
 		klog.Infof("设置默认集群: %s (%s)", cluster.Name, id)
 		break
@@ -328,12 +451,9 @@ func (m *ManagerWithDB) ensureDefaultCluster() error {
 
 // saveClusterToDB 保存集群信息到数据库
 func (m *ManagerWithDB) saveClusterToDB(clusterInfo *ClusterInfo, isInCluster bool) error {
-	// 将标签转换为 JSON 字符串
-	labelsJSON := ""
-	if len(clusterInfo.Labels) > 0 {
-		if labelsBytes, err := json.Marshal(clusterInfo.Labels); err == nil {
-			labelsJSON = string(labelsBytes)
-		}
+	tunnelConfig, err := clusterInfo.Tunnel.Encode()
+	if err != nil {
+		return err
 	}
 
 	clusterModel := &models.ClusterModel{
@@ -344,34 +464,28 @@ func (m *ManagerWithDB) saveClusterToDB(clusterInfo *ClusterInfo, isInCluster bo
 		Version:           clusterInfo.Version,
 		Status:            string(clusterInfo.Status),
 		Context:           clusterInfo.Context,
-		Labels:            labelsJSON,
+		Labels:            clusterInfo.Labels,
 		IsDefault:         clusterInfo.IsDefault,
 		IsInCluster:       isInCluster,
 		KubeconfigPath:    clusterInfo.KubeconfigPath,
-		KubeconfigContent: clusterInfo.KubeconfigContent,
+		KubeconfigContent: models.EncryptedString(clusterInfo.KubeconfigContent),
+		SourceFingerprint: clusterInfo.sourceFingerprint,
 		LastCheck:         clusterInfo.LastCheck,
 		CreatedAt:         clusterInfo.CreatedAt,
 		UpdatedAt:         clusterInfo.UpdatedAt,
 		// Prometheus 配置（如果有的话）
 		PrometheusURL:      clusterInfo.PrometheusURL,
 		PrometheusUsername: clusterInfo.PrometheusUsername,
-		PrometheusPassword: clusterInfo.PrometheusPassword,
+		PrometheusPassword: models.EncryptedString(clusterInfo.PrometheusPassword),
 		PrometheusEnabled:  clusterInfo.PrometheusEnabled,
+		TunnelConfig:       models.EncryptedString(tunnelConfig),
 	}
 
-	return m.repo.Create(clusterModel)
+	return m.repo.Update(clusterModel)
 }
 
 // modelToClusterInfo 将数据库模型转换为集群信息
 func (m *ManagerWithDB) modelToClusterInfo(model *models.ClusterModel) (*ClusterInfo, error) {
-	// 解析标签
-	var labels map[string]string
-	if model.Labels != "" {
-		if err := json.Unmarshal([]byte(model.Labels), &labels); err != nil {
-			klog.Warningf("解析集群标签失败 %s: %v", model.ID, err)
-		}
-	}
-
 	clusterInfo := &ClusterInfo{
 		ID:          model.ID,
 		Name:        model.Name,
@@ -380,7 +494,7 @@ func (m *ManagerWithDB) modelToClusterInfo(model *models.ClusterModel) (*Cluster
 		Version:     model.Version,
 		Status:      ClusterStatus(model.Status),
 		Context:     model.Context,
-		Labels:      labels,
+		Labels:      model.Labels,
 		CreatedAt:   model.CreatedAt,
 		UpdatedAt:   model.UpdatedAt,
 		LastCheck:   model.LastCheck,
@@ -388,23 +502,36 @@ func (m *ManagerWithDB) modelToClusterInfo(model *models.ClusterModel) (*Cluster
 
 		// Kubeconfig 相关字段
 		KubeconfigPath:    model.KubeconfigPath,
-		KubeconfigContent: model.KubeconfigContent,
+		KubeconfigContent: string(model.KubeconfigContent),
+		sourceFingerprint: model.SourceFingerprint,
 
 		// Prometheus 相关字段
 		PrometheusURL:      model.PrometheusURL,
 		PrometheusUsername: model.PrometheusUsername,
-		PrometheusPassword: model.PrometheusPassword,
+		PrometheusPassword: string(model.PrometheusPassword),
 		PrometheusEnabled:  model.PrometheusEnabled,
 	}
 
+	// 解析连接方式
+	tunnelConfig, err := tunnel.ParseConfig(string(model.TunnelConfig))
+	if err != nil {
+		klog.Warningf("解析集群连接方式失败 %s: %v", model.ID, err)
+	}
+	clusterInfo.Tunnel = tunnelConfig
+
 	// 对于 in-cluster 配置，尝试重新创建 REST 配置
 	if model.IsInCluster {
 		if config, err := rest.InClusterConfig(); err == nil {
-			clusterInfo.Config = config
+			clusterInfo.setConfig(config)
 			if client, err := kube.NewK8sClientFromConfig(config); err == nil {
 				clusterInfo.Client = client
 			}
 		}
+	} else if clusterInfo.KubeconfigContent != "" {
+		// 通过 API 添加的集群，使用保存的 kubeconfig 内容重建客户端
+		if err := m.loadClusterFromContent(clusterInfo); err != nil {
+			klog.Warningf("重新加载集群配置失败 %s: %v", model.ID, err)
+		}
 	} else if model.Context != "" {
 		// 对于外部集群，尝试从 kubeconfig 重新加载
 		if err := m.loadClusterFromKubeconfig(clusterInfo, model.Context); err != nil {
@@ -417,42 +544,86 @@ func (m *ManagerWithDB) modelToClusterInfo(model *models.ClusterModel) (*Cluster
 
 // loadClusterFromKubeconfig 从 kubeconfig 重新加载集群配置
 func (m *ManagerWithDB) loadClusterFromKubeconfig(clusterInfo *ClusterInfo, contextName string) error {
-	kubeconfigPath := ""
-	if home := homedir.HomeDir(); home != "" {
-		kubeconfigPath = filepath.Join(home, ".kube", "config")
+	// 优先使用记录的来源文件，否则依次尝试 KUBECONFIG 中的路径
+	paths := kubeconfigPaths()
+	if clusterInfo.KubeconfigPath != "" {
+		paths = append([]string{clusterInfo.KubeconfigPath}, paths...)
 	}
 
-	if envKubeconfig := os.Getenv("KUBECONFIG"); envKubeconfig != "" {
-		kubeconfigPath = envKubeconfig
-	}
-
-	if kubeconfigPath == "" {
+	if len(paths) == 0 {
 		return fmt.Errorf("未找到 kubeconfig 文件")
 	}
 
-	config, err := clientcmd.LoadFromFile(kubeconfigPath)
-	if err != nil {
-		return fmt.Errorf("加载 kubeconfig 失败: %w", err)
+	var lastErr error
+	for _, kubeconfigPath := range paths {
+		config, err := clientcmd.LoadFromFile(kubeconfigPath)
+		if err != nil {
+			lastErr = fmt.Errorf("加载 kubeconfig 失败: %w", err)
+			continue
+		}
+		if _, exists := config.Contexts[contextName]; !exists {
+			lastErr = fmt.Errorf("上下文 %s 不存在于 %s", contextName, kubeconfigPath)
+			continue
+		}
+
+		clientConfig := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{
+			CurrentContext: contextName,
+		})
+
+		restConfig, err := clientConfig.ClientConfig()
+		if err != nil {
+			return fmt.Errorf("创建客户端配置失败: %w", err)
+		}
+
+		clusterInfo.setConfig(restConfig)
+
+		if client, t, err := newClusterClient(restConfig, clusterInfo.Tunnel); err == nil {
+			clusterInfo.Client = client
+			clusterInfo.tunnel = t
+		}
+
+		return nil
 	}
 
-	clientConfig := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{
-		CurrentContext: contextName,
-	})
+	return lastErr
+}
 
-	restConfig, err := clientConfig.ClientConfig()
+// loadClusterFromContent 从保存的 kubeconfig 内容重新加载集群配置
+func (m *ManagerWithDB) loadClusterFromContent(clusterInfo *ClusterInfo) error {
+	restConfig, _, err := restConfigFromKubeconfig(clusterInfo.KubeconfigContent)
 	if err != nil {
-		return fmt.Errorf("创建客户端配置失败: %w", err)
+		return err
 	}
 
-	clusterInfo.Config = restConfig
+	clusterInfo.setConfig(restConfig)
 
-	if client, err := kube.NewK8sClientFromConfig(restConfig); err == nil {
+	if client, t, err := newClusterClient(restConfig, clusterInfo.Tunnel); err == nil {
 		clusterInfo.Client = client
+		clusterInfo.tunnel = t
 	}
 
 	return nil
 }
 
+// UpdateClusterHealth 更新集群健康状态，并保存到数据库供其他副本同步
+func (m *ManagerWithDB) UpdateClusterHealth(clusterID string, status ClusterStatus, checkedAt time.Time) {
+	m.mu.Lock()
+	cluster, exists := m.clusters[clusterID]
+	version := ""
+	if exists {
+		applyClusterHealth(cluster, status, checkedAt)
+		version = cluster.Version
+	}
+	m.mu.Unlock()
+
+	if !exists {
+		return
+	}
+	if err := m.repo.UpdateHealth(clusterID, string(status), version, checkedAt); err != nil {
+		klog.V(2).Infof("保存集群健康状态失败 %s: %v", clusterID, err)
+	}
+}
+
 // getClusterVersion 获取集群版本
 func (m *ManagerWithDB) getClusterVersion(client *kube.K8sClient) (string, error) {
 	version, err := client.ClientSet.Discovery().ServerVersion()
@@ -466,38 +637,22 @@ func (m *ManagerWithDB) getClusterVersion(client *kube.K8sClient) (string, error
 
 // AddCluster 添加新集群
 func (m *ManagerWithDB) AddCluster(name, description, kubeconfigContent string, labels map[string]string) (*ClusterInfo, error) {
-	config, err := clientcmd.Load([]byte(kubeconfigContent))
-	if err != nil {
-		return nil, fmt.Errorf("无效的 kubeconfig: %w", err)
-	}
-
-	currentContext := config.CurrentContext
-	if currentContext == "" {
-		for contextName := range config.Contexts {
-			currentContext = contextName
-			break
-		}
-	}
-
-	if currentContext == "" {
-		return nil, fmt.Errorf("kubeconfig 中未找到有效的上下文")
-	}
-
-	clientConfig := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{
-		CurrentContext: currentContext,
-	})
+	return m.AddClusterWithTunnel(name, description, kubeconfigContent, labels, nil)
+}
 
-	restConfig, err := clientConfig.ClientConfig()
+// AddClusterWithTunnel 添加通过指定连接方式访问的新集群
+func (m *ManagerWithDB) AddClusterWithTunnel(name, description, kubeconfigContent string, labels map[string]string, tunnelConfig *tunnel.Config) (*ClusterInfo, error) {
+	restConfig, currentContext, err := restConfigFromKubeconfig(kubeconfigContent)
 	if err != nil {
-		return nil, fmt.Errorf("创建客户端配置失败: %w", err)
+		return nil, err
 	}
 
-	client, err := kube.NewK8sClientFromConfig(restConfig)
+	client, t, err := newClusterClient(restConfig, tunnelConfig)
 	if err != nil {
 		return nil, fmt.Errorf("创建 kubernetes 客户端失败: %w", err)
 	}
 
-	clusterID := fmt.Sprintf("custom-%d", time.Now().Unix())
+	clusterID := newClusterID()
 	clusterInfo := &ClusterInfo{
 		ID:          clusterID,
 		Name:        name,
@@ -505,34 +660,261 @@ func (m *ManagerWithDB) AddCluster(name, description, kubeconfigContent string,
 		Server:      restConfig.Host,
 		Status:      ClusterStatusUnknown,
 		Config:      restConfig,
+		credentials: InspectCredentials(restConfig),
 		Client:      client,
 		Context:     currentContext,
 		Labels:      labels,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
+
+		KubeconfigContent: kubeconfigContent,
+		Tunnel:            tunnelConfig,
+		tunnel:            t,
 	}
 
 	if version, err := m.getClusterVersion(client); err == nil {
 		clusterInfo.Version = version
 	}
 
+	// 回收站中有相同 API Server 和上下文的集群时沿用其 ID，重新导入后编辑历史等数据仍然关联
+	trashed := m.findTrashedCluster(clusterInfo.Server, currentContext)
+	if trashed != nil {
+		clusterID = trashed.ID
+		clusterInfo.ID = trashed.ID
+		clusterInfo.CreatedAt = trashed.CreatedAt
+		clusterInfo.PrometheusURL = trashed.PrometheusURL
+		clusterInfo.PrometheusUsername = trashed.PrometheusUsername
+		clusterInfo.PrometheusPassword = string(trashed.PrometheusPassword)
+		clusterInfo.PrometheusEnabled = trashed.PrometheusEnabled
+	}
+
 	m.mu.Lock()
+	if existing := findCluster(m.clusters, clusterInfo.Server, currentContext); existing != nil {
+		m.mu.Unlock()
+		clusterInfo.disconnect()
+		return nil, fmt.Errorf("集群 %s 已使用 API Server %s 和上下文 %s，请更新该集群的连接配置", existing.ID, existing.Server, currentContext)
+	}
+	if _, exists := m.clusters[clusterID]; exists {
+		// 同一个回收站集群正在被并发恢复
+		m.mu.Unlock()
+		clusterInfo.disconnect()
+		return nil, fmt.Errorf("集群 %s 已存在", clusterID)
+	}
 	m.clusters[clusterID] = clusterInfo
+	m.beginWriteLocked(clusterID)
 	if len(m.clusters) == 1 {
 		m.defaultID = clusterID
 		clusterInfo.IsDefault = true
 	}
 	m.mu.Unlock()
 
+	if trashed != nil {
+		if err := m.repo.Restore(clusterID); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			klog.Warningf("从回收站恢复集群 %s 失败: %v", clusterID, err)
+		}
+	}
+
 	// 保存到数据库
 	if err := m.saveClusterToDB(clusterInfo, false); err != nil {
 		klog.Warningf("保存自定义集群到数据库失败: %v", err)
 	}
+	m.endWrite(clusterID)
 
-	klog.Infof("添加自定义集群: %s", name)
+	if trashed != nil {
+		klog.Infof("重新添加回收站中的集群: %s (%s)", name, clusterID)
+	} else {
+		klog.Infof("添加自定义集群: %s", name)
+	}
 	return clusterInfo, nil
 }
 
+// findTrashedCluster 查找回收站中通过 API 添加、API Server 和上下文相同的集群，有多个时返回最近删除的
+func (m *ManagerWithDB) findTrashedCluster(server, contextName string) *models.ClusterModel {
+	clusters, err := m.repo.GetDeleted()
+	if err != nil {
+		klog.Warningf("获取回收站集群列表失败: %v", err)
+		return nil
+	}
+	for _, model := range clusters {
+		if model.IsInCluster || model.KubeconfigPath != "" {
+			continue
+		}
+		if model.Server == server && model.Context == contextName {
+			return model
+		}
+	}
+	return nil
+}
+
+// UpdateClusterKubeconfig 更新通过 API 添加的集群的连接配置，并重建客户端
+func (m *ManagerWithDB) UpdateClusterKubeconfig(clusterID, kubeconfigContent string) (*ClusterInfo, error) {
+	m.mu.RLock()
+	cluster, exists := m.clusters[clusterID]
+	m.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("集群 %s 不存在", clusterID)
+	}
+	if err := checkConnectionUpdatable(cluster); err != nil {
+		return nil, err
+	}
+
+	restConfig, currentContext, err := restConfigFromKubeconfig(kubeconfigContent)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := m.reconnectCluster(clusterID, restConfig, cluster.Tunnel, func(updated *ClusterInfo) {
+		updated.Server = restConfig.Host
+		updated.Context = currentContext
+		updated.KubeconfigContent = kubeconfigContent
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	klog.Infof("更新集群连接配置: %s", updated.Name)
+	return updated, nil
+}
+
+// UpdateClusterTunnel 更新集群的连接方式，并重建客户端
+func (m *ManagerWithDB) UpdateClusterTunnel(clusterID string, tunnelConfig *tunnel.Config) (*ClusterInfo, error) {
+	if err := tunnelConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	cluster, exists := m.clusters[clusterID]
+	m.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("集群 %s 不存在", clusterID)
+	}
+	if clusterID == "in-cluster" {
+		return nil, fmt.Errorf("不能修改集群内配置的连接方式")
+	}
+
+	if cluster.Config == nil {
+		return nil, fmt.Errorf("集群 %s 的连接配置不可用", clusterID)
+	}
+
+	updated, err := m.reconnectCluster(clusterID, cluster.Config, tunnelConfig, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	klog.Infof("更新集群 %s 的连接方式: %s", updated.Name, updated.ConnectionType())
+	return updated, nil
+}
+
+// reconnectCluster 使用新的 REST 配置和连接方式重建集群客户端并保存，apply 用于更新其余字段
+func (m *ManagerWithDB) reconnectCluster(clusterID string, config *rest.Config, tunnelConfig *tunnel.Config, apply func(*ClusterInfo)) (*ClusterInfo, error) {
+	client, t, err := newClusterClient(config, tunnelConfig)
+	if err != nil {
+		return nil, fmt.Errorf("创建 kubernetes 客户端失败: %w", err)
+	}
+
+	version, _ := m.getClusterVersion(client)
+
+	m.mu.Lock()
+	old, exists := m.clusters[clusterID]
+	if !exists {
+		m.mu.Unlock()
+		client.Stop()
+		t.Close()
+		return nil, fmt.Errorf("集群 %s 不存在", clusterID)
+	}
+	updated := *old
+	updated.setConfig(config)
+	updated.Client = client
+	updated.Tunnel = tunnelConfig
+	updated.tunnel = t
+	updated.Status = ClusterStatusUnknown
+	updated.UpdatedAt = time.Now()
+	if version != "" {
+		updated.Version = version
+	}
+	if apply != nil {
+		apply(&updated)
+	}
+	m.clusters[clusterID] = &updated
+	m.beginWriteLocked(clusterID)
+	m.mu.Unlock()
+
+	old.disconnect()
+
+	// 保存到数据库
+	if err := m.saveClusterToDB(&updated, false); err != nil {
+		klog.Warningf("保存集群连接配置到数据库失败: %v", err)
+	}
+	m.endWrite(clusterID)
+
+	return &updated, nil
+}
+
+// ReloadClusters 从数据库重新加载指定集群，用于导入备份等直接修改数据库的场景
+//
+// 数据库中已不存在的集群会从内存中移除，默认集群以数据库为准。
+func (m *ManagerWithDB) ReloadClusters(clusterIDs []string) {
+	m.reloadClusters(clusterIDs, nil)
+}
+
+// reloadClusters 从数据库重新加载集群，skip 在持有写锁时判断是否保留内存中的集群
+func (m *ManagerWithDB) reloadClusters(clusterIDs []string, skip func(clusterID string) bool) {
+	for _, clusterID := range clusterIDs {
+		var clusterInfo *ClusterInfo
+		model, err := m.repo.GetByID(clusterID)
+		if err == nil {
+			clusterInfo, err = m.modelToClusterInfo(model)
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			klog.Warningf("重新加载集群失败 %s: %v", clusterID, err)
+			continue
+		}
+
+		m.mu.Lock()
+		if skip != nil && skip(clusterID) {
+			m.mu.Unlock()
+			if clusterInfo != nil {
+				clusterInfo.disconnect()
+			}
+			continue
+		}
+		old, exists := m.clusters[clusterID]
+		if clusterInfo != nil {
+			m.clusters[clusterID] = clusterInfo
+		} else {
+			delete(m.clusters, clusterID)
+		}
+		m.mu.Unlock()
+
+		if exists {
+			old.disconnect()
+		}
+		klog.Infof("重新加载集群: %s", clusterID)
+	}
+
+	defaultID := ""
+	if model, err := m.repo.GetDefault(); err == nil {
+		defaultID = model.ID
+	}
+
+	m.mu.Lock()
+	if _, exists := m.clusters[defaultID]; !exists {
+		defaultID = ""
+	}
+	m.defaultID = defaultID
+	for id, cluster := range m.clusters {
+		cluster.IsDefault = id == defaultID
+	}
+	m.mu.Unlock()
+
+	if !m.isLeader() {
+		return
+	}
+	if err := m.ensureDefaultCluster(); err != nil {
+		klog.Warningf("设置默认集群失败: %v", err)
+	}
+}
+
 // RemoveCluster 移除集群
 func (m *ManagerWithDB) RemoveCluster(clusterID string) error {
 	m.mu.Lock()
@@ -547,12 +929,22 @@ func (m *ManagerWithDB) RemoveCluster(clusterID string) error {
 		return fmt.Errorf("不能删除集群内配置")
 	}
 
+	m.removeClusterLocked(clusterID)
+	cluster.disconnect()
+
+	klog.Infof("移除集群: %s", cluster.Name)
+	return nil
+}
+
+// removeClusterLocked 从内存和数据库中移除集群，调用方需持有写锁
+func (m *ManagerWithDB) removeClusterLocked(clusterID string) {
 	delete(m.clusters, clusterID)
 
 	// 从数据库删除
 	if err := m.repo.Delete(clusterID); err != nil {
 		klog.Warningf("从数据库删除集群失败: %v", err)
 	}
+	m.wroteLocked(clusterID)
 
 	// 如果删除的是默认集群，选择新的默认集群
 	if m.defaultID == clusterID {
@@ -564,12 +956,10 @@ func (m *ManagerWithDB) RemoveCluster(clusterID string) error {
 			if err := m.saveClusterToDB(info, id == "in-cluster"); err != nil {
 				klog.Warningf("更新新默认集群到数据库失败: %v", err)
 			}
+			m.wroteLocked(id)
 			break
 		}
 	}
-
-	klog.Infof("移除集群: %s", cluster.Name)
-	return nil
 }
 
 // GetCluster 获取指定集群
@@ -610,6 +1000,29 @@ func (m *ManagerWithDB) ListClusters() []*ClusterInfo {
 	return clusters
 }
 
+// ListClustersBySelector 通过 cluster_labels 表查询标签匹配选择器的集群
+func (m *ManagerWithDB) ListClustersBySelector(selector labels.Selector) ([]*ClusterInfo, error) {
+	if selector.Empty() {
+		return m.ListClusters(), nil
+	}
+
+	ids, err := m.repo.GetIDsBySelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("按标签查询集群失败: %w", err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	clusters := make([]*ClusterInfo, 0, len(ids))
+	for _, id := range ids {
+		if cluster, ok := m.clusters[id]; ok {
+			clusters = append(clusters, cluster)
+		}
+	}
+	return clusters, nil
+}
+
 // SetDefaultCluster 设置默认集群
 func (m *ManagerWithDB) SetDefaultCluster(clusterID string) error {
 	m.mu.Lock()
@@ -627,6 +1040,7 @@ func (m *ManagerWithDB) SetDefaultCluster(clusterID string) error {
 			if err := m.saveClusterToDB(oldDefault, oldDefault.ID == "in-cluster"); err != nil {
 				klog.Warningf("更新旧默认集群状态失败: %v", err)
 			}
+			m.wroteLocked(oldDefault.ID)
 		}
 	}
 
@@ -637,6 +1051,7 @@ func (m *ManagerWithDB) SetDefaultCluster(clusterID string) error {
 	if err := m.saveClusterToDB(cluster, cluster.ID == "in-cluster"); err != nil {
 		klog.Warningf("更新新默认集群状态失败: %v", err)
 	}
+	m.wroteLocked(clusterID)
 
 	klog.Infof("设置默认集群: %s", cluster.Name)
 	return nil
@@ -659,15 +1074,22 @@ func (m *ManagerWithDB) UpdateClusterLabels(clusterID string, labels map[string]
 	if err := m.saveClusterToDB(cluster, cluster.ID == "in-cluster"); err != nil {
 		klog.Warningf("更新集群标签到数据库失败: %v", err)
 	}
+	m.wroteLocked(clusterID)
 
 	return nil
 }
 
 // Stop 停止集群管理器
 func (m *ManagerWithDB) Stop() {
+	close(m.stopCh)
+	m.elector.Stop()
+	m.nodeTerminalGC.Stop()
 	if m.healthChecker != nil {
 		m.healthChecker.Stop()
 	}
+	if m.kubeconfigWatcher != nil {
+		m.kubeconfigWatcher.Stop()
+	}
 	if m.db != nil {
 		m.db.Close()
 	}
@@ -691,7 +1113,9 @@ func (m *ManagerWithDB) UpdateClusterPrometheus(clusterID, url, username, passwo
 	cluster.UpdatedAt = time.Now()
 
 	// 更新数据库
-	if err := m.repo.UpdatePrometheusConfig(clusterID, url, username, password, enabled); err != nil {
+	err := m.repo.UpdatePrometheusConfig(clusterID, url, username, password, enabled)
+	m.wroteLocked(clusterID)
+	if err != nil {
 		return fmt.Errorf("更新数据库 Prometheus 配置失败: %w", err)
 	}
 
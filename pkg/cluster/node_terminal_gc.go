@@ -0,0 +1,130 @@
+package cluster
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ysicing/nexus/pkg/common"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// nodeTerminalGCInterval 清理失效节点终端 Pod 的间隔
+	nodeTerminalGCInterval = time.Minute
+	// nodeTerminalStaleAfter 超过该时间没有心跳的节点终端 Pod 视为会话已结束
+	nodeTerminalStaleAfter = 3 * common.NodeTerminalHeartbeatInterval
+)
+
+// ClusterLister 列出集群
+type ClusterLister interface {
+	ListClusters() []*ClusterInfo
+}
+
+// NodeTerminalGC 清理失效的节点终端 Pod
+//
+// 节点终端会话结束时由对应的副本删除 Pod，副本异常退出时 Pod 会残留。
+// 会话期间副本定期刷新 Pod 上的心跳注解，这里删除心跳超时的 Pod，
+// 因此任何一个副本都可以清理其他副本遗留的 Pod。
+type NodeTerminalGC struct {
+	manager  ClusterLister
+	interval time.Duration
+	active   func() bool
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	mu       sync.Mutex
+}
+
+// NewNodeTerminalGC 创建节点终端 Pod 清理器
+func NewNodeTerminalGC(manager ClusterLister) *NodeTerminalGC {
+	return &NodeTerminalGC{
+		manager:  manager,
+		interval: nodeTerminalGCInterval,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// SetActive 设置判断是否执行清理的函数，多副本部署时只有主副本执行
+func (g *NodeTerminalGC) SetActive(active func() bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.active = active
+}
+
+func (g *NodeTerminalGC) isActive() bool {
+	g.mu.Lock()
+	active := g.active
+	g.mu.Unlock()
+	return active == nil || active()
+}
+
+// Start 启动清理，阻塞直到 Stop 被调用
+func (g *NodeTerminalGC) Start() {
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if g.isActive() {
+				g.collect()
+			}
+		case <-g.stopCh:
+			return
+		}
+	}
+}
+
+// Stop 停止清理
+func (g *NodeTerminalGC) Stop() {
+	g.stopOnce.Do(func() {
+		close(g.stopCh)
+	})
+}
+
+// collect 删除所有集群中心跳超时的节点终端 Pod
+func (g *NodeTerminalGC) collect() {
+	for _, cluster := range g.manager.ListClusters() {
+		if cluster.Client == nil || cluster.Client.ClientSet == nil {
+			continue
+		}
+		collectNodeTerminalPods(cluster)
+	}
+}
+
+func collectNodeTerminalPods(cluster *ClusterInfo) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pods := cluster.Client.ClientSet.CoreV1().Pods(common.NodeTerminalNamespace)
+	list, err := pods.List(ctx, metav1.ListOptions{
+		LabelSelector: common.NodeTerminalLabel + "=true",
+	})
+	if err != nil {
+		klog.V(4).Infof("Failed to list node terminal pods in cluster %s: %v", cluster.Name, err)
+		return
+	}
+
+	now := time.Now()
+	for _, pod := range list.Items {
+		lastSeen := pod.CreationTimestamp.Time
+		if value := pod.Annotations[common.NodeTerminalHeartbeatAnnotation]; value != "" {
+			if heartbeat, err := time.Parse(time.RFC3339, value); err == nil {
+				lastSeen = heartbeat
+			}
+		}
+		if now.Sub(lastSeen) < nodeTerminalStaleAfter {
+			continue
+		}
+
+		err := pods.Delete(ctx, pod.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			klog.Warningf("Failed to delete stale node terminal pod %s in cluster %s: %v", pod.Name, cluster.Name, err)
+			continue
+		}
+		klog.Infof("Deleted stale node terminal pod %s in cluster %s, last heartbeat at %s",
+			pod.Name, cluster.Name, lastSeen.Format(time.RFC3339))
+	}
+}
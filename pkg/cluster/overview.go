@@ -0,0 +1,297 @@
+package cluster
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ysicing/nexus/pkg/common"
+	"github.com/ysicing/nexus/pkg/kube"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// overviewTTL 集群概览缓存时间
+	overviewTTL = 15 * time.Second
+	// overviewTimeout 单个集群采集概览的超时时间，避免不可达集群拖慢整个请求
+	overviewTimeout = 5 * time.Second
+	// overviewEventWindow 统计告警事件的时间窗口
+	overviewEventWindow = time.Hour
+)
+
+// NodeSummary 节点统计
+type NodeSummary struct {
+	Total int `json:"total"`
+	Ready int `json:"ready"`
+}
+
+// PodSummary Pod 统计
+type PodSummary struct {
+	Total  int            `json:"total"`
+	Phases map[string]int `json:"phases"`
+}
+
+// ClusterOverview 单个集群的概览
+type ClusterOverview struct {
+	ID            string                `json:"id"`
+	Name          string                `json:"name"`
+	Version       string                `json:"version,omitempty"`
+	Status        ClusterStatus         `json:"status"`
+	IsDefault     bool                  `json:"isDefault"`
+	Labels        map[string]string     `json:"labels,omitempty"`
+	Nodes         NodeSummary           `json:"nodes"`
+	Pods          PodSummary            `json:"pods"`
+	Resource      common.ResourceMetric `json:"resource"`
+	WarningEvents int                   `json:"warningEvents"`
+	CollectedAt   time.Time             `json:"collectedAt"`
+	Error         string                `json:"error,omitempty"`
+}
+
+// FleetOverview 所有集群的概览
+type FleetOverview struct {
+	Clusters []ClusterOverview `json:"clusters"`
+	Total    FleetTotals       `json:"total"`
+}
+
+// FleetTotals 所有集群的汇总
+type FleetTotals struct {
+	Clusters      int                   `json:"clusters"`
+	Healthy       int                   `json:"healthy"`
+	Nodes         NodeSummary           `json:"nodes"`
+	Pods          PodSummary            `json:"pods"`
+	Resource      common.ResourceMetric `json:"resource"`
+	WarningEvents int                   `json:"warningEvents"`
+}
+
+// overviewEntry 单个集群的概览缓存
+type overviewEntry struct {
+	mu        sync.Mutex
+	client    *kube.K8sClient
+	overview  ClusterOverview
+	expiresAt time.Time
+}
+
+// overviewCache 按集群缓存概览数据，客户端变化（例如重新加载 kubeconfig）后自动失效
+type overviewCache struct {
+	mu      sync.Mutex
+	entries map[string]*overviewEntry
+}
+
+// get 返回集群概览，缓存过期或 refresh 为 true 时重新采集
+func (o *overviewCache) get(cluster *ClusterInfo, refresh bool) ClusterOverview {
+	o.mu.Lock()
+	if o.entries == nil {
+		o.entries = make(map[string]*overviewEntry)
+	}
+	entry, exists := o.entries[cluster.ID]
+	if !exists {
+		entry = &overviewEntry{}
+		o.entries[cluster.ID] = entry
+	}
+	o.mu.Unlock()
+
+	// 同一集群的并发请求只采集一次
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if !refresh && entry.client == cluster.Client && time.Now().Before(entry.expiresAt) {
+		return withClusterInfo(entry.overview, cluster)
+	}
+
+	// 采集结果会被其他请求复用，不使用当前请求的上下文
+	overview := collectClusterOverview(context.Background(), cluster)
+	entry.client = cluster.Client
+	entry.overview = overview
+	entry.expiresAt = time.Now().Add(overviewTTL)
+	return overview
+}
+
+// prune 清理已不存在的集群的缓存
+func (o *overviewCache) prune(clusters []*ClusterInfo) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	existing := make(map[string]bool, len(clusters))
+	for _, cluster := range clusters {
+		existing[cluster.ID] = true
+	}
+	for id := range o.entries {
+		if !existing[id] {
+			delete(o.entries, id)
+		}
+	}
+}
+
+// withClusterInfo 使用最新的集群信息覆盖缓存中的元数据，健康状态等不受缓存影响
+func withClusterInfo(overview ClusterOverview, cluster *ClusterInfo) ClusterOverview {
+	overview.ID = cluster.ID
+	overview.Name = cluster.Name
+	overview.Version = cluster.Version
+	overview.Status = cluster.Status
+	overview.IsDefault = cluster.IsDefault
+	overview.Labels = cluster.Labels
+	return overview
+}
+
+// collectClusterOverview 从集群客户端的 informer 缓存中采集概览数据
+func collectClusterOverview(ctx context.Context, cluster *ClusterInfo) ClusterOverview {
+	overview := withClusterInfo(ClusterOverview{
+		Pods:        PodSummary{Phases: map[string]int{}},
+		CollectedAt: time.Now(),
+	}, cluster)
+
+	if cluster.Client == nil || cluster.Client.Client == nil {
+		overview.Error = "cluster client not available"
+		return overview
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, overviewTimeout)
+	defer cancel()
+
+	var nodes corev1.NodeList
+	if err := cluster.Client.Client.List(ctx, &nodes); err != nil {
+		overview.Error = "failed to list nodes: " + err.Error()
+		return overview
+	}
+
+	var cpuAllocatable, memAllocatable resource.Quantity
+	for _, node := range nodes.Items {
+		cpuAllocatable.Add(*node.Status.Allocatable.Cpu())
+		memAllocatable.Add(*node.Status.Allocatable.Memory())
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
+				overview.Nodes.Ready++
+				break
+			}
+		}
+	}
+	overview.Nodes.Total = len(nodes.Items)
+
+	var pods corev1.PodList
+	if err := cluster.Client.Client.List(ctx, &pods); err != nil {
+		overview.Error = "failed to list pods: " + err.Error()
+		return overview
+	}
+
+	var cpuRequested, memRequested resource.Quantity
+	var cpuLimited, memLimited resource.Quantity
+	for _, pod := range pods.Items {
+		overview.Pods.Phases[string(pod.Status.Phase)]++
+
+		// 已结束的 Pod 不再占用资源
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			cpuRequested.Add(*container.Resources.Requests.Cpu())
+			memRequested.Add(*container.Resources.Requests.Memory())
+			cpuLimited.Add(*container.Resources.Limits.Cpu())
+			memLimited.Add(*container.Resources.Limits.Memory())
+		}
+	}
+	overview.Pods.Total = len(pods.Items)
+
+	overview.Resource = common.ResourceMetric{
+		CPU: common.Resource{
+			Allocatable: cpuAllocatable.MilliValue(),
+			Requested:   cpuRequested.MilliValue(),
+			Limited:     cpuLimited.MilliValue(),
+		},
+		Mem: common.Resource{
+			Allocatable: memAllocatable.MilliValue(),
+			Requested:   memRequested.MilliValue(),
+			Limited:     memLimited.MilliValue(),
+		},
+	}
+
+	var events corev1.EventList
+	if err := cluster.Client.Client.List(ctx, &events); err != nil {
+		overview.Error = "failed to list events: " + err.Error()
+		return overview
+	}
+
+	since := time.Now().Add(-overviewEventWindow)
+	for _, event := range events.Items {
+		if event.Type == corev1.EventTypeWarning && eventLastSeen(&event).After(since) {
+			overview.WarningEvents++
+		}
+	}
+
+	return overview
+}
+
+// eventLastSeen 事件最后一次发生的时间
+func eventLastSeen(event *corev1.Event) time.Time {
+	switch {
+	case event.Series != nil:
+		return event.Series.LastObservedTime.Time
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
+
+// summarizeFleet 汇总所有集群的概览
+func summarizeFleet(overviews []ClusterOverview) FleetTotals {
+	totals := FleetTotals{
+		Clusters: len(overviews),
+		Pods:     PodSummary{Phases: map[string]int{}},
+	}
+	for _, overview := range overviews {
+		if overview.Status == ClusterStatusHealthy || overview.Status == ClusterStatusWarning {
+			totals.Healthy++
+		}
+		totals.Nodes.Total += overview.Nodes.Total
+		totals.Nodes.Ready += overview.Nodes.Ready
+		totals.Pods.Total += overview.Pods.Total
+		for phase, count := range overview.Pods.Phases {
+			totals.Pods.Phases[phase] += count
+		}
+		totals.Resource.CPU.Allocatable += overview.Resource.CPU.Allocatable
+		totals.Resource.CPU.Requested += overview.Resource.CPU.Requested
+		totals.Resource.CPU.Limited += overview.Resource.CPU.Limited
+		totals.Resource.Mem.Allocatable += overview.Resource.Mem.Allocatable
+		totals.Resource.Mem.Requested += overview.Resource.Mem.Requested
+		totals.Resource.Mem.Limited += overview.Resource.Mem.Limited
+		totals.WarningEvents += overview.WarningEvents
+	}
+	return totals
+}
+
+// GetFleetOverview 获取所有集群的概览
+//
+// 数据来自各集群客户端的 informer 缓存，并按集群缓存 overviewTTL，
+// 各集群并发采集，单个集群最多等待 overviewTimeout。
+// 传入 refresh=true 可跳过缓存。
+func (h *Handler) GetFleetOverview(c *gin.Context) {
+	clusters := h.manager.ListClusters()
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].ID < clusters[j].ID
+	})
+	h.overviews.prune(clusters)
+
+	refresh := c.Query("refresh") == "true"
+	overviews := make([]ClusterOverview, len(clusters))
+
+	var wg sync.WaitGroup
+	for i, cluster := range clusters {
+		wg.Add(1)
+		go func(i int, cluster *ClusterInfo) {
+			defer wg.Done()
+			overviews[i] = h.overviews.get(cluster, refresh)
+		}(i, cluster)
+	}
+	wg.Wait()
+
+	c.JSON(http.StatusOK, FleetOverview{
+		Clusters: overviews,
+		Total:    summarizeFleet(overviews),
+	})
+}
@@ -0,0 +1,265 @@
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ysicing/nexus/pkg/common"
+	"github.com/ysicing/nexus/pkg/middleware"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/proxy"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/transport"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// ProxyUserPrefix 非管理员通过代理访问集群时，模拟的 Kubernetes 用户名为该前缀加 Nexus 用户名
+	ProxyUserPrefix = "nexus:"
+	// ProxyUserGroup 非管理员通过代理访问集群时模拟的用户组
+	ProxyUserGroup = "nexus:users"
+)
+
+// proxyEntry 缓存某个集群的代理处理器，配置变化时重新创建
+type proxyEntry struct {
+	config  *rest.Config
+	handler *proxy.UpgradeAwareHandler
+}
+
+// proxyCache 按集群 ID 缓存代理处理器，避免每次请求都重建 TLS 传输层
+type proxyCache struct {
+	mu      sync.Mutex
+	entries map[string]*proxyEntry
+}
+
+// get 获取集群对应的代理处理器
+func (p *proxyCache) get(clusterID string, config *rest.Config) (*proxy.UpgradeAwareHandler, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if entry, ok := p.entries[clusterID]; ok && entry.config == config {
+		return entry.handler, nil
+	}
+
+	handler, err := newClusterProxyHandler(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.entries == nil {
+		p.entries = make(map[string]*proxyEntry)
+	}
+	p.entries[clusterID] = &proxyEntry{config: config, handler: handler}
+	return handler, nil
+}
+
+// newClusterProxyHandler 基于集群的 rest.Config 创建支持 upgrade（exec、attach、port-forward）的反向代理
+func newClusterProxyHandler(config *rest.Config) (*proxy.UpgradeAwareHandler, error) {
+	target, err := url.Parse(config.Host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cluster server %q: %w", config.Host, err)
+	}
+	if target.Scheme == "" {
+		target.Scheme = "https"
+	}
+	// 目标地址没有路径时，代理会把所有 GET 请求重定向到加上 "/" 的地址
+	if target.Path == "" {
+		target.Path = "/"
+	}
+
+	rt, err := rest.TransportFor(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transport: %w", err)
+	}
+
+	upgradeRT, err := newUpgradeTransport(config)
+	if err != nil {
+		return nil, err
+	}
+
+	handler := proxy.NewUpgradeAwareHandler(target, rt, false, false, &proxyErrorResponder{})
+	handler.UpgradeTransport = upgradeRT
+	handler.UseRequestLocation = true
+	handler.UseLocationHost = true
+	handler.AppendLocationPath = true
+	// watch 请求需要立即刷新响应
+	handler.FlushInterval = 100 * time.Millisecond
+
+	return handler, nil
+}
+
+// newUpgradeTransport 创建用于 SPDY/WebSocket upgrade 请求的传输层，携带集群凭证
+func newUpgradeTransport(config *rest.Config) (proxy.UpgradeRequestRoundTripper, error) {
+	transportConfig, err := config.TransportConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build transport config: %w", err)
+	}
+
+	tlsConfig, err := transport.TLSConfigFor(transportConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build TLS config: %w", err)
+	}
+
+	rt := utilnet.SetOldTransportDefaults(&http.Transport{
+		TLSClientConfig: tlsConfig,
+		// 集群配置了代理或 SSH 通道时，upgrade 连接同样需要经过通道
+		Proxy: config.Proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+	})
+
+	upgrader, err := transport.HTTPWrappersForConfig(transportConfig, proxy.MirrorRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap upgrade transport: %w", err)
+	}
+
+	return proxy.NewUpgradeRequestRoundTripper(rt, upgrader), nil
+}
+
+// proxyErrorResponder 代理出错时返回 502
+type proxyErrorResponder struct{}
+
+func (r *proxyErrorResponder) Error(w http.ResponseWriter, req *http.Request, err error) {
+	klog.Warningf("Cluster proxy error for %s %s: %v", req.Method, req.URL.Path, err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadGateway)
+	_, _ = fmt.Fprintf(w, `{"error":%q}`, err.Error())
+}
+
+// ProxyCluster 将任意 Kubernetes API 请求转发到指定集群
+//
+// 请求使用 Nexus 的令牌认证，转发前会移除 Nexus 的认证信息，
+// 由集群自身的 rest.Config 凭证访问 API Server。管理员直接使用集群凭证，
+// 其他用户的请求模拟为 nexus:<用户名>（用户组 nexus:users），权限由集群中的 RBAC 决定。
+func (h *Handler) ProxyCluster(c *gin.Context) {
+	clusterID := c.Param("id")
+
+	cluster, err := h.manager.GetCluster(clusterID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	if cluster.Config == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cluster config not available"})
+		return
+	}
+
+	config := cluster.RESTConfig()
+	username := middleware.CurrentUser(c)
+	admin := middleware.IsAdmin(username)
+	if !admin && config.Impersonate.UserName != "" {
+		// 集群凭证自身的模拟配置会覆盖按用户设置的 Impersonate-* 头
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cluster credentials already impersonate a user, the proxy is only available to administrators"})
+		return
+	}
+
+	handler, err := h.proxies.get(cluster.ID, config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	path := c.Param("path")
+	if path == "" {
+		path = "/"
+	}
+
+	req := c.Request.Clone(c.Request.Context())
+	req.URL.Path = path
+	req.URL.RawPath = ""
+	// 不能把 Nexus 的令牌和 Cookie 透传给 API Server
+	req.Header.Del("Authorization")
+	req.Header.Del("Cookie")
+	req.Header.Del("X-Cluster-ID")
+	// 集群凭证通常有模拟权限，透传 Impersonate-* 头会让调用方以任意用户身份访问
+	for key := range req.Header {
+		if strings.HasPrefix(key, "Impersonate-") {
+			req.Header.Del(key)
+		}
+	}
+	if !admin {
+		req.Header.Set("Impersonate-User", ProxyUserPrefix+username)
+		req.Header.Set("Impersonate-Group", ProxyUserGroup)
+	}
+
+	handler.ServeHTTP(c.Writer, req)
+}
+
+// GetProxyKubeconfig 生成一个指向 Nexus 代理的 kubeconfig，供 kubectl 直接使用
+//
+// 每次下载都签发一个新的代理令牌，令牌只能访问该集群的代理，有效期由 days 参数指定，
+// 可以随时吊销，与当前的登录会话无关。
+func (h *Handler) GetProxyKubeconfig(c *gin.Context) {
+	clusterID := c.Param("id")
+
+	cluster, err := h.manager.GetCluster(clusterID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	ttl, err := parseProxyTokenTTL(c.Query("days"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	store, ok := h.proxyTokenStore(c)
+	if !ok {
+		return
+	}
+	token, record, err := store.IssueProxyToken(middleware.CurrentUser(c), cluster.ID, ttl)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	klog.Infof("Proxy token %s of cluster %s issued to %s, expires at %s",
+		record.ID, cluster.ID, record.Username, record.ExpiresAt.Format(time.RFC3339))
+
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	host := c.Request.Host
+	// 只有来自受信任反向代理（TRUSTED_PROXIES）的请求才使用 X-Forwarded-* 头
+	if common.IsTrustedProxy(c.RemoteIP()) {
+		if proto := c.GetHeader("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+		if forwardedHost := c.GetHeader("X-Forwarded-Host"); forwardedHost != "" {
+			host = forwardedHost
+		}
+	}
+
+	server := fmt.Sprintf("%s://%s/api/v1/clusters/%s/proxy", scheme, host, url.PathEscape(cluster.ID))
+	name := "nexus-" + cluster.ID
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters[name] = &clientcmdapi.Cluster{Server: server}
+	config.AuthInfos[name] = &clientcmdapi.AuthInfo{Token: token}
+	config.Contexts[name] = &clientcmdapi.Context{
+		Cluster:  name,
+		AuthInfo: name,
+	}
+	config.CurrentContext = name
+
+	content, err := clientcmd.Write(*config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate kubeconfig: " + err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.kubeconfig", name))
+	c.Header("X-Proxy-Token-ID", record.ID)
+	c.Data(http.StatusOK, "application/yaml", content)
+}
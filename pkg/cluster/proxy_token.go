@@ -0,0 +1,211 @@
+package cluster
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ysicing/nexus/pkg/middleware"
+	"github.com/ysicing/nexus/pkg/models"
+	"gorm.io/gorm"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// proxyTokenPrefix 代理令牌的前缀，用于和 Nexus 登录令牌区分
+	proxyTokenPrefix = "nxp_"
+	// defaultProxyTokenDays 代理令牌默认的有效天数
+	defaultProxyTokenDays = 30
+	// maxProxyTokenDays 代理令牌最长的有效天数
+	maxProxyTokenDays = 365
+	// proxyTokenTouchInterval 记录令牌使用时间的最小间隔，避免每个代理请求都写数据库
+	proxyTokenTouchInterval = time.Minute
+)
+
+// ProxyTokenStore 支持代理令牌的集群管理器
+type ProxyTokenStore interface {
+	IssueProxyToken(username, clusterID string, ttl time.Duration) (string, *models.ProxyTokenModel, error)
+	AuthenticateProxyToken(token, clusterID string) (*models.ProxyTokenModel, error)
+	ListProxyTokens(clusterID, username string) ([]*models.ProxyTokenModel, error)
+	RevokeProxyToken(clusterID, id, username string) error
+}
+
+// hashProxyToken 令牌的摘要，数据库中只保存摘要
+func hashProxyToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IssueProxyToken 为用户签发只能访问指定集群代理的令牌，返回令牌本身和保存的记录
+func (m *ManagerWithDB) IssueProxyToken(username, clusterID string, ttl time.Duration) (string, *models.ProxyTokenModel, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, fmt.Errorf("生成代理令牌失败: %w", err)
+	}
+	token := proxyTokenPrefix + hex.EncodeToString(b)
+
+	now := time.Now()
+	record := &models.ProxyTokenModel{
+		ID:        uuid.NewString(),
+		Username:  username,
+		ClusterID: clusterID,
+		TokenHash: hashProxyToken(token),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+	if err := m.proxyTokens.Create(record); err != nil {
+		return "", nil, fmt.Errorf("保存代理令牌失败: %w", err)
+	}
+
+	// 顺便清理已过期的令牌
+	if err := m.proxyTokens.DeleteExpired(now); err != nil {
+		klog.Warningf("清理过期的代理令牌失败: %v", err)
+	}
+	return token, record, nil
+}
+
+// AuthenticateProxyToken 校验代理令牌，令牌必须存在、未过期且属于请求的集群
+func (m *ManagerWithDB) AuthenticateProxyToken(token, clusterID string) (*models.ProxyTokenModel, error) {
+	record, err := m.proxyTokens.GetByHash(hashProxyToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if record.ClusterID != clusterID {
+		return nil, fmt.Errorf("代理令牌不能访问集群 %s", clusterID)
+	}
+	now := time.Now()
+	if now.After(record.ExpiresAt) {
+		return nil, fmt.Errorf("代理令牌已过期")
+	}
+
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) > proxyTokenTouchInterval {
+		if err := m.proxyTokens.Touch(record.ID, now); err != nil {
+			klog.V(2).Infof("记录代理令牌使用时间失败 %s: %v", record.ID, err)
+		}
+	}
+	return record, nil
+}
+
+// ListProxyTokens 列出集群上的代理令牌，username 不为空时只列出该用户的令牌
+func (m *ManagerWithDB) ListProxyTokens(clusterID, username string) ([]*models.ProxyTokenModel, error) {
+	return m.proxyTokens.List(clusterID, username)
+}
+
+// RevokeProxyToken 吊销代理令牌，username 不为空时只能吊销该用户的令牌
+func (m *ManagerWithDB) RevokeProxyToken(clusterID, id, username string) error {
+	return m.proxyTokens.Delete(clusterID, id, username)
+}
+
+// proxyTokenStore 返回支持代理令牌的集群管理器，不支持时返回错误响应
+func (h *Handler) proxyTokenStore(c *gin.Context) (ProxyTokenStore, bool) {
+	store, ok := h.manager.(ProxyTokenStore)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Proxy tokens require database storage"})
+		return nil, false
+	}
+	return store, true
+}
+
+// ProxyAuth 代理路由的认证中间件
+//
+// 携带代理令牌的请求使用令牌认证，令牌只能访问签发时的集群；
+// 其他请求交给 fallback，即 Nexus 的登录认证。
+func (h *Handler) ProxyAuth(fallback gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || !strings.HasPrefix(token, proxyTokenPrefix) {
+			fallback(c)
+			return
+		}
+
+		store, ok := h.manager.(ProxyTokenStore)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Proxy tokens require database storage"})
+			return
+		}
+		record, err := store.AuthenticateProxyToken(token, c.Param("id"))
+		if err != nil {
+			klog.V(2).Infof("Rejected proxy token for cluster %s: %v", c.Param("id"), err)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid, expired or revoked proxy token"})
+			return
+		}
+
+		c.Set("user", gin.H{
+			"id":         record.Username,
+			"username":   record.Username,
+			"name":       record.Username,
+			"avatar_url": "",
+			"provider":   "proxy-token",
+		})
+		c.Next()
+	}
+}
+
+// proxyTokenOwner 查询和吊销令牌时限定的用户，管理员可以管理所有用户的令牌
+func proxyTokenOwner(c *gin.Context) string {
+	username := middleware.CurrentUser(c)
+	if middleware.IsAdmin(username) {
+		return ""
+	}
+	return username
+}
+
+// parseProxyTokenTTL 解析 days 参数，默认 30 天，最长 365 天
+func parseProxyTokenTTL(value string) (time.Duration, error) {
+	days := defaultProxyTokenDays
+	if value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxProxyTokenDays {
+			return 0, fmt.Errorf("days must be between 1 and %d", maxProxyTokenDays)
+		}
+		days = parsed
+	}
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+// ListProxyTokens 列出集群上的代理令牌，管理员可以看到所有用户的令牌
+func (h *Handler) ListProxyTokens(c *gin.Context) {
+	store, ok := h.proxyTokenStore(c)
+	if !ok {
+		return
+	}
+
+	tokens, err := store.ListProxyTokens(c.Param("id"), proxyTokenOwner(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+		"total":  len(tokens),
+	})
+}
+
+// RevokeProxyToken 吊销代理令牌，使用该令牌的 kubeconfig 立即失效
+func (h *Handler) RevokeProxyToken(c *gin.Context) {
+	store, ok := h.proxyTokenStore(c)
+	if !ok {
+		return
+	}
+
+	if err := store.RevokeProxyToken(c.Param("id"), c.Param("tokenId"), proxyTokenOwner(c)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Proxy token not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	klog.Infof("Proxy token %s of cluster %s revoked by %s", c.Param("tokenId"), c.Param("id"), middleware.CurrentUser(c))
+	c.JSON(http.StatusOK, gin.H{"message": "Proxy token revoked successfully"})
+}
@@ -0,0 +1,178 @@
+package cluster
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/ysicing/nexus/pkg/models"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// clusterManagerLease 集群管理器单例任务使用的租约名称
+	clusterManagerLease = "cluster-manager"
+	// registrySyncInterval 从数据库同步集群配置的间隔
+	registrySyncInterval = 10 * time.Second
+)
+
+// connectionFingerprint 连接相关配置的摘要，变化时需要重建客户端
+func connectionFingerprint(server, context, kubeconfigPath, kubeconfigContent, tunnelConfig, sourceFingerprint string) string {
+	h := sha256.New()
+	for _, value := range []string{server, context, kubeconfigPath, kubeconfigContent, tunnelConfig, sourceFingerprint} {
+		h.Write([]byte(value))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// modelConnectionFingerprint 数据库中集群连接配置的摘要
+func modelConnectionFingerprint(model *models.ClusterModel) string {
+	return connectionFingerprint(model.Server, model.Context, model.KubeconfigPath,
+		string(model.KubeconfigContent), string(model.TunnelConfig), model.SourceFingerprint)
+}
+
+// clusterConnectionFingerprint 内存中集群连接配置的摘要，与保存到数据库的内容一致
+func clusterConnectionFingerprint(cluster *ClusterInfo) string {
+	tunnelConfig, _ := cluster.Tunnel.Encode()
+	return connectionFingerprint(cluster.Server, cluster.Context, cluster.KubeconfigPath,
+		cluster.KubeconfigContent, tunnelConfig, cluster.sourceFingerprint)
+}
+
+// applyModelMetadata 使用数据库中较新的元数据和健康状态更新集群，调用方需持有写锁
+func applyModelMetadata(cluster *ClusterInfo, model *models.ClusterModel) {
+	if model.UpdatedAt.After(cluster.UpdatedAt) {
+		cluster.Name = model.Name
+		cluster.Description = model.Description
+		cluster.Labels = model.Labels
+		cluster.PrometheusURL = model.PrometheusURL
+		cluster.PrometheusUsername = model.PrometheusUsername
+		cluster.PrometheusPassword = string(model.PrometheusPassword)
+		cluster.PrometheusEnabled = model.PrometheusEnabled
+		cluster.UpdatedAt = model.UpdatedAt
+	}
+
+	// 健康状态由主副本写入，不修改 updated_at
+	if model.LastCheck.After(cluster.LastCheck) {
+		cluster.Status = ClusterStatus(model.Status)
+		cluster.LastCheck = model.LastCheck
+		if model.Version != "" {
+			cluster.Version = model.Version
+		}
+	}
+}
+
+// syncFromDB 将数据库中的集群配置同步到内存
+//
+// 多个副本共享同一个数据库时，任何副本通过 API 所做的修改都只直接作用于该副本的内存，
+// 其他副本依靠定期同步获得这些修改：连接配置变化的集群重建客户端，
+// 新增和已删除的集群分别加载和移除，其余字段和健康状态直接更新。
+//
+// 读取数据库之后本副本修改过的集群以内存为准，留到下一次同步。
+func (m *ManagerWithDB) syncFromDB() {
+	m.mu.RLock()
+	since := m.writeSeq
+	m.mu.RUnlock()
+
+	clusters, err := m.repo.GetAll()
+	if err != nil {
+		klog.Warningf("从数据库同步集群失败: %v", err)
+		return
+	}
+
+	var reload []string
+	defaultID := ""
+	inDB := make(map[string]bool, len(clusters))
+	changed := func(clusterID string) bool {
+		return m.changedSinceLocked(clusterID, since)
+	}
+
+	m.mu.Lock()
+	for _, model := range clusters {
+		inDB[model.ID] = true
+		if model.IsDefault {
+			defaultID = model.ID
+		}
+		if changed(model.ID) {
+			continue
+		}
+
+		cluster, exists := m.clusters[model.ID]
+		if !exists || clusterConnectionFingerprint(cluster) != modelConnectionFingerprint(model) {
+			reload = append(reload, model.ID)
+			continue
+		}
+		applyModelMetadata(cluster, model)
+	}
+	for id := range m.clusters {
+		if !inDB[id] && !changed(id) {
+			reload = append(reload, id)
+		}
+	}
+
+	// 默认集群以数据库为准，读取数据库之后本副本有过修改时留到下一次同步
+	if _, exists := m.clusters[defaultID]; exists && defaultID != m.defaultID && m.writeSeq == since {
+		m.defaultID = defaultID
+		for id, cluster := range m.clusters {
+			cluster.IsDefault = id == defaultID
+		}
+	}
+	m.pruneWritesLocked(since)
+	m.mu.Unlock()
+
+	if len(reload) > 0 {
+		klog.Infof("从数据库同步集群配置变化: %v", reload)
+		m.reloadClusters(reload, changed)
+	}
+}
+
+// beginWriteLocked 标记集群的内存状态已修改、正在写入数据库，调用方需持有写锁，写入后调用 endWrite
+func (m *ManagerWithDB) beginWriteLocked(clusterID string) {
+	m.pendingWrites[clusterID]++
+}
+
+// endWrite 标记集群已写入数据库
+func (m *ManagerWithDB) endWrite(clusterID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.pendingWrites[clusterID]--; m.pendingWrites[clusterID] <= 0 {
+		delete(m.pendingWrites, clusterID)
+	}
+	m.wroteLocked(clusterID)
+}
+
+// wroteLocked 记录集群刚写入数据库，调用方需持有写锁
+func (m *ManagerWithDB) wroteLocked(clusterID string) {
+	m.writeSeq++
+	m.writtenAt[clusterID] = m.writeSeq
+}
+
+// changedSinceLocked 集群是否正在写入，或在序号 since 之后写入过数据库，调用方需持有锁
+func (m *ManagerWithDB) changedSinceLocked(clusterID string, since uint64) bool {
+	return m.pendingWrites[clusterID] > 0 || m.writtenAt[clusterID] > since
+}
+
+// pruneWritesLocked 清理序号 since 之前的写入记录，之后的同步读取的数据库已包含这些写入
+func (m *ManagerWithDB) pruneWritesLocked(since uint64) {
+	for id, seq := range m.writtenAt {
+		if seq <= since {
+			delete(m.writtenAt, id)
+		}
+	}
+}
+
+// runRegistrySync 定期从数据库同步集群配置
+func (m *ManagerWithDB) runRegistrySync() {
+	ticker := time.NewTicker(registrySyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.syncFromDB()
+		case <-m.stopCh:
+			return
+		}
+	}
+}
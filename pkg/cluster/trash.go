@@ -0,0 +1,239 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ysicing/nexus/pkg/common"
+	"github.com/ysicing/nexus/pkg/models"
+	"gorm.io/gorm"
+	"k8s.io/klog/v2"
+)
+
+// trashPurgeInterval 清理回收站中过期集群的间隔
+const trashPurgeInterval = time.Hour
+
+// DeletedCluster 回收站中的集群
+type DeletedCluster struct {
+	ID             string            `json:"id"`
+	Name           string            `json:"name"`
+	Description    string            `json:"description,omitempty"`
+	Server         string            `json:"server"`
+	Context        string            `json:"context,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	KubeconfigPath string            `json:"kubeconfigPath,omitempty"`
+	CreatedAt      time.Time         `json:"createdAt"`
+	DeletedAt      time.Time         `json:"deletedAt"`
+	// PurgeAt 将被永久删除的时间，为空表示永久保留
+	PurgeAt *time.Time `json:"purgeAt,omitempty"`
+}
+
+// ClusterTrash 支持回收站的集群管理器
+type ClusterTrash interface {
+	ListDeletedClusters() ([]*DeletedCluster, error)
+	RestoreCluster(clusterID string) (*ClusterInfo, error)
+	PurgeCluster(clusterID string) error
+}
+
+// trashRetention 回收站保留时间，0 表示永久保留
+func trashRetention() time.Duration {
+	return time.Duration(common.ClusterTrashRetentionDays) * 24 * time.Hour
+}
+
+// deletedClusterFromModel 将已删除的数据库模型转换为回收站中的集群
+func deletedClusterFromModel(model *models.ClusterModel) *DeletedCluster {
+	deleted := &DeletedCluster{
+		ID:             model.ID,
+		Name:           model.Name,
+		Description:    model.Description,
+		Server:         model.Server,
+		Context:        model.Context,
+		Labels:         model.Labels,
+		KubeconfigPath: model.KubeconfigPath,
+		CreatedAt:      model.CreatedAt,
+		DeletedAt:      model.DeletedAt.Time,
+	}
+	if retention := trashRetention(); retention > 0 {
+		purgeAt := model.DeletedAt.Time.Add(retention)
+		deleted.PurgeAt = &purgeAt
+	}
+	return deleted
+}
+
+// ListDeletedClusters 列出回收站中的集群
+func (m *ManagerWithDB) ListDeletedClusters() ([]*DeletedCluster, error) {
+	clusters, err := m.repo.GetDeleted()
+	if err != nil {
+		return nil, fmt.Errorf("获取回收站集群列表失败: %w", err)
+	}
+
+	deleted := make([]*DeletedCluster, 0, len(clusters))
+	for _, model := range clusters {
+		deleted = append(deleted, deletedClusterFromModel(model))
+	}
+	return deleted, nil
+}
+
+// RestoreCluster 从回收站恢复集群，并使用保存的配置重建客户端
+func (m *ManagerWithDB) RestoreCluster(clusterID string) (*ClusterInfo, error) {
+	m.mu.RLock()
+	_, exists := m.clusters[clusterID]
+	m.mu.RUnlock()
+	if exists {
+		return nil, fmt.Errorf("集群 %s 已存在", clusterID)
+	}
+
+	if err := m.repo.Restore(clusterID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("回收站中不存在集群 %s", clusterID)
+		}
+		return nil, fmt.Errorf("恢复集群失败: %w", err)
+	}
+
+	model, err := m.repo.GetByID(clusterID)
+	if err != nil {
+		return nil, fmt.Errorf("获取集群失败: %w", err)
+	}
+	clusterInfo, err := m.modelToClusterInfo(model)
+	if err != nil {
+		return nil, err
+	}
+	clusterInfo.Status = ClusterStatusUnknown
+	if clusterInfo.Client == nil {
+		klog.Warningf("恢复的集群 %s 暂时无法连接", clusterInfo.Name)
+	}
+
+	m.mu.Lock()
+	if _, exists := m.clusters[clusterID]; exists {
+		// 恢复期间同 ID 的集群已被重新添加（例如 kubeconfig 文件同步）
+		m.mu.Unlock()
+		clusterInfo.disconnect()
+		return nil, fmt.Errorf("集群 %s 已存在", clusterID)
+	}
+	m.clusters[clusterID] = clusterInfo
+	m.wroteLocked(clusterID)
+	if m.defaultID == "" {
+		m.defaultID = clusterID
+		clusterInfo.IsDefault = true
+		if err := m.repo.SetDefault(clusterID); err != nil {
+			klog.Warningf("更新默认集群到数据库失败: %v", err)
+		}
+	}
+	m.mu.Unlock()
+
+	klog.Infof("从回收站恢复集群: %s (%s)", clusterInfo.Name, clusterID)
+	return clusterInfo, nil
+}
+
+// PurgeCluster 从回收站中永久删除集群
+func (m *ManagerWithDB) PurgeCluster(clusterID string) error {
+	if err := m.repo.Purge(clusterID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("回收站中不存在集群 %s", clusterID)
+		}
+		return fmt.Errorf("永久删除集群失败: %w", err)
+	}
+
+	klog.Infof("永久删除集群: %s", clusterID)
+	return nil
+}
+
+// purgeExpiredClusters 永久删除超过保留时间的集群
+func (m *ManagerWithDB) purgeExpiredClusters() {
+	retention := trashRetention()
+	if retention <= 0 || !m.isLeader() {
+		return
+	}
+
+	ids, err := m.repo.PurgeDeletedBefore(time.Now().Add(-retention))
+	if err != nil {
+		klog.Warningf("清理回收站失败: %v", err)
+		return
+	}
+	if len(ids) > 0 {
+		klog.Infof("永久删除回收站中超过 %d 天的集群: %v", common.ClusterTrashRetentionDays, ids)
+	}
+}
+
+// runTrashPurger 定期清理回收站
+func (m *ManagerWithDB) runTrashPurger() {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	m.purgeExpiredClusters()
+	for {
+		select {
+		case <-ticker.C:
+			m.purgeExpiredClusters()
+		case <-m.stopCh:
+			return
+		}
+	}
+}
+
+// trash 返回支持回收站的集群管理器，不支持时返回错误响应
+func (h *Handler) trash(c *gin.Context) (ClusterTrash, bool) {
+	trash, ok := h.manager.(ClusterTrash)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Cluster recycle bin requires database storage"})
+		return nil, false
+	}
+	return trash, true
+}
+
+// ListDeletedClusters 列出回收站中的集群
+func (h *Handler) ListDeletedClusters(c *gin.Context) {
+	trash, ok := h.trash(c)
+	if !ok {
+		return
+	}
+
+	clusters, err := trash.ListDeletedClusters()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"clusters":      clusters,
+		"total":         len(clusters),
+		"retentionDays": common.ClusterTrashRetentionDays,
+	})
+}
+
+// RestoreCluster 从回收站恢复集群
+func (h *Handler) RestoreCluster(c *gin.Context) {
+	trash, ok := h.trash(c)
+	if !ok {
+		return
+	}
+
+	cluster, err := trash.RestoreCluster(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cluster restored successfully",
+		"cluster": clusterResponse(cluster),
+	})
+}
+
+// PurgeCluster 从回收站中永久删除集群
+func (h *Handler) PurgeCluster(c *gin.Context) {
+	trash, ok := h.trash(c)
+	if !ok {
+		return
+	}
+
+	if err := trash.PurgeCluster(c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cluster purged successfully"})
+}
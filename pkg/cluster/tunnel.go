@@ -0,0 +1,70 @@
+package cluster
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ysicing/nexus/pkg/kube"
+	"github.com/ysicing/nexus/pkg/tunnel"
+	"k8s.io/client-go/rest"
+)
+
+// newClusterClient 按集群的连接方式建立通道并创建客户端
+//
+// 客户端使用的 REST 配置带有通道的代理设置，exec/terminal 的 SPDY 连接会随之经过同一通道。
+func newClusterClient(config *rest.Config, tunnelConfig *tunnel.Config) (*kube.K8sClient, *tunnel.Tunnel, error) {
+	if err := validateTunnel(config, tunnelConfig); err != nil {
+		return nil, nil, err
+	}
+
+	t, err := tunnel.Open(tunnelConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open cluster tunnel: %w", err)
+	}
+
+	client, err := kube.NewK8sClientFromConfig(t.RESTConfig(config))
+	if err != nil {
+		t.Close()
+		return nil, nil, err
+	}
+	return client, t, nil
+}
+
+// validateTunnel 校验连接方式，kubeconfig 中已配置代理（proxy-url）时只能直接连接，
+// 否则通道的代理会覆盖 kubeconfig 中的代理
+func validateTunnel(config *rest.Config, tunnelConfig *tunnel.Config) error {
+	if err := tunnelConfig.Validate(); err != nil {
+		return err
+	}
+	if config.Proxy != nil && !tunnelConfig.IsDirect() {
+		return fmt.Errorf("the kubeconfig proxy url cannot be combined with a %s tunnel, remove one of them", tunnelConfig.Type)
+	}
+	return nil
+}
+
+// RESTConfig 返回访问集群实际使用的 REST 配置，包含连接通道的代理设置
+func (c *ClusterInfo) RESTConfig() *rest.Config {
+	if c.Client != nil && c.Client.Configuration != nil {
+		return c.Client.Configuration
+	}
+	return c.tunnel.RESTConfig(c.Config)
+}
+
+// HTTPTransport 返回经过集群连接通道的 HTTP Transport，用于访问集群内的 Prometheus 等服务
+func (c *ClusterInfo) HTTPTransport() http.RoundTripper {
+	return c.tunnel.Transport()
+}
+
+// ConnectionType 集群的连接方式
+func (c *ClusterInfo) ConnectionType() tunnel.Type {
+	if c.Tunnel.IsDirect() {
+		return tunnel.TypeDirect
+	}
+	return c.Tunnel.Type
+}
+
+// disconnect 停止集群客户端并关闭连接通道
+func (c *ClusterInfo) disconnect() {
+	c.Client.Stop()
+	c.tunnel.Close()
+}
@@ -1,7 +1,12 @@
 package common
 
 import (
+	"errors"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ysicing/nexus/pkg/utils"
 	"k8s.io/klog/v2"
@@ -11,26 +16,42 @@ const (
 	JWTExpirationSeconds = 24 * 60 * 60 // 24 hours
 
 	NodeTerminalPodName = "kite-node-terminal-agent"
+	// NodeTerminalNamespace is where node terminal agent pods are created
+	NodeTerminalNamespace = "kube-system"
+	// NodeTerminalLabel marks node terminal agent pods so stale ones can be cleaned up
+	NodeTerminalLabel = "nexus.ysicing.net/node-terminal"
+	// NodeTerminalHeartbeatAnnotation is refreshed while a node terminal session is alive
+	NodeTerminalHeartbeatAnnotation = "nexus.ysicing.net/heartbeat"
+	// NodeTerminalHeartbeatInterval is how often the heartbeat annotation is refreshed
+	NodeTerminalHeartbeatInterval = 30 * time.Second
 )
 
 var (
-	Port            = "8080"
-	JwtSecret       = ""
-	OAuthEnabled    = false
-	OAuthProviders  = ""
-	OAuthAllowUsers = ""
-	EnableAnalytics = false
+	Port      = "8080"
+	JwtSecret = ""
+	// AdminUsers is a comma separated list of users allowed to call admin APIs, "*" allows everyone
+	AdminUsers = ""
 
-	NodeTerminalImage = "busybox:latest"
-
-	WebhookUsername = "kite-webhook"
-	WebhookPassword = "kite-webhook-password"
+	// EncryptionKey is used to encrypt cluster credentials stored in the database.
+	// There is no default: the database mode refuses to start without ENCRYPTION_KEY.
+	EncryptionKey = ""
 
 	KiteUsername         = os.Getenv("KITE_USERNAME")
 	KitePassword         = os.Getenv("KITE_PASSWORD")
 	PasswordLoginEnabled = KiteUsername != "" && KitePassword != ""
 
-	Readonly = false
+	// ReplicaID identifies this process when several replicas share one database
+	ReplicaID = defaultReplicaID()
+
+	// TrustedProxies lists the reverse proxies (IPs or CIDRs) whose X-Forwarded-* headers are trusted
+	TrustedProxies   []string
+	trustedProxyNets []*net.IPNet
+
+	// ClusterTrashRetentionDays is how long deleted clusters stay in the recycle bin, 0 keeps them forever
+	ClusterTrashRetentionDays = 30
+
+	// ClusterCredentialWarningDays marks a healthy cluster as warning when a credential expires within this many days
+	ClusterCredentialWarningDays = 30
 )
 
 func LoadEnvs() {
@@ -47,15 +68,16 @@ func LoadEnvs() {
 		JwtSecret = utils.RandomString(32)
 	}
 
+	settings := DefaultSettings()
 	if enabled := os.Getenv("OAUTH_ENABLED"); enabled == "true" {
-		OAuthEnabled = true
+		settings.OAuthEnabled = true
 		if providers := os.Getenv("OAUTH_PROVIDERS"); providers != "" {
-			OAuthProviders = providers
+			settings.OAuthProviders = providers
 		} else {
 			klog.Warning("OAUTH_PROVIDERS is not set, OAuth will not work as expected")
 		}
 		if allowUsers := os.Getenv("OAUTH_ALLOW_USERS"); allowUsers != "" {
-			OAuthAllowUsers = allowUsers
+			settings.OAuthAllowUsers = allowUsers
 		} else {
 			klog.Warning("OAUTH_ALLOW_USERS is not set, OAuth will not work as expected")
 		}
@@ -68,22 +90,107 @@ func LoadEnvs() {
 	}
 
 	if analytics := os.Getenv("ENABLE_ANALYTICS"); analytics == "true" {
-		EnableAnalytics = true
+		settings.EnableAnalytics = true
 	}
 
 	if nodeTerminalImage := os.Getenv("NODE_TERMINAL_IMAGE"); nodeTerminalImage != "" {
-		NodeTerminalImage = nodeTerminalImage
+		settings.NodeTerminalImage = nodeTerminalImage
 	}
 
 	if webhookUsername := os.Getenv("WEBHOOK_USERNAME"); webhookUsername != "" {
-		WebhookUsername = webhookUsername
+		settings.WebhookUsername = webhookUsername
 	}
 	if webhookPassword := os.Getenv("WEBHOOK_PASSWORD"); webhookPassword != "" {
-		WebhookPassword = webhookPassword
+		settings.WebhookPassword = webhookPassword
 	} else {
 		klog.Warning("WEBHOOK_PASSWORD is not set, using default password")
 	}
+	if encryptionKey := os.Getenv("ENCRYPTION_KEY"); encryptionKey != "" {
+		EncryptionKey = encryptionKey
+	}
+	if adminUsers := os.Getenv("ADMIN_USERS"); adminUsers != "" {
+		AdminUsers = adminUsers
+	}
+	if retention := os.Getenv("CLUSTER_TRASH_RETENTION_DAYS"); retention != "" {
+		if days, err := strconv.Atoi(retention); err == nil && days >= 0 {
+			ClusterTrashRetentionDays = days
+		} else {
+			klog.Warningf("Invalid CLUSTER_TRASH_RETENTION_DAYS %q, using %d days", retention, ClusterTrashRetentionDays)
+		}
+	}
+	if warning := os.Getenv("CLUSTER_CREDENTIAL_WARNING_DAYS"); warning != "" {
+		if days, err := strconv.Atoi(warning); err == nil && days >= 0 {
+			ClusterCredentialWarningDays = days
+		} else {
+			klog.Warningf("Invalid CLUSTER_CREDENTIAL_WARNING_DAYS %q, using %d days", warning, ClusterCredentialWarningDays)
+		}
+	}
+	if replicaID := os.Getenv("NEXUS_REPLICA_ID"); replicaID != "" {
+		ReplicaID = replicaID
+	}
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		setTrustedProxies(proxies)
+	}
 	if readonly := os.Getenv("READONLY"); readonly == "true" {
-		Readonly = true
+		settings.Readonly = true
+	}
+	SetSettings(settings)
+}
+
+// RequireEncryptionKey returns an error unless ENCRYPTION_KEY is set. Credentials
+// stored in the database must not be encrypted with a key known to anyone else.
+func RequireEncryptionKey() error {
+	if EncryptionKey == "" {
+		return errors.New("ENCRYPTION_KEY must be set when DATABASE_DSN is used, it encrypts the credentials stored in the database")
+	}
+	return nil
+}
+
+// setTrustedProxies parses a comma separated list of IPs and CIDRs
+func setTrustedProxies(proxies string) {
+	for proxy := range strings.SplitSeq(proxies, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		cidr := proxy
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			klog.Warningf("Invalid TRUSTED_PROXIES entry %q, ignoring it", proxy)
+			continue
+		}
+		TrustedProxies = append(TrustedProxies, proxy)
+		trustedProxyNets = append(trustedProxyNets, ipNet)
+	}
+}
+
+// IsTrustedProxy reports whether the remote address belongs to TrustedProxies
+func IsTrustedProxy(remoteIP string) bool {
+	ip := net.ParseIP(remoteIP)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range trustedProxyNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultReplicaID returns the hostname with a random suffix, so that a
+// restarted process never mistakes a lease held by its predecessor for its own.
+func defaultReplicaID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "nexus"
 	}
+	return hostname + "-" + utils.RandomString(6)
 }
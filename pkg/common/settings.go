@@ -0,0 +1,46 @@
+package common
+
+import "sync/atomic"
+
+// RuntimeSettings holds the settings that administrators can change at runtime.
+// Environment variables provide the values at startup, and with a database the
+// settings API may override them without a restart.
+type RuntimeSettings struct {
+	OAuthEnabled    bool
+	OAuthProviders  string
+	OAuthAllowUsers string
+
+	WebhookUsername string
+	WebhookPassword string
+
+	NodeTerminalImage string
+
+	Readonly        bool
+	EnableAnalytics bool
+}
+
+var runtimeSettings atomic.Pointer[RuntimeSettings]
+
+func init() {
+	defaults := DefaultSettings()
+	runtimeSettings.Store(&defaults)
+}
+
+// DefaultSettings returns the built-in defaults used when no environment variable is set.
+func DefaultSettings() RuntimeSettings {
+	return RuntimeSettings{
+		WebhookUsername:   "kite-webhook",
+		WebhookPassword:   "kite-webhook-password",
+		NodeTerminalImage: "busybox:latest",
+	}
+}
+
+// Settings returns the current runtime settings.
+func Settings() RuntimeSettings {
+	return *runtimeSettings.Load()
+}
+
+// SetSettings replaces the runtime settings, taking effect for subsequent requests.
+func SetSettings(settings RuntimeSettings) {
+	runtimeSettings.Store(&settings)
+}
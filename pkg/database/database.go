@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/glebarez/sqlite"
@@ -23,6 +24,9 @@ type DatabaseConfig struct {
 	MaxIdleConns    int           `json:"maxIdleConns"`
 	MaxOpenConns    int           `json:"maxOpenConns"`
 	ConnMaxLifetime time.Duration `json:"connMaxLifetime"`
+
+	// LogLevel SQL 日志级别：silent、error、warn、info，默认 warn
+	LogLevel string `json:"logLevel"`
 }
 
 // Database 数据库管理器
@@ -95,7 +99,7 @@ func (d *Database) initialize() error {
 
 	// 配置 GORM
 	config := &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: logger.Default.LogMode(parseLogLevel(d.config.LogLevel)),
 	}
 
 	// 建立数据库连接
@@ -127,6 +131,11 @@ func (d *Database) GetClusterRepository() models.ClusterRepository {
 	return d.clusterRepo
 }
 
+// GetDB 获取数据库连接，用于需要事务的场景
+func (d *Database) GetDB() *gorm.DB {
+	return d.db
+}
+
 // Close 关闭数据库连接
 func (d *Database) Close() error {
 	if d.db != nil {
@@ -148,11 +157,30 @@ func GetDefaultConfig() *DatabaseConfig {
 		MaxIdleConns:    getEnvInt("DB_MAX_IDLE_CONNS", 10),
 		MaxOpenConns:    getEnvInt("DB_MAX_OPEN_CONNS", 100),
 		ConnMaxLifetime: time.Duration(getEnvInt("DB_CONN_MAX_LIFETIME", 3600)) * time.Second,
+		LogLevel:        getEnvString("DB_LOG_LEVEL", "warn"),
 	}
 
 	return config
 }
 
+// parseLogLevel 解析 SQL 日志级别，无法识别时使用 warn，
+// info 会记录每一条 SQL，只适合排查问题时临时开启
+func parseLogLevel(level string) logger.LogLevel {
+	switch strings.ToLower(level) {
+	case "silent":
+		return logger.Silent
+	case "error":
+		return logger.Error
+	case "info":
+		return logger.Info
+	case "", "warn":
+		return logger.Warn
+	default:
+		log.Printf("Unknown DB_LOG_LEVEL %q, using warn", level)
+		return logger.Warn
+	}
+}
+
 // getEnvString 获取环境变量字符串值
 func getEnvString(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -171,16 +199,3 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
-
-// MigrateDatabase 执行数据库迁移
-func (d *Database) MigrateDatabase() error {
-	log.Println("Running database migrations...")
-
-	// 自动迁移集群模型
-	if err := d.db.AutoMigrate(&models.ClusterModel{}); err != nil {
-		return fmt.Errorf("failed to migrate cluster model: %w", err)
-	}
-
-	log.Println("Database migrations completed successfully")
-	return nil
-}
@@ -0,0 +1,392 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// migrationLockName 迁移锁名称，避免多个副本同时执行迁移
+const migrationLockName = "nexus_schema_migrations"
+
+// SchemaMigration 已执行的迁移记录
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false" json:"version"`
+	Name      string    `gorm:"not null;size:255" json:"name"`
+	Checksum  string    `gorm:"not null;size:64" json:"checksum"`
+	AppliedAt time.Time `json:"appliedAt"`
+	// Duration 执行耗时（毫秒）
+	Duration int64 `json:"duration"`
+}
+
+// TableName 指定表名
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Migration 版本化的数据库迁移
+//
+// 已发布的迁移不能再修改，结构变化需要追加新的迁移。
+// 迁移的校验和由各步骤的描述计算，修改已执行的迁移会导致启动失败。
+type Migration struct {
+	Version int
+	Name    string
+	Steps   []Step
+}
+
+// Step 迁移步骤
+type Step struct {
+	// description 参与校验和计算，需要完整描述步骤的内容
+	description string
+	apply       func(tx *gorm.DB) error
+}
+
+// Checksum 迁移的校验和
+func (m Migration) Checksum() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n%s\n", m.Version, m.Name)
+	for _, step := range m.Steps {
+		h.Write([]byte(step.description))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// CreateTables 创建表，已存在的表补充缺少的列和索引
+//
+// 传入的模型必须是迁移内定义的结构快照，不能直接使用 models 包中会继续变化的模型。
+func CreateTables(snapshots ...interface{}) Step {
+	descriptions := make([]string, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		descriptions = append(descriptions, describeModel(snapshot))
+	}
+	return Step{
+		description: "create tables\n" + strings.Join(descriptions, "\n"),
+		apply: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(snapshots...)
+		},
+	}
+}
+
+// AddColumn 添加列，field 为快照结构中的字段名
+func AddColumn(snapshot interface{}, field string) Step {
+	return Step{
+		description: fmt.Sprintf("add column %s\n%s", field, describeModel(snapshot)),
+		apply: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(snapshot, field) {
+				return nil
+			}
+			return tx.Migrator().AddColumn(snapshot, field)
+		},
+	}
+}
+
+// DropColumn 删除列
+//
+// 使用 ALTER TABLE 语句，要求 SQLite 3.35、MySQL 8.0 或更新的版本。
+func DropColumn(table, column string) Step {
+	return Step{
+		description: fmt.Sprintf("drop column %s.%s", table, column),
+		apply: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(table, column) {
+				return nil
+			}
+			return tx.Exec("ALTER TABLE ? DROP COLUMN ?", clause.Table{Name: table}, clause.Column{Name: column}).Error
+		},
+	}
+}
+
+// RenameColumn 重命名列
+//
+// 使用 ALTER TABLE 语句，要求 SQLite 3.25、MySQL 8.0 或更新的版本。
+func RenameColumn(table, oldName, newName string) Step {
+	return Step{
+		description: fmt.Sprintf("rename column %s.%s to %s", table, oldName, newName),
+		apply: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(table, oldName) {
+				return nil
+			}
+			return tx.Exec("ALTER TABLE ? RENAME COLUMN ? TO ?",
+				clause.Table{Name: table}, clause.Column{Name: oldName}, clause.Column{Name: newName}).Error
+		},
+	}
+}
+
+// Exec 执行 SQL，statements 以数据库类型（sqlite、mysql、postgres）为键，"*" 表示所有数据库
+func Exec(statements map[string]string) Step {
+	dialects := make([]string, 0, len(statements))
+	for dialect := range statements {
+		dialects = append(dialects, dialect)
+	}
+	sort.Strings(dialects)
+
+	var b strings.Builder
+	b.WriteString("exec")
+	for _, dialect := range dialects {
+		fmt.Fprintf(&b, "\n%s: %s", dialect, statements[dialect])
+	}
+
+	return Step{
+		description: b.String(),
+		apply: func(tx *gorm.DB) error {
+			statement, ok := statements[tx.Dialector.Name()]
+			if !ok {
+				statement, ok = statements["*"]
+			}
+			if !ok {
+				return nil
+			}
+			return tx.Exec(statement).Error
+		},
+	}
+}
+
+// Run 执行数据迁移等无法用结构快照描述的操作
+//
+// description 参与校验和计算，修改 apply 的行为时需要同时修改 description。
+func Run(description string, apply func(tx *gorm.DB) error) Step {
+	return Step{
+		description: "run " + description,
+		apply:       apply,
+	}
+}
+
+// describeModel 描述结构快照的表名和字段，用于计算校验和
+func describeModel(snapshot interface{}) string {
+	t := reflect.TypeOf(snapshot)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	table := t.Name()
+	if tabler, ok := snapshot.(interface{ TableName() string }); ok {
+		table = tabler.TableName()
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "table %s", table)
+	describeFields(&b, t)
+	return b.String()
+}
+
+func describeFields(b *strings.Builder, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			describeFields(b, field.Type)
+			continue
+		}
+		fmt.Fprintf(b, "\n  %s %s `%s`", field.Name, field.Type.String(), field.Tag.Get("gorm"))
+	}
+}
+
+// MigrationStatus 迁移状态
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+	// ChecksumMismatch 已执行的迁移在代码中被修改
+	ChecksumMismatch bool `json:"checksumMismatch,omitempty"`
+	// Unknown 数据库中存在但当前版本没有的迁移，通常说明数据库被更新版本的 Nexus 迁移过
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+// MigrationStatus 返回所有迁移的执行状态
+func (d *Database) MigrationStatus() ([]MigrationStatus, error) {
+	if err := d.db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	applied, err := appliedMigrations(d.db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	known := make(map[int]bool, len(migrations))
+	for _, migration := range migrations {
+		known[migration.Version] = true
+		status := MigrationStatus{
+			Version: migration.Version,
+			Name:    migration.Name,
+		}
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.ChecksumMismatch = record.Checksum != migration.Checksum()
+		}
+		statuses = append(statuses, status)
+	}
+	for version, record := range applied {
+		if known[version] {
+			continue
+		}
+		appliedAt := record.AppliedAt
+		statuses = append(statuses, MigrationStatus{
+			Version:   version,
+			Name:      record.Name,
+			Applied:   true,
+			AppliedAt: &appliedAt,
+			Unknown:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, nil
+}
+
+// MigrateDatabase 执行数据库迁移
+//
+// 先校验已执行迁移的校验和，再按版本顺序执行未执行的迁移，每个迁移及其记录在同一个事务中提交。
+// MySQL 的 DDL 会隐式提交事务，迁移失败时已执行的结构变更不会回滚。
+func (d *Database) MigrateDatabase() error {
+	log.Println("Running database migrations...")
+
+	if err := d.db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	// 固定一个连接，使数据库锁和迁移使用同一个会话
+	return d.db.Connection(func(conn *gorm.DB) error {
+		// Connection 返回的实例在链式调用之间共享查询条件，需要新的会话
+		conn = conn.Session(&gorm.Session{NewDB: true})
+
+		unlock, err := lockMigrations(conn)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		if err := verifyMigrations(applied); err != nil {
+			return err
+		}
+
+		count := 0
+		for _, migration := range migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := runMigration(conn, migration); err != nil {
+				return err
+			}
+			count++
+		}
+
+		log.Printf("Database migrations completed successfully, %d applied", count)
+		return nil
+	})
+}
+
+// verifyMigrations 校验已执行的迁移与代码一致
+func verifyMigrations(applied map[int]SchemaMigration) error {
+	known := make(map[int]Migration, len(migrations))
+	for _, migration := range migrations {
+		known[migration.Version] = migration
+	}
+
+	for version, record := range applied {
+		migration, ok := known[version]
+		if !ok {
+			return fmt.Errorf("database has migration %d (%s) unknown to this version, refusing to start with a newer schema", version, record.Name)
+		}
+		if record.Checksum != migration.Checksum() {
+			return fmt.Errorf("checksum mismatch for migration %d (%s): applied migrations must not be modified", version, migration.Name)
+		}
+	}
+	return nil
+}
+
+// runMigration 在事务中执行迁移并记录
+func runMigration(conn *gorm.DB, migration Migration) error {
+	log.Printf("Applying migration %d: %s", migration.Version, migration.Name)
+	start := time.Now()
+
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		for i, step := range migration.Steps {
+			if err := step.apply(tx); err != nil {
+				return fmt.Errorf("step %d: %w", i+1, err)
+			}
+		}
+		return tx.Create(&SchemaMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Checksum:  migration.Checksum(),
+			AppliedAt: time.Now(),
+			Duration:  time.Since(start).Milliseconds(),
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply migration %d (%s): %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// appliedMigrations 查询已执行的迁移
+func appliedMigrations(db *gorm.DB) (map[int]SchemaMigration, error) {
+	var records []SchemaMigration
+	if err := db.Order(clause.OrderByColumn{Column: clause.Column{Name: "version"}}).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+
+	applied := make(map[int]SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// lockMigrations 获取迁移锁，SQLite 依靠数据库文件锁，不需要额外加锁
+func lockMigrations(conn *gorm.DB) (func(), error) {
+	switch conn.Dialector.Name() {
+	case "postgres":
+		key := migrationLockKey()
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", key).Error; err != nil {
+			return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		return func() {
+			if err := conn.Exec("SELECT pg_advisory_unlock(?)", key).Error; err != nil {
+				log.Printf("Failed to release migration lock: %v", err)
+			}
+		}, nil
+	case "mysql":
+		var acquired int
+		if err := conn.Raw("SELECT GET_LOCK(?, 300)", migrationLockName).Scan(&acquired).Error; err != nil {
+			return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if acquired != 1 {
+			return nil, fmt.Errorf("timed out waiting for migration lock")
+		}
+		return func() {
+			if err := conn.Exec("SELECT RELEASE_LOCK(?)", migrationLockName).Error; err != nil {
+				log.Printf("Failed to release migration lock: %v", err)
+			}
+		}, nil
+	default:
+		return func() {}, nil
+	}
+}
+
+// migrationLockKey PostgreSQL advisory lock 使用的整数键
+func migrationLockKey() int64 {
+	sum := sha256.Sum256([]byte(migrationLockName))
+	var key int64
+	for _, b := range sum[:8] {
+		key = key<<8 | int64(b)
+	}
+	return key
+}
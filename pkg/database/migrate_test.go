@@ -0,0 +1,157 @@
+package database
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ysicing/nexus/pkg/common"
+)
+
+// testSnapshotV1 用于校验和测试的结构快照
+type testSnapshotV1 struct {
+	ID   string `gorm:"primaryKey;size:255"`
+	Name string `gorm:"size:255"`
+}
+
+func (testSnapshotV1) TableName() string { return "test_snapshots" }
+
+// testSnapshotSize 与 testSnapshotV1 相比只修改了 Name 的长度
+type testSnapshotSize struct {
+	ID   string `gorm:"primaryKey;size:255"`
+	Name string `gorm:"size:512"`
+}
+
+func (testSnapshotSize) TableName() string { return "test_snapshots" }
+
+// testSnapshotType 与 testSnapshotV1 相比只修改了 Name 的类型
+type testSnapshotType struct {
+	ID   string `gorm:"primaryKey;size:255"`
+	Name []byte `gorm:"size:255"`
+}
+
+func (testSnapshotType) TableName() string { return "test_snapshots" }
+
+func TestMigrationChecksum(t *testing.T) {
+	base := Migration{Version: 1, Name: "test", Steps: []Step{CreateTables(&testSnapshotV1{})}}
+
+	tests := []struct {
+		name      string
+		migration Migration
+		changed   bool
+	}{
+		{name: "相同的迁移", migration: Migration{Version: 1, Name: "test", Steps: []Step{CreateTables(&testSnapshotV1{})}}},
+		{name: "快照传值或指针", migration: Migration{Version: 1, Name: "test", Steps: []Step{CreateTables(testSnapshotV1{})}}},
+		{name: "修改版本号", migration: Migration{Version: 2, Name: "test", Steps: []Step{CreateTables(&testSnapshotV1{})}}, changed: true},
+		{name: "修改名称", migration: Migration{Version: 1, Name: "renamed", Steps: []Step{CreateTables(&testSnapshotV1{})}}, changed: true},
+		{name: "修改字段标签", migration: Migration{Version: 1, Name: "test", Steps: []Step{CreateTables(&testSnapshotSize{})}}, changed: true},
+		{name: "修改字段类型", migration: Migration{Version: 1, Name: "test", Steps: []Step{CreateTables(&testSnapshotType{})}}, changed: true},
+		{name: "增加步骤", migration: Migration{Version: 1, Name: "test", Steps: []Step{CreateTables(&testSnapshotV1{}), DropColumn("test_snapshots", "name")}}, changed: true},
+		{name: "修改步骤参数", migration: Migration{Version: 1, Name: "test", Steps: []Step{DropColumn("test_snapshots", "id")}}, changed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if changed := tt.migration.Checksum() != base.Checksum(); changed != tt.changed {
+				t.Errorf("校验和变化 = %v, want %v", changed, tt.changed)
+			}
+		})
+	}
+}
+
+func TestVerifyMigrations(t *testing.T) {
+	record := func(migration Migration) SchemaMigration {
+		return SchemaMigration{Version: migration.Version, Name: migration.Name, Checksum: migration.Checksum()}
+	}
+	first, last := migrations[0], migrations[len(migrations)-1]
+
+	tests := []struct {
+		name    string
+		applied map[int]SchemaMigration
+		wantErr string
+	}{
+		{name: "新数据库", applied: map[int]SchemaMigration{}},
+		{name: "部分已执行", applied: map[int]SchemaMigration{first.Version: record(first)}},
+		{name: "全部已执行", applied: map[int]SchemaMigration{first.Version: record(first), last.Version: record(last)}},
+		{
+			name:    "已执行的迁移被修改",
+			applied: map[int]SchemaMigration{first.Version: {Version: first.Version, Name: first.Name, Checksum: "modified"}},
+			wantErr: "checksum mismatch",
+		},
+		{
+			name:    "更新版本执行的迁移",
+			applied: map[int]SchemaMigration{last.Version + 1: {Version: last.Version + 1, Name: "future"}},
+			wantErr: "unknown to this version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyMigrations(tt.applied)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyMigrations 返回错误: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("verifyMigrations 返回 %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMigrateDatabaseRejectsModifiedMigration(t *testing.T) {
+	key := common.EncryptionKey
+	common.EncryptionKey = "test-encryption-key"
+	t.Cleanup(func() { common.EncryptionKey = key })
+
+	db, err := NewDatabase(&DatabaseConfig{
+		DSN:             "sqlite:" + filepath.Join(t.TempDir(), "nexus.db"),
+		MaxIdleConns:    1,
+		MaxOpenConns:    1,
+		ConnMaxLifetime: time.Hour,
+		LogLevel:        "silent",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.MigrateDatabase(); err != nil {
+		t.Fatalf("首次迁移失败: %v", err)
+	}
+	// 再次执行时没有需要执行的迁移
+	if err := db.MigrateDatabase(); err != nil {
+		t.Fatalf("重复迁移失败: %v", err)
+	}
+
+	statuses, err := db.MigrationStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != len(migrations) {
+		t.Fatalf("迁移状态数量 = %d, want %d", len(statuses), len(migrations))
+	}
+	for _, status := range statuses {
+		if !status.Applied || status.ChecksumMismatch || status.Unknown {
+			t.Errorf("迁移 %d 状态异常: %+v", status.Version, status)
+		}
+	}
+
+	if err := db.GetDB().Model(&SchemaMigration{}).Where("version = ?", migrations[0].Version).
+		Update("checksum", "modified").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.MigrateDatabase(); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("已执行的迁移被修改时 MigrateDatabase 返回 %v", err)
+	}
+	statuses, err = db.MigrationStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !statuses[0].ChecksumMismatch {
+		t.Errorf("迁移 %d 应标记为校验和不一致", statuses[0].Version)
+	}
+}
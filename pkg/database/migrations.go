@@ -0,0 +1,306 @@
+package database
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/ysicing/nexus/pkg/common"
+	"github.com/ysicing/nexus/pkg/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// migrations 按版本顺序排列的数据库迁移
+//
+// 新增表或修改结构时在末尾追加迁移，并在迁移中使用当时的结构快照，
+// 不要引用 models 包中的模型和类型（包括 EncryptedString），否则模型后续的修改
+// 会改变已发布迁移的校验和。
+var migrations = []Migration{
+	{
+		// 引入版本化迁移之前由 AutoMigrate 创建的表，已有数据库会补齐缺少的列
+		Version: 1,
+		Name:    "baseline",
+		Steps: []Step{
+			CreateTables(&clusterV1{}, &leaseV1{}, &systemSecretV1{}),
+		},
+	},
+	{
+		Version: 2,
+		Name:    "settings",
+		Steps: []Step{
+			CreateTables(&settingV2{}),
+		},
+	},
+	{
+		// 标签从 clusters.labels 中的 JSON 移到 cluster_labels 表
+		Version: 3,
+		Name:    "cluster_labels",
+		Steps: []Step{
+			CreateTables(&clusterLabelV3{}),
+			Run("copy clusters.labels JSON into cluster_labels", copyClusterLabelsV3),
+			DropColumn("clusters", "labels"),
+		},
+	},
+	{
+		Version: 4,
+		Name:    "user_preferences",
+		Steps: []Step{
+			CreateTables(&userPreferenceV4{}, &pinnedNamespaceV4{}, &starredResourceV4{}, &recentItemV4{}, &savedFilterV4{}),
+		},
+	},
+	{
+		Version: 5,
+		Name:    "resource_revisions",
+		Steps: []Step{
+			CreateTables(&resourceRevisionV5{}),
+		},
+	},
+	{
+		// 历史版本中可能包含 Secret 的内容，与集群凭据一样加密保存
+		Version: 6,
+		Name:    "encrypt_resource_revisions",
+		Steps: []Step{
+			Run("encrypt resource_revisions.content", encryptRevisionContentV6),
+		},
+	},
+	{
+		Version: 7,
+		Name:    "proxy_tokens",
+		Steps: []Step{
+			CreateTables(&proxyTokenV7{}),
+		},
+	},
+}
+
+// clusterV1 迁移 1 中的集群表结构，加密的列在快照中使用 string，加解密由 models 负责
+type clusterV1 struct {
+	ID                 string `gorm:"primaryKey;size:255"`
+	Name               string `gorm:"not null;size:255"`
+	Description        string `gorm:"size:1000"`
+	Server             string `gorm:"not null;size:500"`
+	Version            string `gorm:"size:50"`
+	Status             string `gorm:"size:20;default:unknown"`
+	Context            string `gorm:"size:255"`
+	Labels             string `gorm:"type:text"`
+	IsDefault          bool   `gorm:"default:false"`
+	IsInCluster        bool   `gorm:"default:false"`
+	KubeconfigPath     string `gorm:"size:500"`
+	KubeconfigContent  string `gorm:"type:text"`
+	PrometheusURL      string `gorm:"size:500"`
+	PrometheusUsername string `gorm:"size:255"`
+	PrometheusPassword string `gorm:"size:1024"`
+	PrometheusEnabled  bool   `gorm:"default:false"`
+	TunnelConfig       string `gorm:"type:text"`
+	SourceFingerprint  string `gorm:"size:64"`
+	LastCheck          time.Time
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	DeletedAt          gorm.DeletedAt `gorm:"index"`
+}
+
+func (clusterV1) TableName() string { return "clusters" }
+
+// leaseV1 迁移 1 中的租约表结构
+type leaseV1 struct {
+	Name      string    `gorm:"primaryKey;size:255"`
+	Holder    string    `gorm:"not null;size:255"`
+	ExpiresAt time.Time `gorm:"index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (leaseV1) TableName() string { return "leases" }
+
+// systemSecretV1 迁移 1 中的系统密钥表结构
+type systemSecretV1 struct {
+	Name      string `gorm:"primaryKey;size:255"`
+	Value     string `gorm:"type:text"`
+	CreatedAt time.Time
+}
+
+func (systemSecretV1) TableName() string { return "system_secrets" }
+
+// settingV2 迁移 2 中的运行时设置表结构
+type settingV2 struct {
+	Name      string `gorm:"primaryKey;size:255"`
+	Value     string `gorm:"type:text"`
+	UpdatedBy string `gorm:"size:255"`
+	UpdatedAt time.Time
+}
+
+func (settingV2) TableName() string { return "settings" }
+
+// clusterLabelV3 迁移 3 中的集群标签表结构
+type clusterLabelV3 struct {
+	ClusterID string `gorm:"primaryKey;size:255"`
+	Name      string `gorm:"primaryKey;size:317;index:idx_cluster_labels_name_value,priority:1"`
+	Value     string `gorm:"size:63;index:idx_cluster_labels_name_value,priority:2"`
+}
+
+func (clusterLabelV3) TableName() string { return "cluster_labels" }
+
+// copyClusterLabelsV3 将 clusters.labels 中的 JSON 标签复制到 cluster_labels，包括回收站中的集群
+func copyClusterLabelsV3(tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn("clusters", "labels") {
+		return nil
+	}
+
+	var rows []struct {
+		ID     string
+		Labels string
+	}
+	if err := tx.Table("clusters").Select("id", "labels").
+		Where("labels IS NOT NULL AND labels <> ''").Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		var set map[string]string
+		if err := json.Unmarshal([]byte(row.Labels), &set); err != nil {
+			log.Printf("Skipping invalid labels of cluster %s: %v", row.ID, err)
+			continue
+		}
+		if len(set) == 0 {
+			continue
+		}
+		labels := make([]clusterLabelV3, 0, len(set))
+		for name, value := range set {
+			// 不符合 Kubernetes 标签格式的标签无法用选择器查询，也可能超出列长度
+			if len(validation.IsQualifiedName(name)) > 0 || len(validation.IsValidLabelValue(value)) > 0 {
+				log.Printf("Skipping invalid label %s=%s of cluster %s", name, value, row.ID)
+				continue
+			}
+			labels = append(labels, clusterLabelV3{ClusterID: row.ID, Name: name, Value: value})
+		}
+		if len(labels) == 0 {
+			continue
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&labels).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// userPreferenceV4 迁移 4 中的用户个人设置表结构
+type userPreferenceV4 struct {
+	Username         string `gorm:"primaryKey;size:255"`
+	DefaultClusterID string `gorm:"size:255"`
+	UpdatedAt        time.Time
+}
+
+func (userPreferenceV4) TableName() string { return "user_preferences" }
+
+// pinnedNamespaceV4 迁移 4 中的固定命名空间表结构
+type pinnedNamespaceV4 struct {
+	Username  string `gorm:"primaryKey;size:255"`
+	ClusterID string `gorm:"primaryKey;size:255"`
+	Namespace string `gorm:"primaryKey;size:63"`
+	CreatedAt time.Time
+}
+
+func (pinnedNamespaceV4) TableName() string { return "user_pinned_namespaces" }
+
+// starredResourceV4 迁移 4 中的收藏资源表结构
+type starredResourceV4 struct {
+	ID        string `gorm:"primaryKey;size:64"`
+	Username  string `gorm:"size:255;not null;index"`
+	ClusterID string `gorm:"size:255;not null"`
+	Resource  string `gorm:"size:255;not null"`
+	Namespace string `gorm:"size:63"`
+	Name      string `gorm:"size:253;not null"`
+	CreatedAt time.Time
+}
+
+func (starredResourceV4) TableName() string { return "user_starred_resources" }
+
+// recentItemV4 迁移 4 中的最近访问表结构
+type recentItemV4 struct {
+	ID        string    `gorm:"primaryKey;size:64"`
+	Username  string    `gorm:"size:255;not null;index"`
+	ClusterID string    `gorm:"size:255;not null"`
+	Resource  string    `gorm:"size:255;not null"`
+	Namespace string    `gorm:"size:63"`
+	Name      string    `gorm:"size:253;not null"`
+	VisitedAt time.Time `gorm:"index"`
+}
+
+func (recentItemV4) TableName() string { return "user_recent_items" }
+
+// savedFilterV4 迁移 4 中的保存的过滤条件表结构
+type savedFilterV4 struct {
+	ID        uint   `gorm:"primaryKey"`
+	Username  string `gorm:"size:255;not null;uniqueIndex:idx_user_saved_filters_name,priority:1"`
+	Resource  string `gorm:"size:255;not null;uniqueIndex:idx_user_saved_filters_name,priority:2"`
+	Name      string `gorm:"size:255;not null;uniqueIndex:idx_user_saved_filters_name,priority:3"`
+	ClusterID string `gorm:"size:255"`
+	Query     string `gorm:"type:text"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (savedFilterV4) TableName() string { return "user_saved_filters" }
+
+// resourceRevisionV5 迁移 5 中的对象历史版本表结构
+type resourceRevisionV5 struct {
+	ID              uint      `gorm:"primaryKey"`
+	ObjectID        string    `gorm:"size:64;not null;index"`
+	ClusterID       string    `gorm:"size:255;not null"`
+	Resource        string    `gorm:"size:255;not null"`
+	Namespace       string    `gorm:"size:63"`
+	Name            string    `gorm:"size:253;not null"`
+	APIVersion      string    `gorm:"size:255;not null"`
+	Kind            string    `gorm:"size:255;not null"`
+	ResourceVersion string    `gorm:"size:64"`
+	Action          string    `gorm:"size:32;not null"`
+	Username        string    `gorm:"size:255"`
+	Content         string    `gorm:"size:16777215"`
+	CreatedAt       time.Time `gorm:"index"`
+}
+
+func (resourceRevisionV5) TableName() string { return "resource_revisions" }
+
+// encryptRevisionContentV6 加密迁移 6 之前以明文保存的历史版本内容
+func encryptRevisionContentV6(tx *gorm.DB) error {
+	if err := common.RequireEncryptionKey(); err != nil {
+		return err
+	}
+
+	var rows []struct {
+		ID      uint
+		Content string
+	}
+	return tx.Table("resource_revisions").Select("id", "content").
+		Where("content IS NOT NULL AND content <> ''").
+		FindInBatches(&rows, 100, func(batch *gorm.DB, _ int) error {
+			for _, row := range rows {
+				if utils.IsEncrypted(row.Content) {
+					continue
+				}
+				content, err := utils.EncryptString(common.EncryptionKey, row.Content)
+				if err != nil {
+					return err
+				}
+				if err := tx.Table("resource_revisions").Where("id = ?", row.ID).
+					Update("content", content).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// proxyTokenV7 迁移 7 中的代理令牌表结构
+type proxyTokenV7 struct {
+	ID         string    `gorm:"primaryKey;size:36"`
+	Username   string    `gorm:"size:255;not null;index"`
+	ClusterID  string    `gorm:"size:255;not null"`
+	TokenHash  string    `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt  time.Time `gorm:"index"`
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+func (proxyTokenV7) TableName() string { return "proxy_tokens" }
@@ -23,25 +23,9 @@ func NewClusterHandler(manager *cluster.Manager) *ClusterHandler {
 
 // GetClusterClient 从请求中获取集群客户端
 func (h *ClusterHandler) GetClusterClient(c *gin.Context) (*kube.K8sClient, error) {
-	clusterID := c.Query("cluster")
-	if clusterID == "" {
-		clusterID = c.GetHeader("X-Cluster-ID")
-	}
-
-	var clusterInfo *cluster.ClusterInfo
-	var err error
-
-	if clusterID != "" {
-		clusterInfo, err = h.manager.GetCluster(clusterID)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		// 使用默认集群
-		clusterInfo, err = h.manager.GetDefaultCluster()
-		if err != nil {
-			return nil, err
-		}
+	clusterInfo, err := h.getCluster(c)
+	if err != nil {
+		return nil, err
 	}
 
 	if clusterInfo.Client == nil {
@@ -51,17 +35,37 @@ func (h *ClusterHandler) GetClusterClient(c *gin.Context) (*kube.K8sClient, erro
 	return clusterInfo.Client, nil
 }
 
-// ClusterMiddleware 集群中间件，自动注入集群客户端
+// getCluster 获取请求指定的集群，未指定时使用默认集群
+func (h *ClusterHandler) getCluster(c *gin.Context) (*cluster.ClusterInfo, error) {
+	clusterID := c.Query("cluster")
+	if clusterID == "" {
+		clusterID = c.GetHeader("X-Cluster-ID")
+	}
+
+	if clusterID != "" {
+		return h.manager.GetCluster(clusterID)
+	}
+	// 使用默认集群
+	return h.manager.GetDefaultCluster()
+}
+
+// ClusterMiddleware 集群中间件，自动注入集群 ID 和集群客户端
 func (h *ClusterHandler) ClusterMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		client, err := h.GetClusterClient(c)
-		if err != nil {
+		clusterInfo, err := h.getCluster(c)
+		switch {
+		case err != nil:
 			klog.Warningf("Failed to get cluster client: %v", err)
 			// 不阻止请求，让处理器自己处理没有客户端的情况
 			c.Set("k8sClient", nil)
-		} else {
-			// 将客户端存储在上下文中
-			c.Set("k8sClient", client)
+		case clusterInfo.Client == nil:
+			klog.Warningf("Failed to get cluster client: cluster client not available for cluster: %s", clusterInfo.Name)
+			c.Set("clusterID", clusterInfo.ID)
+			c.Set("k8sClient", nil)
+		default:
+			// 将集群 ID 和客户端存储在上下文中，按集群区分的状态（如节点排空任务）依赖集群 ID
+			c.Set("clusterID", clusterInfo.ID)
+			c.Set("k8sClient", clusterInfo.Client)
 		}
 		c.Next()
 	}
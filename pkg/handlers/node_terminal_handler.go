@@ -70,13 +70,16 @@ func (h *NodeTerminalHandler) HandleNodeTerminalWebSocket(c *gin.Context) {
 			}
 		}()
 
+		// 会话期间刷新心跳，副本异常退出后残留的 Pod 由集群管理器清理
+		go h.heartbeat(ctx, nodeAgentName)
+
 		if err := h.waitForPodReady(ctx, conn, nodeAgentName); err != nil {
 			log.Printf("Failed to wait for pod ready: %v", err)
 			h.sendErrorMessage(conn, fmt.Sprintf("Failed to wait for pod ready: %v", err))
 			return
 		}
 
-		session := kube.NewTerminalSession(h.k8sClient, conn, "kube-system", nodeAgentName, common.NodeTerminalPodName)
+		session := kube.NewTerminalSession(h.k8sClient, conn, common.NodeTerminalNamespace, nodeAgentName, common.NodeTerminalPodName)
 		if err := session.Start(ctx, "attach"); err != nil {
 			klog.Errorf("Terminal session error: %v", err)
 		}
@@ -90,9 +93,13 @@ func (h *NodeTerminalHandler) createNodeAgent(ctx context.Context, nodeName stri
 	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      podName,
-			Namespace: "kube-system",
+			Namespace: common.NodeTerminalNamespace,
 			Labels: map[string]string{
-				"app": podName,
+				"app":                    podName,
+				common.NodeTerminalLabel: "true",
+			},
+			Annotations: map[string]string{
+				common.NodeTerminalHeartbeatAnnotation: time.Now().UTC().Format(time.RFC3339),
 			},
 		},
 		Spec: corev1.PodSpec{
@@ -109,7 +116,7 @@ func (h *NodeTerminalHandler) createNodeAgent(ctx context.Context, nodeName stri
 			Containers: []corev1.Container{
 				{
 					Name:  common.NodeTerminalPodName,
-					Image: common.NodeTerminalImage,
+					Image: common.Settings().NodeTerminalImage,
 					Command: []string{
 						"nsenter",
 						"--target", "1",
@@ -128,7 +135,7 @@ func (h *NodeTerminalHandler) createNodeAgent(ctx context.Context, nodeName stri
 	}
 
 	object := &corev1.Pod{}
-	namespacedName := types.NamespacedName{Name: podName, Namespace: "kube-system"}
+	namespacedName := types.NamespacedName{Name: podName, Namespace: common.NodeTerminalNamespace}
 	if err := h.k8sClient.Client.Get(ctx, namespacedName, object); err == nil {
 		if utils.IsPodErrorOrSuccess(object) {
 			if err := h.k8sClient.Client.Delete(ctx, object); err != nil {
@@ -166,7 +173,7 @@ func (h *NodeTerminalHandler) waitForPodReady(ctx context.Context, conn *websock
 			h.sendErrorMessage(conn, utils.GetPodErrorMessage(pod))
 			return fmt.Errorf("timeout waiting for pod %s to be ready", podName)
 		case <-ticker.C:
-			pod, err = h.k8sClient.ClientSet.CoreV1().Pods("kube-system").Get(
+			pod, err = h.k8sClient.ClientSet.CoreV1().Pods(common.NodeTerminalNamespace).Get(
 				context.TODO(),
 				podName,
 				metav1.GetOptions{},
@@ -183,8 +190,33 @@ func (h *NodeTerminalHandler) waitForPodReady(ctx context.Context, conn *websock
 	}
 }
 
+// heartbeat refreshes the heartbeat annotation of the node agent pod until ctx is done
+func (h *NodeTerminalHandler) heartbeat(ctx context.Context, podName string) {
+	ticker := time.NewTicker(common.NodeTerminalHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`,
+				common.NodeTerminalHeartbeatAnnotation, time.Now().UTC().Format(time.RFC3339))
+			if _, err := h.k8sClient.ClientSet.CoreV1().Pods(common.NodeTerminalNamespace).Patch(
+				ctx,
+				podName,
+				types.MergePatchType,
+				[]byte(patch),
+				metav1.PatchOptions{},
+			); err != nil {
+				klog.V(2).Infof("Failed to refresh heartbeat of node agent pod %s: %v", podName, err)
+			}
+		}
+	}
+}
+
 func (h *NodeTerminalHandler) cleanupNodeAgentPod(podName string) error {
-	return h.k8sClient.ClientSet.CoreV1().Pods("kube-system").Delete(
+	return h.k8sClient.ClientSet.CoreV1().Pods(common.NodeTerminalNamespace).Delete(
 		context.TODO(),
 		podName,
 		metav1.DeleteOptions{},
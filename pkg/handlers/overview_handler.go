@@ -9,7 +9,6 @@ import (
 	"github.com/ysicing/nexus/pkg/prometheus"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type OverviewHandler struct {
@@ -39,9 +38,9 @@ func (h *OverviewHandler) GetOverview(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	// TODO: if prometheus is enabled, get data from prometheus
-	// Get nodes
-	nodes, err := h.k8sClient.ClientSet.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
-	if err != nil {
+	// Get nodes from the informer cache
+	var nodes v1.NodeList
+	if err := h.k8sClient.Client.List(ctx, &nodes); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -62,8 +61,8 @@ func (h *OverviewHandler) GetOverview(c *gin.Context) {
 	}
 
 	// Get pods
-	pods, err := h.k8sClient.ClientSet.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
-	if err != nil {
+	var pods v1.PodList
+	if err := h.k8sClient.Client.List(ctx, &pods); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -89,15 +88,15 @@ func (h *OverviewHandler) GetOverview(c *gin.Context) {
 	}
 
 	// Get namespaces
-	namespaces, err := h.k8sClient.ClientSet.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
-	if err != nil {
+	var namespaces v1.NamespaceList
+	if err := h.k8sClient.Client.List(ctx, &namespaces); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Get services
-	services, err := h.k8sClient.ClientSet.CoreV1().Services("").List(ctx, metav1.ListOptions{})
-	if err != nil {
+	var services v1.ServiceList
+	if err := h.k8sClient.Client.List(ctx, &services); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -1,17 +1,40 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ysicing/nexus/pkg/history"
 	"github.com/ysicing/nexus/pkg/kube"
+	"github.com/ysicing/nexus/pkg/utils"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 )
 
+// applyKindOrder lists kinds that other objects in the same manifest may depend
+// on. They are applied first, in this order; everything else keeps its order.
+var applyKindOrder = map[string]int{
+	"CustomResourceDefinition": 0,
+	"Namespace":                1,
+}
+
 type ResourceApplyHandler struct {
 	K8sClient *kube.K8sClient
+	// Recorder saves the previous content of objects changed by an apply,
+	// nil when revisions are not recorded
+	Recorder *history.Recorder
 }
 
 func NewResourceApplyHandler(k8sClient *kube.K8sClient) *ResourceApplyHandler {
@@ -21,10 +44,52 @@ func NewResourceApplyHandler(k8sClient *kube.K8sClient) *ResourceApplyHandler {
 }
 
 type ApplyResourceRequest struct {
+	// YAML holds one or more documents separated by ---
 	YAML string `json:"yaml" binding:"required"`
+	// Namespace is used for namespaced objects that do not set one
+	Namespace string `json:"namespace"`
+}
+
+// ApplyResult is the outcome of applying one object
+type ApplyResult struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	// Action is created, configured or unchanged; empty when Error is set
+	Action string `json:"action,omitempty"`
+	// Diff is a unified diff of the live object against the applied object,
+	// returned when requested with diff=true
+	Diff  string `json:"diff,omitempty"`
+	Error string `json:"error,omitempty"`
 }
 
-// ApplyResource applies a YAML resource to the cluster
+// ApplyResourceResponse is returned by ApplyResource
+type ApplyResourceResponse struct {
+	Message string        `json:"message"`
+	DryRun  bool          `json:"dryRun"`
+	Results []ApplyResult `json:"results"`
+	// Kind, Name and Namespace describe the first object, for clients that
+	// apply a single document
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// getClient returns the cluster client from the gin context, falling back to the default client
+func (h *ResourceApplyHandler) getClient(c *gin.Context) *kube.K8sClient {
+	if k8sClient, ok := GetK8sClientFromContext(c); ok && k8sClient != nil {
+		return k8sClient
+	}
+	return h.K8sClient
+}
+
+// ApplyResource applies YAML manifests to the cluster using server-side apply.
+//
+// Query parameters:
+//   - dryRun=All previews the result without persisting anything
+//   - diff=true returns a per-object diff against the live state
+//   - force=true takes ownership of fields managed by other field managers
 func (h *ResourceApplyHandler) ApplyResource(c *gin.Context) {
 	var req ApplyResourceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -32,31 +97,234 @@ func (h *ResourceApplyHandler) ApplyResource(c *gin.Context) {
 		return
 	}
 
-	// Decode YAML into unstructured object
-	decodeUniversal := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
-	obj := &unstructured.Unstructured{}
+	dryRun := false
+	switch value := c.Query("dryRun"); value {
+	case "":
+	case "All":
+		dryRun = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dryRun parameter, only All is supported"})
+		return
+	}
+	withDiff, _ := strconv.ParseBool(c.Query("diff"))
+	force, _ := strconv.ParseBool(c.Query("force"))
 
-	_, _, err := decodeUniversal.Decode([]byte(req.YAML), nil, obj)
+	objects, err := decodeManifests(req.YAML)
 	if err != nil {
 		klog.Errorf("Failed to decode YAML: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid YAML format: " + err.Error()})
 		return
 	}
+	if len(objects) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No resources found in YAML"})
+		return
+	}
+	sortForApply(objects)
+
+	k8sClient := h.getClient(c)
+	if k8sClient == nil || k8sClient.Client == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "No cluster available. Please add a cluster first."})
+		return
+	}
+
+	opts := []client.PatchOption{client.FieldOwner(kube.FieldManager)}
+	if force {
+		opts = append(opts, client.ForceOwnership)
+	}
+	if dryRun {
+		opts = append(opts, client.DryRunAll)
+	}
 
 	ctx := c.Request.Context()
+	response := ApplyResourceResponse{
+		DryRun:  dryRun,
+		Results: make([]ApplyResult, 0, len(objects)),
+	}
+	failed := 0
+	for _, obj := range objects {
+		result, live := applyObject(ctx, k8sClient.Client, obj, req.Namespace, withDiff, opts)
+		if result.Error != "" {
+			failed++
+			klog.Errorf("Failed to apply %s/%s: %s", result.Kind, result.Name, result.Error)
+		} else if !dryRun {
+			klog.Infof("Applied resource %s/%s: %s", result.Kind, result.Name, result.Action)
+			if result.Action == "configured" && h.Recorder != nil {
+				h.Recorder.Record(c, k8sClient, live, "apply")
+			}
+		}
+		response.Results = append(response.Results, result)
+	}
+	response.Kind = response.Results[0].Kind
+	response.Name = response.Results[0].Name
+	response.Namespace = response.Results[0].Namespace
 
-	// Try to create the resource
-	if err := h.K8sClient.Client.Create(ctx, obj); err != nil {
-		klog.Errorf("Failed to create resource: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create resource: " + err.Error()})
+	switch {
+	case failed == 0 && dryRun:
+		response.Message = fmt.Sprintf("%d resource(s) validated (dry run)", len(objects))
+	case failed == 0:
+		response.Message = fmt.Sprintf("%d resource(s) applied successfully", len(objects))
+	default:
+		response.Message = fmt.Sprintf("%d of %d resource(s) failed", failed, len(objects))
+		for _, result := range response.Results {
+			if result.Error != "" {
+				response.Message += fmt.Sprintf(", %s/%s: %s", result.Kind, result.Name, result.Error)
+				break
+			}
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   response.Message,
+			"dryRun":  response.DryRun,
+			"results": response.Results,
+		})
 		return
 	}
+	c.JSON(http.StatusOK, response)
+}
+
+// applyObject server-side applies one object and reports the outcome together
+// with the object as it was before the apply, nil when it did not exist
+func applyObject(ctx context.Context, c client.Client, obj *unstructured.Unstructured, defaultNamespace string, withDiff bool, opts []client.PatchOption) (ApplyResult, *unstructured.Unstructured) {
+	result := ApplyResult{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Name:       obj.GetName(),
+		Namespace:  obj.GetNamespace(),
+	}
+	if obj.GetName() == "" {
+		result.Error = "metadata.name is required"
+		return result, nil
+	}
+
+	namespaced, err := c.IsObjectNamespaced(obj)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	switch {
+	case !namespaced:
+		obj.SetNamespace("")
+	case obj.GetNamespace() == "" && defaultNamespace != "":
+		obj.SetNamespace(defaultNamespace)
+	case obj.GetNamespace() == "":
+		obj.SetNamespace("default")
+	}
+	result.Namespace = obj.GetNamespace()
 
-	klog.Infof("Successfully created resource: %s/%s", obj.GetKind(), obj.GetName())
-	c.JSON(http.StatusCreated, gin.H{
-		"message":   "Resource created successfully",
-		"kind":      obj.GetKind(),
-		"name":      obj.GetName(),
-		"namespace": obj.GetNamespace(),
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(obj.GroupVersionKind())
+	exists := true
+	if err := c.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, live); err != nil {
+		if !apierrors.IsNotFound(err) {
+			result.Error = err.Error()
+			return result, nil
+		}
+		exists = false
+		live = nil
+	}
+
+	// Server-side apply rejects objects carrying managed fields
+	obj.SetManagedFields(nil)
+	if err := c.Patch(ctx, obj, client.Apply, opts...); err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	diff, err := diffObjects(live, obj)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	switch {
+	case !exists:
+		result.Action = "created"
+	case diff == "":
+		result.Action = "unchanged"
+	default:
+		result.Action = "configured"
+	}
+	if withDiff {
+		result.Diff = diff
+	}
+	return result, live
+}
+
+// decodeManifests splits YAML or JSON into objects. Documents may be separated
+// by --- and kind List is expanded into its items.
+func decodeManifests(manifest string) ([]*unstructured.Unstructured, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(strings.NewReader(manifest), 4096)
+
+	var objects []*unstructured.Unstructured
+	for index := 1; ; index++ {
+		var document map[string]interface{}
+		if err := decoder.Decode(&document); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("document %d: %w", index, err)
+		}
+		if len(document) == 0 {
+			continue
+		}
+
+		obj := &unstructured.Unstructured{Object: document}
+		if obj.GetAPIVersion() == "" || obj.GetKind() == "" {
+			return nil, fmt.Errorf("document %d: apiVersion and kind are required", index)
+		}
+		if obj.IsList() {
+			list, err := obj.ToList()
+			if err != nil {
+				return nil, fmt.Errorf("document %d: %w", index, err)
+			}
+			for i := range list.Items {
+				objects = append(objects, &list.Items[i])
+			}
+			continue
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// sortForApply moves kinds other objects may depend on to the front
+func sortForApply(objects []*unstructured.Unstructured) {
+	priority := func(obj *unstructured.Unstructured) int {
+		if order, ok := applyKindOrder[obj.GetKind()]; ok {
+			return order
+		}
+		return len(applyKindOrder)
+	}
+	sort.SliceStable(objects, func(i, j int) bool {
+		return priority(objects[i]) < priority(objects[j])
 	})
 }
+
+// diffObjects returns a unified diff between the live object and the applied
+// object as YAML. live is nil when the object does not exist yet.
+func diffObjects(live, applied *unstructured.Unstructured) (string, error) {
+	from, err := diffYAML(live)
+	if err != nil {
+		return "", err
+	}
+	to, err := diffYAML(applied)
+	if err != nil {
+		return "", err
+	}
+	return utils.UnifiedDiff(from, to, "live", "applied")
+}
+
+// diffYAML renders an object for diffing, without fields that change on every write
+func diffYAML(obj *unstructured.Unstructured) (string, error) {
+	if obj == nil {
+		return "", nil
+	}
+	obj = obj.DeepCopy()
+	obj.SetManagedFields(nil)
+	obj.SetResourceVersion("")
+	unstructured.RemoveNestedField(obj.Object, "metadata", "generation")
+
+	data, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,75 @@
+package resources
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ysicing/nexus/pkg/kube"
+)
+
+// APIResourcesHandler lists the resource types served by the current cluster
+type APIResourcesHandler struct {
+	K8sClient *kube.K8sClient
+}
+
+// NewAPIResourcesHandler creates a new APIResourcesHandler
+func NewAPIResourcesHandler(client *kube.K8sClient) *APIResourcesHandler {
+	return &APIResourcesHandler{K8sClient: client}
+}
+
+// List returns the discovered resources, like kubectl api-resources. The
+// optional query parameters namespaced, group and verb narrow the result.
+func (h *APIResourcesHandler) List(c *gin.Context) {
+	k8sClient := h.K8sClient
+	if value, exists := c.Get("k8sClient"); exists {
+		if client, ok := value.(*kube.K8sClient); ok && client != nil {
+			k8sClient = client
+		}
+	}
+	if k8sClient == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "No cluster available. Please add a cluster first."})
+		return
+	}
+
+	var namespaced *bool
+	if value := c.Query("namespaced"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid namespaced parameter"})
+			return
+		}
+		namespaced = &parsed
+	}
+	group, groupSet := c.GetQuery("group")
+	verb := c.Query("verb")
+
+	resources, err := k8sClient.APIResources()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := make([]kube.APIResource, 0, len(resources))
+	for _, resource := range resources {
+		if namespaced != nil && resource.Namespaced != *namespaced {
+			continue
+		}
+		if groupSet && resource.Group != group {
+			continue
+		}
+		if verb != "" && !resource.HasVerb(verb) {
+			continue
+		}
+		result = append(result, resource)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Group != result[j].Group {
+			return result[i].Group < result[j].Group
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	c.JSON(http.StatusOK, gin.H{"resources": result})
+}
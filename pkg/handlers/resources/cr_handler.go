@@ -1,21 +1,27 @@
 package resources
 
 import (
-	"context"
+	"encoding/json"
+	stderrors "errors"
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ysicing/nexus/pkg/kube"
-	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 )
 
-// CRHandler handles API operations for Custom Resources based on CRD name
+// CRHandler handles API operations for resource types without a typed handler:
+// custom resources, built-in types such as HPAs or PDBs, and aggregated APIs.
+// The :crd route parameter is resolved through the cluster's API discovery, so
+// it accepts a CRD name (plural.group) as well as anything kubectl accepts,
+// e.g. hpa or poddisruptionbudgets.policy.
 type CRHandler struct {
 	K8sClient *kube.K8sClient
 }
@@ -25,198 +31,246 @@ func NewCRHandler(client *kube.K8sClient) *CRHandler {
 	return &CRHandler{K8sClient: client}
 }
 
-// getCRDByName retrieves the CRD definition by name
-func (h *CRHandler) getCRDByName(ctx context.Context, crdName string) (*apiextensionsv1.CustomResourceDefinition, error) {
-	var crd apiextensionsv1.CustomResourceDefinition
-	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: crdName}, &crd); err != nil {
-		return nil, err
-	}
-	return &crd, nil
-}
-
-// getGVRFromCRD extracts GroupVersionResource from CRD
-func (h *CRHandler) getGVRFromCRD(crd *apiextensionsv1.CustomResourceDefinition) schema.GroupVersionResource {
-	// Use the first served version as default
-	var version string
-	for _, v := range crd.Spec.Versions {
-		if v.Served {
-			version = v.Name
-			break
+// getClient returns the cluster client from the gin context, falling back to the default client
+func (h *CRHandler) getClient(c *gin.Context) *kube.K8sClient {
+	if value, exists := c.Get("k8sClient"); exists {
+		if k8sClient, ok := value.(*kube.K8sClient); ok && k8sClient != nil {
+			return k8sClient
 		}
 	}
-
-	return schema.GroupVersionResource{
-		Group:    crd.Spec.Group,
-		Version:  version,
-		Resource: crd.Spec.Names.Plural,
-	}
+	return h.K8sClient
 }
 
-func (h *CRHandler) List(c *gin.Context) {
+// resolve looks up the resource type named by the :crd parameter and checks
+// that it supports verb. It writes the error response and returns false when
+// the request cannot be served.
+func (h *CRHandler) resolve(c *gin.Context, verb string) (*kube.K8sClient, *kube.APIResource, bool) {
 	crdName := c.Param("crd")
 	if crdName == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "CRD name is required"})
-		return
+		return nil, nil, false
 	}
 
-	ctx := c.Request.Context()
+	k8sClient := h.getClient(c)
+	if k8sClient == nil || k8sClient.Client == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "No cluster available. Please add a cluster first."})
+		return nil, nil, false
+	}
 
-	// Get the CRD definition
-	crd, err := h.getCRDByName(ctx, crdName)
+	resource, err := k8sClient.LookupResource(crdName)
 	if err != nil {
-		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "CustomResourceDefinition not found"})
-			return
+		var notFound *kube.ResourceNotFoundError
+		if stderrors.As(err, &notFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return nil, nil, false
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return nil, nil, false
 	}
 
-	// Create GVR from CRD
-	gvr := h.getGVRFromCRD(crd)
-
-	// Create unstructured list object
-	crList := &unstructured.UnstructuredList{}
-	crList.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   gvr.Group,
-		Version: gvr.Version,
-		Kind:    crd.Spec.Names.ListKind,
-	})
+	if !resource.HasVerb(verb) {
+		c.JSON(http.StatusMethodNotAllowed, gin.H{"error": "resource " + resource.FullName() + " does not support " + verb})
+		return nil, nil, false
+	}
+	if !selectVersion(c, resource) {
+		return nil, nil, false
+	}
+	return k8sClient, resource, true
+}
 
-	opts := &client.ListOptions{}
+// selectVersion serves the resource in the version given by ?version=,
+// otherwise in the version discovery prefers, with the scope and subresources
+// of that version. It writes the error response and returns false when the
+// requested version is not served.
+func selectVersion(c *gin.Context, resource *kube.APIResource) bool {
+	requested := c.Query("version")
+	if requested == "" {
+		return true
+	}
+	served, ok := resource.InVersion(requested)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "resource " + resource.FullName() + " is not served in version " + requested + ", served versions: " + strings.Join(resource.Versions, ", ")})
+		return false
+	}
+	*resource = *served
+	return true
+}
 
-	// Handle namespace parameter for namespaced resources
-	if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
-		namespace := c.Param("namespace")
-		if namespace != "" && namespace != "_all" {
-			opts.Namespace = namespace
+// readObject reads the request body, JSON or YAML, as an object of the
+// resource type. It writes the error response and returns false when the body
+// is not an object of that type.
+func readObject(c *gin.Context, resource *kube.APIResource) (*unstructured.Unstructured, bool) {
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	switch c.ContentType() {
+	case "application/yaml", "application/x-yaml", "text/yaml":
+		if data, err = yaml.YAMLToJSON(data); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid YAML: " + err.Error()})
+			return nil, false
 		}
 	}
 
-	if err := h.K8sClient.Client.List(ctx, crList, opts); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	content := map[string]interface{}{}
+	if err := json.Unmarshal(data, &content); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "request body must be an object: " + err.Error()})
+		return nil, false
 	}
+	obj := &unstructured.Unstructured{Object: content}
 
-	c.JSON(http.StatusOK, crList)
+	gvk := resource.GroupVersionKind()
+	if kind := obj.GetKind(); kind != "" && kind != gvk.Kind {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind " + kind + " does not match resource " + resource.FullName()})
+		return nil, false
+	}
+	if apiVersion := obj.GetAPIVersion(); apiVersion != "" && apiVersion != gvk.GroupVersion().String() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "apiVersion " + apiVersion + " does not match " + gvk.GroupVersion().String() + ", use ?version= to select another version"})
+		return nil, false
+	}
+	obj.SetGroupVersionKind(gvk)
+	return obj, true
 }
 
-func (h *CRHandler) Get(c *gin.Context) {
-	crdName := c.Param("crd")
-	name := c.Param("name")
+// dryRun reports whether the request asks for ?dryRun=All, which validates a
+// change, including the CRD's schema, without persisting it. It writes the
+// error response and returns false when the parameter is invalid.
+func dryRun(c *gin.Context) (bool, bool) {
+	switch c.Query("dryRun") {
+	case "":
+		return false, true
+	case "All":
+		return true, true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dryRun parameter, only All is supported"})
+	return false, false
+}
 
-	if crdName == "" || name == "" {
+// namespacedName builds the object key from the route parameters. It writes
+// the error response and returns false when a namespaced resource is addressed
+// without a namespace.
+func namespacedName(c *gin.Context, resource *kube.APIResource) (types.NamespacedName, bool) {
+	name := c.Param("name")
+	if name == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "CRD name and resource name are required"})
-		return
+		return types.NamespacedName{}, false
+	}
+	if !resource.Namespaced {
+		// For cluster-scoped resources, ignore namespace parameter
+		return types.NamespacedName{Name: name}, true
 	}
 
-	ctx := c.Request.Context()
+	namespace := c.Param("namespace")
+	if namespace == "_all" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This resource is namespace-scoped, use /:crd/:namespace/:name endpoint"})
+		return types.NamespacedName{}, false
+	}
+	if namespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "namespace is required for namespaced resources"})
+		return types.NamespacedName{}, false
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, true
+}
+
+func (h *CRHandler) List(c *gin.Context) {
+	k8sClient, resource, ok := h.resolve(c, "list")
+	if !ok {
+		return
+	}
 
-	// Get the CRD definition
-	crd, err := h.getCRDByName(ctx, crdName)
+	query, err := parseListQuery(c)
 	if err != nil {
-		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "CustomResourceDefinition not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Create GVR from CRD
-	gvr := h.getGVRFromCRD(crd)
+	// Create unstructured list object
+	crList := &unstructured.UnstructuredList{}
+	crList.SetGroupVersionKind(resource.GroupVersionKind())
 
-	// Create unstructured object
-	cr := &unstructured.Unstructured{}
-	cr.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   gvr.Group,
-		Version: gvr.Version,
-		Kind:    crd.Spec.Names.Kind,
-	})
-
-	var namespacedName types.NamespacedName
-	if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
-		namespace := c.Param("namespace")
-		// Handle both regular namespace and _all routing
-		if namespace == "_all" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "This custom resource is namespace-scoped, use /:crd/:namespace/:name endpoint"})
-			return
-		}
-		if namespace == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "namespace is required for namespaced custom resources"})
-			return
-		}
-		namespacedName = types.NamespacedName{Namespace: namespace, Name: name}
-	} else {
-		// For cluster-scoped resources, ignore namespace parameter
-		namespacedName = types.NamespacedName{Name: name}
+	listOpts, ok := listOptions(c, resource.Namespaced)
+	if !ok {
+		return
 	}
 
-	if err := h.K8sClient.Client.Get(ctx, namespacedName, cr); err != nil {
-		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Custom resource not found"})
-			return
-		}
+	if err := k8sClient.Client.List(c.Request.Context(), crList, listOpts...); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, cr)
+	query.respond(c, crList)
 }
 
-func (h *CRHandler) Create(c *gin.Context) {
-	crdName := c.Param("crd")
-	if crdName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "CRD name is required"})
+func (h *CRHandler) Get(c *gin.Context) {
+	k8sClient, resource, ok := h.resolve(c, "get")
+	if !ok {
+		return
+	}
+	key, ok := namespacedName(c, resource)
+	if !ok {
 		return
 	}
 
-	ctx := c.Request.Context()
+	cr := &unstructured.Unstructured{}
+	cr.SetGroupVersionKind(resource.GroupVersionKind())
 
-	// Get the CRD definition
-	crd, err := h.getCRDByName(ctx, crdName)
-	if err != nil {
+	if err := k8sClient.Client.Get(c.Request.Context(), key, cr); err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "CustomResourceDefinition not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": "Custom resource not found"})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Create GVR from CRD
-	gvr := h.getGVRFromCRD(crd)
+	c.JSON(http.StatusOK, cr)
+}
 
-	// Parse the request body into unstructured object
-	var cr unstructured.Unstructured
-	if err := c.ShouldBindJSON(&cr); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+// Create creates a custom resource from a JSON or YAML body. The namespace
+// comes from the route, or from the object when posted to /:crd.
+func (h *CRHandler) Create(c *gin.Context) {
+	k8sClient, resource, ok := h.resolve(c, "create")
+	if !ok {
+		return
+	}
+	dry, ok := dryRun(c)
+	if !ok {
+		return
+	}
+	cr, ok := readObject(c, resource)
+	if !ok {
 		return
 	}
-
-	// Set correct GVK
-	cr.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   gvr.Group,
-		Version: gvr.Version,
-		Kind:    crd.Spec.Names.Kind,
-	})
 
 	// Set namespace for namespaced resources
-	if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
+	if resource.Namespaced {
 		namespace := c.Param("namespace")
 		if namespace == "_all" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "This custom resource is namespace-scoped, use /:crd/:namespace endpoint"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This resource is namespace-scoped, use /:crd/:namespace endpoint"})
+			return
+		}
+		if namespace == "" {
+			namespace = cr.GetNamespace()
+		} else if cr.GetNamespace() != "" && cr.GetNamespace() != namespace {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "the namespace of the object does not match the namespace of the request"})
 			return
 		}
 		if namespace == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "namespace is required for namespaced custom resources"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": "namespace is required for namespaced resources"})
 			return
 		}
 		cr.SetNamespace(namespace)
+	} else {
+		cr.SetNamespace("")
 	}
 
-	if err := h.K8sClient.Client.Create(ctx, &cr); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	opts := []client.CreateOption{client.FieldOwner(kube.FieldManager)}
+	if dry {
+		opts = append(opts, client.DryRunAll)
+	}
+	if err := k8sClient.Client.Create(c.Request.Context(), cr, opts...); err != nil {
+		c.JSON(ErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -224,55 +278,21 @@ func (h *CRHandler) Create(c *gin.Context) {
 }
 
 func (h *CRHandler) Update(c *gin.Context) {
-	crdName := c.Param("crd")
-	name := c.Param("name")
-
-	if crdName == "" || name == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "CRD name and resource name are required"})
+	k8sClient, resource, ok := h.resolve(c, "update")
+	if !ok {
 		return
 	}
-
-	ctx := c.Request.Context()
-
-	// Get the CRD definition
-	crd, err := h.getCRDByName(ctx, crdName)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "CustomResourceDefinition not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	key, ok := namespacedName(c, resource)
+	if !ok {
 		return
 	}
 
-	// Create GVR from CRD
-	gvr := h.getGVRFromCRD(crd)
+	ctx := c.Request.Context()
 
 	// First get the existing custom resource
 	existingCR := &unstructured.Unstructured{}
-	existingCR.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   gvr.Group,
-		Version: gvr.Version,
-		Kind:    crd.Spec.Names.Kind,
-	})
-
-	var namespacedName types.NamespacedName
-	if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
-		namespace := c.Param("namespace")
-		if namespace == "_all" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "This custom resource is namespace-scoped, use /:crd/:namespace/:name endpoint"})
-			return
-		}
-		if namespace == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "namespace is required for namespaced custom resources"})
-			return
-		}
-		namespacedName = types.NamespacedName{Namespace: namespace, Name: name}
-	} else {
-		namespacedName = types.NamespacedName{Name: name}
-	}
-
-	if err := h.K8sClient.Client.Get(ctx, namespacedName, existingCR); err != nil {
+	existingCR.SetGroupVersionKind(resource.GroupVersionKind())
+	if err := k8sClient.Client.Get(ctx, key, existingCR); err != nil {
 		if errors.IsNotFound(err) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Custom resource not found"})
 			return
@@ -281,84 +301,95 @@ func (h *CRHandler) Update(c *gin.Context) {
 		return
 	}
 
-	// Parse the request body into unstructured object
-	var updatedCR unstructured.Unstructured
-	if err := c.ShouldBindJSON(&updatedCR); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	dry, ok := dryRun(c)
+	if !ok {
+		return
+	}
+	updatedCR, ok := readObject(c, resource)
+	if !ok {
 		return
 	}
 
 	// Preserve important metadata
 	updatedCR.SetGroupVersionKind(existingCR.GroupVersionKind())
-	updatedCR.SetName(name)
+	updatedCR.SetName(key.Name)
 	updatedCR.SetResourceVersion(existingCR.GetResourceVersion())
 	updatedCR.SetUID(existingCR.GetUID())
 
-	if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
+	if resource.Namespaced {
 		updatedCR.SetNamespace(existingCR.GetNamespace())
 	}
 
-	if err := h.K8sClient.Client.Update(ctx, &updatedCR); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	opts := []client.UpdateOption{client.FieldOwner(kube.FieldManager)}
+	if dry {
+		opts = append(opts, client.DryRunAll)
+	}
+	if err := k8sClient.Client.Update(ctx, updatedCR, opts...); err != nil {
+		c.JSON(ErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, updatedCR)
 }
 
-func (h *CRHandler) Delete(c *gin.Context) {
-	crdName := c.Param("crd")
-	name := c.Param("name")
-
-	if crdName == "" || name == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "CRD name and resource name are required"})
+// Patch applies a JSON patch or JSON merge patch selected by the request
+// content type. Strategic merge patch is accepted for built-in types only.
+func (h *CRHandler) Patch(c *gin.Context) {
+	k8sClient, resource, ok := h.resolve(c, "patch")
+	if !ok {
+		return
+	}
+	key, ok := namespacedName(c, resource)
+	if !ok {
 		return
 	}
+	patch, ok := readPatch(c, k8sClient.Client.Scheme().Recognizes(resource.GroupVersionKind()))
+	if !ok {
+		return
+	}
+	dry, ok := dryRun(c)
+	if !ok {
+		return
+	}
+	opts := []client.PatchOption{client.FieldOwner(kube.FieldManager)}
+	if dry {
+		opts = append(opts, client.DryRunAll)
+	}
 
-	ctx := c.Request.Context()
+	cr := &unstructured.Unstructured{}
+	cr.SetGroupVersionKind(resource.GroupVersionKind())
+	cr.SetNamespace(key.Namespace)
+	cr.SetName(key.Name)
 
-	// Get the CRD definition
-	crd, err := h.getCRDByName(ctx, crdName)
-	if err != nil {
+	if err := k8sClient.Client.Patch(c.Request.Context(), cr, patch, opts...); err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "CustomResourceDefinition not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": "Custom resource not found"})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(ErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	// Create GVR from CRD
-	gvr := h.getGVRFromCRD(crd)
+	c.JSON(http.StatusOK, cr)
+}
 
-	// Create unstructured object to delete
-	cr := &unstructured.Unstructured{}
-	cr.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   gvr.Group,
-		Version: gvr.Version,
-		Kind:    crd.Spec.Names.Kind,
-	})
-
-	var namespacedName types.NamespacedName
-	if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
-		namespace := c.Param("namespace")
-		if namespace == "_all" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "This custom resource is namespace-scoped, use /:crd/:namespace/:name endpoint"})
-			return
-		}
-		if namespace == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "namespace is required for namespaced custom resources"})
-			return
-		}
-		namespacedName = types.NamespacedName{Namespace: namespace, Name: name}
-		cr.SetNamespace(namespace)
-	} else {
-		namespacedName = types.NamespacedName{Name: name}
+func (h *CRHandler) Delete(c *gin.Context) {
+	k8sClient, resource, ok := h.resolve(c, "delete")
+	if !ok {
+		return
+	}
+	key, ok := namespacedName(c, resource)
+	if !ok {
+		return
 	}
-	cr.SetName(name)
+
+	ctx := c.Request.Context()
+
+	cr := &unstructured.Unstructured{}
+	cr.SetGroupVersionKind(resource.GroupVersionKind())
 
 	// First check if the resource exists
-	if err := h.K8sClient.Client.Get(ctx, namespacedName, cr); err != nil {
+	if err := k8sClient.Client.Get(ctx, key, cr); err != nil {
 		if errors.IsNotFound(err) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Custom resource not found"})
 			return
@@ -368,7 +399,7 @@ func (h *CRHandler) Delete(c *gin.Context) {
 	}
 
 	// Delete the custom resource
-	if err := h.K8sClient.Client.Delete(ctx, cr, &client.DeleteOptions{
+	if err := k8sClient.Client.Delete(ctx, cr, &client.DeleteOptions{
 		PropagationPolicy: &[]metav1.DeletionPropagation{metav1.DeletePropagationForeground}[0],
 	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
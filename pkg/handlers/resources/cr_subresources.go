@@ -0,0 +1,194 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ysicing/nexus/pkg/common"
+	"github.com/ysicing/nexus/pkg/kube"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// scaleGVK is the kind the scale subresource is served as
+var scaleGVK = autoscalingv1.SchemeGroupVersion.WithKind("Scale")
+
+// subresourceTarget resolves the object of a subresource route and checks that
+// the resource serves the subresource. It writes the error response and
+// returns false when the request cannot be served.
+func (h *CRHandler) subresourceTarget(c *gin.Context, verb, subresource string) (*kube.K8sClient, *unstructured.Unstructured, bool) {
+	k8sClient, resource, ok := h.resolve(c, verb)
+	if !ok {
+		return nil, nil, false
+	}
+	if !resource.HasSubresource(subresource) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "resource " + resource.FullName() + " has no " + subresource + " subresource"})
+		return nil, nil, false
+	}
+	key, ok := namespacedName(c, resource)
+	if !ok {
+		return nil, nil, false
+	}
+
+	cr := &unstructured.Unstructured{}
+	cr.SetGroupVersionKind(resource.GroupVersionKind())
+	cr.SetNamespace(key.Namespace)
+	cr.SetName(key.Name)
+	return k8sClient, cr, true
+}
+
+func subresourceError(c *gin.Context, err error) {
+	if errors.IsNotFound(err) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Custom resource not found"})
+		return
+	}
+	c.JSON(ErrorStatus(err), gin.H{"error": err.Error()})
+}
+
+// GetScale returns the scale subresource of a custom resource
+func (h *CRHandler) GetScale(c *gin.Context) {
+	k8sClient, cr, ok := h.subresourceTarget(c, "get", "scale")
+	if !ok {
+		return
+	}
+
+	scale := &unstructured.Unstructured{}
+	scale.SetGroupVersionKind(scaleGVK)
+	if err := k8sClient.Client.SubResource("scale").Get(c.Request.Context(), cr, scale); err != nil {
+		subresourceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, scale)
+}
+
+// ScaleCustomResource sets the replicas of a custom resource through its
+// scale subresource, which maps them to the CRD's specReplicasPath
+func (h *CRHandler) ScaleCustomResource(c *gin.Context) {
+	var scaleRequest struct {
+		Replicas *int32 `json:"replicas" binding:"required,min=0"`
+	}
+	if err := c.ShouldBindJSON(&scaleRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	k8sClient, cr, ok := h.subresourceTarget(c, "patch", "scale")
+	if !ok {
+		return
+	}
+
+	data, err := json.Marshal(map[string]interface{}{"spec": map[string]interface{}{"replicas": *scaleRequest.Replicas}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	scale := &unstructured.Unstructured{}
+	scale.SetGroupVersionKind(scaleGVK)
+	if err := k8sClient.Client.SubResource("scale").Patch(c.Request.Context(), cr, client.RawPatch(types.MergePatchType, data),
+		client.WithSubResourceBody(scale), client.FieldOwner(kube.FieldManager)); err != nil {
+		subresourceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Custom resource scaled successfully",
+		"scale":    scale,
+		"replicas": *scaleRequest.Replicas,
+	})
+}
+
+// GetStatus returns a custom resource as served by its status subresource
+func (h *CRHandler) GetStatus(c *gin.Context) {
+	k8sClient, cr, ok := h.subresourceTarget(c, "get", "status")
+	if !ok {
+		return
+	}
+
+	status := &unstructured.Unstructured{}
+	status.SetGroupVersionKind(cr.GroupVersionKind())
+	if err := k8sClient.Client.SubResource("status").Get(c.Request.Context(), cr, status); err != nil {
+		subresourceError(c, err)
+		return
+	}
+	status.SetManagedFields(nil)
+	c.JSON(http.StatusOK, status)
+}
+
+// ListEvents lists the events of a custom resource
+func (h *CRHandler) ListEvents(c *gin.Context) {
+	k8sClient, resource, ok := h.resolve(c, "get")
+	if !ok {
+		return
+	}
+	key, ok := namespacedName(c, resource)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	cr := &unstructured.Unstructured{}
+	cr.SetGroupVersionKind(resource.GroupVersionKind())
+	if err := k8sClient.Client.Get(ctx, key, cr); err != nil {
+		subresourceError(c, err)
+		return
+	}
+
+	// Events of cluster-scoped objects are recorded in any namespace
+	events, err := k8sClient.ClientSet.CoreV1().Events(cr.GetNamespace()).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("involvedObject.uid", string(cr.GetUID())).String(),
+	})
+	if err != nil {
+		c.JSON(ErrorStatus(err), gin.H{"error": "Failed to list events: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+// Search searches custom resources by name. Only resource types that are
+// being watched are searched, from the informer cache, so a search never lists
+// objects through the API server. The cluster client comes from ctx, see
+// WithClient.
+func (h *CRHandler) Search(ctx context.Context, q string, limit int64) ([]common.SearchResult, error) {
+	if len(q) < 3 {
+		return nil, nil
+	}
+	k8sClient := clientFromContext(ctx, h.K8sClient)
+	if k8sClient == nil || k8sClient.Client == nil {
+		return nil, fmt.Errorf("no cluster client available")
+	}
+
+	q = strings.ToLower(q)
+	results := make([]common.SearchResult, 0, limit)
+	for _, resource := range k8sClient.InformedResources() {
+		items, err := k8sClient.ListInformed(ctx, &resource)
+		if err != nil {
+			klog.Errorf("failed to list %s: %v", resource.FullName(), err)
+			continue
+		}
+		for _, item := range items {
+			if !strings.Contains(strings.ToLower(item.GetName()), q) {
+				continue
+			}
+			results = append(results, common.SearchResult{
+				ID:           string(item.GetUID()),
+				Name:         item.GetName(),
+				Namespace:    item.GetNamespace(),
+				ResourceType: resource.FullName(),
+				CreatedAt:    item.GetCreationTimestamp().String(),
+			})
+			if limit > 0 && int64(len(results)) >= limit {
+				return results, nil
+			}
+		}
+	}
+	return results, nil
+}
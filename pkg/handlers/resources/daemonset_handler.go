@@ -0,0 +1,213 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ysicing/nexus/pkg/kube"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type DaemonSetHandler struct {
+	*GenericResourceHandler[*appsv1.DaemonSet, *appsv1.DaemonSetList]
+}
+
+func NewDaemonSetHandler(client *kube.K8sClient) *DaemonSetHandler {
+	return &DaemonSetHandler{
+		GenericResourceHandler: NewGenericResourceHandler[*appsv1.DaemonSet, *appsv1.DaemonSetList](
+			client,
+			"daemonsets",
+			false, // DaemonSets are namespaced resources
+			true,
+		),
+	}
+}
+
+func (h *DaemonSetHandler) Restart(ctx context.Context, namespace, name string) error {
+	return h.restart(ctx, h.K8sClient, namespace, name)
+}
+
+func (h *DaemonSetHandler) restart(ctx context.Context, k8sClient *kube.K8sClient, namespace, name string) error {
+	var daemonSet appsv1.DaemonSet
+	return restartWorkload(ctx, k8sClient, namespace, name, &daemonSet, func() *corev1.PodTemplateSpec {
+		return &daemonSet.Spec.Template
+	})
+}
+
+func (h *DaemonSetHandler) RestartDaemonSet(c *gin.Context) {
+	if err := h.restart(c.Request.Context(), h.getClient(c), c.Param("namespace"), c.Param("name")); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "DaemonSet not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restart daemonset: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "DaemonSet restarted successfully",
+	})
+}
+
+// daemonSetHistory builds the rollout history of a daemonset; the newest
+// revision is the current one
+func daemonSetHistory(c *gin.Context, k8sClient *kube.K8sClient, daemonSet *appsv1.DaemonSet) (*rolloutHistory, bool) {
+	history, err := controllerRevisionHistory(c.Request.Context(), k8sClient, daemonSet, daemonSet.Spec.Selector, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return history, true
+}
+
+// ListRolloutHistory lists the rollout history of a daemonset
+func (h *DaemonSetHandler) ListRolloutHistory(c *gin.Context) {
+	k8sClient := h.getClient(c)
+	var daemonSet appsv1.DaemonSet
+	if !getWorkload(c, k8sClient, "DaemonSet", &daemonSet) {
+		return
+	}
+	history, ok := daemonSetHistory(c, k8sClient, &daemonSet)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"revisions": history.revisions})
+}
+
+// GetRolloutRevision returns one revision of a daemonset with its pod template and diff
+func (h *DaemonSetHandler) GetRolloutRevision(c *gin.Context) {
+	k8sClient := h.getClient(c)
+	var daemonSet appsv1.DaemonSet
+	if !getWorkload(c, k8sClient, "DaemonSet", &daemonSet) {
+		return
+	}
+	history, ok := daemonSetHistory(c, k8sClient, &daemonSet)
+	if !ok {
+		return
+	}
+	writeRevision(c, history, &daemonSet.Spec.Template)
+}
+
+// RollbackDaemonSet rolls a daemonset back to an earlier revision
+func (h *DaemonSetHandler) RollbackDaemonSet(c *gin.Context) {
+	k8sClient := h.getClient(c)
+	var daemonSet appsv1.DaemonSet
+	if !getWorkload(c, k8sClient, "DaemonSet", &daemonSet) {
+		return
+	}
+	history, ok := daemonSetHistory(c, k8sClient, &daemonSet)
+	if !ok {
+		return
+	}
+	rollback(c, k8sClient, "DaemonSet", &daemonSet, history, func() *corev1.PodTemplateSpec {
+		return &daemonSet.Spec.Template
+	})
+}
+
+// UpdateRollingUpdate changes the maxUnavailable and maxSurge of a daemonset's rolling update
+func (h *DaemonSetHandler) UpdateRollingUpdate(c *gin.Context) {
+	var req RollingUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.Partition != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "partition is not supported by daemonsets"})
+		return
+	}
+
+	k8sClient := h.getClient(c)
+	var daemonSet appsv1.DaemonSet
+	if !getWorkload(c, k8sClient, "DaemonSet", &daemonSet) {
+		return
+	}
+	strategy := &daemonSet.Spec.UpdateStrategy
+	if strategy.Type != "" && strategy.Type != appsv1.RollingUpdateDaemonSetStrategyType {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("daemonset uses the %s update strategy", strategy.Type)})
+		return
+	}
+
+	patch := client.MergeFromWithOptions(daemonSet.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	strategy.Type = appsv1.RollingUpdateDaemonSetStrategyType
+	if strategy.RollingUpdate == nil {
+		strategy.RollingUpdate = &appsv1.RollingUpdateDaemonSet{}
+	}
+	if req.MaxUnavailable != nil {
+		strategy.RollingUpdate.MaxUnavailable = req.MaxUnavailable
+	}
+	if req.MaxSurge != nil {
+		strategy.RollingUpdate.MaxSurge = req.MaxSurge
+	}
+	if err := k8sClient.Client.Patch(c.Request.Context(), &daemonSet, patch, client.FieldOwner(kube.FieldManager)); err != nil {
+		c.JSON(ErrorStatus(err), gin.H{"error": "Failed to update daemonset: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "DaemonSet rolling update updated successfully",
+		"updateStrategy": daemonSet.Spec.UpdateStrategy,
+	})
+}
+
+// WatchRolloutStatus streams the rollout progress of a daemonset as
+// server-sent events until every scheduled pod is updated and available
+func (h *DaemonSetHandler) WatchRolloutStatus(c *gin.Context) {
+	k8sClient := h.getClient(c)
+	var daemonSet appsv1.DaemonSet
+	if !getWorkload(c, k8sClient, "DaemonSet", &daemonSet) {
+		return
+	}
+	key := types.NamespacedName{Namespace: daemonSet.Namespace, Name: daemonSet.Name}
+
+	streamRolloutStatus(c, func() (*RolloutStatus, error) {
+		var daemonSet appsv1.DaemonSet
+		if err := k8sClient.Client.Get(c.Request.Context(), key, &daemonSet); err != nil {
+			return nil, err
+		}
+		return daemonSetRolloutStatus(&daemonSet), nil
+	})
+}
+
+// daemonSetRolloutStatus reports rollout progress the way kubectl rollout
+// status does; replicas are the pods the daemonset should schedule
+func daemonSetRolloutStatus(daemonSet *appsv1.DaemonSet) *RolloutStatus {
+	status := &RolloutStatus{
+		Generation:         daemonSet.Generation,
+		ObservedGeneration: daemonSet.Status.ObservedGeneration,
+		Replicas:           daemonSet.Status.DesiredNumberScheduled,
+		UpdatedReplicas:    daemonSet.Status.UpdatedNumberScheduled,
+		ReadyReplicas:      daemonSet.Status.NumberReady,
+		AvailableReplicas:  daemonSet.Status.NumberAvailable,
+	}
+
+	switch {
+	case daemonSet.Spec.UpdateStrategy.Type == appsv1.OnDeleteDaemonSetStrategyType:
+		status.Message = "Rollout status is only available for the RollingUpdate strategy"
+		status.Done = true
+	case daemonSet.Generation > daemonSet.Status.ObservedGeneration:
+		status.Message = "Waiting for daemonset spec update to be observed"
+	case status.UpdatedReplicas < status.Replicas:
+		status.Message = fmt.Sprintf("Waiting for daemonset %q rollout to finish: %d out of %d new pods have been updated", daemonSet.Name, status.UpdatedReplicas, status.Replicas)
+	case status.AvailableReplicas < status.Replicas:
+		status.Message = fmt.Sprintf("Waiting for daemonset %q rollout to finish: %d of %d updated pods are available", daemonSet.Name, status.AvailableReplicas, status.Replicas)
+	default:
+		status.Message = fmt.Sprintf("DaemonSet %q successfully rolled out", daemonSet.Name)
+		status.Done = true
+	}
+	return status
+}
+
+func (h *DaemonSetHandler) registerCustomRoutes(group *gin.RouterGroup) {
+	group.POST("/:namespace/:name/restart", h.RestartDaemonSet)
+	group.GET("/:namespace/:name/history", h.ListRolloutHistory)
+	group.GET("/:namespace/:name/history/:revision", h.GetRolloutRevision)
+	group.POST("/:namespace/:name/rollback", h.RollbackDaemonSet)
+	group.POST("/:namespace/:name/rolling-update", h.UpdateRollingUpdate)
+	group.GET("/:namespace/:name/rollout-status", h.WatchRolloutStatus)
+}
@@ -3,7 +3,6 @@ package resources
 import (
 	"context"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ysicing/nexus/pkg/kube"
@@ -31,15 +30,14 @@ func NewDeploymentHandler(client *kube.K8sClient) *DeploymentHandler {
 }
 
 func (h *DeploymentHandler) Restart(ctx context.Context, namespace, name string) error {
+	return h.restart(ctx, h.K8sClient, namespace, name)
+}
+
+func (h *DeploymentHandler) restart(ctx context.Context, k8sClient *kube.K8sClient, namespace, name string) error {
 	var deployment appsv1.Deployment
-	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &deployment); err != nil {
-		return err
-	}
-	if deployment.Spec.Template.Annotations == nil {
-		deployment.Spec.Template.Annotations = make(map[string]string)
-	}
-	deployment.Spec.Template.Annotations["kite.kubernetes.io/restartedAt"] = time.Now().Format(time.RFC3339)
-	return h.K8sClient.Client.Update(ctx, &deployment)
+	return restartWorkload(ctx, k8sClient, namespace, name, &deployment, func() *corev1.PodTemplateSpec {
+		return &deployment.Spec.Template
+	})
 }
 
 func (h *DeploymentHandler) RestartDeployment(c *gin.Context) {
@@ -47,7 +45,7 @@ func (h *DeploymentHandler) RestartDeployment(c *gin.Context) {
 	name := c.Param("name")
 	ctx := c.Request.Context()
 
-	if err := h.Restart(ctx, namespace, name); err != nil {
+	if err := h.restart(ctx, h.getClient(c), namespace, name); err != nil {
 		if errors.IsNotFound(err) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Deployment not found"})
 			return
@@ -123,6 +121,11 @@ func (h *DeploymentHandler) ScaleDeployment(c *gin.Context) {
 	namespace := c.Param("namespace")
 	name := c.Param("name")
 	ctx := c.Request.Context()
+	k8sClient := h.getClient(c)
+	if k8sClient == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "k8s client not available"})
+		return
+	}
 
 	// Parse the request body to get the desired replica count
 	var scaleRequest struct {
@@ -141,7 +144,7 @@ func (h *DeploymentHandler) ScaleDeployment(c *gin.Context) {
 
 	// Get the current deployment
 	var deployment appsv1.Deployment
-	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &deployment); err != nil {
+	if err := k8sClient.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &deployment); err != nil {
 		if errors.IsNotFound(err) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Deployment not found"})
 			return
@@ -154,7 +157,7 @@ func (h *DeploymentHandler) ScaleDeployment(c *gin.Context) {
 	deployment.Spec.Replicas = scaleRequest.Replicas
 
 	// Update the deployment
-	if err := h.K8sClient.Client.Update(ctx, &deployment); err != nil {
+	if err := k8sClient.Client.Update(ctx, &deployment); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scale deployment: " + err.Error()})
 		return
 	}
@@ -170,4 +173,10 @@ func (h *DeploymentHandler) registerCustomRoutes(group *gin.RouterGroup) {
 	group.GET("/:namespace/:name/related", h.ListDeploymentRelatedResources)
 	group.POST("/:namespace/:name/scale", h.ScaleDeployment)
 	group.POST("/:namespace/:name/restart", h.RestartDeployment)
+	group.GET("/:namespace/:name/history", h.ListRolloutHistory)
+	group.GET("/:namespace/:name/history/:revision", h.GetRolloutRevision)
+	group.POST("/:namespace/:name/rollback", h.RollbackDeployment)
+	group.POST("/:namespace/:name/pause", h.PauseDeployment)
+	group.POST("/:namespace/:name/resume", h.ResumeDeployment)
+	group.GET("/:namespace/:name/rollout-status", h.WatchRolloutStatus)
 }
@@ -0,0 +1,226 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ysicing/nexus/pkg/kube"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// deploymentRevisionAnnotation is set by the deployment controller on
+	// the deployment and each of its ReplicaSets
+	deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+	// progressDeadlineExceeded is the Progressing condition reason of a
+	// deployment that stopped making progress
+	progressDeadlineExceeded = "ProgressDeadlineExceeded"
+)
+
+// ownedReplicaSets lists the ReplicaSets controlled by the deployment
+func ownedReplicaSets(ctx context.Context, k8sClient *kube.K8sClient, deployment *appsv1.Deployment) ([]appsv1.ReplicaSet, error) {
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	var list appsv1.ReplicaSetList
+	if err := k8sClient.Client.List(ctx, &list, client.InNamespace(deployment.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+
+	owned := make([]appsv1.ReplicaSet, 0, len(list.Items))
+	for _, rs := range list.Items {
+		if owner := metav1.GetControllerOf(&rs); owner != nil && owner.UID == deployment.UID {
+			owned = append(owned, rs)
+		}
+	}
+	return owned, nil
+}
+
+// deploymentHistory builds the rollout history of a deployment from its
+// ReplicaSets. ReplicaSets without a revision are skipped.
+func deploymentHistory(ctx context.Context, k8sClient *kube.K8sClient, deployment *appsv1.Deployment) (*rolloutHistory, error) {
+	replicaSets, err := ownedReplicaSets(ctx, k8sClient, deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	current, _ := strconv.ParseInt(deployment.Annotations[deploymentRevisionAnnotation], 10, 64)
+	history := newRolloutHistory(len(replicaSets))
+	for i := range replicaSets {
+		rs := &replicaSets[i]
+		revision, err := strconv.ParseInt(rs.Annotations[deploymentRevisionAnnotation], 10, 64)
+		if err != nil {
+			continue
+		}
+		history.add(RolloutRevision{
+			Revision:    revision,
+			Name:        rs.Name,
+			ChangeCause: rs.Annotations[changeCauseAnnotation],
+			Replicas:    rs.Status.Replicas,
+			Current:     revision == current,
+			CreatedAt:   rs.CreationTimestamp.Time,
+		}, &rs.Spec.Template)
+	}
+	history.sort()
+	return history, nil
+}
+
+// ListRolloutHistory lists the rollout history of a deployment
+func (h *DeploymentHandler) ListRolloutHistory(c *gin.Context) {
+	k8sClient := h.getClient(c)
+	var deployment appsv1.Deployment
+	if !getWorkload(c, k8sClient, "Deployment", &deployment) {
+		return
+	}
+
+	history, err := deploymentHistory(c.Request.Context(), k8sClient, &deployment)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"revisions": history.revisions})
+}
+
+// GetRolloutRevision returns one revision of a deployment with its pod template and diff
+func (h *DeploymentHandler) GetRolloutRevision(c *gin.Context) {
+	k8sClient := h.getClient(c)
+	var deployment appsv1.Deployment
+	if !getWorkload(c, k8sClient, "Deployment", &deployment) {
+		return
+	}
+
+	history, err := deploymentHistory(c.Request.Context(), k8sClient, &deployment)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	writeRevision(c, history, &deployment.Spec.Template)
+}
+
+// RollbackDeployment rolls a deployment back to an earlier revision
+func (h *DeploymentHandler) RollbackDeployment(c *gin.Context) {
+	k8sClient := h.getClient(c)
+	var deployment appsv1.Deployment
+	if !getWorkload(c, k8sClient, "Deployment", &deployment) {
+		return
+	}
+	if deployment.Spec.Paused {
+		c.JSON(http.StatusConflict, gin.H{"error": "cannot roll back a paused deployment, resume it first"})
+		return
+	}
+
+	history, err := deploymentHistory(c.Request.Context(), k8sClient, &deployment)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	rollback(c, k8sClient, "Deployment", &deployment, history, func() *corev1.PodTemplateSpec {
+		return &deployment.Spec.Template
+	})
+}
+
+// PauseDeployment pauses the rollout of a deployment
+func (h *DeploymentHandler) PauseDeployment(c *gin.Context) {
+	h.setPaused(c, true)
+}
+
+// ResumeDeployment resumes a paused rollout
+func (h *DeploymentHandler) ResumeDeployment(c *gin.Context) {
+	h.setPaused(c, false)
+}
+
+func (h *DeploymentHandler) setPaused(c *gin.Context, paused bool) {
+	k8sClient := h.getClient(c)
+	var deployment appsv1.Deployment
+	if !getWorkload(c, k8sClient, "Deployment", &deployment) {
+		return
+	}
+	state := "resumed"
+	if paused {
+		state = "paused"
+	}
+	if deployment.Spec.Paused == paused {
+		c.JSON(http.StatusOK, gin.H{"message": "Deployment is already " + state, "paused": paused})
+		return
+	}
+
+	patch := client.MergeFrom(deployment.DeepCopy())
+	deployment.Spec.Paused = paused
+	if err := k8sClient.Client.Patch(c.Request.Context(), &deployment, patch, client.FieldOwner(kube.FieldManager)); err != nil {
+		c.JSON(ErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Deployment " + state + " successfully", "paused": paused})
+}
+
+// WatchRolloutStatus streams the rollout progress of a deployment as
+// server-sent events until it completes or exceeds its progress deadline
+func (h *DeploymentHandler) WatchRolloutStatus(c *gin.Context) {
+	k8sClient := h.getClient(c)
+	var deployment appsv1.Deployment
+	if !getWorkload(c, k8sClient, "Deployment", &deployment) {
+		return
+	}
+	key := types.NamespacedName{Namespace: deployment.Namespace, Name: deployment.Name}
+
+	streamRolloutStatus(c, func() (*RolloutStatus, error) {
+		var deployment appsv1.Deployment
+		if err := k8sClient.Client.Get(c.Request.Context(), key, &deployment); err != nil {
+			return nil, err
+		}
+		return deploymentRolloutStatus(&deployment), nil
+	})
+}
+
+// deploymentRolloutStatus reports rollout progress the way kubectl rollout status does
+func deploymentRolloutStatus(deployment *appsv1.Deployment) *RolloutStatus {
+	status := &RolloutStatus{
+		Generation:         deployment.Generation,
+		ObservedGeneration: deployment.Status.ObservedGeneration,
+		Replicas:           deployment.Status.Replicas,
+		UpdatedReplicas:    deployment.Status.UpdatedReplicas,
+		ReadyReplicas:      deployment.Status.ReadyReplicas,
+		AvailableReplicas:  deployment.Status.AvailableReplicas,
+	}
+	desired := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+
+	switch {
+	case deployment.Generation > deployment.Status.ObservedGeneration:
+		status.Message = "Waiting for deployment spec update to be observed"
+	case progressDeadlineHit(deployment):
+		status.Message = fmt.Sprintf("Deployment %q exceeded its progress deadline", deployment.Name)
+		status.Failed = true
+	case deployment.Spec.Paused:
+		status.Message = "Deployment is paused"
+	case status.UpdatedReplicas < desired:
+		status.Message = fmt.Sprintf("Waiting for rollout to finish: %d out of %d new replicas have been updated", status.UpdatedReplicas, desired)
+	case status.Replicas > status.UpdatedReplicas:
+		status.Message = fmt.Sprintf("Waiting for rollout to finish: %d old replicas are pending termination", status.Replicas-status.UpdatedReplicas)
+	case status.AvailableReplicas < status.UpdatedReplicas:
+		status.Message = fmt.Sprintf("Waiting for rollout to finish: %d of %d updated replicas are available", status.AvailableReplicas, status.UpdatedReplicas)
+	default:
+		status.Message = fmt.Sprintf("Deployment %q successfully rolled out", deployment.Name)
+		status.Done = true
+	}
+	return status
+}
+
+func progressDeadlineHit(deployment *appsv1.Deployment) bool {
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing {
+			return condition.Status == corev1.ConditionFalse && condition.Reason == progressDeadlineExceeded
+		}
+	}
+	return false
+}
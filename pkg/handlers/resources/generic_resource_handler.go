@@ -5,8 +5,6 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
-	"sort"
-	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -15,7 +13,6 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -137,51 +134,15 @@ func (h *GenericResourceHandler[T, V]) List(c *gin.Context) {
 
 	ctx := c.Request.Context()
 
-	var listOpts []client.ListOption
-	if !h.isClusterScoped {
-		namespace := c.Param("namespace")
-		if namespace != "" && namespace != "_all" {
-			listOpts = append(listOpts, client.InNamespace(namespace))
-		}
-	}
-	if c.Query("limit") != "" {
-		limit, err := strconv.ParseInt(c.Query("limit"), 10, 64)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
-			return
-		}
-		listOpts = append(listOpts, client.Limit(limit))
-	}
-
-	if c.Query("continue") != "" {
-		continueToken := c.Query("continue")
-		listOpts = append(listOpts, client.Continue(continueToken))
-	}
-
-	// Add label selector support
-	if c.Query("labelSelector") != "" {
-		labelSelector := c.Query("labelSelector")
-		selector, err := metav1.ParseToLabelSelector(labelSelector)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid labelSelector parameter: " + err.Error()})
-			return
-		}
-		labelSelectorOption, err := metav1.LabelSelectorAsSelector(selector)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to convert labelSelector: " + err.Error()})
-			return
-		}
-		listOpts = append(listOpts, client.MatchingLabelsSelector{Selector: labelSelectorOption})
+	query, err := parseListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	if c.Query("fieldSelector") != "" {
-		fieldSelector := c.Query("fieldSelector")
-		fieldSelectorOption, err := fields.ParseSelector(fieldSelector)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fieldSelector parameter: " + err.Error()})
-			return
-		}
-		listOpts = append(listOpts, client.MatchingFieldsSelector{Selector: fieldSelectorOption})
+	listOpts, ok := listOptions(c, !h.isClusterScoped)
+	if !ok {
+		return
 	}
 
 	// 从上下文中获取集群客户端
@@ -196,32 +157,7 @@ func (h *GenericResourceHandler[T, V]) List(c *gin.Context) {
 		return
 	}
 
-	// Sort by creation timestamp in descending order (newest first)
-	// Extract items using reflection and sort them directly
-
-	items, err := meta.ExtractList(objectList)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to extract items from list"})
-		return
-	}
-	sort.Slice(items, func(i, j int) bool {
-		o1, _ := meta.Accessor(items[i])
-		o2, _ := meta.Accessor(items[j])
-		if o1 == nil || o2 == nil {
-			return false // Handle nil cases gracefully
-		}
-
-		t1 := o1.GetCreationTimestamp()
-		t2 := o2.GetCreationTimestamp()
-		if t1.Equal(&t2) {
-			return o1.GetName() < o2.GetName()
-		}
-
-		return t1.After(t2.Time)
-	})
-	_ = meta.SetList(objectList, items)
-
-	c.JSON(http.StatusOK, objectList)
+	query.respond(c, objectList)
 }
 
 func (h *GenericResourceHandler[T, V]) Create(c *gin.Context) {
@@ -280,6 +216,42 @@ func (h *GenericResourceHandler[T, V]) Update(c *gin.Context) {
 	c.JSON(http.StatusOK, resource)
 }
 
+// Patch applies a JSON patch, JSON merge patch or strategic merge patch
+// selected by the request content type
+func (h *GenericResourceHandler[T, V]) Patch(c *gin.Context) {
+	patch, ok := readPatch(c, true)
+	if !ok {
+		return
+	}
+
+	resource := reflect.New(h.objectType).Interface().(T)
+	resource.SetName(c.Param("name"))
+	if !h.isClusterScoped {
+		namespace := c.Param("namespace")
+		if namespace != "" && namespace != "_all" {
+			resource.SetNamespace(namespace)
+		}
+	}
+
+	// 从上下文中获取集群客户端
+	k8sClient := h.getClient(c)
+	if k8sClient == nil || k8sClient.Client == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "No cluster available. Please add a cluster first."})
+		return
+	}
+
+	if err := k8sClient.Client.Patch(c.Request.Context(), resource, patch, client.FieldOwner(kube.FieldManager)); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(ErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resource)
+}
+
 func (h *GenericResourceHandler[T, V]) Delete(c *gin.Context) {
 	name := c.Param("name")
 	resource := reflect.New(h.objectType).Interface().(T)
@@ -336,13 +308,14 @@ func (h *GenericResourceHandler[T, V]) Search(ctx context.Context, q string, lim
 		return nil, nil
 	}
 
-	// 注意：这个方法没有 gin.Context，所以无法获取动态客户端
-	if h.K8sClient == nil || h.K8sClient.Client == nil {
+	// 集群客户端由搜索请求通过 context 传入
+	k8sClient := clientFromContext(ctx, h.K8sClient)
+	if k8sClient == nil || k8sClient.Client == nil {
 		return nil, fmt.Errorf("no cluster client available")
 	}
 
 	objectList := reflect.New(h.listType).Interface().(V)
-	if err := h.K8sClient.Client.List(ctx, objectList); err != nil {
+	if err := k8sClient.Client.List(ctx, objectList); err != nil {
 		klog.Errorf("failed to list %s: %v", h.name, err)
 		return nil, err
 	}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	"github.com/gin-gonic/gin"
-	"github.com/ysicing/nexus/pkg/cluster"
 	"github.com/ysicing/nexus/pkg/common"
 	"github.com/ysicing/nexus/pkg/kube"
 	appsv1 "k8s.io/api/apps/v1"
@@ -24,6 +23,7 @@ type resourceHandler interface {
 	Get(c *gin.Context)
 	Create(c *gin.Context)
 	Update(c *gin.Context)
+	Patch(c *gin.Context)
 	Delete(c *gin.Context)
 
 	IsClusterScoped() bool
@@ -41,8 +41,11 @@ type Restartable interface {
 
 var handlers = map[string]resourceHandler{}
 
-func RegisterRoutes(group *gin.RouterGroup, k8sClient *kube.K8sClient) {
-	handlers = map[string]resourceHandler{
+// typedHandlers returns the handlers of the resource types with typed objects
+// and custom routes. Every other resource type discovered from the cluster is
+// served by CRHandler through the /:crd routes.
+func typedHandlers(k8sClient *kube.K8sClient) map[string]resourceHandler {
+	return map[string]resourceHandler{
 		"pods":                   NewGenericResourceHandler[*corev1.Pod, *corev1.PodList](k8sClient, "pods", false, true),
 		"namespaces":             NewGenericResourceHandler[*corev1.Namespace, *corev1.NamespaceList](k8sClient, "namespaces", true, false),
 		"nodes":                  NewNodeHandler(k8sClient),
@@ -58,8 +61,8 @@ func RegisterRoutes(group *gin.RouterGroup, k8sClient *kube.K8sClient) {
 		"events":                 NewEventHandler(k8sClient),
 		"deployments":            NewDeploymentHandler(k8sClient),
 		"replicasets":            NewGenericResourceHandler[*appsv1.ReplicaSet, *appsv1.ReplicaSetList](k8sClient, "replicasets", false, false),
-		"statefulsets":           NewGenericResourceHandler[*appsv1.StatefulSet, *appsv1.StatefulSetList](k8sClient, "statefulsets", false, false),
-		"daemonsets":             NewGenericResourceHandler[*appsv1.DaemonSet, *appsv1.DaemonSetList](k8sClient, "daemonsets", false, true),
+		"statefulsets":           NewStatefulSetHandler(k8sClient),
+		"daemonsets":             NewDaemonSetHandler(k8sClient),
 		"jobs":                   NewGenericResourceHandler[*batchv1.Job, *batchv1.JobList](k8sClient, "jobs", false, false),
 		"cronjobs":               NewGenericResourceHandler[*batchv1.CronJob, *batchv1.CronJobList](k8sClient, "cronjobs", false, false),
 		"ingresses":              NewGenericResourceHandler[*networkingv1.Ingress, *networkingv1.IngressList](k8sClient, "ingresses", false, false),
@@ -71,14 +74,28 @@ func RegisterRoutes(group *gin.RouterGroup, k8sClient *kube.K8sClient) {
 		"podmetrics":             NewGenericResourceHandler[*metricsv1.PodMetrics, *metricsv1.PodMetricsList](k8sClient, "metrics.k8s.io", false, false),
 		"nodemetrics":            NewGenericResourceHandler[*metricsv1.NodeMetrics, *metricsv1.NodeMetricsList](k8sClient, "metrics.k8s.io", false, false),
 	}
+}
+
+func RegisterRoutes(group *gin.RouterGroup, k8sClient *kube.K8sClient) {
+	handlers = typedHandlers(k8sClient)
+	registerRoutes(group, handlers, k8sClient)
+}
 
-	for name, handler := range handlers {
+// registerRoutes registers the typed handlers, the API discovery endpoint and
+// the /:crd routes for the remaining resource types
+func registerRoutes(group *gin.RouterGroup, typed map[string]resourceHandler, k8sClient *kube.K8sClient) {
+	for name, handler := range typed {
 		g := group.Group("/" + name)
 		handler.registerCustomRoutes(g)
+		list := withTable(listOrWatch(handler.List, staticName(name), k8sClient), staticName(name), k8sClient)
+		get := withTable(handler.Get, staticName(name), k8sClient)
+		graph := resourceGraph(staticName(name), k8sClient)
 		if handler.IsClusterScoped() {
-			registerClusterScopeRoutes(g, handler)
+			registerClusterScopeRoutes(g, handler, list, get)
+			g.GET("/_all/:name/graph", graph)
 		} else {
-			registerNamespaceScopeRoutes(g, handler)
+			registerNamespaceScopeRoutes(g, handler, list, get)
+			g.GET("/:namespace/:name/graph", graph)
 		}
 
 		if handler.Searchable() {
@@ -86,116 +103,98 @@ func RegisterRoutes(group *gin.RouterGroup, k8sClient *kube.K8sClient) {
 		}
 	}
 
+	apiResourcesHandler := NewAPIResourcesHandler(k8sClient)
+	group.GET("/api-resources", apiResourcesHandler.List)
+
 	crHandler := NewCRHandler(k8sClient)
+	crdName := func(c *gin.Context) string { return c.Param("crd") }
+	crList := withTable(listOrWatch(crHandler.List, crdName, k8sClient), crdName, k8sClient)
+	crGet := withTable(crHandler.Get, crdName, k8sClient)
+	crGraph := resourceGraph(crdName, k8sClient)
 	otherGroup := group.Group("/:crd")
 	{
-		otherGroup.GET("", crHandler.List)
-		otherGroup.GET("/_all", crHandler.List)
-		otherGroup.GET("/_all/:name", crHandler.Get)
+		otherGroup.GET("", crList)
+		otherGroup.POST("", crHandler.Create)
+		otherGroup.GET("/_all", crList)
+		otherGroup.POST("/_all", crHandler.Create)
+		otherGroup.GET("/_all/:name", crGet)
+		otherGroup.GET("/_all/:name/graph", crGraph)
+		otherGroup.GET("/_all/:name/events", crHandler.ListEvents)
+		otherGroup.GET("/_all/:name/status", crHandler.GetStatus)
+		otherGroup.GET("/_all/:name/scale", crHandler.GetScale)
+		otherGroup.POST("/_all/:name/scale", crHandler.ScaleCustomResource)
 		otherGroup.PUT("/_all/:name", crHandler.Update)
+		otherGroup.PATCH("/_all/:name", crHandler.Patch)
 		otherGroup.DELETE("/_all/:name", crHandler.Delete)
 
-		otherGroup.GET("/:namespace", crHandler.List)
-		otherGroup.GET("/:namespace/:name", crHandler.Get)
+		otherGroup.GET("/:namespace", crList)
+		otherGroup.POST("/:namespace", crHandler.Create)
+		otherGroup.GET("/:namespace/:name", crGet)
+		otherGroup.GET("/:namespace/:name/graph", crGraph)
+		otherGroup.GET("/:namespace/:name/events", crHandler.ListEvents)
+		otherGroup.GET("/:namespace/:name/status", crHandler.GetStatus)
+		otherGroup.GET("/:namespace/:name/scale", crHandler.GetScale)
+		otherGroup.POST("/:namespace/:name/scale", crHandler.ScaleCustomResource)
 		otherGroup.PUT("/:namespace/:name", crHandler.Update)
+		otherGroup.PATCH("/:namespace/:name", crHandler.Patch)
 		otherGroup.DELETE("/:namespace/:name", crHandler.Delete)
 	}
+	RegisterSearchFunc("customresources", crHandler.Search)
+}
+
+// staticName returns the resource name of a typed handler for listOrWatch, withTable and resourceGraph
+func staticName(name string) func(c *gin.Context) string {
+	return func(c *gin.Context) string { return name }
 }
 
-func registerClusterScopeRoutes(group *gin.RouterGroup, handler resourceHandler) {
-	group.GET("", handler.List)
-	group.GET("/_all", handler.List)
-	group.GET("/_all/:name", handler.Get)
+func registerClusterScopeRoutes(group *gin.RouterGroup, handler resourceHandler, list, get gin.HandlerFunc) {
+	group.GET("", list)
+	group.GET("/_all", list)
+	group.GET("/_all/:name", get)
 	group.POST("/_all", handler.Create)
 	group.PUT("/_all/:name", handler.Update)
+	group.PATCH("/_all/:name", handler.Patch)
 	group.DELETE("/_all/:name", handler.Delete)
 }
 
-func registerNamespaceScopeRoutes(group *gin.RouterGroup, handler resourceHandler) {
-	group.GET("", handler.List)
-	group.GET("/:namespace", handler.List)
-	group.GET("/:namespace/:name", handler.Get)
+func registerNamespaceScopeRoutes(group *gin.RouterGroup, handler resourceHandler, list, get gin.HandlerFunc) {
+	group.GET("", list)
+	group.GET("/:namespace", list)
+	group.GET("/:namespace/:name", get)
 	group.POST("/:namespace", handler.Create)
 	group.PUT("/:namespace/:name", handler.Update)
+	group.PATCH("/:namespace/:name", handler.Patch)
 	group.DELETE("/:namespace/:name", handler.Delete)
 }
 
-var SearchFuncs = map[string]func(ctx context.Context, query string, limit int64) ([]common.SearchResult, error){}
+// clientContextKey is the context key of the cluster client passed to search functions
+type clientContextKey struct{}
 
-func RegisterSearchFunc(resourceType string, searchFunc func(ctx context.Context, query string, limit int64) ([]common.SearchResult, error)) {
-	SearchFuncs[resourceType] = searchFunc
+// WithClient returns a context that carries the cluster client, so that search
+// functions, which have no gin context, search the cluster of the request
+func WithClient(ctx context.Context, k8sClient *kube.K8sClient) context.Context {
+	return context.WithValue(ctx, clientContextKey{}, k8sClient)
 }
 
-// RegisterRoutesWithCluster 注册资源路由，支持多集群
-func RegisterRoutesWithCluster(group *gin.RouterGroup, clusterManager *cluster.Manager) {
-
-	// 动态创建处理器
-	createHandlers := func() map[string]resourceHandler {
-		// 这里使用一个占位符客户端，实际的客户端会在运行时从上下文获取
-		var placeholderClient *kube.K8sClient
-
-		return map[string]resourceHandler{
-			"pods":                   NewGenericResourceHandler[*corev1.Pod, *corev1.PodList](placeholderClient, "pods", false, true),
-			"namespaces":             NewGenericResourceHandler[*corev1.Namespace, *corev1.NamespaceList](placeholderClient, "namespaces", true, false),
-			"nodes":                  NewNodeHandler(placeholderClient),
-			"services":               NewGenericResourceHandler[*corev1.Service, *corev1.ServiceList](placeholderClient, "services", false, true),
-			"endpoints":              NewGenericResourceHandler[*corev1.Endpoints, *corev1.EndpointsList](placeholderClient, "endpoints", false, false),
-			"endpointslices":         NewGenericResourceHandler[*discoveryv1.EndpointSlice, *discoveryv1.EndpointSliceList](placeholderClient, "endpointslices", false, false),
-			"configmaps":             NewGenericResourceHandler[*corev1.ConfigMap, *corev1.ConfigMapList](placeholderClient, "configmaps", false, true),
-			"secrets":                NewGenericResourceHandler[*corev1.Secret, *corev1.SecretList](placeholderClient, "secrets", false, true),
-			"persistentvolumes":      NewGenericResourceHandler[*corev1.PersistentVolume, *corev1.PersistentVolumeList](placeholderClient, "persistentvolumes", true, true),
-			"persistentvolumeclaims": NewGenericResourceHandler[*corev1.PersistentVolumeClaim, *corev1.PersistentVolumeClaimList](placeholderClient, "persistentvolumeclaims", false, true),
-			"serviceaccounts":        NewGenericResourceHandler[*corev1.ServiceAccount, *corev1.ServiceAccountList](placeholderClient, "serviceaccounts", false, false),
-			"crds":                   NewGenericResourceHandler[*apiextensionsv1.CustomResourceDefinition, *apiextensionsv1.CustomResourceDefinitionList](placeholderClient, "crds", true, false),
-			"events":                 NewEventHandler(placeholderClient),
-			"deployments":            NewDeploymentHandler(placeholderClient),
-			"replicasets":            NewGenericResourceHandler[*appsv1.ReplicaSet, *appsv1.ReplicaSetList](placeholderClient, "replicasets", false, false),
-			"statefulsets":           NewGenericResourceHandler[*appsv1.StatefulSet, *appsv1.StatefulSetList](placeholderClient, "statefulsets", false, false),
-			"daemonsets":             NewGenericResourceHandler[*appsv1.DaemonSet, *appsv1.DaemonSetList](placeholderClient, "daemonsets", false, true),
-			"jobs":                   NewGenericResourceHandler[*batchv1.Job, *batchv1.JobList](placeholderClient, "jobs", false, false),
-			"cronjobs":               NewGenericResourceHandler[*batchv1.CronJob, *batchv1.CronJobList](placeholderClient, "cronjobs", false, false),
-			"ingresses":              NewGenericResourceHandler[*networkingv1.Ingress, *networkingv1.IngressList](placeholderClient, "ingresses", false, false),
-			"storageclasses":         NewGenericResourceHandler[*storagev1.StorageClass, *storagev1.StorageClassList](placeholderClient, "storageclasses", true, false),
-			"roles":                  NewGenericResourceHandler[*rbacv1.Role, *rbacv1.RoleList](placeholderClient, "roles", false, false),
-			"rolebindings":           NewGenericResourceHandler[*rbacv1.RoleBinding, *rbacv1.RoleBindingList](placeholderClient, "rolebindings", false, false),
-			"clusterroles":           NewGenericResourceHandler[*rbacv1.ClusterRole, *rbacv1.ClusterRoleList](placeholderClient, "clusterroles", true, false),
-			"clusterrolebindings":    NewGenericResourceHandler[*rbacv1.ClusterRoleBinding, *rbacv1.ClusterRoleBindingList](placeholderClient, "clusterrolebindings", true, false),
-			"podmetrics":             NewGenericResourceHandler[*metricsv1.PodMetrics, *metricsv1.PodMetricsList](placeholderClient, "metrics.k8s.io", false, false),
-			"nodemetrics":            NewGenericResourceHandler[*metricsv1.NodeMetrics, *metricsv1.NodeMetricsList](placeholderClient, "metrics.k8s.io", false, false),
-		}
+// clientFromContext returns the cluster client carried by ctx, or fallback
+func clientFromContext(ctx context.Context, fallback *kube.K8sClient) *kube.K8sClient {
+	if k8sClient, ok := ctx.Value(clientContextKey{}).(*kube.K8sClient); ok && k8sClient != nil {
+		return k8sClient
 	}
+	return fallback
+}
 
-	clusterHandlers := createHandlers()
-
-	for name, handler := range clusterHandlers {
-		g := group.Group("/" + name)
-		handler.registerCustomRoutes(g)
-		if handler.IsClusterScoped() {
-			registerClusterScopeRoutes(g, handler)
-		} else {
-			registerNamespaceScopeRoutes(g, handler)
-		}
-
-		if handler.Searchable() {
-			RegisterSearchFunc(name, handler.Search)
-		}
-	}
+var SearchFuncs = map[string]func(ctx context.Context, query string, limit int64) ([]common.SearchResult, error){}
 
-	// CR处理器需要特殊处理
-	crHandler := NewCRHandler(nil) // 占位符客户端
-	otherGroup := group.Group("/:crd")
-	{
-		otherGroup.GET("", crHandler.List)
-		otherGroup.GET("/_all", crHandler.List)
-		otherGroup.GET("/_all/:name", crHandler.Get)
-		otherGroup.PUT("/_all/:name", crHandler.Update)
-		otherGroup.DELETE("/_all/:name", crHandler.Delete)
+func RegisterSearchFunc(resourceType string, searchFunc func(ctx context.Context, query string, limit int64) ([]common.SearchResult, error)) {
+	SearchFuncs[resourceType] = searchFunc
+}
 
-		otherGroup.GET("/:namespace", crHandler.List)
-		otherGroup.GET("/:namespace/:name", crHandler.Get)
-		otherGroup.PUT("/:namespace/:name", crHandler.Update)
-		otherGroup.DELETE("/:namespace/:name", crHandler.Delete)
-	}
+// RegisterRoutesWithCluster 注册资源路由，支持多集群
+//
+// 处理器不持有客户端，实际的客户端在运行时由集群中间件放入上下文
+func RegisterRoutesWithCluster(group *gin.RouterGroup) {
+	registerRoutes(group, typedHandlers(nil), nil)
 }
 
 func GetResource(ctx context.Context, resource, namespace, name string) (interface{}, error) {
@@ -206,8 +205,10 @@ func GetResource(ctx context.Context, resource, namespace, name string) (interfa
 	return handler.GetResource(ctx, namespace, name)
 }
 
-func GetHandler(resource string) (resourceHandler, error) {
-	handler, exists := handlers[resource]
+// GetHandler returns the typed handler of resource bound to k8sClient. The
+// handler does not depend on the routes registered for the cluster manager mode.
+func GetHandler(resource string, k8sClient *kube.K8sClient) (resourceHandler, error) {
+	handler, exists := typedHandlers(k8sClient)[resource]
 	if !exists {
 		return nil, fmt.Errorf("handler for resource %s not found", resource)
 	}
@@ -0,0 +1,515 @@
+package resources
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Sort fields of list endpoints
+const (
+	sortByName              = "name"
+	sortByNamespace         = "namespace"
+	sortByCreationTimestamp = "creationTimestamp"
+	sortByStatus            = "status"
+	sortByNode              = "node"
+)
+
+// listResponse is what list endpoints return: the list metadata and items
+// plus the number of objects that matched the filters
+type listResponse struct {
+	APIVersion string           `json:"apiVersion,omitempty"`
+	Kind       string           `json:"kind,omitempty"`
+	Metadata   metav1.ListMeta  `json:"metadata"`
+	Items      []runtime.Object `json:"items"`
+	// Total counts the objects matching the filters, across all pages. It is
+	// omitted for limit/continue pages when the API server does not report
+	// the remaining item count.
+	Total      *int `json:"total,omitempty"`
+	Page       int  `json:"page,omitempty"`
+	PageSize   int  `json:"pageSize,omitempty"`
+	TotalPages int  `json:"totalPages,omitempty"`
+}
+
+// listQuery holds the filtering, sorting and paging parameters of a list request:
+//
+//	name        name contains, case-insensitive
+//	nameRegex   name matches a regular expression
+//	status      comma-separated statuses, e.g. Running,Pending or Ready
+//	owner       owner reference as name or kind/name
+//	node        node of a pod
+//	sortBy      name, namespace, creationTimestamp (default), status or node
+//	sortOrder   asc or desc (default for creationTimestamp)
+//	page        1-based page number
+//	pageSize    objects per page
+//
+// They are evaluated on the listed objects, which come from the informer cache.
+type listQuery struct {
+	name       string
+	nameRegex  *regexp.Regexp
+	statuses   []string
+	ownerKind  string
+	ownerName  string
+	node       string
+	sortBy     string
+	descending bool
+	page       int
+	pageSize   int
+}
+
+func parseListQuery(c *gin.Context) (*listQuery, error) {
+	q := &listQuery{
+		name:   strings.ToLower(c.Query("name")),
+		node:   c.Query("node"),
+		sortBy: sortByCreationTimestamp,
+	}
+
+	if value := c.Query("nameRegex"); value != "" {
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid nameRegex parameter: %w", err)
+		}
+		q.nameRegex = re
+	}
+	for _, status := range strings.Split(c.Query("status"), ",") {
+		if status = strings.TrimSpace(status); status != "" {
+			q.statuses = append(q.statuses, status)
+		}
+	}
+	if value := c.Query("owner"); value != "" {
+		if kind, name, ok := strings.Cut(value, "/"); ok {
+			q.ownerKind, q.ownerName = kind, name
+		} else {
+			q.ownerName = value
+		}
+	}
+
+	if value := c.Query("sortBy"); value != "" {
+		switch value {
+		case sortByName, sortByNamespace, sortByCreationTimestamp, sortByStatus, sortByNode:
+			q.sortBy = value
+		default:
+			return nil, fmt.Errorf("invalid sortBy parameter: %s", value)
+		}
+	}
+	switch c.Query("sortOrder") {
+	case "":
+		// Newest first by default, alphabetical otherwise
+		q.descending = q.sortBy == sortByCreationTimestamp
+	case "asc":
+	case "desc":
+		q.descending = true
+	default:
+		return nil, fmt.Errorf("invalid sortOrder parameter: %s", c.Query("sortOrder"))
+	}
+
+	var err error
+	if value := c.Query("page"); value != "" {
+		if q.page, err = strconv.Atoi(value); err != nil || q.page < 1 {
+			return nil, fmt.Errorf("invalid page parameter")
+		}
+	}
+	if value := c.Query("pageSize"); value != "" {
+		if q.pageSize, err = strconv.Atoi(value); err != nil || q.pageSize < 1 {
+			return nil, fmt.Errorf("invalid pageSize parameter")
+		}
+	}
+	if q.paged() {
+		if c.Query("limit") != "" || c.Query("continue") != "" {
+			return nil, fmt.Errorf("limit and continue cannot be combined with page and pageSize")
+		}
+		if q.page == 0 {
+			q.page = 1
+		}
+		if q.pageSize == 0 {
+			q.pageSize = defaultPageSize
+		}
+	}
+	return q, nil
+}
+
+// defaultPageSize is the page size when only page is given
+const defaultPageSize = 50
+
+func (q *listQuery) paged() bool {
+	return q.page > 0 || q.pageSize > 0
+}
+
+// filtered reports whether any filter is evaluated on the listed objects
+func (q *listQuery) filtered() bool {
+	return q.name != "" || q.nameRegex != nil || len(q.statuses) > 0 || q.ownerName != "" || q.node != ""
+}
+
+// respond filters, sorts and pages the items of list and writes them with the totals
+func (q *listQuery) respond(c *gin.Context, list runtime.Object) {
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to extract items from list"})
+		return
+	}
+	if items, err = q.filter(items); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	q.sort(items)
+
+	response := &listResponse{Items: items}
+	gvk := list.GetObjectKind().GroupVersionKind()
+	response.APIVersion, response.Kind = gvk.GroupVersion().String(), gvk.Kind
+	if gvk.Empty() {
+		response.APIVersion = ""
+	}
+	if listMeta, err := meta.ListAccessor(list); err == nil {
+		response.Metadata.ResourceVersion = listMeta.GetResourceVersion()
+		response.Metadata.Continue = listMeta.GetContinue()
+		response.Metadata.RemainingItemCount = listMeta.GetRemainingItemCount()
+	}
+
+	total := len(items)
+	switch {
+	case c.Query("limit") == "" && c.Query("continue") == "":
+		response.Total = &total
+	case c.Query("continue") == "" && !q.filtered() && response.Metadata.RemainingItemCount != nil:
+		// The first page of a limited list plus what the API server has left;
+		// later pages and filtered pages cannot count the objects they skipped
+		total += int(*response.Metadata.RemainingItemCount)
+		response.Total = &total
+	}
+
+	if q.paged() {
+		response.Page, response.PageSize = q.page, q.pageSize
+		response.TotalPages = (len(items) + q.pageSize - 1) / q.pageSize
+		start := min((q.page-1)*q.pageSize, len(items))
+		end := min(start+q.pageSize, len(items))
+		response.Items = items[start:end]
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (q *listQuery) filter(items []runtime.Object) ([]runtime.Object, error) {
+	filtered := make([]runtime.Object, 0, len(items))
+	for _, item := range items {
+		obj, err := meta.Accessor(item)
+		if err != nil {
+			continue
+		}
+		if q.name != "" && !strings.Contains(strings.ToLower(obj.GetName()), q.name) {
+			continue
+		}
+		if q.nameRegex != nil && !q.nameRegex.MatchString(obj.GetName()) {
+			continue
+		}
+		if q.ownerName != "" && !hasOwner(obj, q.ownerKind, q.ownerName) {
+			continue
+		}
+		if q.node != "" {
+			node, ok := objectNode(item)
+			if !ok {
+				return nil, fmt.Errorf("node filter is only supported for pods and resources with spec.nodeName")
+			}
+			if node != q.node {
+				continue
+			}
+		}
+		if len(q.statuses) > 0 && !matchStatus(objectStatus(item), q.statuses) {
+			continue
+		}
+		filtered = append(filtered, item)
+	}
+	return filtered, nil
+}
+
+// sortKey holds the fields an item is sorted by, computed once per item
+// because deriving the status or node of an object is not free
+type sortKey struct {
+	item      runtime.Object
+	name      string
+	namespace string
+	created   metav1.Time
+	value     string
+}
+
+func (q *listQuery) sort(items []runtime.Object) {
+	keys := make([]sortKey, len(items))
+	for i, item := range items {
+		key := sortKey{item: item}
+		if obj, err := meta.Accessor(item); err == nil {
+			key.name, key.namespace, key.created = obj.GetName(), obj.GetNamespace(), obj.GetCreationTimestamp()
+		}
+		switch q.sortBy {
+		case sortByStatus:
+			key.value = objectStatus(item)
+		case sortByNode:
+			key.value, _ = objectNode(item)
+		}
+		keys[i] = key
+	}
+
+	compare := func(a, b *sortKey) int {
+		switch q.sortBy {
+		case sortByName:
+			return strings.Compare(a.name, b.name)
+		case sortByNamespace:
+			return strings.Compare(a.namespace, b.namespace)
+		case sortByCreationTimestamp:
+			return a.created.Time.Compare(b.created.Time)
+		case sortByStatus, sortByNode:
+			return strings.Compare(a.value, b.value)
+		}
+		return 0
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := &keys[i], &keys[j]
+		result := compare(a, b)
+		if q.descending {
+			result = -result
+		}
+		if result != 0 {
+			return result < 0
+		}
+		// Ties are always in name then namespace order
+		if a.name != b.name {
+			return a.name < b.name
+		}
+		return a.namespace < b.namespace
+	})
+
+	for i := range keys {
+		items[i] = keys[i].item
+	}
+}
+
+// hasOwner reports whether obj has an owner reference with the name and, if
+// set, the kind
+func hasOwner(obj metav1.Object, kind, name string) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.Name == name && (kind == "" || strings.EqualFold(ref.Kind, kind)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchStatus reports whether status, or one of its comma-separated parts
+// such as SchedulingDisabled, is one of statuses
+func matchStatus(status string, statuses []string) bool {
+	for _, want := range statuses {
+		if strings.EqualFold(status, want) {
+			return true
+		}
+		for _, part := range strings.Split(status, ",") {
+			if strings.EqualFold(part, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// objectNode returns the node of a pod or of a custom resource with spec.nodeName
+func objectNode(obj runtime.Object) (string, bool) {
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		return o.Spec.NodeName, true
+	case *unstructured.Unstructured:
+		node, found, _ := unstructured.NestedString(o.Object, "spec", "nodeName")
+		return node, found
+	}
+	return "", false
+}
+
+// objectStatus returns the status of an object as kubectl get shows it, as
+// far as it can be derived from the object alone
+func objectStatus(obj runtime.Object) string {
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		return podStatus(o)
+	case *corev1.Node:
+		status := "Unknown"
+		for _, condition := range o.Status.Conditions {
+			if condition.Type == corev1.NodeReady {
+				if condition.Status == corev1.ConditionTrue {
+					status = "Ready"
+				} else {
+					status = "NotReady"
+				}
+			}
+		}
+		if o.Spec.Unschedulable {
+			status += ",SchedulingDisabled"
+		}
+		return status
+	case *corev1.Namespace:
+		return string(o.Status.Phase)
+	case *corev1.PersistentVolume:
+		return string(o.Status.Phase)
+	case *corev1.PersistentVolumeClaim:
+		return string(o.Status.Phase)
+	case *appsv1.Deployment:
+		return replicaStatus(o.Spec.Replicas, o.Status.ReadyReplicas)
+	case *appsv1.StatefulSet:
+		return replicaStatus(o.Spec.Replicas, o.Status.ReadyReplicas)
+	case *appsv1.ReplicaSet:
+		return replicaStatus(o.Spec.Replicas, o.Status.ReadyReplicas)
+	case *appsv1.DaemonSet:
+		return replicaStatus(&o.Status.DesiredNumberScheduled, o.Status.NumberReady)
+	case *batchv1.Job:
+		for _, condition := range o.Status.Conditions {
+			if condition.Status == corev1.ConditionTrue && (condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed) {
+				return string(condition.Type)
+			}
+		}
+		return "Running"
+	case *batchv1.CronJob:
+		if o.Spec.Suspend != nil && *o.Spec.Suspend {
+			return "Suspended"
+		}
+		return "Active"
+	}
+	return unstructuredStatus(obj)
+}
+
+// replicaStatus is Ready when all desired replicas are ready
+func replicaStatus(desired *int32, ready int32) string {
+	want := int32(1)
+	if desired != nil {
+		want = *desired
+	}
+	if ready >= want {
+		return "Ready"
+	}
+	return "NotReady"
+}
+
+// podStatus follows the STATUS column of kubectl get pods
+func podStatus(pod *corev1.Pod) string {
+	if pod.DeletionTimestamp != nil {
+		return "Terminating"
+	}
+	status := string(pod.Status.Phase)
+	if pod.Status.Reason != "" {
+		status = pod.Status.Reason
+	}
+	for i, container := range pod.Status.InitContainerStatuses {
+		state := container.State
+		switch {
+		case state.Terminated != nil && state.Terminated.ExitCode == 0:
+			continue
+		case state.Terminated != nil && state.Terminated.Reason != "":
+			return "Init:" + state.Terminated.Reason
+		case state.Terminated != nil:
+			return "Init:Error"
+		case state.Waiting != nil && state.Waiting.Reason != "" && state.Waiting.Reason != "PodInitializing":
+			return "Init:" + state.Waiting.Reason
+		}
+		return fmt.Sprintf("Init:%d/%d", i, len(pod.Spec.InitContainers))
+	}
+	for i := len(pod.Status.ContainerStatuses) - 1; i >= 0; i-- {
+		state := pod.Status.ContainerStatuses[i].State
+		if state.Waiting != nil && state.Waiting.Reason != "" {
+			return state.Waiting.Reason
+		}
+		if state.Terminated != nil && state.Terminated.Reason != "" {
+			return state.Terminated.Reason
+		}
+	}
+	return status
+}
+
+// unstructuredStatus reads status.phase, or the Ready condition, of any object
+func unstructuredStatus(obj runtime.Object) string {
+	var content map[string]interface{}
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		content = u.Object
+	} else {
+		var err error
+		if content, err = runtime.DefaultUnstructuredConverter.ToUnstructured(obj); err != nil {
+			return ""
+		}
+	}
+	if phase, found, _ := unstructured.NestedString(content, "status", "phase"); found {
+		return phase
+	}
+	conditions, _, _ := unstructured.NestedSlice(content, "status", "conditions")
+	for _, condition := range conditions {
+		condition, ok := condition.(map[string]interface{})
+		if !ok || condition["type"] != "Ready" {
+			continue
+		}
+		switch condition["status"] {
+		case "True":
+			return "Ready"
+		case "False":
+			return "NotReady"
+		}
+		return "Unknown"
+	}
+	return ""
+}
+
+// listOptions builds the list options from the namespace route parameter and
+// the limit, continue, labelSelector and fieldSelector query parameters. It
+// writes the error response and returns false when a parameter is invalid.
+func listOptions(c *gin.Context, namespaced bool) ([]client.ListOption, bool) {
+	var listOpts []client.ListOption
+	if namespaced {
+		namespace := c.Param("namespace")
+		if namespace != "" && namespace != "_all" {
+			listOpts = append(listOpts, client.InNamespace(namespace))
+		}
+	}
+	if c.Query("limit") != "" {
+		limit, err := strconv.ParseInt(c.Query("limit"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+			return nil, false
+		}
+		listOpts = append(listOpts, client.Limit(limit))
+	}
+
+	if c.Query("continue") != "" {
+		continueToken := c.Query("continue")
+		listOpts = append(listOpts, client.Continue(continueToken))
+	}
+
+	// Add label selector support
+	if c.Query("labelSelector") != "" {
+		labelSelector := c.Query("labelSelector")
+		selector, err := metav1.ParseToLabelSelector(labelSelector)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid labelSelector parameter: " + err.Error()})
+			return nil, false
+		}
+		labelSelectorOption, err := metav1.LabelSelectorAsSelector(selector)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to convert labelSelector: " + err.Error()})
+			return nil, false
+		}
+		listOpts = append(listOpts, client.MatchingLabelsSelector{Selector: labelSelectorOption})
+	}
+
+	if c.Query("fieldSelector") != "" {
+		fieldSelector := c.Query("fieldSelector")
+		fieldSelectorOption, err := fields.ParseSelector(fieldSelector)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fieldSelector parameter: " + err.Error()})
+			return nil, false
+		}
+		listOpts = append(listOpts, client.MatchingFieldsSelector{Selector: fieldSelectorOption})
+	}
+	return listOpts, true
+}
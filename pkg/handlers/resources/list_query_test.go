@@ -0,0 +1,240 @@
+package resources
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// testPod builds a pod created minutesAgo minutes ago
+func testPod(namespace, name, node string, phase corev1.PodPhase, minutesAgo int, owner string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         namespace,
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Duration(minutesAgo) * time.Minute)),
+		},
+		Spec:   corev1.PodSpec{NodeName: node},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+	if owner != "" {
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: owner}}
+	}
+	return pod
+}
+
+func testPods() []runtime.Object {
+	return []runtime.Object{
+		testPod("default", "web-1", "node-b", corev1.PodRunning, 30, "web-rs"),
+		testPod("default", "web-2", "node-a", corev1.PodPending, 10, "web-rs"),
+		testPod("kube-system", "dns-1", "node-a", corev1.PodRunning, 60, "dns-rs"),
+		testPod("default", "job-1", "node-c", corev1.PodSucceeded, 20, ""),
+		testPod("other", "web-1", "node-c", corev1.PodFailed, 40, ""),
+	}
+}
+
+func podKeys(items []runtime.Object) []string {
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		pod := item.(*corev1.Pod)
+		keys = append(keys, pod.Namespace+"/"+pod.Name)
+	}
+	return keys
+}
+
+// testQuery parses the list query of a request with the given query string
+func testQuery(t *testing.T, rawQuery string) (*listQuery, error) {
+	t.Helper()
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/?"+rawQuery, nil)
+	return parseListQuery(c)
+}
+
+func TestParseListQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		query   string
+		want    listQuery
+		wantErr bool
+	}{
+		{query: "", want: listQuery{sortBy: sortByCreationTimestamp, descending: true}},
+		{query: "name=WEB&node=node-a", want: listQuery{name: "web", node: "node-a", sortBy: sortByCreationTimestamp, descending: true}},
+		{query: "status=Running,+Pending,", want: listQuery{statuses: []string{"Running", "Pending"}, sortBy: sortByCreationTimestamp, descending: true}},
+		{query: "owner=ReplicaSet/web-rs", want: listQuery{ownerKind: "ReplicaSet", ownerName: "web-rs", sortBy: sortByCreationTimestamp, descending: true}},
+		{query: "owner=web-rs", want: listQuery{ownerName: "web-rs", sortBy: sortByCreationTimestamp, descending: true}},
+		{query: "sortBy=name", want: listQuery{sortBy: sortByName}},
+		{query: "sortBy=status&sortOrder=desc", want: listQuery{sortBy: sortByStatus, descending: true}},
+		{query: "sortOrder=asc", want: listQuery{sortBy: sortByCreationTimestamp}},
+		{query: "page=2", want: listQuery{sortBy: sortByCreationTimestamp, descending: true, page: 2, pageSize: defaultPageSize}},
+		{query: "pageSize=10", want: listQuery{sortBy: sortByCreationTimestamp, descending: true, page: 1, pageSize: 10}},
+		{query: "sortBy=size", wantErr: true},
+		{query: "sortOrder=up", wantErr: true},
+		{query: "nameRegex=(", wantErr: true},
+		{query: "page=0", wantErr: true},
+		{query: "pageSize=abc", wantErr: true},
+		{query: "page=1&limit=10", wantErr: true},
+		{query: "pageSize=10&continue=token", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := testQuery(t, tt.query)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseListQuery(%q) should fail", tt.query)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseListQuery(%q) failed: %v", tt.query, err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("parseListQuery(%q) = %+v, want %+v", tt.query, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListQueryFilterAndSort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// Newest first by default
+		{query: "", want: []string{"default/web-2", "default/job-1", "default/web-1", "other/web-1", "kube-system/dns-1"}},
+		{query: "sortOrder=asc", want: []string{"kube-system/dns-1", "other/web-1", "default/web-1", "default/job-1", "default/web-2"}},
+		// Ties are in name then namespace order
+		{query: "sortBy=name", want: []string{"kube-system/dns-1", "default/job-1", "default/web-1", "other/web-1", "default/web-2"}},
+		{query: "sortBy=name&sortOrder=desc", want: []string{"default/web-2", "default/web-1", "other/web-1", "default/job-1", "kube-system/dns-1"}},
+		{query: "sortBy=namespace", want: []string{"default/job-1", "default/web-1", "default/web-2", "kube-system/dns-1", "other/web-1"}},
+		{query: "sortBy=status", want: []string{"other/web-1", "default/web-2", "kube-system/dns-1", "default/web-1", "default/job-1"}},
+		{query: "sortBy=node", want: []string{"kube-system/dns-1", "default/web-2", "default/web-1", "default/job-1", "other/web-1"}},
+		{query: "name=WEB&sortBy=name", want: []string{"default/web-1", "other/web-1", "default/web-2"}},
+		{query: "nameRegex=^web-[0-9]$&sortBy=name", want: []string{"default/web-1", "other/web-1", "default/web-2"}},
+		{query: "status=running,pending&sortBy=name", want: []string{"kube-system/dns-1", "default/web-1", "default/web-2"}},
+		{query: "owner=web-rs&sortBy=name", want: []string{"default/web-1", "default/web-2"}},
+		{query: "owner=Deployment/web-rs", want: []string{}},
+		{query: "node=node-a&sortBy=name", want: []string{"kube-system/dns-1", "default/web-2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := testQuery(t, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			items, err := q.filter(testPods())
+			if err != nil {
+				t.Fatal(err)
+			}
+			q.sort(items)
+			if got := podKeys(items); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%q = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListQueryNodeFilterRequiresNode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	q, err := testQuery(t, "node=node-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.filter([]runtime.Object{&corev1.ConfigMap{}}); err == nil {
+		t.Error("filtering config maps by node should fail")
+	}
+}
+
+func TestListQueryRespond(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	remaining := int64(7)
+	tests := []struct {
+		query          string
+		remaining      *int64
+		wantItems      []string
+		wantTotal      *int
+		wantPage       int
+		wantTotalPages int
+	}{
+		{query: "sortBy=name", wantItems: []string{"kube-system/dns-1", "default/job-1", "default/web-1", "other/web-1", "default/web-2"}, wantTotal: intPtr(5)},
+		{query: "sortBy=name&page=1&pageSize=2", wantItems: []string{"kube-system/dns-1", "default/job-1"}, wantTotal: intPtr(5), wantPage: 1, wantTotalPages: 3},
+		{query: "sortBy=name&page=3&pageSize=2", wantItems: []string{"default/web-2"}, wantTotal: intPtr(5), wantPage: 3, wantTotalPages: 3},
+		{query: "sortBy=name&page=4&pageSize=2", wantItems: []string{}, wantTotal: intPtr(5), wantPage: 4, wantTotalPages: 3},
+		{query: "name=web&sortBy=name&pageSize=2", wantItems: []string{"default/web-1", "other/web-1"}, wantTotal: intPtr(3), wantPage: 1, wantTotalPages: 2},
+		// The first limited page counts what the API server has left
+		{query: "limit=5&sortBy=name", remaining: &remaining, wantItems: []string{"kube-system/dns-1", "default/job-1", "default/web-1", "other/web-1", "default/web-2"}, wantTotal: intPtr(12)},
+		{query: "limit=5&sortBy=name", wantItems: []string{"kube-system/dns-1", "default/job-1", "default/web-1", "other/web-1", "default/web-2"}},
+		{query: "limit=5&continue=token&sortBy=name", remaining: &remaining, wantItems: []string{"kube-system/dns-1", "default/job-1", "default/web-1", "other/web-1", "default/web-2"}},
+		{query: "limit=5&name=web&sortBy=name", remaining: &remaining, wantItems: []string{"default/web-1", "other/web-1", "default/web-2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			q, err := parseListQuery(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			list := &corev1.PodList{ListMeta: metav1.ListMeta{RemainingItemCount: tt.remaining}}
+			for _, item := range testPods() {
+				list.Items = append(list.Items, *item.(*corev1.Pod))
+			}
+			q.respond(c, list)
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", recorder.Code, recorder.Body.String())
+			}
+
+			var response struct {
+				Items      []corev1.Pod `json:"items"`
+				Total      *int         `json:"total"`
+				Page       int          `json:"page"`
+				TotalPages int          `json:"totalPages"`
+			}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			items := make([]runtime.Object, 0, len(response.Items))
+			for i := range response.Items {
+				items = append(items, &response.Items[i])
+			}
+			if got := podKeys(items); !reflect.DeepEqual(got, tt.wantItems) {
+				t.Errorf("items = %v, want %v", got, tt.wantItems)
+			}
+			if !reflect.DeepEqual(response.Total, tt.wantTotal) {
+				t.Errorf("total = %v, want %v", valueOf(response.Total), valueOf(tt.wantTotal))
+			}
+			if response.Page != tt.wantPage || response.TotalPages != tt.wantTotalPages {
+				t.Errorf("page = %d of %d, want %d of %d", response.Page, response.TotalPages, tt.wantPage, tt.wantTotalPages)
+			}
+		})
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+// valueOf prints an optional total
+func valueOf(i *int) interface{} {
+	if i == nil {
+		return nil
+	}
+	return *i
+}
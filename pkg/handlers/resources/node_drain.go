@@ -0,0 +1,521 @@
+package resources
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ysicing/nexus/pkg/common"
+	"github.com/ysicing/nexus/pkg/kube"
+	"golang.org/x/net/websocket"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// defaultDrainTimeout bounds a drain when the request sets no timeout
+	defaultDrainTimeout = 10 * time.Minute
+	// podDeletionPollInterval is how often an evicted pod is checked for deletion
+	podDeletionPollInterval = time.Second
+	// drainEvictionWorkers bounds how many pods of one node are evicted at once
+	drainEvictionWorkers = 10
+)
+
+// drainJobRetention is how long a finished drain job stays available to
+// status and follow requests before it is forgotten
+var drainJobRetention = time.Hour
+
+// evictionRetryInterval is how long to wait before retrying an eviction
+// refused by a PodDisruptionBudget
+var evictionRetryInterval = 5 * time.Second
+
+// Drain job states
+const (
+	DrainRunning   = "running"
+	DrainSucceeded = "succeeded"
+	DrainFailed    = "failed"
+	DrainCancelled = "cancelled"
+)
+
+// DrainOptions control how a node is drained, like the kubectl drain flags
+type DrainOptions struct {
+	// Force also deletes pods that are not managed by a controller
+	Force bool `json:"force"`
+	// GracePeriod overrides the termination grace period of evicted pods in
+	// seconds; negative or unset uses the grace period of each pod
+	GracePeriod *int64 `json:"gracePeriod,omitempty"`
+	// DeleteLocalData also evicts pods using emptyDir volumes, whose data is lost
+	DeleteLocalData bool `json:"deleteLocalData"`
+	// IgnoreDaemonsets skips DaemonSet-managed pods instead of failing
+	IgnoreDaemonsets bool `json:"ignoreDaemonsets"`
+	// TimeoutSeconds bounds the whole drain; 0 uses the default of 10 minutes
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty" binding:"min=0"`
+}
+
+// DrainEvent is one progress report of a drain job
+type DrainEvent struct {
+	Time time.Time `json:"time"`
+	// Type is one of cordoned, skipped, evicting, retrying, evicted, deleted, failed or finished
+	Type    string `json:"type"`
+	Pod     string `json:"pod,omitempty"`
+	Message string `json:"message"`
+}
+
+// DrainJob describes a drain running in the background or its outcome
+type DrainJob struct {
+	ID   string `json:"id"`
+	Node string `json:"node"`
+	// Replica is the Nexus replica running the job, see drainJobs
+	Replica    string       `json:"replica"`
+	Options    DrainOptions `json:"options"`
+	Status     string       `json:"status"`
+	Error      string       `json:"error,omitempty"`
+	StartedAt  time.Time    `json:"startedAt"`
+	FinishedAt *time.Time   `json:"finishedAt,omitempty"`
+	Events     []DrainEvent `json:"events"`
+}
+
+// drainJob is a DrainJob shared between the goroutine running it and the
+// requests following its progress
+type drainJob struct {
+	mu     sync.Mutex
+	job    DrainJob
+	cancel context.CancelFunc
+	// updated is closed and replaced whenever an event is added
+	updated chan struct{}
+}
+
+func (j *drainJob) record(eventType, pod, message string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.addEvent(eventType, pod, message)
+}
+
+// addEvent appends an event and wakes up followers; j.mu must be held
+func (j *drainJob) addEvent(eventType, pod, message string) {
+	j.job.Events = append(j.job.Events, DrainEvent{Time: time.Now(), Type: eventType, Pod: pod, Message: message})
+	close(j.updated)
+	j.updated = make(chan struct{})
+}
+
+func (j *drainJob) finish(status string, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	j.job.Status = status
+	j.job.FinishedAt = &now
+	message := "Node " + j.job.Node + " drained"
+	if err != nil {
+		j.job.Error = err.Error()
+		message = err.Error()
+	}
+	j.addEvent("finished", "", message)
+}
+
+func (j *drainJob) snapshot() DrainJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job := j.job
+	job.Events = append(make([]DrainEvent, 0, len(j.job.Events)), j.job.Events...)
+	return job
+}
+
+func (j *drainJob) running() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.job.Status == DrainRunning
+}
+
+// since returns the events after the first n, whether the job has finished
+// and a channel that is closed when more events arrive
+func (j *drainJob) since(n int) ([]DrainEvent, bool, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	events := append([]DrainEvent(nil), j.job.Events[n:]...)
+	return events, j.job.Status != DrainRunning, j.updated
+}
+
+// drainJobs keeps the latest drain job of each node. Jobs live in memory of
+// the replica that started them and are removed drainJobRetention after they
+// finish. With several replicas, requests for a drain must reach the replica
+// running it; responses carry the replica in the X-Nexus-Replica header so a
+// load balancer can pin them (see the multi-replica deployment docs).
+type drainJobs struct {
+	mu   sync.Mutex
+	jobs map[string]*drainJob
+}
+
+func newDrainJobs() *drainJobs {
+	return &drainJobs{jobs: make(map[string]*drainJob)}
+}
+
+// replicaHeader names the replica that handled a drain request
+const replicaHeader = "X-Nexus-Replica"
+
+// drainJobKey identifies a node across clusters
+func drainJobKey(c *gin.Context, node string) string {
+	return c.GetString("clusterID") + "/" + node
+}
+
+// start runs a new drain job unless one is already running for the node
+func (s *drainJobs) start(key, node string, options DrainOptions, k8sClient *kube.K8sClient) (*drainJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, exists := s.jobs[key]; exists && existing.running() {
+		return existing, false
+	}
+
+	timeout := defaultDrainTimeout
+	if options.TimeoutSeconds > 0 {
+		timeout = time.Duration(options.TimeoutSeconds) * time.Second
+	}
+	// The job must outlive the request that started it
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	job := &drainJob{
+		job: DrainJob{
+			ID:        fmt.Sprintf("%s-%d", node, time.Now().UnixMilli()),
+			Node:      node,
+			Replica:   common.ReplicaID,
+			Options:   options,
+			Status:    DrainRunning,
+			StartedAt: time.Now(),
+			Events:    []DrainEvent{},
+		},
+		cancel:  cancel,
+		updated: make(chan struct{}),
+	}
+	s.jobs[key] = job
+
+	go func() {
+		defer cancel()
+		err := drainNode(ctx, k8sClient, node, options, job.record)
+		switch {
+		case err == nil:
+			job.finish(DrainSucceeded, nil)
+		case stderrors.Is(ctx.Err(), context.Canceled):
+			job.finish(DrainCancelled, fmt.Errorf("drain cancelled"))
+		case stderrors.Is(ctx.Err(), context.DeadlineExceeded):
+			job.finish(DrainFailed, fmt.Errorf("drain timed out after %s: %w", timeout, err))
+		default:
+			job.finish(DrainFailed, err)
+		}
+		klog.Infof("drain of node %s finished: %s", node, job.snapshot().Status)
+		time.AfterFunc(drainJobRetention, func() { s.remove(key, job) })
+	}()
+	return job, true
+}
+
+// remove forgets the job unless a newer job of the node replaced it
+func (s *drainJobs) remove(key string, job *drainJob) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.jobs[key] == job {
+		delete(s.jobs, key)
+	}
+}
+
+func (s *drainJobs) get(key string) *drainJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jobs[key]
+}
+
+// drainNode cordons the node and evicts its pods, reporting progress through record
+func drainNode(ctx context.Context, k8sClient *kube.K8sClient, nodeName string, options DrainOptions, record func(eventType, pod, message string)) error {
+	var node corev1.Node
+	if err := k8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
+		return err
+	}
+	if !node.Spec.Unschedulable {
+		patch := client.MergeFrom(node.DeepCopy())
+		node.Spec.Unschedulable = true
+		if err := k8sClient.Client.Patch(ctx, &node, patch, client.FieldOwner(kube.FieldManager)); err != nil {
+			return fmt.Errorf("failed to cordon node: %w", err)
+		}
+	}
+	record("cordoned", "", "Node "+nodeName+" cordoned")
+
+	var pods corev1.PodList
+	if err := k8sClient.Client.List(ctx, &pods, client.MatchingFields{"spec.nodeName": nodeName}); err != nil {
+		return fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	// Check every pod before evicting any, like kubectl drain
+	var evict []corev1.Pod
+	var refused []string
+	for _, pod := range pods.Items {
+		skip, reason, err := drainFilter(&pod, options)
+		switch {
+		case err != nil:
+			refused = append(refused, fmt.Sprintf("%s/%s: %v", pod.Namespace, pod.Name, err))
+		case skip:
+			if reason != "" {
+				record("skipped", podKey(&pod), reason)
+			}
+		default:
+			evict = append(evict, pod)
+		}
+	}
+	if len(refused) > 0 {
+		return fmt.Errorf("cannot drain node %s: %s", nodeName, strings.Join(refused, "; "))
+	}
+
+	// A fixed number of workers evicts the pods instead of one goroutine per pod
+	queue := make(chan *corev1.Pod)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed []string
+	for range min(drainEvictionWorkers, len(evict)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pod := range queue {
+				if err := evictPod(ctx, k8sClient, pod, options, record); err != nil {
+					record("failed", podKey(pod), err.Error())
+					mu.Lock()
+					failed = append(failed, podKey(pod))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range evict {
+		queue <- &evict[i]
+	}
+	close(queue)
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to evict %d pods: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// drainFilter decides what happens to a pod on a drained node. Pods that
+// need an option the request did not set are refused with an error.
+func drainFilter(pod *corev1.Pod, options DrainOptions) (skip bool, reason string, err error) {
+	if _, mirror := pod.Annotations[corev1.MirrorPodAnnotationKey]; mirror {
+		return true, "", nil
+	}
+	// Finished pods can always be removed
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false, "", nil
+	}
+
+	controller := metav1.GetControllerOf(pod)
+	if controller != nil && controller.Kind == "DaemonSet" {
+		if !options.IgnoreDaemonsets {
+			return false, "", fmt.Errorf("DaemonSet-managed pod, set ignoreDaemonsets to skip it")
+		}
+		return true, "Ignoring DaemonSet-managed pod", nil
+	}
+	if controller == nil && !options.Force {
+		return false, "", fmt.Errorf("pod is not managed by a controller, set force to delete it")
+	}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.EmptyDir != nil && !options.DeleteLocalData {
+			return false, "", fmt.Errorf("pod uses emptyDir volume %s, set deleteLocalData to delete it", volume.Name)
+		}
+	}
+	return false, "", nil
+}
+
+// evictPod evicts a pod through the Eviction API, retrying while a
+// PodDisruptionBudget refuses it, and waits until the pod is gone
+func evictPod(ctx context.Context, k8sClient *kube.K8sClient, pod *corev1.Pod, options DrainOptions, record func(eventType, pod, message string)) error {
+	key := podKey(pod)
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+	}
+	if options.GracePeriod != nil && *options.GracePeriod >= 0 {
+		eviction.DeleteOptions = &metav1.DeleteOptions{GracePeriodSeconds: options.GracePeriod}
+	}
+
+	record("evicting", key, "Evicting pod")
+	for {
+		err := k8sClient.Client.SubResource("eviction").Create(ctx, pod, eviction)
+		if err == nil {
+			break
+		}
+		if errors.IsNotFound(err) {
+			record("deleted", key, "Pod already deleted")
+			return nil
+		}
+		if !errors.IsTooManyRequests(err) {
+			return fmt.Errorf("eviction failed: %w", err)
+		}
+		// The eviction would violate a PodDisruptionBudget
+		record("retrying", key, fmt.Sprintf("Eviction refused, retrying in %s: %v", evictionRetryInterval, err))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(evictionRetryInterval):
+		}
+	}
+	record("evicted", key, "Pod evicted, waiting for deletion")
+
+	for {
+		var current corev1.Pod
+		err := k8sClient.Client.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, &current)
+		if errors.IsNotFound(err) || (err == nil && current.UID != pod.UID) {
+			record("deleted", key, "Pod deleted")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(podDeletionPollInterval):
+		}
+	}
+}
+
+func podKey(pod *corev1.Pod) string {
+	return pod.Namespace + "/" + pod.Name
+}
+
+// DrainNode starts draining a node in the background. The drain keeps
+// running after the request ends; its progress is available from GetDrain,
+// StreamDrain and DrainWebSocket.
+func (h *NodeHandler) DrainNode(c *gin.Context) {
+	nodeName := c.Param("name")
+	c.Header(replicaHeader, common.ReplicaID)
+
+	var options DrainOptions
+	if err := c.ShouldBindJSON(&options); err != nil && !stderrors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	k8sClient := h.getClient(c)
+	if k8sClient == nil || k8sClient.Client == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "No cluster available. Please add a cluster first."})
+		return
+	}
+
+	// Get the node first to ensure it exists
+	var node corev1.Node
+	if err := k8sClient.Client.Get(c.Request.Context(), types.NamespacedName{Name: nodeName}, &node); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	job, started := h.drains.start(drainJobKey(c, nodeName), nodeName, options, k8sClient)
+	if !started {
+		c.JSON(http.StatusConflict, gin.H{"error": "Node " + nodeName + " is already being drained", "job": job.snapshot()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": fmt.Sprintf("Node %s drain started", nodeName),
+		"job":     job.snapshot(),
+	})
+}
+
+// drainJob returns the latest drain job of the node, writing 404 when there is none
+func (h *NodeHandler) drainJob(c *gin.Context) (*drainJob, bool) {
+	c.Header(replicaHeader, common.ReplicaID)
+	job := h.drains.get(drainJobKey(c, c.Param("name")))
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No drain job found for node " + c.Param("name") + " on replica " + common.ReplicaID})
+		return nil, false
+	}
+	return job, true
+}
+
+// GetDrain returns the latest drain job of a node
+func (h *NodeHandler) GetDrain(c *gin.Context) {
+	job, ok := h.drainJob(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, job.snapshot())
+}
+
+// CancelDrain stops a running drain. The node stays cordoned.
+func (h *NodeHandler) CancelDrain(c *gin.Context) {
+	job, ok := h.drainJob(c)
+	if !ok {
+		return
+	}
+	if !job.running() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Drain job is not running"})
+		return
+	}
+	job.cancel()
+	c.JSON(http.StatusOK, gin.H{"message": "Drain of node " + c.Param("name") + " cancelled"})
+}
+
+// followDrain sends every event of the job, starting with those already
+// recorded, and the final job once it finishes. It stops early when send fails.
+func followDrain(ctx context.Context, job *drainJob, send func(event string, data interface{}) bool) {
+	sent := 0
+	for {
+		events, finished, updated := job.since(sent)
+		for _, event := range events {
+			if !send("progress", event) {
+				return
+			}
+		}
+		sent += len(events)
+		if finished {
+			send("close", job.snapshot())
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-updated:
+		}
+	}
+}
+
+// StreamDrain streams the progress of the latest drain job of a node as server-sent events
+func (h *NodeHandler) StreamDrain(c *gin.Context) {
+	job, ok := h.drainJob(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+
+	followDrain(c.Request.Context(), job, func(event string, data interface{}) bool {
+		return writeSSE(c, event, data)
+	})
+}
+
+// DrainWebSocket streams the progress of the latest drain job of a node over
+// a WebSocket as {"type": "progress" | "close", "data": ...} messages
+func (h *NodeHandler) DrainWebSocket(c *gin.Context) {
+	job, ok := h.drainJob(c)
+	if !ok {
+		return
+	}
+
+	websocket.Handler(func(ws *websocket.Conn) {
+		defer ws.Close()
+		followDrain(c.Request.Context(), job, func(event string, data interface{}) bool {
+			return websocket.JSON.Send(ws, gin.H{"type": event, "data": data}) == nil
+		})
+	}).ServeHTTP(c.Writer, c.Request)
+}
@@ -14,6 +14,8 @@ import (
 
 type NodeHandler struct {
 	*GenericResourceHandler[*corev1.Node, *corev1.NodeList]
+
+	drains *drainJobs
 }
 
 func NewNodeHandler(client *kube.K8sClient) *NodeHandler {
@@ -24,53 +26,10 @@ func NewNodeHandler(client *kube.K8sClient) *NodeHandler {
 			true, // Nodes are cluster-scoped resources
 			true,
 		),
+		drains: newDrainJobs(),
 	}
 }
 
-// DrainNode drains a node by evicting all pods
-func (h *NodeHandler) DrainNode(c *gin.Context) {
-	nodeName := c.Param("name")
-	ctx := c.Request.Context()
-
-	// Parse the request body for drain options
-	var drainRequest struct {
-		Force            bool `json:"force" binding:"required"`
-		GracePeriod      int  `json:"gracePeriod" binding:"min=0"`
-		DeleteLocal      bool `json:"deleteLocalData"`
-		IgnoreDaemonsets bool `json:"ignoreDaemonsets"`
-	}
-
-	if err := c.ShouldBindJSON(&drainRequest); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
-		return
-	}
-
-	// Get the node first to ensure it exists
-	var node corev1.Node
-	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
-		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	// TODO: Implement actual drain logic
-	// For now, we'll simulate the drain operation
-	// In a real implementation, you would:
-	// 1. Mark the node as unschedulable (cordon)
-	// 2. Evict all pods from the node
-	// 3. Handle daemonsets appropriately
-	// 4. Wait for pods to be evicted or force delete them
-
-	c.JSON(http.StatusOK, gin.H{
-		"message": fmt.Sprintf("Node %s drain initiated", nodeName),
-		"node":    node.Name,
-		"options": drainRequest,
-	})
-}
-
 func (h *NodeHandler) markNodeSchedulable(ctx context.Context, nodeName string, schedulable bool) error {
 	// Get the current node
 	var node corev1.Node
@@ -241,6 +200,10 @@ func (h *NodeHandler) UntaintNode(c *gin.Context) {
 
 func (h *NodeHandler) registerCustomRoutes(group *gin.RouterGroup) {
 	group.POST("/_all/:name/drain", h.DrainNode)
+	group.GET("/_all/:name/drain", h.GetDrain)
+	group.DELETE("/_all/:name/drain", h.CancelDrain)
+	group.GET("/_all/:name/drain/events", h.StreamDrain)
+	group.GET("/_all/:name/drain/ws", h.DrainWebSocket)
 	group.POST("/_all/:name/cordon", h.CordonNode)
 	group.POST("/_all/:name/uncordon", h.UncordonNode)
 	group.POST("/_all/:name/taint", h.TaintNode)
@@ -0,0 +1,69 @@
+package resources
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// patchTypes are the patch formats accepted by PATCH routes, selected by the
+// request content type
+var patchTypes = map[string]types.PatchType{
+	string(types.JSONPatchType):           types.JSONPatchType,
+	string(types.MergePatchType):          types.MergePatchType,
+	string(types.StrategicMergePatchType): types.StrategicMergePatchType,
+}
+
+// readPatch reads the request body as a patch of the type given by the content
+// type. Strategic merge patch is only valid for types compiled into the API
+// server, so it is rejected when allowStrategic is false. It writes the error
+// response and returns false when the request is invalid.
+func readPatch(c *gin.Context, allowStrategic bool) (client.Patch, bool) {
+	patchType, ok := patchTypes[c.ContentType()]
+	if !ok {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error": "Content-Type must be application/json-patch+json, application/merge-patch+json or application/strategic-merge-patch+json",
+		})
+		return nil, false
+	}
+	if patchType == types.StrategicMergePatchType && !allowStrategic {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "strategic merge patch is not supported for custom resources, use a JSON merge patch instead"})
+		return nil, false
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if !json.Valid(data) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "patch body must be valid JSON"})
+		return nil, false
+	}
+	if patchType == types.JSONPatchType {
+		var operations []map[string]interface{}
+		if err := json.Unmarshal(data, &operations); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "JSON patch must be an array of operations"})
+			return nil, false
+		}
+	}
+	return client.RawPatch(patchType, data), true
+}
+
+// ErrorStatus returns the HTTP status reported by the API server, so that
+// conflicts and invalid patches are not reported as internal errors
+func ErrorStatus(err error) int {
+	var status errors.APIStatus
+	if stderrors.As(err, &status) {
+		if code := int(status.Status().Code); code != 0 {
+			return code
+		}
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,115 @@
+package resources
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestReadPatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		contentType    string
+		body           string
+		allowStrategic bool
+		wantStatus     int
+		wantType       types.PatchType
+	}{
+		{
+			name:        "merge patch",
+			contentType: "application/merge-patch+json",
+			body:        `{"metadata":{"labels":{"app":"web"}}}`,
+			wantType:    types.MergePatchType,
+		},
+		{
+			name:        "merge patch with charset",
+			contentType: "application/merge-patch+json; charset=utf-8",
+			body:        `{"spec":{"replicas":2}}`,
+			wantType:    types.MergePatchType,
+		},
+		{
+			name:        "JSON patch",
+			contentType: "application/json-patch+json",
+			body:        `[{"op":"replace","path":"/spec/replicas","value":2}]`,
+			wantType:    types.JSONPatchType,
+		},
+		{
+			name:           "strategic merge patch for built-in types",
+			contentType:    "application/strategic-merge-patch+json",
+			body:           `{"spec":{"template":{"spec":{"containers":[{"name":"web","image":"nginx:1.27"}]}}}}`,
+			allowStrategic: true,
+			wantType:       types.StrategicMergePatchType,
+		},
+		{
+			name:        "strategic merge patch for custom resources",
+			contentType: "application/strategic-merge-patch+json",
+			body:        `{"spec":{}}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "plain JSON",
+			contentType: "application/json",
+			body:        `{"spec":{}}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "missing content type",
+			body:        `{"spec":{}}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "invalid JSON",
+			contentType: "application/merge-patch+json",
+			body:        `{"spec":`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "JSON patch that is not an array",
+			contentType: "application/json-patch+json",
+			body:        `{"op":"remove","path":"/spec"}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				c.Request.Header.Set("Content-Type", tt.contentType)
+			}
+
+			patch, ok := readPatch(c, tt.allowStrategic)
+			if tt.wantStatus != 0 {
+				if ok {
+					t.Fatalf("readPatch accepted the request, want status %d", tt.wantStatus)
+				}
+				if recorder.Code != tt.wantStatus {
+					t.Errorf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body.String())
+				}
+				return
+			}
+
+			if !ok {
+				t.Fatalf("readPatch rejected the request: %d %s", recorder.Code, recorder.Body.String())
+			}
+			if patch.Type() != tt.wantType {
+				t.Errorf("patch type = %s, want %s", patch.Type(), tt.wantType)
+			}
+			data, err := patch.Data(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.body {
+				t.Errorf("patch data = %s, want %s", data, tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,680 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ysicing/nexus/pkg/kube"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+const (
+	defaultGraphDepth = 2
+	maxGraphDepth     = 5
+	// maxGraphNodes bounds the graph of objects with many relations, such as
+	// a StorageClass or the default ServiceAccount
+	maxGraphNodes = 500
+)
+
+// Relations between objects. An edge points from the object that controls,
+// selects or references another to that object.
+const (
+	RelationOwns           = "owns"           // ownerReferences, owner to owned
+	RelationEndpoints      = "endpoints"      // Service to EndpointSlice
+	RelationTargets        = "targets"        // EndpointSlice to Pod
+	RelationRoutes         = "routes"         // Ingress to Service
+	RelationMounts         = "mounts"         // Pod to a volume's PVC, ConfigMap or Secret
+	RelationReferences     = "references"     // env, envFrom, imagePullSecrets and Ingress TLS
+	RelationServiceAccount = "serviceAccount" // Pod to ServiceAccount
+	RelationBinds          = "binds"          // PVC to PV
+	RelationStorageClass   = "storageClass"   // PVC or PV to StorageClass
+	RelationScales         = "scales"         // HPA to its scale target
+)
+
+// GraphNode is an object of a relationship graph
+type GraphNode struct {
+	ID         string    `json:"id"`
+	APIVersion string    `json:"apiVersion"`
+	Kind       string    `json:"kind"`
+	Namespace  string    `json:"namespace,omitempty"`
+	Name       string    `json:"name"`
+	UID        types.UID `json:"uid,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	// Missing marks an object that is referenced but does not exist
+	Missing bool `json:"missing,omitempty"`
+	// Depth is the number of relations between the node and the root
+	Depth int `json:"depth"`
+}
+
+// GraphEdge is a relation between two graph nodes
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
+}
+
+// ResourceGraph holds the objects related to a root object up to a depth
+type ResourceGraph struct {
+	Root  string       `json:"root"`
+	Depth int          `json:"depth"`
+	Nodes []*GraphNode `json:"nodes"`
+	Edges []GraphEdge  `json:"edges"`
+	// Truncated is set when the graph reached maxGraphNodes
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// resourceGraph serves the relationship graph of the object named by the
+// route, of the resource type returned by resourceName. ?depth= limits how
+// many relations are followed from the object.
+func resourceGraph(resourceName func(c *gin.Context) string, fallback *kube.K8sClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		k8sClient := requestClient(c, fallback)
+		if k8sClient == nil || k8sClient.Client == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "No cluster available. Please add a cluster first."})
+			return
+		}
+		resource, ok := lookupResource(c, k8sClient, resourceName(c))
+		if !ok {
+			return
+		}
+		if !resource.HasVerb("get") {
+			c.JSON(http.StatusMethodNotAllowed, gin.H{"error": "resource " + resource.FullName() + " does not support get"})
+			return
+		}
+
+		depth := defaultGraphDepth
+		if value := c.Query("depth"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 || parsed > maxGraphDepth {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("depth must be between 1 and %d", maxGraphDepth)})
+				return
+			}
+			depth = parsed
+		}
+		namespace := c.Param("namespace")
+		if namespace == "_all" || !resource.Namespaced {
+			namespace = ""
+		}
+		if resource.Namespaced && namespace == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "namespace is required for namespaced resources"})
+			return
+		}
+
+		graph, err := buildResourceGraph(c.Request.Context(), k8sClient.Client, resource.GroupVersionKind(), namespace, c.Param("name"), depth)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+				return
+			}
+			c.JSON(ErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, graph)
+	}
+}
+
+// objectRef is a reference from one object to another by kind and name
+type objectRef struct {
+	gvk      schema.GroupVersionKind
+	name     string
+	relation string
+}
+
+var (
+	configMapGVK      = corev1.SchemeGroupVersion.WithKind("ConfigMap")
+	secretGVK         = corev1.SchemeGroupVersion.WithKind("Secret")
+	pvcGVK            = corev1.SchemeGroupVersion.WithKind("PersistentVolumeClaim")
+	pvGVK             = corev1.SchemeGroupVersion.WithKind("PersistentVolume")
+	serviceGVK        = corev1.SchemeGroupVersion.WithKind("Service")
+	serviceAccountGVK = corev1.SchemeGroupVersion.WithKind("ServiceAccount")
+	podGVK            = corev1.SchemeGroupVersion.WithKind("Pod")
+	storageClassGVK   = storagev1.SchemeGroupVersion.WithKind("StorageClass")
+)
+
+// ownedLists are the types whose objects commonly have a controller in the
+// same namespace, searched for the objects a node owns
+var ownedLists = []func() client.ObjectList{
+	func() client.ObjectList { return &appsv1.ReplicaSetList{} },
+	func() client.ObjectList { return &corev1.PodList{} },
+	func() client.ObjectList { return &batchv1.JobList{} },
+	func() client.ObjectList { return &discoveryv1.EndpointSliceList{} },
+	func() client.ObjectList { return &corev1.PersistentVolumeClaimList{} },
+}
+
+type graphBuilder struct {
+	ctx     context.Context
+	client  client.Client
+	graph   *ResourceGraph
+	nodes   map[string]*GraphNode
+	objects map[string]client.Object
+	edges   map[GraphEdge]bool
+	lists   map[string][]client.Object
+	queue   []string
+}
+
+// buildResourceGraph walks the relations of an object breadth first up to depth
+func buildResourceGraph(ctx context.Context, c client.Client, gvk schema.GroupVersionKind, namespace, name string, depth int) (*ResourceGraph, error) {
+	b := &graphBuilder{
+		ctx:     ctx,
+		client:  c,
+		graph:   &ResourceGraph{Depth: depth, Nodes: []*GraphNode{}, Edges: []GraphEdge{}},
+		nodes:   map[string]*GraphNode{},
+		objects: map[string]client.Object{},
+		edges:   map[GraphEdge]bool{},
+		lists:   map[string][]client.Object{},
+	}
+
+	root, err := b.get(gvk, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	b.graph.Root = b.add(root, 0)
+
+	for len(b.queue) > 0 {
+		id := b.queue[0]
+		b.queue = b.queue[1:]
+		if node := b.nodes[id]; node.Depth < depth {
+			if err := b.expand(id, b.objects[id], node.Depth+1); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b.graph, nil
+}
+
+func nodeID(gvk schema.GroupVersionKind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", gvk.GroupKind().String(), namespace, name)
+}
+
+func (b *graphBuilder) gvkOf(obj client.Object) schema.GroupVersionKind {
+	if gvk, err := apiutil.GVKForObject(obj, b.client.Scheme()); err == nil {
+		return gvk
+	}
+	return obj.GetObjectKind().GroupVersionKind()
+}
+
+// add records obj as a node at depth and returns its ID, or "" when the graph is full
+func (b *graphBuilder) add(obj client.Object, depth int) string {
+	gvk := b.gvkOf(obj)
+	id := nodeID(gvk, obj.GetNamespace(), obj.GetName())
+	if _, ok := b.nodes[id]; ok {
+		return id
+	}
+	if len(b.nodes) >= maxGraphNodes {
+		b.graph.Truncated = true
+		return ""
+	}
+	node := &GraphNode{
+		ID:         id,
+		APIVersion: gvk.GroupVersion().String(),
+		Kind:       gvk.Kind,
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		UID:        obj.GetUID(),
+		Status:     objectStatus(obj),
+		Depth:      depth,
+	}
+	b.nodes[id] = node
+	b.objects[id] = obj
+	b.graph.Nodes = append(b.graph.Nodes, node)
+	b.queue = append(b.queue, id)
+	return id
+}
+
+// addMissing records a referenced object that does not exist
+func (b *graphBuilder) addMissing(gvk schema.GroupVersionKind, namespace, name string, depth int) string {
+	id := nodeID(gvk, namespace, name)
+	if _, ok := b.nodes[id]; ok {
+		return id
+	}
+	if len(b.nodes) >= maxGraphNodes {
+		b.graph.Truncated = true
+		return ""
+	}
+	node := &GraphNode{
+		ID:         id,
+		APIVersion: gvk.GroupVersion().String(),
+		Kind:       gvk.Kind,
+		Namespace:  namespace,
+		Name:       name,
+		Missing:    true,
+		Depth:      depth,
+	}
+	b.nodes[id] = node
+	b.graph.Nodes = append(b.graph.Nodes, node)
+	return id
+}
+
+func (b *graphBuilder) link(from, to, relation string) {
+	if from == "" || to == "" || from == to {
+		return
+	}
+	edge := GraphEdge{From: from, To: to, Type: relation}
+	if !b.edges[edge] {
+		b.edges[edge] = true
+		b.graph.Edges = append(b.graph.Edges, edge)
+	}
+}
+
+// newObject returns an empty object of a kind, typed when the scheme knows it
+func (b *graphBuilder) newObject(gvk schema.GroupVersionKind) client.Object {
+	if typed, err := b.client.Scheme().New(gvk); err == nil {
+		if obj, ok := typed.(client.Object); ok {
+			return obj
+		}
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	return u
+}
+
+// key builds the key of an object, without namespace for cluster-scoped kinds
+func (b *graphBuilder) key(obj client.Object, namespace, name string) (types.NamespacedName, error) {
+	namespaced, err := b.client.IsObjectNamespaced(obj)
+	if err != nil {
+		return types.NamespacedName{}, err
+	}
+	if !namespaced {
+		namespace = ""
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
+
+func (b *graphBuilder) get(gvk schema.GroupVersionKind, namespace, name string) (client.Object, error) {
+	obj := b.newObject(gvk)
+	key, err := b.key(obj, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.client.Get(b.ctx, key, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// ref adds the referenced object, or a missing node when it does not exist,
+// and returns its ID. Kinds the cluster does not serve are skipped.
+func (b *graphBuilder) ref(gvk schema.GroupVersionKind, namespace, name string, depth int) (string, error) {
+	obj, err := b.get(gvk, namespace, name)
+	switch {
+	case err == nil:
+		return b.add(obj, depth), nil
+	case errors.IsNotFound(err):
+		key, _ := b.key(b.newObject(gvk), namespace, name)
+		return b.addMissing(gvk, key.Namespace, name, depth), nil
+	case meta.IsNoMatchError(err) || discovery.IsGroupDiscoveryFailedError(err):
+		return "", nil
+	}
+	return "", err
+}
+
+// list returns the objects of a list type in namespace, or in all namespaces
+// when namespace is empty. Results are reused within the graph.
+func (b *graphBuilder) list(newList func() client.ObjectList, namespace string) ([]client.Object, error) {
+	list := newList()
+	key := fmt.Sprintf("%T/%s", list, namespace)
+	if objects, ok := b.lists[key]; ok {
+		return objects, nil
+	}
+
+	var opts []client.ListOption
+	if namespace != "" {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+	if err := b.client.List(b.ctx, list, opts...); err != nil {
+		if meta.IsNoMatchError(err) {
+			b.lists[key] = nil
+			return nil, nil
+		}
+		return nil, err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+	objects := make([]client.Object, 0, len(items))
+	for _, item := range items {
+		if obj, ok := item.(client.Object); ok {
+			objects = append(objects, obj)
+		}
+	}
+	b.lists[key] = objects
+	return objects, nil
+}
+
+// expand adds the objects related to obj at depth
+func (b *graphBuilder) expand(id string, obj client.Object, depth int) error {
+	if obj == nil {
+		return nil
+	}
+	namespace := obj.GetNamespace()
+
+	for _, owner := range obj.GetOwnerReferences() {
+		gv, err := schema.ParseGroupVersion(owner.APIVersion)
+		if err != nil {
+			continue
+		}
+		ownerID, err := b.ref(gv.WithKind(owner.Kind), namespace, owner.Name, depth)
+		if err != nil {
+			return err
+		}
+		b.link(ownerID, id, RelationOwns)
+	}
+	if namespace != "" {
+		for _, newList := range ownedLists {
+			objects, err := b.list(newList, namespace)
+			if err != nil {
+				return err
+			}
+			for _, owned := range objects {
+				for _, owner := range owned.GetOwnerReferences() {
+					if owner.UID == obj.GetUID() {
+						b.link(id, b.add(owned, depth), RelationOwns)
+					}
+				}
+			}
+		}
+		if err := b.expandScalers(id, obj, depth); err != nil {
+			return err
+		}
+	}
+
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		return b.expandPod(id, o, depth)
+	case *corev1.Service:
+		return b.expandService(id, o, depth)
+	case *discoveryv1.EndpointSlice:
+		return b.expandEndpointSlice(id, o, depth)
+	case *networkingv1.Ingress:
+		return b.addRefs(id, namespace, ingressRefs(o), depth)
+	case *corev1.PersistentVolumeClaim:
+		return b.expandPVC(id, o, depth)
+	case *corev1.PersistentVolume:
+		return b.expandPV(id, o, depth)
+	case *storagev1.StorageClass:
+		return b.expandStorageClass(id, o, depth)
+	case *corev1.ConfigMap:
+		return b.addPodReferrers(id, namespace, configMapGVK, o.Name, depth)
+	case *corev1.Secret:
+		if err := b.addPodReferrers(id, namespace, secretGVK, o.Name, depth); err != nil {
+			return err
+		}
+		return b.addIngressReferrers(id, namespace, secretGVK, o.Name, depth)
+	case *corev1.ServiceAccount:
+		return b.addPodReferrers(id, namespace, serviceAccountGVK, o.Name, depth)
+	case *autoscalingv2.HorizontalPodAutoscaler:
+		gv, err := schema.ParseGroupVersion(o.Spec.ScaleTargetRef.APIVersion)
+		if err != nil {
+			return nil
+		}
+		return b.addRefs(id, namespace, []objectRef{{gvk: gv.WithKind(o.Spec.ScaleTargetRef.Kind), name: o.Spec.ScaleTargetRef.Name, relation: RelationScales}}, depth)
+	}
+	return nil
+}
+
+func (b *graphBuilder) addRefs(id, namespace string, refs []objectRef, depth int) error {
+	for _, ref := range refs {
+		refID, err := b.ref(ref.gvk, namespace, ref.name, depth)
+		if err != nil {
+			return err
+		}
+		b.link(id, refID, ref.relation)
+	}
+	return nil
+}
+
+// expandScalers adds the HPAs that scale obj
+func (b *graphBuilder) expandScalers(id string, obj client.Object, depth int) error {
+	gvk := b.gvkOf(obj)
+	hpas, err := b.list(func() client.ObjectList { return &autoscalingv2.HorizontalPodAutoscalerList{} }, obj.GetNamespace())
+	if err != nil {
+		return err
+	}
+	for _, item := range hpas {
+		hpa := item.(*autoscalingv2.HorizontalPodAutoscaler)
+		target := hpa.Spec.ScaleTargetRef
+		gv, err := schema.ParseGroupVersion(target.APIVersion)
+		if err != nil || gv.Group != gvk.Group || target.Kind != gvk.Kind || target.Name != obj.GetName() {
+			continue
+		}
+		b.link(b.add(hpa, depth), id, RelationScales)
+	}
+	return nil
+}
+
+// podRefs returns the ConfigMaps, Secrets, PVCs and ServiceAccount a pod uses
+func podRefs(pod *corev1.Pod) []objectRef {
+	var refs []objectRef
+	for _, volume := range pod.Spec.Volumes {
+		switch {
+		case volume.PersistentVolumeClaim != nil:
+			refs = append(refs, objectRef{pvcGVK, volume.PersistentVolumeClaim.ClaimName, RelationMounts})
+		case volume.ConfigMap != nil:
+			refs = append(refs, objectRef{configMapGVK, volume.ConfigMap.Name, RelationMounts})
+		case volume.Secret != nil:
+			refs = append(refs, objectRef{secretGVK, volume.Secret.SecretName, RelationMounts})
+		case volume.Projected != nil:
+			for _, source := range volume.Projected.Sources {
+				if source.ConfigMap != nil {
+					refs = append(refs, objectRef{configMapGVK, source.ConfigMap.Name, RelationMounts})
+				}
+				if source.Secret != nil {
+					refs = append(refs, objectRef{secretGVK, source.Secret.Name, RelationMounts})
+				}
+			}
+		}
+	}
+
+	containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for _, container := range containers {
+		for _, env := range container.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if ref := env.ValueFrom.ConfigMapKeyRef; ref != nil {
+				refs = append(refs, objectRef{configMapGVK, ref.Name, RelationReferences})
+			}
+			if ref := env.ValueFrom.SecretKeyRef; ref != nil {
+				refs = append(refs, objectRef{secretGVK, ref.Name, RelationReferences})
+			}
+		}
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.ConfigMapRef != nil {
+				refs = append(refs, objectRef{configMapGVK, envFrom.ConfigMapRef.Name, RelationReferences})
+			}
+			if envFrom.SecretRef != nil {
+				refs = append(refs, objectRef{secretGVK, envFrom.SecretRef.Name, RelationReferences})
+			}
+		}
+	}
+	for _, secret := range pod.Spec.ImagePullSecrets {
+		refs = append(refs, objectRef{secretGVK, secret.Name, RelationReferences})
+	}
+	if pod.Spec.ServiceAccountName != "" {
+		refs = append(refs, objectRef{serviceAccountGVK, pod.Spec.ServiceAccountName, RelationServiceAccount})
+	}
+	return refs
+}
+
+// ingressRefs returns the Services an ingress routes to and its TLS Secrets
+func ingressRefs(ingress *networkingv1.Ingress) []objectRef {
+	var refs []objectRef
+	addBackend := func(backend *networkingv1.IngressBackend) {
+		if backend != nil && backend.Service != nil {
+			refs = append(refs, objectRef{serviceGVK, backend.Service.Name, RelationRoutes})
+		}
+	}
+	addBackend(ingress.Spec.DefaultBackend)
+	for _, rule := range ingress.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for i := range rule.HTTP.Paths {
+			addBackend(&rule.HTTP.Paths[i].Backend)
+		}
+	}
+	for _, tls := range ingress.Spec.TLS {
+		if tls.SecretName != "" {
+			refs = append(refs, objectRef{secretGVK, tls.SecretName, RelationReferences})
+		}
+	}
+	return refs
+}
+
+func (b *graphBuilder) expandPod(id string, pod *corev1.Pod, depth int) error {
+	if err := b.addRefs(id, pod.Namespace, podRefs(pod), depth); err != nil {
+		return err
+	}
+	slices, err := b.list(func() client.ObjectList { return &discoveryv1.EndpointSliceList{} }, pod.Namespace)
+	if err != nil {
+		return err
+	}
+	for _, item := range slices {
+		slice := item.(*discoveryv1.EndpointSlice)
+		for _, endpoint := range slice.Endpoints {
+			if ref := endpoint.TargetRef; ref != nil && ref.Kind == "Pod" && ref.Name == pod.Name {
+				b.link(b.add(slice, depth), id, RelationTargets)
+			}
+		}
+	}
+	return nil
+}
+
+func (b *graphBuilder) expandService(id string, service *corev1.Service, depth int) error {
+	slices, err := b.list(func() client.ObjectList { return &discoveryv1.EndpointSliceList{} }, service.Namespace)
+	if err != nil {
+		return err
+	}
+	for _, item := range slices {
+		if item.GetLabels()[discoveryv1.LabelServiceName] == service.Name {
+			b.link(id, b.add(item, depth), RelationEndpoints)
+		}
+	}
+	return b.addIngressReferrers(id, service.Namespace, serviceGVK, service.Name, depth)
+}
+
+func (b *graphBuilder) expandEndpointSlice(id string, slice *discoveryv1.EndpointSlice, depth int) error {
+	if service := slice.Labels[discoveryv1.LabelServiceName]; service != "" {
+		serviceID, err := b.ref(serviceGVK, slice.Namespace, service, depth)
+		if err != nil {
+			return err
+		}
+		b.link(serviceID, id, RelationEndpoints)
+	}
+	for _, endpoint := range slice.Endpoints {
+		if ref := endpoint.TargetRef; ref != nil && ref.Kind == "Pod" {
+			namespace := ref.Namespace
+			if namespace == "" {
+				namespace = slice.Namespace
+			}
+			podID, err := b.ref(podGVK, namespace, ref.Name, depth)
+			if err != nil {
+				return err
+			}
+			b.link(id, podID, RelationTargets)
+		}
+	}
+	return nil
+}
+
+func (b *graphBuilder) expandPVC(id string, pvc *corev1.PersistentVolumeClaim, depth int) error {
+	var refs []objectRef
+	if pvc.Spec.VolumeName != "" {
+		refs = append(refs, objectRef{pvGVK, pvc.Spec.VolumeName, RelationBinds})
+	}
+	if pvc.Spec.StorageClassName != nil && *pvc.Spec.StorageClassName != "" {
+		refs = append(refs, objectRef{storageClassGVK, *pvc.Spec.StorageClassName, RelationStorageClass})
+	}
+	if err := b.addRefs(id, pvc.Namespace, refs, depth); err != nil {
+		return err
+	}
+	return b.addPodReferrers(id, pvc.Namespace, pvcGVK, pvc.Name, depth)
+}
+
+func (b *graphBuilder) expandPV(id string, pv *corev1.PersistentVolume, depth int) error {
+	if pv.Spec.StorageClassName != "" {
+		if err := b.addRefs(id, "", []objectRef{{storageClassGVK, pv.Spec.StorageClassName, RelationStorageClass}}, depth); err != nil {
+			return err
+		}
+	}
+	if claim := pv.Spec.ClaimRef; claim != nil {
+		claimID, err := b.ref(pvcGVK, claim.Namespace, claim.Name, depth)
+		if err != nil {
+			return err
+		}
+		b.link(claimID, id, RelationBinds)
+	}
+	return nil
+}
+
+func (b *graphBuilder) expandStorageClass(id string, class *storagev1.StorageClass, depth int) error {
+	pvs, err := b.list(func() client.ObjectList { return &corev1.PersistentVolumeList{} }, "")
+	if err != nil {
+		return err
+	}
+	for _, item := range pvs {
+		if item.(*corev1.PersistentVolume).Spec.StorageClassName == class.Name {
+			b.link(b.add(item, depth), id, RelationStorageClass)
+		}
+	}
+	pvcs, err := b.list(func() client.ObjectList { return &corev1.PersistentVolumeClaimList{} }, "")
+	if err != nil {
+		return err
+	}
+	for _, item := range pvcs {
+		if name := item.(*corev1.PersistentVolumeClaim).Spec.StorageClassName; name != nil && *name == class.Name {
+			b.link(b.add(item, depth), id, RelationStorageClass)
+		}
+	}
+	return nil
+}
+
+// addPodReferrers adds the pods in namespace that use the object of kind gvk named name
+func (b *graphBuilder) addPodReferrers(id, namespace string, gvk schema.GroupVersionKind, name string, depth int) error {
+	pods, err := b.list(func() client.ObjectList { return &corev1.PodList{} }, namespace)
+	if err != nil {
+		return err
+	}
+	for _, item := range pods {
+		for _, ref := range podRefs(item.(*corev1.Pod)) {
+			if ref.gvk == gvk && ref.name == name {
+				b.link(b.add(item, depth), id, ref.relation)
+			}
+		}
+	}
+	return nil
+}
+
+// addIngressReferrers adds the ingresses in namespace that use the object of kind gvk named name
+func (b *graphBuilder) addIngressReferrers(id, namespace string, gvk schema.GroupVersionKind, name string, depth int) error {
+	ingresses, err := b.list(func() client.ObjectList { return &networkingv1.IngressList{} }, namespace)
+	if err != nil {
+		return err
+	}
+	for _, item := range ingresses {
+		for _, ref := range ingressRefs(item.(*networkingv1.Ingress)) {
+			if ref.gvk == gvk && ref.name == name {
+				b.link(b.add(item, depth), id, ref.relation)
+			}
+		}
+	}
+	return nil
+}
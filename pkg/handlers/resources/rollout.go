@@ -0,0 +1,389 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ysicing/nexus/pkg/kube"
+	"github.com/ysicing/nexus/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// changeCauseAnnotation records why a revision was created
+	changeCauseAnnotation = "kubernetes.io/change-cause"
+	// rolloutStatusInterval is how often rollout-status streams re-check the workload
+	rolloutStatusInterval = 2 * time.Second
+)
+
+// RolloutRevision is one entry of a workload's rollout history
+type RolloutRevision struct {
+	Revision    int64     `json:"revision"`
+	Name        string    `json:"name"`
+	ChangeCause string    `json:"changeCause,omitempty"`
+	Images      []string  `json:"images"`
+	Replicas    int32     `json:"replicas"`
+	Current     bool      `json:"current"`
+	CreatedAt   time.Time `json:"createdAt"`
+	// Template is only returned when a single revision is requested
+	Template *corev1.PodTemplateSpec `json:"template,omitempty"`
+	// Diff is the pod template diff against the compared revision
+	Diff string `json:"diff,omitempty"`
+}
+
+// RolloutStatus is one progress report of a rollout-status stream
+type RolloutStatus struct {
+	Generation         int64  `json:"generation"`
+	ObservedGeneration int64  `json:"observedGeneration"`
+	Replicas           int32  `json:"replicas"`
+	UpdatedReplicas    int32  `json:"updatedReplicas"`
+	ReadyReplicas      int32  `json:"readyReplicas"`
+	AvailableReplicas  int32  `json:"availableReplicas"`
+	Message            string `json:"message"`
+	// Done is set when the rollout completed
+	Done bool `json:"done"`
+	// Failed is set when the rollout can no longer make progress
+	Failed bool `json:"failed,omitempty"`
+}
+
+// RollbackRequest selects the revision to roll back to; 0 means the previous one
+type RollbackRequest struct {
+	Revision int64 `json:"revision"`
+}
+
+// RollingUpdateRequest changes the rolling update of a StatefulSet or
+// DaemonSet; fields left out are not changed
+type RollingUpdateRequest struct {
+	// Partition is the lowest StatefulSet pod ordinal that is updated
+	Partition      *int32              `json:"partition,omitempty"`
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+	// MaxSurge is only supported by DaemonSets
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+}
+
+// templateImages lists the container images of a pod template
+func templateImages(template *corev1.PodTemplateSpec) []string {
+	images := make([]string, 0, len(template.Spec.InitContainers)+len(template.Spec.Containers))
+	for _, container := range template.Spec.InitContainers {
+		images = append(images, container.Image)
+	}
+	for _, container := range template.Spec.Containers {
+		images = append(images, container.Image)
+	}
+	return images
+}
+
+// diffTemplates returns a unified diff of two pod templates as YAML, ignoring
+// the labels the controllers add to tell revisions apart
+func diffTemplates(from, to *corev1.PodTemplateSpec, fromName, toName string) (string, error) {
+	fromYAML, err := templateYAML(from)
+	if err != nil {
+		return "", err
+	}
+	toYAML, err := templateYAML(to)
+	if err != nil {
+		return "", err
+	}
+	return utils.UnifiedDiff(fromYAML, toYAML, fromName, toName)
+}
+
+func templateYAML(template *corev1.PodTemplateSpec) (string, error) {
+	template = template.DeepCopy()
+	delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+	delete(template.Labels, appsv1.ControllerRevisionHashLabelKey)
+	delete(template.Labels, appsv1.StatefulSetRevisionLabel)
+	data, err := yaml.Marshal(template)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// rolloutHistory is the revision history of a workload, newest first, with
+// the pod template of each revision
+type rolloutHistory struct {
+	revisions []RolloutRevision
+	templates map[int64]*corev1.PodTemplateSpec
+}
+
+func newRolloutHistory(size int) *rolloutHistory {
+	return &rolloutHistory{
+		revisions: make([]RolloutRevision, 0, size),
+		templates: make(map[int64]*corev1.PodTemplateSpec, size),
+	}
+}
+
+func (h *rolloutHistory) add(revision RolloutRevision, template *corev1.PodTemplateSpec) {
+	revision.Images = templateImages(template)
+	h.revisions = append(h.revisions, revision)
+	h.templates[revision.Revision] = template
+}
+
+// sort orders the history newest first
+func (h *rolloutHistory) sort() {
+	sort.Slice(h.revisions, func(i, j int) bool {
+		return h.revisions[i].Revision > h.revisions[j].Revision
+	})
+}
+
+// find returns the requested revision, or the one before the current
+// revision when revision is 0
+func (h *rolloutHistory) find(revision int64) (*RolloutRevision, error) {
+	if revision == 0 {
+		for i := range h.revisions {
+			if h.revisions[i].Current && i+1 < len(h.revisions) {
+				return &h.revisions[i+1], nil
+			}
+		}
+		return nil, fmt.Errorf("no previous revision found")
+	}
+	for i := range h.revisions {
+		if h.revisions[i].Revision == revision {
+			return &h.revisions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("revision %d not found", revision)
+}
+
+// writeRevision responds with one revision, its pod template and the diff
+// from the revision given by ?compareTo to it. Without ?compareTo the diff is
+// taken from the current pod template of the workload.
+func writeRevision(c *gin.Context, history *rolloutHistory, current *corev1.PodTemplateSpec) {
+	revision, ok := parseRevision(c)
+	if !ok {
+		return
+	}
+	result, err := history.find(revision)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	template := history.templates[revision]
+
+	from, fromName := current, "current"
+	if value := c.Query("compareTo"); value != "" {
+		compareTo, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid compareTo parameter"})
+			return
+		}
+		compared, exists := history.templates[compareTo]
+		if !exists {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("revision %d not found", compareTo)})
+			return
+		}
+		from, fromName = compared, "revision "+value
+	}
+
+	if result.Diff, err = diffTemplates(from, template, fromName, "revision "+strconv.FormatInt(revision, 10)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	result.Template = template
+	c.JSON(http.StatusOK, result)
+}
+
+// rollback points a workload at the pod template of the revision named in the
+// request body, like kubectl rollout undo. template must return the pod
+// template field of obj, which is patched with an optimistic lock.
+func rollback(c *gin.Context, k8sClient *kube.K8sClient, kind string, obj client.Object, history *rolloutHistory, template func() *corev1.PodTemplateSpec) {
+	var req RollbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !stderrors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.Revision < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision"})
+		return
+	}
+
+	target, err := history.find(req.Revision)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if target.Current {
+		c.JSON(http.StatusOK, gin.H{"message": kind + " is already at the requested revision", "revision": target.Revision})
+		return
+	}
+
+	restored := history.templates[target.Revision].DeepCopy()
+	delete(restored.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+	delete(restored.Labels, appsv1.ControllerRevisionHashLabelKey)
+
+	patch := client.MergeFromWithOptions(obj.DeepCopyObject().(client.Object), client.MergeFromWithOptimisticLock{})
+	*template() = *restored
+	if err := k8sClient.Client.Patch(c.Request.Context(), obj, patch, client.FieldOwner(kube.FieldManager)); err != nil {
+		c.JSON(ErrorStatus(err), gin.H{"error": "Failed to roll back " + strings.ToLower(kind) + ": " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  fmt.Sprintf("%s rolled back to revision %d", kind, target.Revision),
+		"revision": target.Revision,
+	})
+}
+
+// controllerRevisionHistory builds the rollout history of a StatefulSet or
+// DaemonSet from the ControllerRevisions it owns. current is the name of the
+// revision the workload is updating to; when empty the newest revision is current.
+func controllerRevisionHistory(ctx context.Context, k8sClient *kube.K8sClient, owner client.Object, selector *metav1.LabelSelector, current string) (*rolloutHistory, error) {
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	var list appsv1.ControllerRevisionList
+	if err := k8sClient.Client.List(ctx, &list, client.InNamespace(owner.GetNamespace()), client.MatchingLabelsSelector{Selector: labelSelector}); err != nil {
+		return nil, err
+	}
+
+	history := newRolloutHistory(len(list.Items))
+	for i := range list.Items {
+		revision := &list.Items[i]
+		if ref := metav1.GetControllerOf(revision); ref == nil || ref.UID != owner.GetUID() {
+			continue
+		}
+		// The revision data is a patch that replaces the pod template
+		var data struct {
+			Spec struct {
+				Template corev1.PodTemplateSpec `json:"template"`
+			} `json:"spec"`
+		}
+		raw, err := json.Marshal(revision.Data)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return nil, fmt.Errorf("invalid data in controller revision %s: %w", revision.Name, err)
+		}
+		history.add(RolloutRevision{
+			Revision:    revision.Revision,
+			Name:        revision.Name,
+			ChangeCause: revision.Annotations[changeCauseAnnotation],
+			Current:     revision.Name == current,
+			CreatedAt:   revision.CreationTimestamp.Time,
+		}, &data.Spec.Template)
+	}
+	history.sort()
+	if current == "" && len(history.revisions) > 0 {
+		history.revisions[0].Current = true
+	}
+	return history, nil
+}
+
+// restartWorkload reads a workload into obj and changes the pod template
+// returned by template so that its controller replaces every pod
+func restartWorkload(ctx context.Context, k8sClient *kube.K8sClient, namespace, name string, obj client.Object, template func() *corev1.PodTemplateSpec) error {
+	if k8sClient == nil || k8sClient.Client == nil {
+		return fmt.Errorf("no cluster client available")
+	}
+	if err := k8sClient.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, obj); err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	podTemplate := template()
+	if podTemplate.Annotations == nil {
+		podTemplate.Annotations = make(map[string]string)
+	}
+	podTemplate.Annotations["kite.kubernetes.io/restartedAt"] = time.Now().Format(time.RFC3339)
+	return k8sClient.Client.Patch(ctx, obj, patch, client.FieldOwner(kube.FieldManager))
+}
+
+// getWorkload reads the object addressed by the route parameters into obj. It
+// writes the error response and returns false when the request cannot be served.
+func getWorkload(c *gin.Context, k8sClient *kube.K8sClient, kind string, obj client.Object) bool {
+	if k8sClient == nil || k8sClient.Client == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "No cluster available. Please add a cluster first."})
+		return false
+	}
+
+	key := types.NamespacedName{Namespace: c.Param("namespace"), Name: c.Param("name")}
+	if err := k8sClient.Client.Get(c.Request.Context(), key, obj); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": kind + " not found"})
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// parseRevision reads the :revision route parameter
+func parseRevision(c *gin.Context) (int64, bool) {
+	revision, err := strconv.ParseInt(c.Param("revision"), 10, 64)
+	if err != nil || revision <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision"})
+		return 0, false
+	}
+	return revision, true
+}
+
+// streamRolloutStatus reports the status returned by check as server-sent
+// events until the rollout is done or failed, or the client goes away
+func streamRolloutStatus(c *gin.Context, check func() (*RolloutStatus, error)) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+
+	ticker := time.NewTicker(rolloutStatusInterval)
+	defer ticker.Stop()
+
+	var last string
+	for {
+		status, err := check()
+		if err != nil {
+			writeSSE(c, "error", gin.H{"error": err.Error()})
+			return
+		}
+		data, _ := json.Marshal(status)
+		// Only report changes, the workload is polled more often than it changes
+		if string(data) != last {
+			last = string(data)
+			if !writeSSE(c, "status", status) {
+				return
+			}
+		}
+		if status.Done || status.Failed {
+			writeSSE(c, "close", gin.H{"status": "closed"})
+			return
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// writeSSE writes one server-sent event and reports whether the client is still connected
+func writeSSE(c *gin.Context, event string, data interface{}) bool {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return false
+	}
+	if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return false
+	}
+	c.Writer.Flush()
+	return true
+}
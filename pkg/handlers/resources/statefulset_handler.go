@@ -0,0 +1,318 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ysicing/nexus/pkg/kube"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type StatefulSetHandler struct {
+	*GenericResourceHandler[*appsv1.StatefulSet, *appsv1.StatefulSetList]
+}
+
+func NewStatefulSetHandler(client *kube.K8sClient) *StatefulSetHandler {
+	return &StatefulSetHandler{
+		GenericResourceHandler: NewGenericResourceHandler[*appsv1.StatefulSet, *appsv1.StatefulSetList](
+			client,
+			"statefulsets",
+			false, // StatefulSets are namespaced resources
+			false,
+		),
+	}
+}
+
+func (h *StatefulSetHandler) Restart(ctx context.Context, namespace, name string) error {
+	return h.restart(ctx, h.K8sClient, namespace, name)
+}
+
+func (h *StatefulSetHandler) restart(ctx context.Context, k8sClient *kube.K8sClient, namespace, name string) error {
+	var statefulSet appsv1.StatefulSet
+	return restartWorkload(ctx, k8sClient, namespace, name, &statefulSet, func() *corev1.PodTemplateSpec {
+		return &statefulSet.Spec.Template
+	})
+}
+
+func (h *StatefulSetHandler) RestartStatefulSet(c *gin.Context) {
+	if err := h.restart(c.Request.Context(), h.getClient(c), c.Param("namespace"), c.Param("name")); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "StatefulSet not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restart statefulset: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "StatefulSet restarted successfully",
+	})
+}
+
+// ScaleStatefulSet scales a statefulset to the specified number of replicas
+func (h *StatefulSetHandler) ScaleStatefulSet(c *gin.Context) {
+	var scaleRequest struct {
+		Replicas *int32 `json:"replicas" binding:"required,min=0"`
+	}
+	if err := c.ShouldBindJSON(&scaleRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	k8sClient := h.getClient(c)
+	var statefulSet appsv1.StatefulSet
+	if !getWorkload(c, k8sClient, "StatefulSet", &statefulSet) {
+		return
+	}
+
+	patch := client.MergeFrom(statefulSet.DeepCopy())
+	statefulSet.Spec.Replicas = scaleRequest.Replicas
+	if err := k8sClient.Client.Patch(c.Request.Context(), &statefulSet, patch, client.FieldOwner(kube.FieldManager)); err != nil {
+		c.JSON(ErrorStatus(err), gin.H{"error": "Failed to scale statefulset: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "StatefulSet scaled successfully",
+		"statefulset": statefulSet,
+		"replicas":    *scaleRequest.Replicas,
+	})
+}
+
+// statefulSetHistory builds the rollout history of a statefulset; the current
+// revision is the one the statefulset is updating to
+func statefulSetHistory(c *gin.Context, k8sClient *kube.K8sClient, statefulSet *appsv1.StatefulSet) (*rolloutHistory, bool) {
+	history, err := controllerRevisionHistory(c.Request.Context(), k8sClient, statefulSet, statefulSet.Spec.Selector, statefulSet.Status.UpdateRevision)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return history, true
+}
+
+// ListRolloutHistory lists the rollout history of a statefulset
+func (h *StatefulSetHandler) ListRolloutHistory(c *gin.Context) {
+	k8sClient := h.getClient(c)
+	var statefulSet appsv1.StatefulSet
+	if !getWorkload(c, k8sClient, "StatefulSet", &statefulSet) {
+		return
+	}
+	history, ok := statefulSetHistory(c, k8sClient, &statefulSet)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"revisions": history.revisions})
+}
+
+// GetRolloutRevision returns one revision of a statefulset with its pod template and diff
+func (h *StatefulSetHandler) GetRolloutRevision(c *gin.Context) {
+	k8sClient := h.getClient(c)
+	var statefulSet appsv1.StatefulSet
+	if !getWorkload(c, k8sClient, "StatefulSet", &statefulSet) {
+		return
+	}
+	history, ok := statefulSetHistory(c, k8sClient, &statefulSet)
+	if !ok {
+		return
+	}
+	writeRevision(c, history, &statefulSet.Spec.Template)
+}
+
+// RollbackStatefulSet rolls a statefulset back to an earlier revision
+func (h *StatefulSetHandler) RollbackStatefulSet(c *gin.Context) {
+	k8sClient := h.getClient(c)
+	var statefulSet appsv1.StatefulSet
+	if !getWorkload(c, k8sClient, "StatefulSet", &statefulSet) {
+		return
+	}
+	history, ok := statefulSetHistory(c, k8sClient, &statefulSet)
+	if !ok {
+		return
+	}
+	rollback(c, k8sClient, "StatefulSet", &statefulSet, history, func() *corev1.PodTemplateSpec {
+		return &statefulSet.Spec.Template
+	})
+}
+
+// UpdateRollingUpdate changes the partition and maxUnavailable of a
+// statefulset's rolling update. Pods with an ordinal below the partition keep
+// the old revision.
+func (h *StatefulSetHandler) UpdateRollingUpdate(c *gin.Context) {
+	var req RollingUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.MaxSurge != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "maxSurge is not supported by statefulsets"})
+		return
+	}
+	if req.Partition != nil && *req.Partition < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "partition must not be negative"})
+		return
+	}
+
+	h.patchRollingUpdate(c, func(statefulSet *appsv1.StatefulSet, rollingUpdate *appsv1.RollingUpdateStatefulSetStrategy) {
+		if req.Partition != nil {
+			rollingUpdate.Partition = req.Partition
+		}
+		if req.MaxUnavailable != nil {
+			rollingUpdate.MaxUnavailable = req.MaxUnavailable
+		}
+	})
+}
+
+// pausedPartitionAnnotation keeps the partition a statefulset had before it
+// was paused, empty when it had none, so resume can restore it
+const pausedPartitionAnnotation = "nexus.ysicing.net/paused-partition"
+
+// PauseStatefulSet stops the rolling update by raising the partition to the
+// number of replicas, so no further pods move to the new revision
+func (h *StatefulSetHandler) PauseStatefulSet(c *gin.Context) {
+	h.patchRollingUpdate(c, func(statefulSet *appsv1.StatefulSet, rollingUpdate *appsv1.RollingUpdateStatefulSetStrategy) {
+		// Pausing twice must not overwrite the partition saved by the first pause
+		if _, paused := statefulSet.Annotations[pausedPartitionAnnotation]; !paused {
+			previous := ""
+			if rollingUpdate.Partition != nil {
+				previous = strconv.FormatInt(int64(*rollingUpdate.Partition), 10)
+			}
+			if statefulSet.Annotations == nil {
+				statefulSet.Annotations = map[string]string{}
+			}
+			statefulSet.Annotations[pausedPartitionAnnotation] = previous
+		}
+		partition := int32(1)
+		if statefulSet.Spec.Replicas != nil {
+			partition = *statefulSet.Spec.Replicas
+		}
+		rollingUpdate.Partition = &partition
+	})
+}
+
+// ResumeStatefulSet lets the rolling update continue by restoring the
+// partition saved when the statefulset was paused. Statefulsets paused
+// without a saved partition are updated on every pod.
+func (h *StatefulSetHandler) ResumeStatefulSet(c *gin.Context) {
+	h.patchRollingUpdate(c, func(statefulSet *appsv1.StatefulSet, rollingUpdate *appsv1.RollingUpdateStatefulSetStrategy) {
+		rollingUpdate.Partition = nil
+		previous, paused := statefulSet.Annotations[pausedPartitionAnnotation]
+		if !paused {
+			return
+		}
+		delete(statefulSet.Annotations, pausedPartitionAnnotation)
+		if partition, err := strconv.ParseInt(previous, 10, 32); err == nil && partition > 0 {
+			value := int32(partition)
+			rollingUpdate.Partition = &value
+		}
+	})
+}
+
+// patchRollingUpdate lets update change the rolling update strategy of the
+// statefulset and patches it. Statefulsets using OnDelete are rejected.
+func (h *StatefulSetHandler) patchRollingUpdate(c *gin.Context, update func(*appsv1.StatefulSet, *appsv1.RollingUpdateStatefulSetStrategy)) {
+	k8sClient := h.getClient(c)
+	var statefulSet appsv1.StatefulSet
+	if !getWorkload(c, k8sClient, "StatefulSet", &statefulSet) {
+		return
+	}
+	strategy := &statefulSet.Spec.UpdateStrategy
+	if strategy.Type != "" && strategy.Type != appsv1.RollingUpdateStatefulSetStrategyType {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("statefulset uses the %s update strategy", strategy.Type)})
+		return
+	}
+
+	patch := client.MergeFromWithOptions(statefulSet.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	strategy.Type = appsv1.RollingUpdateStatefulSetStrategyType
+	if strategy.RollingUpdate == nil {
+		strategy.RollingUpdate = &appsv1.RollingUpdateStatefulSetStrategy{}
+	}
+	update(&statefulSet, strategy.RollingUpdate)
+	if err := k8sClient.Client.Patch(c.Request.Context(), &statefulSet, patch, client.FieldOwner(kube.FieldManager)); err != nil {
+		c.JSON(ErrorStatus(err), gin.H{"error": "Failed to update statefulset: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "StatefulSet rolling update updated successfully",
+		"updateStrategy": statefulSet.Spec.UpdateStrategy,
+	})
+}
+
+// WatchRolloutStatus streams the rollout progress of a statefulset as
+// server-sent events until every pod runs the update revision
+func (h *StatefulSetHandler) WatchRolloutStatus(c *gin.Context) {
+	k8sClient := h.getClient(c)
+	var statefulSet appsv1.StatefulSet
+	if !getWorkload(c, k8sClient, "StatefulSet", &statefulSet) {
+		return
+	}
+	key := types.NamespacedName{Namespace: statefulSet.Namespace, Name: statefulSet.Name}
+
+	streamRolloutStatus(c, func() (*RolloutStatus, error) {
+		var statefulSet appsv1.StatefulSet
+		if err := k8sClient.Client.Get(c.Request.Context(), key, &statefulSet); err != nil {
+			return nil, err
+		}
+		return statefulSetRolloutStatus(&statefulSet), nil
+	})
+}
+
+// statefulSetRolloutStatus reports rollout progress the way kubectl rollout status does
+func statefulSetRolloutStatus(statefulSet *appsv1.StatefulSet) *RolloutStatus {
+	status := &RolloutStatus{
+		Generation:         statefulSet.Generation,
+		ObservedGeneration: statefulSet.Status.ObservedGeneration,
+		Replicas:           statefulSet.Status.Replicas,
+		UpdatedReplicas:    statefulSet.Status.UpdatedReplicas,
+		ReadyReplicas:      statefulSet.Status.ReadyReplicas,
+		AvailableReplicas:  statefulSet.Status.AvailableReplicas,
+	}
+	desired := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		desired = *statefulSet.Spec.Replicas
+	}
+	var partition int32
+	if rollingUpdate := statefulSet.Spec.UpdateStrategy.RollingUpdate; rollingUpdate != nil && rollingUpdate.Partition != nil {
+		partition = *rollingUpdate.Partition
+	}
+
+	switch {
+	case statefulSet.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType:
+		status.Message = "Rollout status is only available for the RollingUpdate strategy"
+		status.Done = true
+	case statefulSet.Generation > statefulSet.Status.ObservedGeneration:
+		status.Message = "Waiting for statefulset spec update to be observed"
+	case status.ReadyReplicas < desired:
+		status.Message = fmt.Sprintf("Waiting for %d pods to be ready", desired-status.ReadyReplicas)
+	case partition > 0 && status.UpdatedReplicas < desired-partition:
+		status.Message = fmt.Sprintf("Waiting for partitioned roll out to finish: %d out of %d new pods have been updated", status.UpdatedReplicas, desired-partition)
+	case partition > 0:
+		status.Message = fmt.Sprintf("Partitioned roll out complete: %d new pods have been updated", status.UpdatedReplicas)
+		status.Done = true
+	case statefulSet.Status.UpdateRevision != statefulSet.Status.CurrentRevision:
+		status.Message = fmt.Sprintf("Waiting for statefulset rolling update to complete: %d pods at revision %s", status.UpdatedReplicas, statefulSet.Status.UpdateRevision)
+	default:
+		status.Message = fmt.Sprintf("StatefulSet rolling update complete: %d pods at revision %s", status.ReadyReplicas, statefulSet.Status.CurrentRevision)
+		status.Done = true
+	}
+	return status
+}
+
+func (h *StatefulSetHandler) registerCustomRoutes(group *gin.RouterGroup) {
+	group.POST("/:namespace/:name/scale", h.ScaleStatefulSet)
+	group.POST("/:namespace/:name/restart", h.RestartStatefulSet)
+	group.GET("/:namespace/:name/history", h.ListRolloutHistory)
+	group.GET("/:namespace/:name/history/:revision", h.GetRolloutRevision)
+	group.POST("/:namespace/:name/rollback", h.RollbackStatefulSet)
+	group.POST("/:namespace/:name/pause", h.PauseStatefulSet)
+	group.POST("/:namespace/:name/resume", h.ResumeStatefulSet)
+	group.POST("/:namespace/:name/rolling-update", h.UpdateRollingUpdate)
+	group.GET("/:namespace/:name/rollout-status", h.WatchRolloutStatus)
+}
@@ -0,0 +1,60 @@
+package resources
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ysicing/nexus/pkg/kube"
+)
+
+// withTable serves requests with ?format=table as a Table of the resource type
+// returned by resourceName, with the columns kubectl shows, and everything else
+// with next. ?wide=true adds the columns of kubectl's wide output.
+func withTable(next gin.HandlerFunc, resourceName func(c *gin.Context) string, fallback *kube.K8sClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Query("format") != "table" {
+			next(c)
+			return
+		}
+
+		k8sClient := requestClient(c, fallback)
+		if k8sClient == nil || k8sClient.Client == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "No cluster available. Please add a cluster first."})
+			return
+		}
+		resource, ok := lookupResource(c, k8sClient, resourceName(c))
+		if !ok {
+			return
+		}
+
+		opts := kube.TableOptions{
+			Name:          c.Param("name"),
+			LabelSelector: c.Query("labelSelector"),
+			FieldSelector: c.Query("fieldSelector"),
+			Continue:      c.Query("continue"),
+			Wide:          c.Query("wide") == "true",
+		}
+		if namespace := c.Param("namespace"); namespace != "_all" {
+			opts.Namespace = namespace
+		}
+		if resource.Namespaced && opts.Name != "" && opts.Namespace == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "namespace is required for namespaced resources"})
+			return
+		}
+		if value := c.Query("limit"); value != "" {
+			var err error
+			if opts.Limit, err = strconv.ParseInt(value, 10, 64); err != nil || opts.Limit < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+				return
+			}
+		}
+
+		table, err := k8sClient.Table(c.Request.Context(), resource, opts)
+		if err != nil {
+			c.JSON(ErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, table)
+	}
+}
@@ -0,0 +1,186 @@
+package resources
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ysicing/nexus/pkg/kube"
+	"golang.org/x/net/websocket"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// watchHeartbeatInterval is how often an idle watch stream sends a keep-alive
+const watchHeartbeatInterval = 30 * time.Second
+
+// watchMessage is what a watch stream sends: the initial list first, then
+// one message per change
+type watchMessage struct {
+	// Type is INITIAL for the initial list, otherwise the watch event type
+	Type            string           `json:"type"`
+	Items           []runtime.Object `json:"items,omitempty"`
+	Object          runtime.Object   `json:"object,omitempty"`
+	ResourceVersion string           `json:"resourceVersion,omitempty"`
+}
+
+// listOrWatch serves requests with ?watch=true as a watch stream of the
+// resource type returned by resourceName, and everything else with list
+func listOrWatch(list gin.HandlerFunc, resourceName func(c *gin.Context) string, fallback *kube.K8sClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Query("watch") {
+		case "true", "1":
+			watchResource(c, resourceName(c), fallback)
+		default:
+			list(c)
+		}
+	}
+}
+
+// requestClient returns the cluster client of the request, falling back to the given client
+func requestClient(c *gin.Context, fallback *kube.K8sClient) *kube.K8sClient {
+	if value, exists := c.Get("k8sClient"); exists {
+		if k8sClient, ok := value.(*kube.K8sClient); ok && k8sClient != nil {
+			return k8sClient
+		}
+	}
+	return fallback
+}
+
+// lookupResource resolves a resource type name through API discovery. It
+// writes the error response and returns false when the name is unknown.
+func lookupResource(c *gin.Context, k8sClient *kube.K8sClient, name string) (*kube.APIResource, bool) {
+	resource, err := k8sClient.LookupResource(name)
+	if err != nil {
+		var notFound *kube.ResourceNotFoundError
+		if stderrors.As(err, &notFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return resource, true
+}
+
+// watchResource streams a resource type, over a WebSocket when the request
+// asks for an upgrade and as server-sent events otherwise. Reconnecting
+// clients resume with ?resourceVersion= or the Last-Event-ID header, which
+// every SSE change carries.
+func watchResource(c *gin.Context, resourceName string, fallback *kube.K8sClient) {
+	k8sClient := requestClient(c, fallback)
+	if k8sClient == nil || k8sClient.Client == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "No cluster available. Please add a cluster first."})
+		return
+	}
+
+	resource, ok := lookupResource(c, k8sClient, resourceName)
+	if !ok {
+		return
+	}
+
+	namespace := c.Param("namespace")
+	if namespace == "_all" {
+		namespace = ""
+	}
+	resourceVersion := c.Query("resourceVersion")
+	if resourceVersion == "" {
+		resourceVersion = c.GetHeader("Last-Event-ID")
+	}
+
+	w, err := k8sClient.Watch(c.Request.Context(), resource, namespace, resourceVersion)
+	if err != nil {
+		c.JSON(ErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	defer w.Stop()
+
+	if c.GetHeader("Upgrade") == "websocket" {
+		websocket.Handler(func(ws *websocket.Conn) {
+			defer ws.Close()
+			streamWatch(c, w, func(message *watchMessage) bool {
+				return websocket.JSON.Send(ws, message) == nil
+			}, nil)
+		}).ServeHTTP(c.Writer, c.Request)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+	streamWatch(c, w, func(message *watchMessage) bool {
+		return writeWatchSSE(c, message)
+	}, func() bool {
+		if _, err := c.Writer.WriteString(": keep-alive\n\n"); err != nil {
+			return false
+		}
+		c.Writer.Flush()
+		return true
+	})
+}
+
+// streamWatch sends the initial list, if any, and every change until the
+// watch ends or send fails. heartbeat, if set, is called when the stream is idle.
+func streamWatch(c *gin.Context, w *kube.ResourceWatch, send func(*watchMessage) bool, heartbeat func() bool) {
+	if w.Initial != nil {
+		items := make([]runtime.Object, 0, len(w.Initial))
+		for _, obj := range w.Initial {
+			items = append(items, trimObject(obj))
+		}
+		if !send(&watchMessage{Type: "INITIAL", Items: items, ResourceVersion: w.ResourceVersion}) {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(watchHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case event, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			message := &watchMessage{Type: event.Type, Object: trimObject(event.Object)}
+			if accessor, err := meta.Accessor(event.Object); err == nil {
+				message.ResourceVersion = accessor.GetResourceVersion()
+			}
+			if !send(message) {
+				return
+			}
+		case <-ticker.C:
+			if heartbeat != nil && !heartbeat() {
+				return
+			}
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
+
+// trimObject returns a copy of obj without managed fields; objects from the
+// informer cache are shared and must not be modified
+func trimObject(obj runtime.Object) runtime.Object {
+	accessor, err := meta.Accessor(obj)
+	if err != nil || len(accessor.GetManagedFields()) == 0 {
+		return obj
+	}
+	obj = obj.DeepCopyObject()
+	if accessor, err := meta.Accessor(obj); err == nil {
+		accessor.SetManagedFields(nil)
+	}
+	return obj
+}
+
+// writeWatchSSE writes a watch message as a server-sent event whose id is the
+// resourceVersion to resume from
+func writeWatchSSE(c *gin.Context, message *watchMessage) bool {
+	if message.ResourceVersion != "" && message.Type != kube.WatchError {
+		if _, err := fmt.Fprintf(c.Writer, "id: %s\n", message.ResourceVersion); err != nil {
+			return false
+		}
+	}
+	return writeSSE(c, "watch", message)
+}
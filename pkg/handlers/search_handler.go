@@ -32,11 +32,13 @@ func NewSearchHandler(client *kube.K8sClient) *SearchHandler {
 	}
 }
 
-func (h *SearchHandler) createCacheKey(query string) string {
-	return fmt.Sprintf("search:%s", query)
+func (h *SearchHandler) createCacheKey(clusterID, query string) string {
+	return fmt.Sprintf("search:%s:%s", clusterID, query)
 }
 
-func (h *SearchHandler) Search(ctx context.Context, query string, limit int) ([]common.SearchResult, error) {
+// Search searches every registered resource type of the cluster whose client
+// ctx carries, see resources.WithClient
+func (h *SearchHandler) Search(ctx context.Context, clusterID, query string, limit int) ([]common.SearchResult, error) {
 	var allResults []common.SearchResult
 
 	// Search in different resource types
@@ -60,7 +62,7 @@ func (h *SearchHandler) Search(ctx context.Context, query string, limit int) ([]
 		allResults = allResults[:limit]
 	}
 
-	h.cache.Add(h.createCacheKey(query), allResults)
+	h.cache.Add(h.createCacheKey(clusterID, query), allResults)
 	return allResults, nil
 }
 
@@ -79,7 +81,15 @@ func (h *SearchHandler) GlobalSearch(c *gin.Context) {
 		limit = 50
 	}
 
-	cacheKey := h.createCacheKey(query)
+	clusterID := c.GetString("clusterID")
+	ctx := c.Request.Context()
+	if value, exists := c.Get("k8sClient"); exists {
+		if k8sClient, ok := value.(*kube.K8sClient); ok && k8sClient != nil {
+			ctx = resources.WithClient(ctx, k8sClient)
+		}
+	}
+
+	cacheKey := h.createCacheKey(clusterID, query)
 
 	if cachedResults, found := h.cache.Get(cacheKey); found {
 		response := SearchResponse{
@@ -88,14 +98,13 @@ func (h *SearchHandler) GlobalSearch(c *gin.Context) {
 		}
 		go func() {
 			// Perform search in the background to update cache
-			_, _ = h.Search(context.Background(), query, limit)
+			_, _ = h.Search(context.WithoutCancel(ctx), clusterID, query, limit)
 		}()
 		c.JSON(http.StatusOK, response)
 		return
 	}
 
-	ctx := c.Request.Context()
-	allResults, err := h.Search(ctx, query, limit)
+	allResults, err := h.Search(ctx, clusterID, query, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to perform search"})
 		return
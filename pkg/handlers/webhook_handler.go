@@ -18,6 +18,14 @@ func NewWebhookHandler(k8sClient *kube.K8sClient) *WebhookHandler {
 	}
 }
 
+// getClient returns the cluster client from the gin context, falling back to the default client
+func (h *WebhookHandler) getClient(c *gin.Context) *kube.K8sClient {
+	if k8sClient, ok := GetK8sClientFromContext(c); ok && k8sClient != nil {
+		return k8sClient
+	}
+	return h.k8sClient
+}
+
 func (h *WebhookHandler) HandleWebhook(c *gin.Context) {
 	var body common.WebhookRequest
 	if err := c.ShouldBindJSON(&body); err != nil {
@@ -29,7 +37,7 @@ func (h *WebhookHandler) HandleWebhook(c *gin.Context) {
 	klog.V(2).Infof("Received webhook request: %+v", body)
 	switch body.Action {
 	case common.ActionRestart:
-		handler, err := resources.GetHandler(body.Resource)
+		handler, err := resources.GetHandler(body.Resource, h.getClient(c))
 		if err != nil {
 			c.JSON(400, gin.H{
 				"error": "Invalid resource type",
@@ -49,6 +57,9 @@ func (h *WebhookHandler) HandleWebhook(c *gin.Context) {
 			})
 			return
 		}
+		c.JSON(400, gin.H{
+			"error": "Resource type does not support restart",
+		})
 	case common.ActionUpdateImage:
 	default:
 		c.JSON(400, gin.H{
@@ -0,0 +1,269 @@
+package history
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ysicing/nexus/pkg/handlers/resources"
+	"github.com/ysicing/nexus/pkg/kube"
+	"github.com/ysicing/nexus/pkg/middleware"
+	"github.com/ysicing/nexus/pkg/models"
+	"github.com/ysicing/nexus/pkg/utils"
+	"gorm.io/gorm"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// defaultListLimit 历史版本列表默认返回的条数
+const defaultListLimit = 20
+
+// Handler 对象历史版本处理器
+type Handler struct {
+	recorder *Recorder
+}
+
+// NewHandler 创建对象历史版本处理器
+func NewHandler(recorder *Recorder) *Handler {
+	return &Handler{recorder: recorder}
+}
+
+// RestoreRequest 恢复历史版本请求
+type RestoreRequest struct {
+	// ResourceVersion 调用方看到的对象当前的 resourceVersion，对象在此之后被修改时拒绝恢复。
+	// 对象已被删除时留空。
+	ResourceVersion string `json:"resourceVersion"`
+}
+
+// List 列出对象的历史版本
+func (h *Handler) List(c *gin.Context) {
+	k8sClient, ok := requireClient(c)
+	if !ok {
+		return
+	}
+
+	resourceName, name := c.Query("resource"), c.Query("name")
+	if resourceName == "" || name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "resource and name are required"})
+		return
+	}
+	resource, err := k8sClient.LookupResource(resourceName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	limit := defaultListLimit
+	if value := c.Query("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 || limit > maxRevisionsPerObject {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+			return
+		}
+	}
+
+	ref := models.ResourceRef{
+		ClusterID: c.GetString("clusterID"),
+		Resource:  resource.FullName(),
+		Name:      name,
+	}
+	if resource.Namespaced {
+		ref.Namespace = c.Query("namespace")
+	}
+
+	revisions, err := h.recorder.repo.List(ref, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions})
+}
+
+// Get 获取历史版本及其内容
+func (h *Handler) Get(c *gin.Context) {
+	revision, ok := h.revision(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, revision)
+}
+
+// Diff 比较历史版本与对象的当前内容
+func (h *Handler) Diff(c *gin.Context) {
+	k8sClient, ok := requireClient(c)
+	if !ok {
+		return
+	}
+	revision, ok := h.revision(c)
+	if !ok {
+		return
+	}
+
+	live, err := h.live(c, k8sClient, revision)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	current, resourceVersion := "", ""
+	if live != nil {
+		if current, err = cleanYAML(live); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		resourceVersion = live.GetResourceVersion()
+	}
+	diff, err := utils.UnifiedDiff(current, string(revision.Content), "live", "revision "+strconv.FormatUint(uint64(revision.ID), 10))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"diff":            diff,
+		"exists":          live != nil,
+		"resourceVersion": resourceVersion,
+	})
+}
+
+// Restore 重新应用历史版本
+//
+// 对象存在时要求请求中的 resourceVersion 与对象当前的一致，避免覆盖其他人在此之后的修改；
+// 对象已被删除时重新创建。恢复前对象的内容同样保存为历史版本。
+func (h *Handler) Restore(c *gin.Context) {
+	k8sClient, ok := requireClient(c)
+	if !ok {
+		return
+	}
+	revision, ok := h.revision(c)
+	if !ok {
+		return
+	}
+
+	var req RestoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var obj unstructured.Unstructured
+	if err := yaml.Unmarshal([]byte(revision.Content), &obj.Object); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid revision content: " + err.Error()})
+		return
+	}
+
+	live, err := h.live(c, k8sClient, revision)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+	username := middleware.CurrentUser(c)
+
+	if live == nil {
+		if req.ResourceVersion != "" {
+			c.JSON(http.StatusConflict, gin.H{"error": "the object has been deleted since it was last read"})
+			return
+		}
+		if err := k8sClient.Client.Create(ctx, &obj, client.FieldOwner(kube.FieldManager)); err != nil {
+			c.JSON(resources.ErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		if req.ResourceVersion == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "resourceVersion of the live object is required"})
+			return
+		}
+		if req.ResourceVersion != live.GetResourceVersion() {
+			c.JSON(http.StatusConflict, gin.H{"error": "the object has been modified since it was last read"})
+			return
+		}
+
+		current, err := cleanYAML(live)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		before := &models.ResourceRevisionModel{
+			ResourceRef:     revision.ResourceRef,
+			APIVersion:      live.GetAPIVersion(),
+			Kind:            live.GetKind(),
+			ResourceVersion: live.GetResourceVersion(),
+			Action:          "restore",
+			Username:        username,
+			Content:         models.EncryptedString(current),
+		}
+
+		// 使用当前的 resourceVersion 更新，期间对象被修改时 API Server 返回冲突
+		obj.SetResourceVersion(live.GetResourceVersion())
+		if err := k8sClient.Client.Update(ctx, &obj, client.FieldOwner(kube.FieldManager)); err != nil {
+			c.JSON(resources.ErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		if err := h.recorder.repo.Create(before, maxRevisionsPerObject); err != nil {
+			klog.Errorf("保存 %s %s/%s 的历史版本失败: %v", before.Resource, before.Namespace, before.Name, err)
+		}
+	}
+
+	klog.Infof("用户 %s 将 %s %s/%s 恢复到历史版本 %d", username, revision.Resource, revision.Namespace, revision.Name, revision.ID)
+	c.JSON(http.StatusOK, &obj)
+}
+
+// revision 根据路径参数获取当前集群中的历史版本
+func (h *Handler) revision(c *gin.Context) (*models.ResourceRevisionModel, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision id"})
+		return nil, false
+	}
+
+	revision, err := h.recorder.repo.Get(c.GetString("clusterID"), uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return revision, true
+}
+
+// live 读取历史版本对应对象的当前内容，对象不存在时返回 nil
+func (h *Handler) live(c *gin.Context, k8sClient *kube.K8sClient, revision *models.ResourceRevisionModel) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(revision.APIVersion)
+	obj.SetKind(revision.Kind)
+	key := types.NamespacedName{Namespace: revision.Namespace, Name: revision.Name}
+	if err := k8sClient.Client.Get(c.Request.Context(), key, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return obj, nil
+}
+
+func requireClient(c *gin.Context) (*kube.K8sClient, bool) {
+	k8sClient, ok := clientFromContext(c)
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "No cluster available. Please add a cluster first."})
+		return nil, false
+	}
+	return k8sClient, true
+}
+
+// RegisterRoutes 注册历史版本路由，需要放在集群中间件之后
+func (h *Handler) RegisterRoutes(group *gin.RouterGroup) {
+	historyGroup := group.Group("/history")
+	{
+		historyGroup.GET("", h.List)
+		historyGroup.GET("/:id", h.Get)
+		historyGroup.GET("/:id/diff", h.Diff)
+		historyGroup.POST("/:id/restore", h.Restore)
+	}
+}
@@ -0,0 +1,205 @@
+package history
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ysicing/nexus/pkg/kube"
+	"github.com/ysicing/nexus/pkg/middleware"
+	"github.com/ysicing/nexus/pkg/models"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// maxRevisionsPerObject 每个对象保留的历史版本数
+	maxRevisionsPerObject = 50
+	// lastAppliedAnnotation kubectl apply 保存的上次配置，内容与对象重复
+	lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+)
+
+// Recorder 在通过 Nexus 修改或删除对象之前保存对象的当前内容
+type Recorder struct {
+	repo models.RevisionRepository
+}
+
+// NewRecorder 创建历史版本记录器
+func NewRecorder(repo models.RevisionRepository) *Recorder {
+	return &Recorder{repo: repo}
+}
+
+// Middleware 返回记录历史版本的中间件，basePath 为资源路由组的路径
+//
+// 中间件只处理指定了对象名称的修改请求：在处理器执行前读取对象，处理器成功后保存。
+// 读取失败不影响请求本身。
+func (r *Recorder) Middleware(basePath string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if c.Param("name") == "" {
+			c.Next()
+			return
+		}
+
+		revision := r.snapshot(c, basePath)
+		c.Next()
+
+		if revision == nil || c.Writer.Status() < 200 || c.Writer.Status() >= 300 {
+			return
+		}
+		if err := r.repo.Create(revision, maxRevisionsPerObject); err != nil {
+			klog.Errorf("保存 %s %s/%s 的历史版本失败: %v", revision.Resource, revision.Namespace, revision.Name, err)
+		}
+	}
+}
+
+// snapshot 读取请求要修改的对象，对象不存在或无法识别时返回 nil
+func (r *Recorder) snapshot(c *gin.Context, basePath string) *models.ResourceRevisionModel {
+	k8sClient, ok := clientFromContext(c)
+	if !ok {
+		return nil
+	}
+
+	resourceName := c.Param("crd")
+	if resourceName == "" {
+		path := strings.TrimPrefix(strings.TrimPrefix(c.FullPath(), basePath), "/")
+		resourceName, _, _ = strings.Cut(path, "/")
+	}
+	resource, err := k8sClient.LookupResource(resourceName)
+	if err != nil {
+		// podmetrics 等不对应 API 资源的路由
+		klog.V(4).Infof("跳过 %s 的历史版本: %v", resourceName, err)
+		return nil
+	}
+
+	namespace := c.Param("namespace")
+	if !resource.Namespaced || namespace == "_all" {
+		namespace = ""
+	}
+	ref := models.ResourceRef{
+		ClusterID: c.GetString("clusterID"),
+		Resource:  resource.FullName(),
+		Namespace: namespace,
+		Name:      c.Param("name"),
+	}
+
+	revision, err := capture(c.Request.Context(), k8sClient, resource, ref, action(c), middleware.CurrentUser(c))
+	if err != nil {
+		klog.Warningf("读取 %s %s/%s 失败，不保存历史版本: %v", ref.Resource, ref.Namespace, ref.Name, err)
+		return nil
+	}
+	return revision
+}
+
+// Record 保存对象被修改之前的内容，用于没有对象名称路由参数、不经过 Middleware 的修改，
+// 例如一次应用多个对象的 apply。live 为修改前读取到的对象。
+func (r *Recorder) Record(c *gin.Context, k8sClient *kube.K8sClient, live *unstructured.Unstructured, action string) {
+	mapping, err := k8sClient.Client.RESTMapper().RESTMapping(live.GroupVersionKind().GroupKind(), live.GroupVersionKind().Version)
+	if err != nil {
+		klog.Warningf("无法识别 %s，不保存 %s/%s 的历史版本: %v", live.GroupVersionKind(), live.GetNamespace(), live.GetName(), err)
+		return
+	}
+	resource := mapping.Resource.Resource
+	if group := mapping.Resource.Group; group != "" {
+		resource += "." + group
+	}
+	ref := models.ResourceRef{
+		ClusterID: c.GetString("clusterID"),
+		Resource:  resource,
+		Namespace: live.GetNamespace(),
+		Name:      live.GetName(),
+	}
+
+	revision, err := newRevision(live, ref, action, middleware.CurrentUser(c))
+	if err == nil {
+		err = r.repo.Create(revision, maxRevisionsPerObject)
+	}
+	if err != nil {
+		klog.Errorf("保存 %s %s/%s 的历史版本失败: %v", ref.Resource, ref.Namespace, ref.Name, err)
+	}
+}
+
+// capture 读取对象的当前内容，对象不存在时返回 nil
+func capture(ctx context.Context, k8sClient *kube.K8sClient, resource *kube.APIResource, ref models.ResourceRef, action, username string) (*models.ResourceRevisionModel, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(resource.GroupVersionKind())
+	if err := k8sClient.Client.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return newRevision(obj, ref, action, username)
+}
+
+// newRevision 由对象的当前内容生成历史版本
+func newRevision(obj *unstructured.Unstructured, ref models.ResourceRef, action, username string) (*models.ResourceRevisionModel, error) {
+	content, err := cleanYAML(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ResourceRevisionModel{
+		ResourceRef:     ref,
+		APIVersion:      obj.GetAPIVersion(),
+		Kind:            obj.GetKind(),
+		ResourceVersion: obj.GetResourceVersion(),
+		Action:          action,
+		Username:        username,
+		Content:         models.EncryptedString(content),
+	}, nil
+}
+
+// action 根据请求方法和路径得到操作名称，例如 POST .../scale 为 scale
+func action(c *gin.Context) string {
+	switch c.Request.Method {
+	case http.MethodPut:
+		return "update"
+	case http.MethodPatch:
+		return "patch"
+	case http.MethodDelete:
+		return "delete"
+	}
+	path := c.FullPath()
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+// cleanYAML 去掉状态和由服务端维护的字段，得到可以重新应用的 YAML
+func cleanYAML(obj *unstructured.Unstructured) (string, error) {
+	obj = obj.DeepCopy()
+	obj.SetManagedFields(nil)
+	obj.SetUID("")
+	obj.SetResourceVersion("")
+	obj.SetGeneration(0)
+	obj.SetSelfLink("")
+	unstructured.RemoveNestedField(obj.Object, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(obj.Object, "metadata", "deletionTimestamp")
+	unstructured.RemoveNestedField(obj.Object, "metadata", "deletionGracePeriodSeconds")
+	unstructured.RemoveNestedField(obj.Object, "status")
+	if annotations := obj.GetAnnotations(); annotations != nil {
+		delete(annotations, lastAppliedAnnotation)
+		obj.SetAnnotations(annotations)
+	}
+
+	data, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func clientFromContext(c *gin.Context) (*kube.K8sClient, bool) {
+	value, exists := c.Get("k8sClient")
+	if !exists {
+		return nil, false
+	}
+	k8sClient, ok := value.(*kube.K8sClient)
+	return k8sClient, ok && k8sClient != nil && k8sClient.Client != nil
+}
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
@@ -26,12 +28,29 @@ import (
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 )
 
+// FieldManager is the field manager recorded for changes made through Nexus
+const FieldManager = "nexus"
+
 // K8sClient holds the Kubernetes client instances
 type K8sClient struct {
 	Client        client.Client
 	ClientSet     *kubernetes.Clientset
 	Configuration *rest.Config
 	MetricsClient *metricsclient.Clientset
+
+	// registry caches the resource types discovered from the cluster
+	registry *resourceRegistry
+
+	// cache is the informer cache behind Client, nil when caching is disabled
+	cache cache.Cache
+	// informed holds the resource types without a typed object whose
+	// informers were started for watches, keyed by GroupVersionKind
+	informed sync.Map
+	// dynamic serves watches that do not come from the informer cache
+	dynamic dynamic.Interface
+
+	// stop cancels the informer cache started for this client
+	stop context.CancelFunc
 }
 
 func init() {
@@ -86,7 +105,14 @@ func NewK8sClientFromConfig(config *rest.Config) (*K8sClient, error) {
 	_ = apiextensionsv1.AddToScheme(runtimeScheme)
 	_ = metricsv1.AddToScheme(runtimeScheme)
 
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
 	var c client.Client
+	var informerCache cache.Cache
+	var stop context.CancelFunc
 	if os.Getenv("DISABLE_CACHE") == "true" {
 		c, err = client.New(config, client.Options{
 			Scheme: runtimeScheme,
@@ -121,16 +147,20 @@ func NewK8sClientFromConfig(config *rest.Config) (*K8sClient, error) {
 			return nil, fmt.Errorf("failed to create field indexer for spec.nodeName: %w", err)
 		}
 
+		ctx, cancel := context.WithCancel(context.Background())
 		go func() {
-			if err := mgr.Start(context.Background()); err != nil {
+			if err := mgr.Start(ctx); err != nil {
 				fmt.Printf("Error starting manager: %v\n", err)
 			}
 		}()
-		if !mgr.GetCache().WaitForCacheSync(context.Background()) {
+		if !mgr.GetCache().WaitForCacheSync(ctx) {
+			cancel()
 			return nil, fmt.Errorf("failed to wait for cache sync")
 		}
+		stop = cancel
 		klog.Info("Cache sync completed successfully")
 		c = mgr.GetClient()
+		informerCache = mgr.GetCache()
 	}
 
 	return &K8sClient{
@@ -138,5 +168,17 @@ func NewK8sClientFromConfig(config *rest.Config) (*K8sClient, error) {
 		ClientSet:     clientset,
 		Configuration: config,
 		MetricsClient: metricsClient,
+		registry:      newResourceRegistry(clientset.Discovery()),
+		cache:         informerCache,
+		dynamic:       dynamicClient,
+		stop:          stop,
 	}, nil
 }
+
+// Stop releases the informer cache held by the client. The client must not be
+// used after Stop is called.
+func (k *K8sClient) Stop() {
+	if k != nil && k.stop != nil {
+		k.stop()
+	}
+}
@@ -0,0 +1,281 @@
+package kube
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// discoveryTTL is how long discovered resources are served before the
+	// cluster is asked again.
+	discoveryTTL = 5 * time.Minute
+	// discoveryMissRefresh is the minimum interval between refreshes triggered
+	// by looking up an unknown resource, e.g. right after a CRD is installed.
+	discoveryMissRefresh = 30 * time.Second
+)
+
+// APIResource describes a resource type served by a cluster, using the
+// preferred version of its group.
+type APIResource struct {
+	Name         string   `json:"name"`
+	SingularName string   `json:"singularName"`
+	ShortNames   []string `json:"shortNames,omitempty"`
+	Kind         string   `json:"kind"`
+	Group        string   `json:"group"`
+	Version      string   `json:"version"`
+	Namespaced   bool     `json:"namespaced"`
+	Verbs        []string `json:"verbs"`
+	Categories   []string `json:"categories,omitempty"`
+	// Subresources lists the subresources served for the resource, e.g. status or scale
+	Subresources []string `json:"subresources,omitempty"`
+	// Versions lists every version the resource is served in, Version first
+	Versions []string `json:"versions,omitempty"`
+
+	// served holds the resource as discovered in each of Versions
+	served map[string]APIResource
+}
+
+// InVersion returns the resource as served in version, with the kind, scope
+// and subresources of that version, or false when it is not served in version.
+func (r *APIResource) InVersion(version string) (*APIResource, bool) {
+	if version == r.Version {
+		resource := *r
+		return &resource, true
+	}
+	resource, ok := r.served[version]
+	if !ok {
+		return nil, false
+	}
+	resource.Versions = r.Versions
+	resource.served = r.served
+	return &resource, true
+}
+
+// GroupVersionKind returns the kind of the resource's objects.
+func (r *APIResource) GroupVersionKind() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: r.Group, Version: r.Version, Kind: r.Kind}
+}
+
+// GroupVersionResource returns the resource's REST path components.
+func (r *APIResource) GroupVersionResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: r.Group, Version: r.Version, Resource: r.Name}
+}
+
+// FullName returns the resource name qualified with its group, e.g.
+// horizontalpodautoscalers.autoscaling. Core resources have no group suffix.
+func (r *APIResource) FullName() string {
+	if r.Group == "" {
+		return r.Name
+	}
+	return r.Name + "." + r.Group
+}
+
+// HasVerb reports whether the resource supports the verb.
+func (r *APIResource) HasVerb(verb string) bool {
+	for _, v := range r.Verbs {
+		if v == verb {
+			return true
+		}
+	}
+	return false
+}
+
+// HasSubresource reports whether the resource serves the subresource.
+func (r *APIResource) HasSubresource(name string) bool {
+	for _, subresource := range r.Subresources {
+		if subresource == name {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether name refers to this resource the way kubectl
+// resolves it: plural, singular or short name, optionally qualified with the
+// group.
+func (r *APIResource) matches(name string) bool {
+	name = strings.ToLower(name)
+	candidates := append([]string{r.Name, r.SingularName}, r.ShortNames...)
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		if name == candidate || (r.Group != "" && name == candidate+"."+r.Group) {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceRegistry caches the resources discovered from one cluster.
+type resourceRegistry struct {
+	discovery discovery.DiscoveryInterface
+
+	mu        sync.Mutex
+	resources []APIResource
+	loadedAt  time.Time
+}
+
+func newResourceRegistry(client discovery.DiscoveryInterface) *resourceRegistry {
+	return &resourceRegistry{discovery: client}
+}
+
+// list returns the cached resources, refreshing them when older than maxAge.
+func (r *resourceRegistry) list(maxAge time.Duration) ([]APIResource, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.loadedAt.IsZero() && time.Since(r.loadedAt) < maxAge {
+		return r.resources, nil
+	}
+
+	resources, err := r.load()
+	if err != nil {
+		if !r.loadedAt.IsZero() {
+			klog.Warningf("failed to refresh API resources, using cached list: %v", err)
+			return r.resources, nil
+		}
+		return nil, err
+	}
+	r.resources = resources
+	r.loadedAt = time.Now()
+	return resources, nil
+}
+
+func (r *resourceRegistry) load() ([]APIResource, error) {
+	groups, lists, err := r.discovery.ServerGroupsAndResources()
+	if err != nil {
+		// An unavailable aggregated API must not hide every other resource.
+		if !discovery.IsGroupDiscoveryFailedError(err) || len(lists) == 0 {
+			return nil, fmt.Errorf("failed to discover API resources: %w", err)
+		}
+		klog.Warningf("partial API discovery: %v", err)
+	}
+	byGroupVersion := make(map[string]*metav1.APIResourceList, len(lists))
+	for _, list := range lists {
+		if list != nil {
+			byGroupVersion[list.GroupVersion] = list
+		}
+	}
+
+	var resources []APIResource
+	for _, group := range groups {
+		// Each resource is served in the preferred version of its group,
+		// otherwise in the first version that has it, like kubectl does.
+		versions := make([]metav1.GroupVersionForDiscovery, 0, len(group.Versions))
+		versions = append(versions, group.PreferredVersion)
+		for _, version := range group.Versions {
+			if version.Version != group.PreferredVersion.Version {
+				versions = append(versions, version)
+			}
+		}
+
+		index := map[string]int{}
+		for _, version := range versions {
+			list, ok := byGroupVersion[version.GroupVersion]
+			if !ok {
+				continue
+			}
+			gv := schema.GroupVersion{Group: group.Name, Version: version.Version}
+			subresources := map[string][]string{}
+			for _, res := range list.APIResources {
+				if name, subresource, ok := strings.Cut(res.Name, "/"); ok {
+					subresources[name] = append(subresources[name], subresource)
+				}
+			}
+			for _, res := range list.APIResources {
+				// Subresources such as pods/log are served by dedicated routes.
+				if strings.Contains(res.Name, "/") {
+					continue
+				}
+				resource := newAPIResource(gv, res)
+				resource.Subresources = subresources[res.Name]
+
+				i, seen := index[res.Name]
+				if !seen {
+					i = len(resources)
+					index[res.Name] = i
+					resources = append(resources, resource)
+					resources[i].served = map[string]APIResource{}
+				}
+				resources[i].Versions = append(resources[i].Versions, gv.Version)
+				resources[i].served[gv.Version] = resource
+			}
+		}
+	}
+
+	// Core resources come first so that an ambiguous name such as "events"
+	// resolves the way kubectl does.
+	sort.SliceStable(resources, func(i, j int) bool {
+		return resources[i].Group == "" && resources[j].Group != ""
+	})
+	return resources, nil
+}
+
+func newAPIResource(gv schema.GroupVersion, res metav1.APIResource) APIResource {
+	singular := res.SingularName
+	if singular == "" {
+		singular = strings.ToLower(res.Kind)
+	}
+	return APIResource{
+		Name:         res.Name,
+		SingularName: singular,
+		ShortNames:   res.ShortNames,
+		Kind:         res.Kind,
+		Group:        gv.Group,
+		Version:      gv.Version,
+		Namespaced:   res.Namespaced,
+		Verbs:        res.Verbs,
+		Categories:   res.Categories,
+	}
+}
+
+// APIResources returns the resources served by the cluster.
+func (k *K8sClient) APIResources() ([]APIResource, error) {
+	if k.registry == nil {
+		return nil, fmt.Errorf("API discovery is not available")
+	}
+	return k.registry.list(discoveryTTL)
+}
+
+// LookupResource resolves a resource by plural, singular or short name,
+// optionally qualified with its group (e.g. hpa, deployments.apps or
+// certificates.cert-manager.io). Unknown names trigger a rediscovery so that
+// newly installed CRDs are found without waiting for the cache to expire.
+func (k *K8sClient) LookupResource(name string) (*APIResource, error) {
+	if k.registry == nil {
+		return nil, fmt.Errorf("API discovery is not available")
+	}
+
+	for _, maxAge := range []time.Duration{discoveryTTL, discoveryMissRefresh} {
+		resources, err := k.registry.list(maxAge)
+		if err != nil {
+			return nil, err
+		}
+		for i := range resources {
+			if resources[i].matches(name) {
+				resource := resources[i]
+				return &resource, nil
+			}
+		}
+	}
+	return nil, &ResourceNotFoundError{Name: name}
+}
+
+// ResourceNotFoundError is returned when the cluster serves no resource with
+// the requested name.
+type ResourceNotFoundError struct {
+	Name string
+}
+
+func (e *ResourceNotFoundError) Error() string {
+	return fmt.Sprintf("the server doesn't have a resource type %q", e.Name)
+}
@@ -0,0 +1,111 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// tableAccept asks the API server to convert the response to a Table, the
+// format kubectl get prints
+const tableAccept = "application/json;as=Table;v=v1;g=meta.k8s.io,application/json;as=Table;v=v1beta1;g=meta.k8s.io,application/json"
+
+// TableOptions select the objects of a Table request
+type TableOptions struct {
+	// Namespace limits a list to one namespace; empty lists all namespaces
+	Namespace string
+	// Name requests a single object instead of a list
+	Name          string
+	LabelSelector string
+	FieldSelector string
+	Limit         int64
+	Continue      string
+	// Wide keeps the columns kubectl only shows with -o wide
+	Wide bool
+}
+
+// Table returns objects of a resource type as rows with the columns kubectl
+// shows, as computed by the API server. This includes the additional printer
+// columns of custom resources. Each row carries the object metadata.
+func (k *K8sClient) Table(ctx context.Context, resource *APIResource, opts TableOptions) (*metav1.Table, error) {
+	if k.ClientSet == nil {
+		return nil, fmt.Errorf("no cluster client available")
+	}
+
+	segments := []string{"/api"}
+	if resource.Group != "" {
+		segments = []string{"/apis", resource.Group}
+	}
+	segments = append(segments, resource.Version)
+	if resource.Namespaced && opts.Namespace != "" {
+		segments = append(segments, "namespaces", opts.Namespace)
+	}
+	segments = append(segments, resource.Name)
+	if opts.Name != "" {
+		segments = append(segments, opts.Name)
+	}
+
+	request := k.ClientSet.CoreV1().RESTClient().Get().
+		AbsPath(path.Join(segments...)).
+		SetHeader("Accept", tableAccept).
+		Param("includeObject", string(metav1.IncludeMetadata))
+	if opts.Name == "" {
+		if opts.LabelSelector != "" {
+			request.Param("labelSelector", opts.LabelSelector)
+		}
+		if opts.FieldSelector != "" {
+			request.Param("fieldSelector", opts.FieldSelector)
+		}
+		if opts.Limit > 0 {
+			request.Param("limit", strconv.FormatInt(opts.Limit, 10))
+		}
+		if opts.Continue != "" {
+			request.Param("continue", opts.Continue)
+		}
+	}
+
+	// The clientset scheme does not know meta.k8s.io/v1 Table
+	data, err := request.DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	table := &metav1.Table{}
+	if err := json.Unmarshal(data, table); err != nil {
+		return nil, err
+	}
+	if table.Kind != "Table" {
+		return nil, fmt.Errorf("the API server did not return a table for %s", resource.FullName())
+	}
+	if !opts.Wide {
+		narrowTable(table)
+	}
+	return table, nil
+}
+
+// narrowTable drops the columns with a priority above 0, which kubectl only
+// shows in wide output
+func narrowTable(table *metav1.Table) {
+	keep := make([]int, 0, len(table.ColumnDefinitions))
+	columns := table.ColumnDefinitions[:0]
+	for i, column := range table.ColumnDefinitions {
+		if column.Priority == 0 {
+			keep = append(keep, i)
+			columns = append(columns, column)
+		}
+	}
+	table.ColumnDefinitions = columns
+
+	for i := range table.Rows {
+		cells := make([]interface{}, 0, len(keep))
+		for _, index := range keep {
+			if index < len(table.Rows[i].Cells) {
+				cells = append(cells, table.Rows[i].Cells[index])
+			}
+		}
+		table.Rows[i].Cells = cells
+	}
+}
@@ -0,0 +1,282 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Watch event types, as in the Kubernetes watch API
+const (
+	WatchAdded    = string(watch.Added)
+	WatchModified = string(watch.Modified)
+	WatchDeleted  = string(watch.Deleted)
+	WatchBookmark = string(watch.Bookmark)
+	WatchError    = string(watch.Error)
+)
+
+// WatchEvent is one change of a watched resource type
+type WatchEvent struct {
+	Type   string         `json:"type"`
+	Object runtime.Object `json:"object"`
+}
+
+// ResourceWatch delivers the objects of a resource type and their changes
+type ResourceWatch struct {
+	// Initial holds the current objects. It is nil when the watch resumed
+	// from a resourceVersion and only replays the changes since then.
+	Initial []runtime.Object
+	// ResourceVersion is the version of the initial list, if known
+	ResourceVersion string
+	// Events delivers the changes and is closed when the watch ends. An
+	// ERROR event with a 410 status means the client must start over
+	// without a resourceVersion.
+	Events <-chan WatchEvent
+
+	stop context.CancelFunc
+}
+
+// Stop ends the watch
+func (w *ResourceWatch) Stop() {
+	w.stop()
+}
+
+// Watch watches a resource type in namespace, or in all namespaces when
+// namespace is empty.
+//
+// Without resourceVersion the current objects and the following changes come
+// from the informer cache; types the cache does not hold yet get a dynamic
+// informer. With resourceVersion the changes since that version are replayed
+// by an API server watch. When the version is too old to replay, the watch
+// starts over with the current objects.
+func (k *K8sClient) Watch(ctx context.Context, resource *APIResource, namespace, resourceVersion string) (*ResourceWatch, error) {
+	if !resource.HasVerb("watch") {
+		return nil, fmt.Errorf("resource %s does not support watch", resource.FullName())
+	}
+	if !resource.Namespaced {
+		namespace = ""
+	}
+
+	if resourceVersion != "" {
+		w, err := k.watchAPI(ctx, resource, namespace, resourceVersion)
+		if err == nil || !isExpired(err) {
+			return w, err
+		}
+	}
+	if k.cache == nil {
+		return k.listAndWatchAPI(ctx, resource, namespace)
+	}
+	return k.watchInformer(ctx, resource, namespace)
+}
+
+func isExpired(err error) bool {
+	return apierrors.IsResourceExpired(err) || apierrors.IsGone(err)
+}
+
+// informerFor returns the shared informer of a resource type, starting one if needed
+func (k *K8sClient) informerFor(ctx context.Context, resource *APIResource) (cache.Informer, error) {
+	gvk := resource.GroupVersionKind()
+	if k.Client.Scheme().Recognizes(gvk) {
+		return k.cache.GetInformerForKind(ctx, gvk)
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	informer, err := k.cache.GetInformer(ctx, obj)
+	if err == nil {
+		k.informed.Store(gvk, *resource)
+	}
+	return informer, err
+}
+
+// InformedResources returns the resource types without a typed object, such
+// as custom resources, whose informers were started for watches
+func (k *K8sClient) InformedResources() []APIResource {
+	var resources []APIResource
+	k.informed.Range(func(_, value interface{}) bool {
+		resources = append(resources, value.(APIResource))
+		return true
+	})
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].FullName() < resources[j].FullName()
+	})
+	return resources
+}
+
+// ListInformed lists the objects of a resource type returned by
+// InformedResources from the informer cache, without contacting the API server
+func (k *K8sClient) ListInformed(ctx context.Context, resource *APIResource) ([]unstructured.Unstructured, error) {
+	if k.cache == nil {
+		return nil, fmt.Errorf("informer cache is disabled")
+	}
+	gvk := resource.GroupVersionKind()
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := k.cache.List(ctx, list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+type informerNotification struct {
+	event   WatchEvent
+	initial bool
+}
+
+func (k *K8sClient) watchInformer(ctx context.Context, resource *APIResource, namespace string) (*ResourceWatch, error) {
+	informer, err := k.informerFor(ctx, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	notifications := make(chan informerNotification, 256)
+	notify := func(eventType string, obj interface{}, initial bool) {
+		if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		object, ok := obj.(client.Object)
+		if !ok || (namespace != "" && object.GetNamespace() != namespace) {
+			return
+		}
+		select {
+		case notifications <- informerNotification{event: WatchEvent{Type: eventType, Object: object}, initial: initial}:
+		case <-ctx.Done():
+		}
+	}
+	// A handler added to a running informer first receives every cached
+	// object flagged as initial, then the changes after them
+	registration, err := informer.AddEventHandler(toolscache.ResourceEventHandlerDetailedFuncs{
+		AddFunc:    func(obj interface{}, initial bool) { notify(WatchAdded, obj, initial) },
+		UpdateFunc: func(_, obj interface{}) { notify(WatchModified, obj, false) },
+		DeleteFunc: func(obj interface{}) { notify(WatchDeleted, obj, false) },
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	stop := func() {
+		cancel()
+		_ = informer.RemoveEventHandler(registration)
+	}
+
+	initial := []runtime.Object{}
+	var first *WatchEvent
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+collect:
+	for {
+		select {
+		case notification := <-notifications:
+			if !notification.initial {
+				first = &notification.event
+				break collect
+			}
+			initial = append(initial, notification.event.Object)
+		case <-ticker.C:
+			// Once the registration has synced every initial object is queued
+			if registration.HasSynced() && len(notifications) == 0 {
+				break collect
+			}
+		case <-ctx.Done():
+			stop()
+			return nil, ctx.Err()
+		}
+	}
+
+	events := make(chan WatchEvent)
+	go func() {
+		defer close(events)
+		defer stop()
+		if first != nil {
+			select {
+			case events <- *first:
+			case <-ctx.Done():
+				return
+			}
+		}
+		for {
+			select {
+			case notification := <-notifications:
+				select {
+				case events <- notification.event:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	w := &ResourceWatch{Initial: initial, Events: events, stop: stop}
+	if versioned, ok := informer.(interface{ LastSyncResourceVersion() string }); ok {
+		w.ResourceVersion = versioned.LastSyncResourceVersion()
+	}
+	return w, nil
+}
+
+// listAndWatchAPI lists and watches through the API server, for clients without an informer cache
+func (k *K8sClient) listAndWatchAPI(ctx context.Context, resource *APIResource, namespace string) (*ResourceWatch, error) {
+	list, err := k.dynamic.Resource(resource.GroupVersionResource()).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	w, err := k.watchAPI(ctx, resource, namespace, list.GetResourceVersion())
+	if err != nil {
+		return nil, err
+	}
+	w.Initial = make([]runtime.Object, 0, len(list.Items))
+	for i := range list.Items {
+		w.Initial = append(w.Initial, &list.Items[i])
+	}
+	w.ResourceVersion = list.GetResourceVersion()
+	return w, nil
+}
+
+// watchAPI watches through the API server, starting after resourceVersion
+func (k *K8sClient) watchAPI(ctx context.Context, resource *APIResource, namespace, resourceVersion string) (*ResourceWatch, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	watcher, err := k.dynamic.Resource(resource.GroupVersionResource()).Namespace(namespace).Watch(ctx, metav1.ListOptions{
+		ResourceVersion:     resourceVersion,
+		AllowWatchBookmarks: true,
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	events := make(chan WatchEvent)
+	go func() {
+		defer close(events)
+		defer watcher.Stop()
+		for {
+			select {
+			case event, ok := <-watcher.ResultChan():
+				if !ok {
+					return
+				}
+				select {
+				case events <- WatchEvent{Type: string(event.Type), Object: event.Object}:
+				case <-ctx.Done():
+					return
+				}
+				if event.Type == watch.Error {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return &ResourceWatch{ResourceVersion: resourceVersion, Events: events, stop: cancel}, nil
+}
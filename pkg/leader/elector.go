@@ -0,0 +1,158 @@
+package leader
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ysicing/nexus/pkg/models"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DefaultLeaseDuration 租约有效期，主副本异常退出后其他副本最多等待这么久接管
+	DefaultLeaseDuration = 15 * time.Second
+	// DefaultRenewInterval 续约间隔
+	DefaultRenewInterval = 5 * time.Second
+)
+
+// Elector 基于数据库租约的选主
+//
+// 只有持有租约的副本执行健康检查、kubeconfig 扫描、清理等单例任务。
+// 续约失败且超过租约有效期后主动放弃主副本身份，避免与新的主副本同时工作。
+type Elector struct {
+	repo          models.LeaseRepository
+	name          string
+	identity      string
+	leaseDuration time.Duration
+	renewInterval time.Duration
+
+	mu               sync.Mutex
+	leader           bool
+	lastRenew        time.Time
+	onStartedLeading []func()
+	onStoppedLeading []func()
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+}
+
+// NewElector 创建选主器，name 为租约名称，identity 为当前副本的唯一标识
+func NewElector(repo models.LeaseRepository, name, identity string) *Elector {
+	return &Elector{
+		repo:          repo,
+		name:          name,
+		identity:      identity,
+		leaseDuration: DefaultLeaseDuration,
+		renewInterval: DefaultRenewInterval,
+		stopCh:        make(chan struct{}),
+	}
+}
+
+// Identity 当前副本的标识
+func (e *Elector) Identity() string {
+	return e.identity
+}
+
+// OnStartedLeading 注册成为主副本时的回调
+//
+// 回调在选主协程中同步执行，续约会等待回调返回，因此回调不能阻塞，
+// 耗时的工作需要放到单独的协程中，并在 OnStoppedLeading 中取消。
+func (e *Elector) OnStartedLeading(f func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onStartedLeading = append(e.onStartedLeading, f)
+}
+
+// OnStoppedLeading 注册失去主副本身份（包括 Stop）时的回调，回调同样不能阻塞
+func (e *Elector) OnStoppedLeading(f func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onStoppedLeading = append(e.onStoppedLeading, f)
+}
+
+// IsLeader 当前副本是否为主副本
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+// TryAcquireOrRenew 尝试获取或续约一次租约，返回当前是否为主副本
+func (e *Elector) TryAcquireOrRenew() bool {
+	acquired, err := e.repo.TryAcquire(e.name, e.identity, e.leaseDuration)
+	now := time.Now()
+
+	e.mu.Lock()
+	wasLeader := e.leader
+	switch {
+	case err == nil:
+		if acquired {
+			e.lastRenew = now
+		}
+		e.leader = acquired
+	case wasLeader && now.Sub(e.lastRenew) < e.leaseDuration:
+		// 数据库暂时不可用，租约未过期前保持主副本身份
+		klog.Warningf("Failed to renew lease %s: %v", e.name, err)
+	default:
+		klog.Warningf("Failed to acquire lease %s: %v", e.name, err)
+		e.leader = false
+	}
+	isLeader := e.leader
+	var callbacks []func()
+	if isLeader && !wasLeader {
+		callbacks = append(callbacks, e.onStartedLeading...)
+	} else if !isLeader && wasLeader {
+		callbacks = append(callbacks, e.onStoppedLeading...)
+	}
+	e.mu.Unlock()
+
+	if isLeader != wasLeader {
+		if isLeader {
+			klog.Infof("Replica %s became leader of %s", e.identity, e.name)
+		} else {
+			klog.Warningf("Replica %s lost leadership of %s", e.identity, e.name)
+		}
+	}
+	for _, f := range callbacks {
+		f()
+	}
+
+	return isLeader
+}
+
+// Run 定期获取或续约租约，直到 Stop 被调用
+func (e *Elector) Run() {
+	ticker := time.NewTicker(e.renewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.TryAcquireOrRenew()
+		case <-e.stopCh:
+			return
+		}
+	}
+}
+
+// Stop 停止选主，是主副本时释放租约，使其他副本可以立即接管
+func (e *Elector) Stop() {
+	e.stopOnce.Do(func() {
+		close(e.stopCh)
+
+		e.mu.Lock()
+		wasLeader := e.leader
+		e.leader = false
+		callbacks := append([]func(){}, e.onStoppedLeading...)
+		e.mu.Unlock()
+
+		if wasLeader {
+			for _, f := range callbacks {
+				f()
+			}
+			if err := e.repo.Release(e.name, e.identity); err != nil {
+				klog.Warningf("Failed to release lease %s: %v", e.name, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ysicing/nexus/pkg/common"
+)
+
+// RequireAdmin only lets administrators through. It must run after the auth
+// middleware. Users listed in ADMIN_USERS are administrators; when it is not
+// set, the password login user is. Without any login method every request is
+// treated as coming from an administrator.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !IsAdmin(CurrentUser(c)) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "Administrator permission is required",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// IsAdmin reports whether the user is allowed to call admin APIs.
+func IsAdmin(username string) bool {
+	if !common.Settings().OAuthEnabled && !common.PasswordLoginEnabled {
+		return true
+	}
+	if username == "" || username == "-" {
+		return false
+	}
+
+	if common.AdminUsers == "" {
+		return common.PasswordLoginEnabled && username == common.KiteUsername
+	}
+	for admin := range strings.SplitSeq(common.AdminUsers, ",") {
+		admin = strings.TrimSpace(admin)
+		if admin == "*" || admin == username {
+			return true
+		}
+	}
+	return false
+}
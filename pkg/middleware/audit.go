@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog/v2"
+)
+
+// auditAlwaysPaths lists route patterns whose requests are audited even when
+// they are read-only, e.g. raw Kubernetes API calls forwarded by the proxy.
+var auditAlwaysPaths = []string{
+	"/proxy/",
+}
+
+// CurrentUser returns the name of the user stored in the context by the auth
+// middleware, or "-" when the request is not authenticated.
+func CurrentUser(c *gin.Context) string {
+	return userFromKeys(c.Keys)
+}
+
+func userFromKeys(keys map[string]any) string {
+	user, ok := keys["user"].(gin.H)
+	if !ok {
+		return "-"
+	}
+	if name, _ := user["username"].(string); name != "" {
+		return name
+	}
+	if name, _ := user["name"].(string); name != "" {
+		return name
+	}
+	return "-"
+}
+
+// Audit logs every write request, and every request to an always-audited
+// route, together with the user, target cluster and response status.
+func Audit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if !shouldAudit(c) {
+			return
+		}
+
+		clusterID := c.Param("id")
+		if clusterID == "" {
+			clusterID = c.Query("cluster")
+		}
+		if clusterID == "" {
+			clusterID = c.GetHeader("X-Cluster-ID")
+		}
+		if clusterID == "" {
+			clusterID = "-"
+		}
+
+		klog.Infof("audit: user=%s cluster=%s method=%s path=%s status=%d client=%s",
+			CurrentUser(c),
+			clusterID,
+			c.Request.Method,
+			c.Request.URL.Path,
+			c.Writer.Status(),
+			c.ClientIP(),
+		)
+	}
+}
+
+func shouldAudit(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		return true
+	}
+
+	fullPath := c.FullPath()
+	for _, path := range auditAlwaysPaths {
+		if strings.Contains(fullPath, path) {
+			return true
+		}
+	}
+	return false
+}
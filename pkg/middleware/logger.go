@@ -22,14 +22,7 @@ func Logger() gin.HandlerFunc {
 					return ""
 				}
 			}
-			user, ok := param.Keys["user"].(gin.H)
-			name := "-"
-			if ok {
-				name = user["username"].(string)
-				if user["username"] == "" {
-					name = user["name"].(string)
-				}
-			}
+			name := userFromKeys(param.Keys)
 
 			return fmt.Sprintf("%s - %s \"%s %s\" %d %s %s\n",
 				param.ClientIP,
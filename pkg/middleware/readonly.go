@@ -2,21 +2,90 @@ package middleware
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ysicing/nexus/pkg/common"
+	"k8s.io/apimachinery/pkg/util/httpstream"
 )
 
+// readonlyExemptPaths are write requests allowed in read-only mode: changing
+// settings must stay possible so that read-only mode can be turned off again,
+// exporting a backup does not modify anything, and personal preferences only
+// affect the user who changes them.
+var readonlyExemptPaths = []string{
+	"/api/v1/admin/settings",
+	"/api/v1/admin/backup/export",
+	"/api/v1/preferences",
+}
+
+// clusterProxyPrefix is the path prefix of the Kubernetes API proxy, see
+// /api/v1/clusters/:id/proxy/*path
+const clusterProxyPrefix = "/api/v1/clusters/"
+
+// proxyStreamSubresources open interactive streams into pods. kubectl sends
+// them as GET upgrade requests, so the method alone does not reveal them.
+var proxyStreamSubresources = map[string]bool{
+	"exec":        true,
+	"attach":      true,
+	"portforward": true,
+}
+
 func ReadonlyMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if common.Readonly {
+		if common.Settings().Readonly && !readonlyExempt(c.Request.URL.Path) {
 			if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
 				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
 					"error": "Server is in read-only mode, write operations are not allowed",
 				})
 				return
 			}
+			if proxyStreamRequest(c.Request) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"error": "Server is in read-only mode, exec, attach and port-forward through the proxy are not allowed",
+				})
+				return
+			}
 		}
 		c.Next()
 	}
 }
+
+// proxyStreamRequest reports whether a request to the Kubernetes API proxy is
+// an upgrade request or targets the exec, attach or portforward subresource
+func proxyStreamRequest(req *http.Request) bool {
+	path, ok := strings.CutPrefix(req.URL.Path, clusterProxyPrefix)
+	if !ok {
+		return false
+	}
+	_, path, ok = strings.Cut(path, "/proxy/")
+	if !ok {
+		return false
+	}
+	if httpstream.IsUpgradeRequest(req) {
+		return true
+	}
+	path = strings.TrimSuffix(path, "/")
+	return proxyStreamSubresources[path[strings.LastIndex(path, "/")+1:]]
+}
+
+func readonlyExempt(path string) bool {
+	for _, prefix := range readonlyExemptPaths {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return proxyTokenPath(path)
+}
+
+// proxyTokenPath reports whether path issues or revokes a proxy token, see
+// /api/v1/clusters/:id/kubeconfig and /api/v1/clusters/:id/proxy-tokens. The
+// proxy itself stays read-only, so the tokens are allowed in read-only mode.
+func proxyTokenPath(path string) bool {
+	rest, ok := strings.CutPrefix(path, clusterProxyPrefix)
+	if !ok {
+		return false
+	}
+	id, sub, ok := strings.Cut(rest, "/")
+	return ok && id != "trash" && (sub == "kubeconfig" || sub == "proxy-tokens" || strings.HasPrefix(sub, "proxy-tokens/"))
+}
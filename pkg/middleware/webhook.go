@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ysicing/nexus/pkg/common"
+)
+
+// WebhookAuth checks the webhook basic auth credentials. Unlike gin.BasicAuth
+// it reads the credentials on every request, so changes made through the
+// settings API apply immediately.
+func WebhookAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		settings := common.Settings()
+		username, password, ok := c.Request.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(username), []byte(settings.WebhookUsername)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(password), []byte(settings.WebhookPassword)) != 1 {
+			c.Header("WWW-Authenticate", `Basic realm="Authorization Required"`)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Set(gin.AuthUserKey, username)
+		c.Next()
+	}
+}
@@ -1,9 +1,13 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
 )
 
 // ClusterModel 集群信息数据库模型
@@ -15,19 +19,27 @@ type ClusterModel struct {
 	Version     string `gorm:"size:50" json:"version,omitempty"`
 	Status      string `gorm:"size:20;default:unknown" json:"status"`
 	Context     string `gorm:"size:255" json:"context,omitempty"`
-	Labels      string `gorm:"type:text" json:"labels,omitempty"` // JSON 字符串存储
 	IsDefault   bool   `gorm:"default:false" json:"isDefault"`
 	IsInCluster bool   `gorm:"default:false" json:"isInCluster"`
 
+	// Labels 保存在 cluster_labels 表中，由仓库负责读写
+	Labels map[string]string `gorm:"-" json:"labels,omitempty"`
+
 	// Kubeconfig 相关字段
-	KubeconfigPath    string `gorm:"size:500" json:"kubeconfigPath,omitempty"`
-	KubeconfigContent string `gorm:"type:text" json:"kubeconfigContent,omitempty"`
+	KubeconfigPath    string          `gorm:"size:500" json:"kubeconfigPath,omitempty"`
+	KubeconfigContent EncryptedString `gorm:"type:text" json:"kubeconfigContent,omitempty"` // 加密存储
 
 	// Prometheus 相关字段
-	PrometheusURL      string `gorm:"size:500" json:"prometheusUrl,omitempty"`
-	PrometheusUsername string `gorm:"size:255" json:"prometheusUsername,omitempty"`
-	PrometheusPassword string `gorm:"size:255" json:"prometheusPassword,omitempty"`
-	PrometheusEnabled  bool   `gorm:"default:false" json:"prometheusEnabled"`
+	PrometheusURL      string          `gorm:"size:500" json:"prometheusUrl,omitempty"`
+	PrometheusUsername string          `gorm:"size:255" json:"prometheusUsername,omitempty"`
+	PrometheusPassword EncryptedString `gorm:"size:1024" json:"prometheusPassword,omitempty"` // 加密存储
+	PrometheusEnabled  bool            `gorm:"default:false" json:"prometheusEnabled"`
+
+	// 连接方式（HTTP/SOCKS5 代理、SSH 跳板机），JSON 字符串加密存储
+	TunnelConfig EncryptedString `gorm:"type:text" json:"-"`
+
+	// 从 kubeconfig 文件加载时对应上下文内容的摘要，用于多副本之间检测文件变化
+	SourceFingerprint string `gorm:"size:64" json:"-"`
 
 	// 健康检查相关
 	LastCheck time.Time `json:"lastCheck"`
@@ -43,6 +55,19 @@ func (ClusterModel) TableName() string {
 	return "clusters"
 }
 
+// ClusterLabelModel 集群标签，每个标签一行，用于按标签选择器查询集群
+type ClusterLabelModel struct {
+	ClusterID string `gorm:"primaryKey;size:255" json:"clusterId"`
+	// Name 标签键，最长为 253 字符的前缀加 63 字符的名称
+	Name  string `gorm:"primaryKey;size:317;index:idx_cluster_labels_name_value,priority:1" json:"name"`
+	Value string `gorm:"size:63;index:idx_cluster_labels_name_value,priority:2" json:"value"`
+}
+
+// TableName 指定表名
+func (ClusterLabelModel) TableName() string {
+	return "cluster_labels"
+}
+
 // ClusterRepository 集群信息仓库接口
 type ClusterRepository interface {
 	// 基础 CRUD
@@ -50,6 +75,8 @@ type ClusterRepository interface {
 	GetByID(id string) (*ClusterModel, error)
 	GetAll() ([]*ClusterModel, error)
 	Update(cluster *ClusterModel) error
+	// Save 保存集群，覆盖同 ID 的记录，包括已软删除的记录
+	Save(cluster *ClusterModel) error
 	Delete(id string) error
 
 	// 业务方法
@@ -60,11 +87,23 @@ type ClusterRepository interface {
 
 	// 批量操作
 	CreateBatch(clusters []*ClusterModel) error
-	GetByLabels(labels map[string]string) ([]*ClusterModel, error)
+
+	// GetIDsBySelector 根据标签选择器获取集群 ID，支持 Kubernetes 标签选择器的全部语法
+	GetIDsBySelector(selector labels.Selector) ([]string, error)
 
 	// Prometheus 相关方法
 	UpdatePrometheusConfig(id string, url, username, password string, enabled bool) error
 	GetClustersWithPrometheus() ([]*ClusterModel, error)
+
+	// 健康检查
+	UpdateHealth(id, status, version string, lastCheck time.Time) error
+
+	// 回收站（已软删除的集群）
+	GetDeleted() ([]*ClusterModel, error)
+	GetDeletedByID(id string) (*ClusterModel, error)
+	Restore(id string) error
+	Purge(id string) error
+	PurgeDeletedBefore(before time.Time) ([]string, error)
 }
 
 // ClusterRepositoryImpl 集群信息仓库实现
@@ -79,44 +118,52 @@ func NewClusterRepository(db *gorm.DB) ClusterRepository {
 
 // Create 创建集群
 func (r *ClusterRepositoryImpl) Create(cluster *ClusterModel) error {
-	return r.db.Create(cluster).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(cluster).Error; err != nil {
+			return err
+		}
+		return replaceLabels(tx, cluster.ID, cluster.Labels)
+	})
 }
 
 // GetByID 根据ID获取集群
 func (r *ClusterRepositoryImpl) GetByID(id string) (*ClusterModel, error) {
-	var cluster ClusterModel
-	err := r.db.Where("id = ?", id).First(&cluster).Error
-	if err != nil {
-		return nil, err
-	}
-	return &cluster, nil
+	return r.first(r.db.Where("id = ?", id))
 }
 
 // GetAll 获取所有集群
 func (r *ClusterRepositoryImpl) GetAll() ([]*ClusterModel, error) {
-	var clusters []*ClusterModel
-	err := r.db.Find(&clusters).Error
-	return clusters, err
+	return r.find(r.db)
 }
 
 // Update 更新集群
 func (r *ClusterRepositoryImpl) Update(cluster *ClusterModel) error {
-	return r.db.Save(cluster).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(cluster).Error; err != nil {
+			return err
+		}
+		return replaceLabels(tx, cluster.ID, cluster.Labels)
+	})
 }
 
-// Delete 删除集群
+// Save 保存集群，覆盖同 ID 的记录，包括已软删除的记录
+func (r *ClusterRepositoryImpl) Save(cluster *ClusterModel) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Save(cluster).Error; err != nil {
+			return err
+		}
+		return replaceLabels(tx, cluster.ID, cluster.Labels)
+	})
+}
+
+// Delete 删除集群，标签保留到集群从回收站中永久删除
 func (r *ClusterRepositoryImpl) Delete(id string) error {
 	return r.db.Where("id = ?", id).Delete(&ClusterModel{}).Error
 }
 
 // GetDefault 获取默认集群
 func (r *ClusterRepositoryImpl) GetDefault() (*ClusterModel, error) {
-	var cluster ClusterModel
-	err := r.db.Where("is_default = ?", true).First(&cluster).Error
-	if err != nil {
-		return nil, err
-	}
-	return &cluster, nil
+	return r.first(r.db.Where("is_default = ?", true))
 }
 
 // SetDefault 设置默认集群
@@ -133,41 +180,84 @@ func (r *ClusterRepositoryImpl) SetDefault(id string) error {
 
 // GetByContext 根据上下文获取集群
 func (r *ClusterRepositoryImpl) GetByContext(context string) (*ClusterModel, error) {
-	var cluster ClusterModel
-	err := r.db.Where("context = ?", context).First(&cluster).Error
-	if err != nil {
-		return nil, err
-	}
-	return &cluster, nil
+	return r.first(r.db.Where("context = ?", context))
 }
 
 // GetInCluster 获取集群内配置
 func (r *ClusterRepositoryImpl) GetInCluster() (*ClusterModel, error) {
-	var cluster ClusterModel
-	err := r.db.Where("is_in_cluster = ?", true).First(&cluster).Error
-	if err != nil {
-		return nil, err
-	}
-	return &cluster, nil
+	return r.first(r.db.Where("is_in_cluster = ?", true))
 }
 
 // CreateBatch 批量创建集群
 func (r *ClusterRepositoryImpl) CreateBatch(clusters []*ClusterModel) error {
-	return r.db.Create(&clusters).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&clusters).Error; err != nil {
+			return err
+		}
+		for _, cluster := range clusters {
+			if err := replaceLabels(tx, cluster.ID, cluster.Labels); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
-// GetByLabels 根据标签获取集群（简单实现，实际应该解析JSON）
-func (r *ClusterRepositoryImpl) GetByLabels(labels map[string]string) ([]*ClusterModel, error) {
-	var clusters []*ClusterModel
-	query := r.db
+// GetIDsBySelector 根据标签选择器获取集群 ID
+//
+// 选择器的每个条件转换为对 cluster_labels 的子查询；数值比较（gt、lt）
+// 无法在各数据库中统一表达，先查出该标签的值再在这里比较。
+func (r *ClusterRepositoryImpl) GetIDsBySelector(selector labels.Selector) ([]string, error) {
+	requirements, selectable := selector.Requirements()
+	if !selectable {
+		return []string{}, nil
+	}
+
+	query := r.db.Model(&ClusterModel{})
+	for _, requirement := range requirements {
+		withKey := r.db.Model(&ClusterLabelModel{}).Select("cluster_id").Where("name = ?", requirement.Key())
+		switch requirement.Operator() {
+		case selection.Equals, selection.DoubleEquals, selection.In:
+			query = query.Where("id IN (?)", withKey.Where("value IN ?", requirement.ValuesUnsorted()))
+		case selection.NotEquals, selection.NotIn:
+			// 与 Kubernetes 一致，不存在该标签的集群同样匹配
+			query = query.Where("id NOT IN (?)", withKey.Where("value IN ?", requirement.ValuesUnsorted()))
+		case selection.Exists:
+			query = query.Where("id IN (?)", withKey)
+		case selection.DoesNotExist:
+			query = query.Where("id NOT IN (?)", withKey)
+		case selection.GreaterThan, selection.LessThan:
+			ids, err := r.compareLabel(requirement)
+			if err != nil {
+				return nil, err
+			}
+			query = query.Where("id IN ?", ids)
+		default:
+			return nil, fmt.Errorf("不支持的标签选择器操作符: %s", requirement.Operator())
+		}
+	}
 
-	// 这里是简化实现，实际应该使用 JSON 查询
-	for key, value := range labels {
-		query = query.Where("labels LIKE ?", "%\""+key+"\":\""+value+"\"%")
+	var ids []string
+	if err := query.Order("id").Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// compareLabel 返回标签值满足数值比较条件的集群 ID
+func (r *ClusterRepositoryImpl) compareLabel(requirement labels.Requirement) ([]string, error) {
+	var rows []ClusterLabelModel
+	if err := r.db.Where("name = ?", requirement.Key()).Find(&rows).Error; err != nil {
+		return nil, err
 	}
 
-	err := query.Find(&clusters).Error
-	return clusters, err
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if requirement.Matches(labels.Set{row.Name: row.Value}) {
+			ids = append(ids, row.ClusterID)
+		}
+	}
+	return ids, nil
 }
 
 // UpdatePrometheusConfig 更新 Prometheus 配置
@@ -175,14 +265,153 @@ func (r *ClusterRepositoryImpl) UpdatePrometheusConfig(id string, url, username,
 	return r.db.Model(&ClusterModel{}).Where("id = ?", id).Updates(map[string]interface{}{
 		"prometheus_url":      url,
 		"prometheus_username": username,
-		"prometheus_password": password,
+		"prometheus_password": EncryptedString(password),
 		"prometheus_enabled":  enabled,
 	}).Error
 }
 
 // GetClustersWithPrometheus 获取具有 Prometheus 配置的集群
 func (r *ClusterRepositoryImpl) GetClustersWithPrometheus() ([]*ClusterModel, error) {
+	return r.find(r.db.Where("prometheus_enabled = ?", true))
+}
+
+// UpdateHealth 更新集群健康状态，不修改 updated_at
+func (r *ClusterRepositoryImpl) UpdateHealth(id, status, version string, lastCheck time.Time) error {
+	columns := map[string]interface{}{
+		"status":     status,
+		"last_check": lastCheck,
+	}
+	if version != "" {
+		columns["version"] = version
+	}
+	return r.db.Model(&ClusterModel{}).Where("id = ?", id).UpdateColumns(columns).Error
+}
+
+// GetDeleted 获取回收站中的集群，按删除时间倒序
+func (r *ClusterRepositoryImpl) GetDeleted() ([]*ClusterModel, error) {
+	return r.find(r.db.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC"))
+}
+
+// GetDeletedByID 根据ID获取回收站中的集群
+func (r *ClusterRepositoryImpl) GetDeletedByID(id string) (*ClusterModel, error) {
+	return r.first(r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id))
+}
+
+// Restore 从回收站恢复集群，恢复后的集群不是默认集群
+func (r *ClusterRepositoryImpl) Restore(id string) error {
+	result := r.db.Unscoped().Model(&ClusterModel{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"is_default": false,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Purge 从回收站中永久删除集群
+func (r *ClusterRepositoryImpl) Purge(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).Delete(&ClusterModel{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("cluster_id = ?", id).Delete(&ClusterLabelModel{}).Error
+	})
+}
+
+// PurgeDeletedBefore 永久删除在指定时间之前进入回收站的集群，返回被删除的集群 ID
+func (r *ClusterRepositoryImpl) PurgeDeletedBefore(before time.Time) ([]string, error) {
+	var ids []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&ClusterModel{}).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := tx.Unscoped().Where("id IN ?", ids).Delete(&ClusterModel{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("cluster_id IN ?", ids).Delete(&ClusterLabelModel{}).Error
+	})
+	return ids, err
+}
+
+// first 查询一个集群并加载标签
+func (r *ClusterRepositoryImpl) first(query *gorm.DB) (*ClusterModel, error) {
+	var cluster ClusterModel
+	if err := query.First(&cluster).Error; err != nil {
+		return nil, err
+	}
+	if err := loadLabels(r.db, &cluster); err != nil {
+		return nil, err
+	}
+	return &cluster, nil
+}
+
+// find 查询集群并加载标签
+func (r *ClusterRepositoryImpl) find(query *gorm.DB) ([]*ClusterModel, error) {
 	var clusters []*ClusterModel
-	err := r.db.Where("prometheus_enabled = ?", true).Find(&clusters).Error
-	return clusters, err
+	if err := query.Find(&clusters).Error; err != nil {
+		return nil, err
+	}
+	if err := loadLabels(r.db, clusters...); err != nil {
+		return nil, err
+	}
+	return clusters, nil
+}
+
+// loadLabels 一次查询加载多个集群的标签
+func loadLabels(db *gorm.DB, clusters ...*ClusterModel) error {
+	if len(clusters) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*ClusterModel, len(clusters))
+	ids := make([]string, 0, len(clusters))
+	for _, cluster := range clusters {
+		cluster.Labels = nil
+		byID[cluster.ID] = cluster
+		ids = append(ids, cluster.ID)
+	}
+
+	var rows []ClusterLabelModel
+	if err := db.Where("cluster_id IN ?", ids).Find(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		cluster := byID[row.ClusterID]
+		if cluster.Labels == nil {
+			cluster.Labels = make(map[string]string)
+		}
+		cluster.Labels[row.Name] = row.Value
+	}
+	return nil
+}
+
+// replaceLabels 用给定的标签替换集群现有的标签
+func replaceLabels(tx *gorm.DB, clusterID string, set map[string]string) error {
+	if err := tx.Where("cluster_id = ?", clusterID).Delete(&ClusterLabelModel{}).Error; err != nil {
+		return err
+	}
+	if len(set) == 0 {
+		return nil
+	}
+
+	rows := make([]ClusterLabelModel, 0, len(set))
+	for name, value := range set {
+		rows = append(rows, ClusterLabelModel{ClusterID: clusterID, Name: name, Value: value})
+	}
+	return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&rows).Error
 }
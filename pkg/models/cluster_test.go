@@ -0,0 +1,80 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// newTestClusterRepository 基于内存 SQLite 的集群仓库
+func newTestClusterRepository(t *testing.T) ClusterRepository {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&ClusterModel{}, &ClusterLabelModel{}); err != nil {
+		t.Fatal(err)
+	}
+	return NewClusterRepository(db)
+}
+
+func TestGetIDsBySelector(t *testing.T) {
+	repo := newTestClusterRepository(t)
+	clusters := map[string]map[string]string{
+		"prod-bj":   {"env": "prod", "region": "beijing"},
+		"prod-sh":   {"env": "prod", "region": "shanghai", "tier": "3"},
+		"dev":       {"env": "dev", "tier": "1"},
+		"unlabeled": nil,
+	}
+	for id, set := range clusters {
+		if err := repo.Create(&ClusterModel{ID: id, Name: id, Server: "https://" + id, Labels: set}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 回收站中的集群不参与查询
+	if err := repo.Create(&ClusterModel{ID: "deleted", Name: "deleted", Server: "https://deleted", Labels: map[string]string{"env": "prod"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete("deleted"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{selector: "env=prod", want: []string{"prod-bj", "prod-sh"}},
+		{selector: "env==dev", want: []string{"dev"}},
+		{selector: "env!=prod", want: []string{"dev", "unlabeled"}},
+		{selector: "region in (beijing,guangzhou)", want: []string{"prod-bj"}},
+		{selector: "region notin (beijing)", want: []string{"dev", "prod-sh", "unlabeled"}},
+		{selector: "region", want: []string{"prod-bj", "prod-sh"}},
+		{selector: "!region", want: []string{"dev", "unlabeled"}},
+		{selector: "tier>2", want: []string{"prod-sh"}},
+		{selector: "tier<2", want: []string{"dev"}},
+		{selector: "tier>5", want: []string{}},
+		{selector: "env=prod,!tier", want: []string{"prod-bj"}},
+		{selector: "env=prod,tier<2", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			selector, err := labels.Parse(tt.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := repo.GetIDsBySelector(selector)
+			if err != nil {
+				t.Fatalf("GetIDsBySelector(%q) 返回错误: %v", tt.selector, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetIDsBySelector(%q) = %v, want %v", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
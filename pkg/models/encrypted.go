@@ -0,0 +1,46 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/ysicing/nexus/pkg/common"
+	"github.com/ysicing/nexus/pkg/utils"
+)
+
+// EncryptedString 在数据库中加密存储的字符串，读写时自动加解密
+type EncryptedString string
+
+// Value 写入数据库前加密
+func (s EncryptedString) Value() (driver.Value, error) {
+	if s == "" {
+		return "", nil
+	}
+	if err := common.RequireEncryptionKey(); err != nil {
+		return nil, err
+	}
+	return utils.EncryptString(common.EncryptionKey, string(s))
+}
+
+// Scan 从数据库读取后解密，兼容未加密的历史数据
+func (s *EncryptedString) Scan(value interface{}) error {
+	var raw string
+	switch v := value.(type) {
+	case nil:
+		*s = ""
+		return nil
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return fmt.Errorf("unsupported type %T for EncryptedString", value)
+	}
+
+	plaintext, err := utils.DecryptString(common.EncryptionKey, raw)
+	if err != nil {
+		return err
+	}
+	*s = EncryptedString(plaintext)
+	return nil
+}
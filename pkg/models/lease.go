@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LeaseModel 租约，用于多副本之间的选主
+type LeaseModel struct {
+	Name      string    `gorm:"primaryKey;size:255" json:"name"`
+	Holder    string    `gorm:"not null;size:255" json:"holder"`
+	ExpiresAt time.Time `gorm:"index" json:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (LeaseModel) TableName() string {
+	return "leases"
+}
+
+// LeaseRepository 租约仓库接口
+type LeaseRepository interface {
+	// TryAcquire 获取或续约租约，租约由其他持有者持有且未过期时返回 false
+	TryAcquire(name, holder string, ttl time.Duration) (bool, error)
+	// Release 释放自己持有的租约
+	Release(name, holder string) error
+	// Get 获取租约
+	Get(name string) (*LeaseModel, error)
+}
+
+// LeaseRepositoryImpl 租约仓库实现
+type LeaseRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewLeaseRepository 创建租约仓库
+func NewLeaseRepository(db *gorm.DB) LeaseRepository {
+	return &LeaseRepositoryImpl{db: db}
+}
+
+// TryAcquire 获取或续约租约
+//
+// 续约和抢占已过期的租约通过带条件的 UPDATE 完成，租约不存在时插入新记录，
+// 两种情况都依赖数据库的行级原子性，多个副本同时竞争时只有一个能成功。
+func (r *LeaseRepositoryImpl) TryAcquire(name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	result := r.db.Model(&LeaseModel{}).
+		Where("name = ? AND (holder = ? OR expires_at < ?)", name, holder, now).
+		Updates(map[string]interface{}{
+			"holder":     holder,
+			"expires_at": expiresAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	result = r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&LeaseModel{
+		Name:      name,
+		Holder:    holder,
+		ExpiresAt: expiresAt,
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Release 释放自己持有的租约，其他副本可以立即获取
+func (r *LeaseRepositoryImpl) Release(name, holder string) error {
+	return r.db.Model(&LeaseModel{}).
+		Where("name = ? AND holder = ?", name, holder).
+		Update("expires_at", time.Time{}).Error
+}
+
+// Get 获取租约
+func (r *LeaseRepositoryImpl) Get(name string) (*LeaseModel, error) {
+	var lease LeaseModel
+	if err := r.db.Where("name = ?", name).First(&lease).Error; err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
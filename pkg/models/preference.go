@@ -0,0 +1,279 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserPreferenceModel 用户的个人设置
+type UserPreferenceModel struct {
+	Username string `gorm:"primaryKey;size:255" json:"-"`
+	// DefaultClusterID 用户的默认集群，为空时使用全局默认集群
+	DefaultClusterID string    `gorm:"size:255" json:"defaultClusterId"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (UserPreferenceModel) TableName() string {
+	return "user_preferences"
+}
+
+// PinnedNamespaceModel 用户在集群中固定的命名空间
+type PinnedNamespaceModel struct {
+	Username  string    `gorm:"primaryKey;size:255" json:"-"`
+	ClusterID string    `gorm:"primaryKey;size:255" json:"clusterId"`
+	Namespace string    `gorm:"primaryKey;size:63" json:"namespace"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// TableName 指定表名
+func (PinnedNamespaceModel) TableName() string {
+	return "user_pinned_namespaces"
+}
+
+// ResourceRef 集群中的一个资源
+type ResourceRef struct {
+	ClusterID string `gorm:"size:255;not null" json:"clusterId" binding:"required"`
+	// Resource 资源类型，与资源接口路径中的名称一致，例如 deployments
+	Resource string `gorm:"size:255;not null" json:"resource" binding:"required"`
+	// Namespace 集群级资源为空
+	Namespace string `gorm:"size:63" json:"namespace,omitempty"`
+	Name      string `gorm:"size:253;not null" json:"name" binding:"required"`
+}
+
+// refID 资源引用的主键，组合字段超出 MySQL 索引长度限制，因此使用摘要
+func refID(username string, ref ResourceRef) string {
+	h := sha256.New()
+	for _, value := range []string{username, ref.ClusterID, ref.Resource, ref.Namespace, ref.Name} {
+		h.Write([]byte(value))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// StarredResourceModel 用户收藏的资源
+type StarredResourceModel struct {
+	ID          string `gorm:"primaryKey;size:64" json:"id"`
+	Username    string `gorm:"size:255;not null;index" json:"-"`
+	ResourceRef `gorm:"embedded"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// TableName 指定表名
+func (StarredResourceModel) TableName() string {
+	return "user_starred_resources"
+}
+
+// RecentItemModel 用户最近访问的资源
+type RecentItemModel struct {
+	ID          string `gorm:"primaryKey;size:64" json:"id"`
+	Username    string `gorm:"size:255;not null;index" json:"-"`
+	ResourceRef `gorm:"embedded"`
+	VisitedAt   time.Time `gorm:"index" json:"visitedAt"`
+}
+
+// TableName 指定表名
+func (RecentItemModel) TableName() string {
+	return "user_recent_items"
+}
+
+// SavedFilterModel 用户保存的资源列表过滤条件
+type SavedFilterModel struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	Username string `gorm:"size:255;not null;uniqueIndex:idx_user_saved_filters_name,priority:1" json:"-"`
+	Resource string `gorm:"size:255;not null;uniqueIndex:idx_user_saved_filters_name,priority:2" json:"resource"`
+	Name     string `gorm:"size:255;not null;uniqueIndex:idx_user_saved_filters_name,priority:3" json:"name"`
+	// ClusterID 为空表示适用于所有集群
+	ClusterID string `gorm:"size:255" json:"clusterId,omitempty"`
+	// Query 列表接口的查询参数，例如 namespace=default&labelSelector=app%3Dweb
+	Query     string    `gorm:"type:text" json:"query"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (SavedFilterModel) TableName() string {
+	return "user_saved_filters"
+}
+
+// PreferenceRepository 用户个人设置仓库接口，所有方法只操作指定用户的数据
+type PreferenceRepository interface {
+	// 默认集群，clusterID 为空时清除
+	GetDefaultCluster(username string) (string, error)
+	SetDefaultCluster(username, clusterID string) error
+
+	// 固定的命名空间
+	ListPinnedNamespaces(username string) ([]*PinnedNamespaceModel, error)
+	PinNamespace(username, clusterID, namespace string) error
+	UnpinNamespace(username, clusterID, namespace string) error
+
+	// 收藏的资源
+	ListStarred(username string) ([]*StarredResourceModel, error)
+	Star(username string, ref ResourceRef) (*StarredResourceModel, error)
+	Unstar(username string, ref ResourceRef) error
+
+	// 最近访问，只保留最新的 limit 条
+	ListRecent(username string) ([]*RecentItemModel, error)
+	TouchRecent(username string, ref ResourceRef, limit int) error
+	ClearRecent(username string) error
+
+	// 保存的过滤条件，同一资源类型下按名称覆盖
+	ListFilters(username string) ([]*SavedFilterModel, error)
+	SaveFilter(filter *SavedFilterModel) error
+	DeleteFilter(username string, id uint) error
+}
+
+// PreferenceRepositoryImpl 用户个人设置仓库实现
+type PreferenceRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewPreferenceRepository 创建用户个人设置仓库
+func NewPreferenceRepository(db *gorm.DB) PreferenceRepository {
+	return &PreferenceRepositoryImpl{db: db}
+}
+
+// GetDefaultCluster 获取用户的默认集群，未设置时返回空字符串
+func (r *PreferenceRepositoryImpl) GetDefaultCluster(username string) (string, error) {
+	var preference UserPreferenceModel
+	err := r.db.Where("username = ?", username).Limit(1).Find(&preference).Error
+	return preference.DefaultClusterID, err
+}
+
+// SetDefaultCluster 设置用户的默认集群
+func (r *PreferenceRepositoryImpl) SetDefaultCluster(username, clusterID string) error {
+	return r.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&UserPreferenceModel{
+		Username:         username,
+		DefaultClusterID: clusterID,
+	}).Error
+}
+
+// ListPinnedNamespaces 获取用户固定的命名空间
+func (r *PreferenceRepositoryImpl) ListPinnedNamespaces(username string) ([]*PinnedNamespaceModel, error) {
+	var namespaces []*PinnedNamespaceModel
+	err := r.db.Where("username = ?", username).Order("cluster_id, namespace").Find(&namespaces).Error
+	return namespaces, err
+}
+
+// PinNamespace 固定命名空间，已固定时忽略
+func (r *PreferenceRepositoryImpl) PinNamespace(username, clusterID, namespace string) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&PinnedNamespaceModel{
+		Username:  username,
+		ClusterID: clusterID,
+		Namespace: namespace,
+	}).Error
+}
+
+// UnpinNamespace 取消固定命名空间
+func (r *PreferenceRepositoryImpl) UnpinNamespace(username, clusterID, namespace string) error {
+	return r.db.Where("username = ? AND cluster_id = ? AND namespace = ?", username, clusterID, namespace).
+		Delete(&PinnedNamespaceModel{}).Error
+}
+
+// ListStarred 获取用户收藏的资源，按收藏时间倒序
+func (r *PreferenceRepositoryImpl) ListStarred(username string) ([]*StarredResourceModel, error) {
+	var starred []*StarredResourceModel
+	err := r.db.Where("username = ?", username).Order("created_at DESC").Find(&starred).Error
+	return starred, err
+}
+
+// Star 收藏资源，已收藏时返回已有的记录
+func (r *PreferenceRepositoryImpl) Star(username string, ref ResourceRef) (*StarredResourceModel, error) {
+	starred := &StarredResourceModel{
+		ID:          refID(username, ref),
+		Username:    username,
+		ResourceRef: ref,
+	}
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(starred).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("id = ?", starred.ID).First(starred).Error; err != nil {
+		return nil, err
+	}
+	return starred, nil
+}
+
+// Unstar 取消收藏
+func (r *PreferenceRepositoryImpl) Unstar(username string, ref ResourceRef) error {
+	return r.db.Where("id = ?", refID(username, ref)).Delete(&StarredResourceModel{}).Error
+}
+
+// ListRecent 获取用户最近访问的资源，按访问时间倒序
+func (r *PreferenceRepositoryImpl) ListRecent(username string) ([]*RecentItemModel, error) {
+	var items []*RecentItemModel
+	err := r.db.Where("username = ?", username).Order("visited_at DESC").Find(&items).Error
+	return items, err
+}
+
+// TouchRecent 记录一次访问，并删除超出 limit 的旧记录
+func (r *PreferenceRepositoryImpl) TouchRecent(username string, ref ResourceRef, limit int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"visited_at"}),
+		}).Create(&RecentItemModel{
+			ID:          refID(username, ref),
+			Username:    username,
+			ResourceRef: ref,
+			VisitedAt:   time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		var stale []string
+		if err := tx.Model(&RecentItemModel{}).Where("username = ?", username).
+			Order("visited_at DESC").Offset(limit).Limit(1000).Pluck("id", &stale).Error; err != nil {
+			return err
+		}
+		if len(stale) == 0 {
+			return nil
+		}
+		return tx.Where("id IN ?", stale).Delete(&RecentItemModel{}).Error
+	})
+}
+
+// ClearRecent 清空最近访问记录
+func (r *PreferenceRepositoryImpl) ClearRecent(username string) error {
+	return r.db.Where("username = ?", username).Delete(&RecentItemModel{}).Error
+}
+
+// ListFilters 获取用户保存的过滤条件
+func (r *PreferenceRepositoryImpl) ListFilters(username string) ([]*SavedFilterModel, error) {
+	var filters []*SavedFilterModel
+	err := r.db.Where("username = ?", username).Order("resource, name").Find(&filters).Error
+	return filters, err
+}
+
+// SaveFilter 保存过滤条件，同一用户同一资源类型下同名的过滤条件会被覆盖
+func (r *PreferenceRepositoryImpl) SaveFilter(filter *SavedFilterModel) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing SavedFilterModel
+		err := tx.Where("username = ? AND resource = ? AND name = ?", filter.Username, filter.Resource, filter.Name).
+			Limit(1).Find(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing.ID != 0 {
+			filter.ID = existing.ID
+			filter.CreatedAt = existing.CreatedAt
+		}
+		return tx.Save(filter).Error
+	})
+}
+
+// DeleteFilter 删除过滤条件
+func (r *PreferenceRepositoryImpl) DeleteFilter(username string, id uint) error {
+	result := r.db.Where("username = ? AND id = ?", username, id).Delete(&SavedFilterModel{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ProxyTokenModel 代理 kubeconfig 使用的访问令牌
+//
+// 令牌只能访问签发时指定集群的 Kubernetes API 代理，与 Nexus 登录令牌相互独立，
+// 可以单独吊销。数据库中只保存令牌的摘要。
+type ProxyTokenModel struct {
+	ID        string `gorm:"primaryKey;size:36" json:"id"`
+	Username  string `gorm:"size:255;not null;index" json:"username"`
+	ClusterID string `gorm:"size:255;not null" json:"clusterId"`
+	// TokenHash 令牌的 SHA-256 摘要
+	TokenHash  string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt  time.Time  `gorm:"index" json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// TableName 指定表名
+func (ProxyTokenModel) TableName() string {
+	return "proxy_tokens"
+}
+
+// ProxyTokenRepository 代理令牌仓库接口
+type ProxyTokenRepository interface {
+	Create(token *ProxyTokenModel) error
+	// GetByHash 按令牌摘要获取令牌
+	GetByHash(hash string) (*ProxyTokenModel, error)
+	// List 列出集群上的令牌，username 不为空时只列出该用户的令牌
+	List(clusterID, username string) ([]*ProxyTokenModel, error)
+	// Delete 删除令牌，username 不为空时只能删除该用户的令牌，令牌不存在时返回 gorm.ErrRecordNotFound
+	Delete(clusterID, id, username string) error
+	// DeleteExpired 删除已过期的令牌
+	DeleteExpired(now time.Time) error
+	// Touch 记录令牌最近的使用时间
+	Touch(id string, usedAt time.Time) error
+}
+
+// ProxyTokenRepositoryImpl 代理令牌仓库实现
+type ProxyTokenRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewProxyTokenRepository 创建代理令牌仓库
+func NewProxyTokenRepository(db *gorm.DB) ProxyTokenRepository {
+	return &ProxyTokenRepositoryImpl{db: db}
+}
+
+// Create 保存令牌
+func (r *ProxyTokenRepositoryImpl) Create(token *ProxyTokenModel) error {
+	return r.db.Create(token).Error
+}
+
+// GetByHash 按令牌摘要获取令牌
+func (r *ProxyTokenRepositoryImpl) GetByHash(hash string) (*ProxyTokenModel, error) {
+	var token ProxyTokenModel
+	if err := r.db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// List 列出集群上的令牌，按签发时间倒序
+func (r *ProxyTokenRepositoryImpl) List(clusterID, username string) ([]*ProxyTokenModel, error) {
+	query := r.db.Where("cluster_id = ?", clusterID)
+	if username != "" {
+		query = query.Where("username = ?", username)
+	}
+	var tokens []*ProxyTokenModel
+	err := query.Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// Delete 删除令牌
+func (r *ProxyTokenRepositoryImpl) Delete(clusterID, id, username string) error {
+	query := r.db.Where("cluster_id = ? AND id = ?", clusterID, id)
+	if username != "" {
+		query = query.Where("username = ?", username)
+	}
+	result := query.Delete(&ProxyTokenModel{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteExpired 删除已过期的令牌
+func (r *ProxyTokenRepositoryImpl) DeleteExpired(now time.Time) error {
+	return r.db.Where("expires_at < ?", now).Delete(&ProxyTokenModel{}).Error
+}
+
+// Touch 记录令牌最近的使用时间
+func (r *ProxyTokenRepositoryImpl) Touch(id string, usedAt time.Time) error {
+	return r.db.Model(&ProxyTokenModel{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ResourceRevisionModel 通过 Nexus 修改或删除对象之前保存的对象内容
+type ResourceRevisionModel struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+	// ObjectID 集群、资源类型、命名空间和名称的摘要，用于按对象查询
+	ObjectID    string `gorm:"size:64;not null;index" json:"-"`
+	ResourceRef `gorm:"embedded"`
+	APIVersion  string `gorm:"size:255;not null" json:"apiVersion"`
+	Kind        string `gorm:"size:255;not null" json:"kind"`
+	// ResourceVersion 保存时对象的 resourceVersion
+	ResourceVersion string `gorm:"size:64" json:"resourceVersion"`
+	// Action 导致保存的操作，例如 update、patch、apply、delete、restore 或 scale
+	Action   string `gorm:"size:32;not null" json:"action"`
+	Username string `gorm:"size:255" json:"username"`
+	// Content 去掉状态和服务端字段后的 YAML，加密存储，Secret 等对象包含敏感数据
+	Content   EncryptedString `gorm:"size:16777215" json:"content,omitempty"`
+	CreatedAt time.Time       `gorm:"index" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (ResourceRevisionModel) TableName() string {
+	return "resource_revisions"
+}
+
+// revisionObjectID 对象的摘要
+func revisionObjectID(ref ResourceRef) string {
+	return refID("", ref)
+}
+
+// RevisionRepository 对象历史版本仓库接口
+type RevisionRepository interface {
+	// Create 保存历史版本，并删除同一对象超出 keep 条的旧版本
+	Create(revision *ResourceRevisionModel, keep int) error
+	// List 按时间倒序列出对象的历史版本，不包含内容
+	List(ref ResourceRef, limit int) ([]*ResourceRevisionModel, error)
+	// Get 获取集群中的一个历史版本
+	Get(clusterID string, id uint) (*ResourceRevisionModel, error)
+}
+
+// RevisionRepositoryImpl 对象历史版本仓库实现
+type RevisionRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewRevisionRepository 创建对象历史版本仓库
+func NewRevisionRepository(db *gorm.DB) RevisionRepository {
+	return &RevisionRepositoryImpl{db: db}
+}
+
+// Create 保存历史版本
+func (r *RevisionRepositoryImpl) Create(revision *ResourceRevisionModel, keep int) error {
+	revision.ObjectID = revisionObjectID(revision.ResourceRef)
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(revision).Error; err != nil {
+			return err
+		}
+
+		var stale []uint
+		if err := tx.Model(&ResourceRevisionModel{}).Where("object_id = ?", revision.ObjectID).
+			Order("id DESC").Offset(keep).Limit(1000).Pluck("id", &stale).Error; err != nil {
+			return err
+		}
+		if len(stale) == 0 {
+			return nil
+		}
+		return tx.Where("id IN ?", stale).Delete(&ResourceRevisionModel{}).Error
+	})
+}
+
+// List 列出对象的历史版本
+func (r *RevisionRepositoryImpl) List(ref ResourceRef, limit int) ([]*ResourceRevisionModel, error) {
+	var revisions []*ResourceRevisionModel
+	err := r.db.Omit("content").Where("object_id = ?", revisionObjectID(ref)).
+		Order("id DESC").Limit(limit).Find(&revisions).Error
+	return revisions, err
+}
+
+// Get 获取历史版本
+func (r *RevisionRepositoryImpl) Get(clusterID string, id uint) (*ResourceRevisionModel, error) {
+	var revision ResourceRevisionModel
+	if err := r.db.Where("cluster_id = ? AND id = ?", clusterID, id).First(&revision).Error; err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
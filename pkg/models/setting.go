@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SettingModel 运行时设置，覆盖环境变量提供的默认值
+type SettingModel struct {
+	Name      string          `gorm:"primaryKey;size:255" json:"name"`
+	Value     EncryptedString `gorm:"type:text" json:"-"` // 加密存储，设置中包含 Webhook 密码等敏感信息
+	UpdatedBy string          `gorm:"size:255" json:"updatedBy,omitempty"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (SettingModel) TableName() string {
+	return "settings"
+}
+
+// SettingRepository 运行时设置仓库接口
+type SettingRepository interface {
+	GetAll() ([]*SettingModel, error)
+	// Set 在一个事务中保存多个设置
+	Set(values map[string]string, updatedBy string) error
+	// Delete 删除设置，恢复为环境变量或内置的默认值
+	Delete(name string) error
+}
+
+// SettingRepositoryImpl 运行时设置仓库实现
+type SettingRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewSettingRepository 创建运行时设置仓库
+func NewSettingRepository(db *gorm.DB) SettingRepository {
+	return &SettingRepositoryImpl{db: db}
+}
+
+// GetAll 获取所有设置
+func (r *SettingRepositoryImpl) GetAll() ([]*SettingModel, error) {
+	var settings []*SettingModel
+	err := r.db.Find(&settings).Error
+	return settings, err
+}
+
+// Set 在一个事务中保存多个设置
+func (r *SettingRepositoryImpl) Set(values map[string]string, updatedBy string) error {
+	now := time.Now()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for name, value := range values {
+			err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&SettingModel{
+				Name:      name,
+				Value:     EncryptedString(value),
+				UpdatedBy: updatedBy,
+				UpdatedAt: now,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Delete 删除设置
+func (r *SettingRepositoryImpl) Delete(name string) error {
+	return r.db.Where("name = ?", name).Delete(&SettingModel{}).Error
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SystemSecretModel 多副本之间共享的系统密钥，例如未配置 JWT_SECRET 时自动生成的签名密钥
+type SystemSecretModel struct {
+	Name      string          `gorm:"primaryKey;size:255" json:"name"`
+	Value     EncryptedString `gorm:"type:text" json:"-"` // 加密存储
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// TableName 指定表名
+func (SystemSecretModel) TableName() string {
+	return "system_secrets"
+}
+
+// GetOrCreateSystemSecret 获取共享密钥，不存在时保存 generate 生成的值
+//
+// 多个副本同时启动时只有第一个写入的值生效，其余副本读取到的是同一个值。
+func GetOrCreateSystemSecret(db *gorm.DB, name string, generate func() string) (string, error) {
+	err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&SystemSecretModel{
+		Name:  name,
+		Value: EncryptedString(generate()),
+	}).Error
+	if err != nil {
+		return "", err
+	}
+
+	var secret SystemSecretModel
+	if err := db.Where("name = ?", name).First(&secret).Error; err != nil {
+		return "", err
+	}
+	return string(secret.Value), nil
+}
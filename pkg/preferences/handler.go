@@ -0,0 +1,348 @@
+package preferences
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ysicing/nexus/pkg/cluster"
+	"github.com/ysicing/nexus/pkg/middleware"
+	"github.com/ysicing/nexus/pkg/models"
+	"gorm.io/gorm"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ClusterLookup 校验偏好中引用的集群是否存在
+type ClusterLookup interface {
+	GetCluster(clusterID string) (*cluster.ClusterInfo, error)
+}
+
+// Handler 用户个人设置处理器，每个用户只能读写自己的设置
+type Handler struct {
+	store    *Store
+	clusters ClusterLookup
+}
+
+// NewHandler 创建用户个人设置处理器
+func NewHandler(store *Store, clusters ClusterLookup) *Handler {
+	return &Handler{
+		store:    store,
+		clusters: clusters,
+	}
+}
+
+// Preferences 用户的全部个人设置
+type Preferences struct {
+	DefaultClusterID string                         `json:"defaultClusterId"`
+	PinnedNamespaces map[string][]string            `json:"pinnedNamespaces"`
+	Starred          []*models.StarredResourceModel `json:"starred"`
+	Recent           []*models.RecentItemModel      `json:"recent"`
+	Filters          []*models.SavedFilterModel     `json:"filters"`
+}
+
+// DefaultClusterRequest 设置默认集群请求
+type DefaultClusterRequest struct {
+	ClusterID string `json:"clusterId" binding:"required"`
+}
+
+// PinnedNamespaceRequest 固定命名空间请求
+type PinnedNamespaceRequest struct {
+	ClusterID string `json:"clusterId" form:"clusterId" binding:"required"`
+	Namespace string `json:"namespace" form:"namespace" binding:"required"`
+}
+
+// SaveFilterRequest 保存过滤条件请求
+type SaveFilterRequest struct {
+	Resource  string `json:"resource" binding:"required"`
+	Name      string `json:"name" binding:"required"`
+	ClusterID string `json:"clusterId"`
+	Query     string `json:"query"`
+}
+
+// user 返回当前用户，无法识别用户时返回错误响应
+func user(c *gin.Context) (string, bool) {
+	username := middleware.CurrentUser(c)
+	if username == "" || username == "-" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not identified"})
+		return "", false
+	}
+	return username, true
+}
+
+// Get 获取当前用户的全部个人设置
+func (h *Handler) Get(c *gin.Context) {
+	username, ok := user(c)
+	if !ok {
+		return
+	}
+	repo := h.store.Repository()
+
+	preferences := &Preferences{
+		DefaultClusterID: h.store.DefaultCluster(username),
+		PinnedNamespaces: make(map[string][]string),
+	}
+	pinned, err := repo.ListPinnedNamespaces(username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, p := range pinned {
+		preferences.PinnedNamespaces[p.ClusterID] = append(preferences.PinnedNamespaces[p.ClusterID], p.Namespace)
+	}
+	if preferences.Starred, err = repo.ListStarred(username); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if preferences.Recent, err = repo.ListRecent(username); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if preferences.Filters, err = repo.ListFilters(username); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, preferences)
+}
+
+// SetDefaultCluster 设置当前用户的默认集群，不影响其他用户
+func (h *Handler) SetDefaultCluster(c *gin.Context) {
+	username, ok := user(c)
+	if !ok {
+		return
+	}
+
+	var req DefaultClusterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := h.clusters.GetCluster(req.ClusterID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.store.SetDefaultCluster(username, req.ClusterID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"defaultClusterId": req.ClusterID})
+}
+
+// ClearDefaultCluster 清除当前用户的默认集群，恢复使用全局默认集群
+func (h *Handler) ClearDefaultCluster(c *gin.Context) {
+	username, ok := user(c)
+	if !ok {
+		return
+	}
+
+	if err := h.store.SetDefaultCluster(username, ""); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"defaultClusterId": ""})
+}
+
+// PinNamespace 固定命名空间
+func (h *Handler) PinNamespace(c *gin.Context) {
+	username, ok := user(c)
+	if !ok {
+		return
+	}
+
+	var req PinnedNamespaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errs := validation.IsDNS1123Label(req.Namespace); len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid namespace: " + strings.Join(errs, "; ")})
+		return
+	}
+
+	if err := h.store.Repository().PinNamespace(username, req.ClusterID, req.Namespace); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Namespace pinned successfully"})
+}
+
+// UnpinNamespace 取消固定命名空间，参数通过查询字符串传递
+func (h *Handler) UnpinNamespace(c *gin.Context) {
+	username, ok := user(c)
+	if !ok {
+		return
+	}
+
+	var req PinnedNamespaceRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.store.Repository().UnpinNamespace(username, req.ClusterID, req.Namespace); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Namespace unpinned successfully"})
+}
+
+// Star 收藏资源
+func (h *Handler) Star(c *gin.Context) {
+	username, ok := user(c)
+	if !ok {
+		return
+	}
+
+	var ref models.ResourceRef
+	if err := c.ShouldBindJSON(&ref); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	starred, err := h.store.Repository().Star(username, ref)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, starred)
+}
+
+// Unstar 取消收藏，资源通过查询字符串传递
+func (h *Handler) Unstar(c *gin.Context) {
+	username, ok := user(c)
+	if !ok {
+		return
+	}
+
+	ref, ok := resourceRefFromQuery(c)
+	if !ok {
+		return
+	}
+
+	if err := h.store.Repository().Unstar(username, ref); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Resource unstarred successfully"})
+}
+
+// TouchRecent 记录一次资源访问
+func (h *Handler) TouchRecent(c *gin.Context) {
+	username, ok := user(c)
+	if !ok {
+		return
+	}
+
+	var ref models.ResourceRef
+	if err := c.ShouldBindJSON(&ref); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.store.Repository().TouchRecent(username, ref, maxRecentItems); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Recent item recorded"})
+}
+
+// ClearRecent 清空最近访问记录
+func (h *Handler) ClearRecent(c *gin.Context) {
+	username, ok := user(c)
+	if !ok {
+		return
+	}
+
+	if err := h.store.Repository().ClearRecent(username); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Recent items cleared"})
+}
+
+// SaveFilter 保存资源列表的过滤条件，同名时覆盖
+func (h *Handler) SaveFilter(c *gin.Context) {
+	username, ok := user(c)
+	if !ok {
+		return
+	}
+
+	var req SaveFilterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := &models.SavedFilterModel{
+		Username:  username,
+		Resource:  req.Resource,
+		Name:      req.Name,
+		ClusterID: req.ClusterID,
+		Query:     req.Query,
+	}
+	if err := h.store.Repository().SaveFilter(filter); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, filter)
+}
+
+// DeleteFilter 删除保存的过滤条件
+func (h *Handler) DeleteFilter(c *gin.Context) {
+	username, ok := user(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid filter id"})
+		return
+	}
+
+	if err := h.store.Repository().DeleteFilter(username, uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Filter not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Filter deleted successfully"})
+}
+
+// resourceRefFromQuery 从查询字符串解析资源引用
+func resourceRefFromQuery(c *gin.Context) (models.ResourceRef, bool) {
+	ref := models.ResourceRef{
+		ClusterID: c.Query("clusterId"),
+		Resource:  c.Query("resource"),
+		Namespace: c.Query("namespace"),
+		Name:      c.Query("name"),
+	}
+	if ref.ClusterID == "" || ref.Resource == "" || ref.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "clusterId, resource and name are required"})
+		return ref, false
+	}
+	return ref, true
+}
+
+// RegisterRoutes 注册用户个人设置路由
+func (h *Handler) RegisterRoutes(group *gin.RouterGroup) {
+	preferencesGroup := group.Group("/preferences")
+	{
+		preferencesGroup.GET("", h.Get)
+		preferencesGroup.PUT("/default-cluster", h.SetDefaultCluster)
+		preferencesGroup.DELETE("/default-cluster", h.ClearDefaultCluster)
+		preferencesGroup.POST("/pinned-namespaces", h.PinNamespace)
+		preferencesGroup.DELETE("/pinned-namespaces", h.UnpinNamespace)
+		preferencesGroup.POST("/starred", h.Star)
+		preferencesGroup.DELETE("/starred", h.Unstar)
+		preferencesGroup.POST("/recent", h.TouchRecent)
+		preferencesGroup.DELETE("/recent", h.ClearRecent)
+		preferencesGroup.POST("/filters", h.SaveFilter)
+		preferencesGroup.DELETE("/filters/:id", h.DeleteFilter)
+	}
+}
@@ -0,0 +1,78 @@
+package preferences
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ysicing/nexus/pkg/models"
+	"gorm.io/gorm"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// maxRecentItems 每个用户保留的最近访问记录数
+	maxRecentItems = 20
+	// defaultClusterCacheTTL 默认集群缓存时间，其他副本上的修改最多延迟这么久生效
+	defaultClusterCacheTTL = 10 * time.Second
+)
+
+type cachedDefault struct {
+	clusterID string
+	loadedAt  time.Time
+}
+
+// Store 用户个人设置
+//
+// 用户的默认集群在每个未指定集群的请求中都会用到，因此在内存中缓存一小段时间。
+type Store struct {
+	repo models.PreferenceRepository
+
+	mu       sync.Mutex
+	defaults map[string]cachedDefault
+}
+
+// NewStore 创建用户个人设置
+func NewStore(db *gorm.DB) *Store {
+	return &Store{
+		repo:     models.NewPreferenceRepository(db),
+		defaults: make(map[string]cachedDefault),
+	}
+}
+
+// Repository 返回用户个人设置仓库
+func (s *Store) Repository() models.PreferenceRepository {
+	return s.repo
+}
+
+// DefaultCluster 返回用户的默认集群，未设置或查询失败时返回空字符串
+func (s *Store) DefaultCluster(username string) string {
+	s.mu.Lock()
+	cached, ok := s.defaults[username]
+	s.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < defaultClusterCacheTTL {
+		return cached.clusterID
+	}
+
+	clusterID, err := s.repo.GetDefaultCluster(username)
+	if err != nil {
+		klog.Warningf("获取用户 %s 的默认集群失败: %v", username, err)
+		return ""
+	}
+
+	s.mu.Lock()
+	s.defaults[username] = cachedDefault{clusterID: clusterID, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return clusterID
+}
+
+// SetDefaultCluster 设置用户的默认集群，clusterID 为空时恢复使用全局默认集群
+func (s *Store) SetDefaultCluster(username, clusterID string) error {
+	if err := s.repo.SetDefaultCluster(username, clusterID); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.defaults[username] = cachedDefault{clusterID: clusterID, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return nil
+}
@@ -73,8 +73,15 @@ func NewClient(prometheusURL string) (*Client, error) {
 
 // NewClientWithAuth creates a new Prometheus client with authentication
 func NewClientWithAuth(prometheusURL, username, password string) (*Client, error) {
+	return NewClientWithTransport(prometheusURL, username, password, api.DefaultRoundTripper)
+}
+
+// NewClientWithTransport creates a new Prometheus client with authentication
+// that sends requests through the given transport, e.g. a cluster proxy or SSH tunnel
+func NewClientWithTransport(prometheusURL, username, password string, transport http.RoundTripper) (*Client, error) {
 	config := api.Config{
-		Address: prometheusURL,
+		Address:      prometheusURL,
+		RoundTripper: transport,
 	}
 
 	// 如果提供了用户名和密码，设置 HTTP 基本认证
@@ -82,7 +89,7 @@ func NewClientWithAuth(prometheusURL, username, password string) (*Client, error
 		config.RoundTripper = &basicAuthRoundTripper{
 			username: username,
 			password: password,
-			next:     api.DefaultRoundTripper,
+			next:     transport,
 		}
 	}
 
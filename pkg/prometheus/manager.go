@@ -6,12 +6,14 @@ import (
 	"time"
 
 	"github.com/ysicing/nexus/pkg/models"
+	"github.com/ysicing/nexus/pkg/tunnel"
 	"k8s.io/klog/v2"
 )
 
 // Manager Prometheus 管理器
 type Manager struct {
 	clients map[string]*Client // clusterID -> Prometheus Client
+	tunnels map[string]*tunnel.Tunnel
 	repo    models.ClusterRepository
 	mu      sync.RWMutex
 }
@@ -20,6 +22,7 @@ type Manager struct {
 func NewManager(repo models.ClusterRepository) *Manager {
 	return &Manager{
 		clients: make(map[string]*Client),
+		tunnels: make(map[string]*tunnel.Tunnel),
 		repo:    repo,
 	}
 }
@@ -38,12 +41,10 @@ func (m *Manager) Initialize() error {
 
 	for _, cluster := range clusters {
 		if cluster.PrometheusEnabled && cluster.PrometheusURL != "" {
-			client, err := NewClientWithAuth(cluster.PrometheusURL, cluster.PrometheusUsername, cluster.PrometheusPassword)
-			if err != nil {
+			if err := m.setClientLocked(cluster); err != nil {
 				klog.Warningf("创建集群 %s 的 Prometheus 客户端失败: %v", cluster.ID, err)
 				continue
 			}
-			m.clients[cluster.ID] = client
 			klog.Infof("为集群 %s 创建 Prometheus 客户端: %s", cluster.ID, cluster.PrometheusURL)
 		}
 	}
@@ -75,15 +76,17 @@ func (m *Manager) UpdateClusterPrometheus(clusterID, url, username, password str
 	defer m.mu.Unlock()
 
 	// 删除旧客户端
-	delete(m.clients, clusterID)
+	m.removeClientLocked(clusterID)
 
 	// 如果启用，创建新客户端
 	if enabled && url != "" {
-		client, err := NewClientWithAuth(url, username, password)
+		cluster, err := m.repo.GetByID(clusterID)
 		if err != nil {
+			return fmt.Errorf("获取集群 %s 失败: %w", clusterID, err)
+		}
+		if err := m.setClientLocked(cluster); err != nil {
 			return fmt.Errorf("创建 Prometheus 客户端失败: %w", err)
 		}
-		m.clients[clusterID] = client
 		klog.Infof("更新集群 %s 的 Prometheus 配置: %s", clusterID, url)
 	} else {
 		klog.Infof("禁用集群 %s 的 Prometheus 配置", clusterID)
@@ -109,7 +112,7 @@ func (m *Manager) RemoveCluster(clusterID string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	delete(m.clients, clusterID)
+	m.removeClientLocked(clusterID)
 	klog.Infof("移除集群 %s 的 Prometheus 配置", clusterID)
 }
 
@@ -124,17 +127,17 @@ func (m *Manager) RefreshFromDatabase() error {
 	defer m.mu.Unlock()
 
 	// 清空现有客户端
-	m.clients = make(map[string]*Client)
+	for clusterID := range m.clients {
+		m.removeClientLocked(clusterID)
+	}
 
 	// 重新加载
 	for _, cluster := range clusters {
 		if cluster.PrometheusEnabled && cluster.PrometheusURL != "" {
-			client, err := NewClientWithAuth(cluster.PrometheusURL, cluster.PrometheusUsername, cluster.PrometheusPassword)
-			if err != nil {
+			if err := m.setClientLocked(cluster); err != nil {
 				klog.Warningf("创建集群 %s 的 Prometheus 客户端失败: %v", cluster.ID, err)
 				continue
 			}
-			m.clients[cluster.ID] = client
 		}
 	}
 
@@ -155,3 +158,36 @@ func (m *Manager) HealthCheck() map[string]error {
 	}
 	return results
 }
+
+// setClientLocked 按集群的连接方式创建 Prometheus 客户端，调用方需持有写锁
+func (m *Manager) setClientLocked(cluster *models.ClusterModel) error {
+	tunnelConfig, err := tunnel.ParseConfig(string(cluster.TunnelConfig))
+	if err != nil {
+		return err
+	}
+	t, err := tunnel.Open(tunnelConfig)
+	if err != nil {
+		return err
+	}
+
+	client, err := NewClientWithTransport(cluster.PrometheusURL, cluster.PrometheusUsername, string(cluster.PrometheusPassword), t.Transport())
+	if err != nil {
+		t.Close()
+		return err
+	}
+
+	m.clients[cluster.ID] = client
+	if t != nil {
+		m.tunnels[cluster.ID] = t
+	}
+	return nil
+}
+
+// removeClientLocked 移除 Prometheus 客户端并关闭连接通道，调用方需持有写锁
+func (m *Manager) removeClientLocked(clusterID string) {
+	delete(m.clients, clusterID)
+	if t, exists := m.tunnels[clusterID]; exists {
+		t.Close()
+		delete(m.tunnels, clusterID)
+	}
+}
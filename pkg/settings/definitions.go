@@ -0,0 +1,150 @@
+package settings
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ysicing/nexus/pkg/common"
+)
+
+// Type 设置值的类型
+type Type string
+
+const (
+	TypeString Type = "string"
+	TypeBool   Type = "bool"
+)
+
+// Definition 可在运行时修改的设置
+type Definition struct {
+	Key         string
+	Type        Type
+	Description string
+	// Env 提供默认值的环境变量
+	Env string
+	// Sensitive 敏感设置在接口中不返回实际值
+	Sensitive bool
+	// Required 不允许设置为空字符串
+	Required bool
+
+	field func(s *common.RuntimeSettings) interface{}
+}
+
+// definitions 所有可在运行时修改的设置
+var definitions = []Definition{
+	{
+		Key:         "oauth.enabled",
+		Type:        TypeBool,
+		Description: "Enable OAuth login",
+		Env:         "OAUTH_ENABLED",
+		field:       func(s *common.RuntimeSettings) interface{} { return &s.OAuthEnabled },
+	},
+	{
+		Key:         "oauth.providers",
+		Type:        TypeString,
+		Description: "Comma separated OAuth providers, each configured by <NAME>_CLIENT_ID and related environment variables",
+		Env:         "OAUTH_PROVIDERS",
+		field:       func(s *common.RuntimeSettings) interface{} { return &s.OAuthProviders },
+	},
+	{
+		Key:         "oauth.allowUsers",
+		Type:        TypeString,
+		Description: "Comma separated users allowed to log in with OAuth, \"*\" allows everyone",
+		Env:         "OAUTH_ALLOW_USERS",
+		field:       func(s *common.RuntimeSettings) interface{} { return &s.OAuthAllowUsers },
+	},
+	{
+		Key:         "webhook.username",
+		Type:        TypeString,
+		Description: "Basic auth username for the webhook endpoint",
+		Env:         "WEBHOOK_USERNAME",
+		Required:    true,
+		field:       func(s *common.RuntimeSettings) interface{} { return &s.WebhookUsername },
+	},
+	{
+		Key:         "webhook.password",
+		Type:        TypeString,
+		Description: "Basic auth password for the webhook endpoint",
+		Env:         "WEBHOOK_PASSWORD",
+		Sensitive:   true,
+		Required:    true,
+		field:       func(s *common.RuntimeSettings) interface{} { return &s.WebhookPassword },
+	},
+	{
+		Key:         "nodeTerminal.image",
+		Type:        TypeString,
+		Description: "Image used by node terminal agent pods",
+		Env:         "NODE_TERMINAL_IMAGE",
+		Required:    true,
+		field:       func(s *common.RuntimeSettings) interface{} { return &s.NodeTerminalImage },
+	},
+	{
+		Key:         "readonly",
+		Type:        TypeBool,
+		Description: "Reject write operations except changing settings",
+		Env:         "READONLY",
+		field:       func(s *common.RuntimeSettings) interface{} { return &s.Readonly },
+	},
+	{
+		Key:         "analytics.enabled",
+		Type:        TypeBool,
+		Description: "Inject the analytics script into the web UI",
+		Env:         "ENABLE_ANALYTICS",
+		field:       func(s *common.RuntimeSettings) interface{} { return &s.EnableAnalytics },
+	},
+}
+
+// Definitions 返回所有可在运行时修改的设置
+func Definitions() []Definition {
+	return definitions
+}
+
+// lookup 按名称查找设置
+func lookup(key string) (Definition, bool) {
+	for _, def := range definitions {
+		if def.Key == key {
+			return def, true
+		}
+	}
+	return Definition{}, false
+}
+
+// get 以字符串形式返回设置的值
+func (d Definition) get(s *common.RuntimeSettings) string {
+	switch field := d.field(s).(type) {
+	case *bool:
+		return strconv.FormatBool(*field)
+	case *string:
+		return *field
+	}
+	return ""
+}
+
+// set 校验并设置值
+func (d Definition) set(s *common.RuntimeSettings, value string) error {
+	switch field := d.field(s).(type) {
+	case *bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%w: %s must be true or false", ErrInvalid, d.Key)
+		}
+		*field = parsed
+	case *string:
+		value = strings.TrimSpace(value)
+		if d.Required && value == "" {
+			return fmt.Errorf("%w: %s must not be empty", ErrInvalid, d.Key)
+		}
+		*field = value
+	}
+	return nil
+}
+
+// normalize 返回保存到数据库的规范化值
+func (d Definition) normalize(value string) (string, error) {
+	var s common.RuntimeSettings
+	if err := d.set(&s, value); err != nil {
+		return "", err
+	}
+	return d.get(&s), nil
+}
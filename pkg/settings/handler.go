@@ -0,0 +1,91 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ysicing/nexus/pkg/middleware"
+)
+
+// Handler 运行时设置处理器
+type Handler struct {
+	manager *Manager
+}
+
+// NewHandler 创建运行时设置处理器
+func NewHandler(manager *Manager) *Handler {
+	return &Handler{manager: manager}
+}
+
+// UpdateRequest 修改设置请求，值可以是字符串或布尔值
+type UpdateRequest struct {
+	Settings map[string]interface{} `json:"settings" binding:"required"`
+}
+
+// List 列出所有设置
+func (h *Handler) List(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"settings": h.manager.List()})
+}
+
+// Update 修改设置
+func (h *Handler) Update(c *gin.Context) {
+	var req UpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	values := make(map[string]string, len(req.Settings))
+	for key, value := range req.Settings {
+		switch v := value.(type) {
+		case string:
+			values[key] = v
+		case bool:
+			values[key] = strconv.FormatBool(v)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("value of %s must be a string or boolean", key)})
+			return
+		}
+	}
+
+	if err := h.manager.Update(values, middleware.CurrentUser(c)); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"settings": h.manager.List()})
+}
+
+// Reset 恢复设置为环境变量或内置的默认值
+func (h *Handler) Reset(c *gin.Context) {
+	if err := h.manager.Reset(c.Param("key"), middleware.CurrentUser(c)); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"settings": h.manager.List()})
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrLocked):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalid):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// RegisterRoutes 注册设置路由，调用方负责管理员权限校验
+func (h *Handler) RegisterRoutes(group *gin.RouterGroup) {
+	settingsGroup := group.Group("/settings")
+	{
+		settingsGroup.GET("", h.List)
+		settingsGroup.PUT("", h.Update)
+		settingsGroup.DELETE("/:key", h.Reset)
+	}
+}
@@ -0,0 +1,294 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ysicing/nexus/pkg/common"
+	"github.com/ysicing/nexus/pkg/models"
+	"gorm.io/gorm"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// syncInterval 从数据库同步设置的间隔，多个副本之间最多延迟这么久生效
+	syncInterval = 10 * time.Second
+	// maskedValue 敏感设置在接口中返回的值
+	maskedValue = "******"
+)
+
+var (
+	// ErrInvalid 设置名称或值无效
+	ErrInvalid = errors.New("invalid setting")
+	// ErrLocked 设置由 SETTINGS_ENV_OVERRIDE 锁定为环境变量的值
+	ErrLocked = errors.New("setting is locked by SETTINGS_ENV_OVERRIDE")
+)
+
+// Source 设置当前值的来源
+type Source string
+
+const (
+	SourceDefault  Source = "default"
+	SourceEnv      Source = "env"
+	SourceDatabase Source = "database"
+)
+
+// Setting 设置的当前状态
+type Setting struct {
+	Key         string      `json:"key"`
+	Type        Type        `json:"type"`
+	Description string      `json:"description"`
+	Value       interface{} `json:"value"`
+	Env         string      `json:"env"`
+	Source      Source      `json:"source"`
+	Sensitive   bool        `json:"sensitive,omitempty"`
+	Locked      bool        `json:"locked,omitempty"`
+	UpdatedBy   string      `json:"updatedBy,omitempty"`
+	UpdatedAt   *time.Time  `json:"updatedAt,omitempty"`
+}
+
+// Manager 管理保存在数据库中的运行时设置
+//
+// 启动时环境变量提供的值作为默认值，数据库中的设置覆盖默认值后写入 common.Settings。
+// 删除数据库中的设置即恢复为默认值。SETTINGS_ENV_OVERRIDE 中列出的设置（"*" 表示全部）
+// 始终使用环境变量提供的值，不能通过接口修改。
+type Manager struct {
+	repo      models.SettingRepository
+	bootstrap common.RuntimeSettings
+	locked    map[string]bool
+
+	mu     sync.Mutex
+	stored map[string]*models.SettingModel
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+}
+
+// NewManager 创建设置管理器，需要在 common.LoadEnvs 之后调用
+func NewManager(db *gorm.DB) *Manager {
+	return &Manager{
+		repo:      models.NewSettingRepository(db),
+		bootstrap: common.Settings(),
+		locked:    parseLocked(os.Getenv("SETTINGS_ENV_OVERRIDE")),
+		stored:    make(map[string]*models.SettingModel),
+		stopCh:    make(chan struct{}),
+	}
+}
+
+// parseLocked 解析 SETTINGS_ENV_OVERRIDE
+func parseLocked(value string) map[string]bool {
+	locked := make(map[string]bool)
+	for key := range strings.SplitSeq(value, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		if key == "*" {
+			for _, def := range definitions {
+				locked[def.Key] = true
+			}
+			continue
+		}
+		if _, ok := lookup(key); !ok {
+			klog.Warningf("SETTINGS_ENV_OVERRIDE 中包含未知的设置: %s", key)
+			continue
+		}
+		locked[key] = true
+	}
+	return locked
+}
+
+// Load 从数据库加载设置并立即生效
+func (m *Manager) Load() error {
+	rows, err := m.repo.GetAll()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	settings := m.bootstrap
+	stored := make(map[string]*models.SettingModel, len(rows))
+	for _, row := range rows {
+		def, ok := lookup(row.Name)
+		if !ok {
+			// 更新版本的副本写入的设置，忽略
+			continue
+		}
+		stored[row.Name] = row
+		if m.locked[row.Name] {
+			continue
+		}
+		if err := def.set(&settings, string(row.Value)); err != nil {
+			klog.Warningf("忽略数据库中无效的设置 %s: %v", row.Name, err)
+		}
+	}
+
+	m.stored = stored
+	common.SetSettings(settings)
+	return nil
+}
+
+// List 返回所有设置的当前状态
+func (m *Manager) List() []Setting {
+	current := common.Settings()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]Setting, 0, len(definitions))
+	for _, def := range definitions {
+		setting := Setting{
+			Key:         def.Key,
+			Type:        def.Type,
+			Description: def.Description,
+			Env:         def.Env,
+			Source:      SourceDefault,
+			Sensitive:   def.Sensitive,
+			Locked:      m.locked[def.Key],
+		}
+		if os.Getenv(def.Env) != "" {
+			setting.Source = SourceEnv
+		}
+		if row, ok := m.stored[def.Key]; ok && !setting.Locked {
+			updatedAt := row.UpdatedAt
+			setting.Source = SourceDatabase
+			setting.UpdatedBy = row.UpdatedBy
+			setting.UpdatedAt = &updatedAt
+		}
+
+		value := def.get(&current)
+		switch {
+		case def.Sensitive:
+			if value != "" {
+				setting.Value = maskedValue
+			} else {
+				setting.Value = ""
+			}
+		case def.Type == TypeBool:
+			setting.Value = value == "true"
+		default:
+			setting.Value = value
+		}
+		list = append(list, setting)
+	}
+	return list
+}
+
+// Update 保存多个设置，全部校验通过后在一个事务中写入，并立即在当前副本生效
+func (m *Manager) Update(values map[string]string, updatedBy string) error {
+	if len(values) == 0 {
+		return fmt.Errorf("%w: no settings given", ErrInvalid)
+	}
+
+	candidate := common.Settings()
+	normalized := make(map[string]string, len(values))
+	for key, value := range values {
+		def, ok := lookup(key)
+		if !ok {
+			return fmt.Errorf("%w: unknown setting %s", ErrInvalid, key)
+		}
+		if m.locked[key] {
+			return fmt.Errorf("%w: %s", ErrLocked, key)
+		}
+		if def.Sensitive && value == maskedValue {
+			// 接口返回的掩码原样提交时保持不变
+			continue
+		}
+		value, err := def.normalize(value)
+		if err != nil {
+			return err
+		}
+		if err := def.set(&candidate, value); err != nil {
+			return err
+		}
+		normalized[key] = value
+	}
+	if err := checkAuthentication(candidate); err != nil {
+		return err
+	}
+	if len(normalized) == 0 {
+		return nil
+	}
+
+	if err := m.repo.Set(normalized, updatedBy); err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+	klog.Infof("用户 %s 修改设置: %v", updatedBy, keys(normalized))
+	return m.Load()
+}
+
+// Reset 删除数据库中的设置，恢复为环境变量或内置的默认值
+func (m *Manager) Reset(key, updatedBy string) error {
+	if _, ok := lookup(key); !ok {
+		return fmt.Errorf("%w: unknown setting %s", ErrInvalid, key)
+	}
+	if m.locked[key] {
+		return fmt.Errorf("%w: %s", ErrLocked, key)
+	}
+
+	candidate := m.bootstrap
+	m.mu.Lock()
+	for name, row := range m.stored {
+		if def, ok := lookup(name); ok && name != key && !m.locked[name] {
+			_ = def.set(&candidate, string(row.Value))
+		}
+	}
+	m.mu.Unlock()
+	if err := checkAuthentication(candidate); err != nil {
+		return err
+	}
+
+	if err := m.repo.Delete(key); err != nil {
+		return fmt.Errorf("failed to reset setting: %w", err)
+	}
+	klog.Infof("用户 %s 恢复设置默认值: %s", updatedBy, key)
+	return m.Load()
+}
+
+// checkAuthentication 拒绝导致服务不再需要登录的修改
+func checkAuthentication(candidate common.RuntimeSettings) error {
+	current := common.Settings()
+	authenticated := current.OAuthEnabled || common.PasswordLoginEnabled
+	if authenticated && !candidate.OAuthEnabled && !common.PasswordLoginEnabled {
+		return fmt.Errorf("%w: disabling OAuth would leave the server without authentication", ErrInvalid)
+	}
+	return nil
+}
+
+// Run 定期从数据库同步设置，使其他副本所做的修改生效，直到 Stop 被调用
+func (m *Manager) Run() {
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := m.Load(); err != nil {
+				klog.Warningf("从数据库同步设置失败: %v", err)
+			}
+		case <-m.stopCh:
+			return
+		}
+	}
+}
+
+// Stop 停止同步
+func (m *Manager) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.stopCh)
+	})
+}
+
+func keys(values map[string]string) []string {
+	list := make([]string, 0, len(values))
+	for key := range values {
+		list = append(list, key)
+	}
+	return list
+}
@@ -0,0 +1,356 @@
+package tunnel
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// sshDialTimeout 连接跳板机的超时时间
+	sshDialTimeout = 10 * time.Second
+	// sshKeepaliveInterval 跳板机保活间隔
+	sshKeepaliveInterval = 30 * time.Second
+	// proxyUser 本地转发代理的认证用户名
+	proxyUser = "nexus"
+)
+
+// signer 解析私钥
+func (c *SSHConfig) signer() (ssh.Signer, error) {
+	var (
+		signer ssh.Signer
+		err    error
+	)
+	if c.Passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(c.PrivateKey), []byte(c.Passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey([]byte(c.PrivateKey))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid ssh private key: %w", err)
+	}
+	return signer, nil
+}
+
+// hostKeyCallback 根据配置的跳板机公钥校验主机密钥，只有显式开启 InsecureSkipHostKeyVerify 时才跳过校验
+func (c *SSHConfig) hostKeyCallback() (ssh.HostKeyCallback, error) {
+	if c.InsecureSkipHostKeyVerify {
+		if c.HostKey != "" {
+			return nil, fmt.Errorf("ssh host key cannot be used with insecureSkipHostKeyVerify")
+		}
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+	if c.HostKey == "" {
+		return nil, fmt.Errorf("ssh host key is required, set insecureSkipHostKeyVerify to skip host key verification")
+	}
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(c.HostKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ssh host key: %w", err)
+	}
+	return ssh.FixedHostKey(key), nil
+}
+
+// sshForwarder 在本地回环地址上提供 HTTP 代理，所有连接经由 SSH 跳板机转发
+type sshForwarder struct {
+	addr     string
+	config   *ssh.ClientConfig
+	password string
+	listener net.Listener
+	server   *http.Server
+	// forward 转发普通 HTTP 代理请求，CONNECT 请求直接建立隧道
+	forward *httputil.ReverseProxy
+
+	mu     sync.Mutex
+	client *ssh.Client
+	closed bool
+	stopCh chan struct{}
+}
+
+// newSSHForwarder 创建并启动 SSH 转发代理，跳板机连接在首次使用时建立
+func newSSHForwarder(c *SSHConfig) (*sshForwarder, error) {
+	signer, err := c.signer()
+	if err != nil {
+		return nil, err
+	}
+	hostKeyCallback, err := c.hostKeyCallback()
+	if err != nil {
+		return nil, err
+	}
+	if c.InsecureSkipHostKeyVerify {
+		klog.Warningf("SSH host key verification for bastion %s is disabled by insecureSkipHostKeyVerify", c.address())
+	}
+
+	password, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for ssh tunnel: %w", err)
+	}
+
+	f := &sshForwarder{
+		addr: c.address(),
+		config: &ssh.ClientConfig{
+			User:            c.User,
+			Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+			HostKeyCallback: hostKeyCallback,
+			Timeout:         sshDialTimeout,
+		},
+		password: password,
+		listener: listener,
+		stopCh:   make(chan struct{}),
+	}
+	f.forward = &httputil.ReverseProxy{
+		Director: func(req *http.Request) {},
+		Transport: &http.Transport{
+			DialContext:           f.dial,
+			MaxIdleConnsPerHost:   10,
+			IdleConnTimeout:       90 * time.Second,
+			ResponseHeaderTimeout: 60 * time.Second,
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			klog.V(2).Infof("SSH tunnel failed to forward %s via %s: %v", r.URL.Host, f.addr, err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		},
+	}
+	f.server = &http.Server{
+		Handler:           f,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := f.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			klog.Errorf("SSH tunnel proxy for %s stopped: %v", f.addr, err)
+		}
+	}()
+	go f.keepalive()
+
+	return f, nil
+}
+
+// proxyURL 本地转发代理地址
+func (f *sshForwarder) proxyURL() *url.URL {
+	return &url.URL{
+		Scheme: "http",
+		User:   url.UserPassword(proxyUser, f.password),
+		Host:   f.listener.Addr().String(),
+	}
+}
+
+// ServeHTTP 处理代理请求
+func (f *sshForwarder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.authorized(r) {
+		w.Header().Set("Proxy-Authenticate", `Basic realm="nexus"`)
+		http.Error(w, "proxy authentication required", http.StatusProxyAuthRequired)
+		return
+	}
+
+	if r.Method != http.MethodConnect {
+		if !r.URL.IsAbs() {
+			http.Error(w, "absolute url is required", http.StatusBadRequest)
+			return
+		}
+		r.Header.Del("Proxy-Authorization")
+		f.forward.ServeHTTP(w, r)
+		return
+	}
+
+	remote, err := f.dial(r.Context(), "tcp", r.Host)
+	if err != nil {
+		klog.V(2).Infof("SSH tunnel failed to dial %s via %s: %v", r.Host, f.addr, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		_ = remote.Close()
+		http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	local, buf, err := hijacker.Hijack()
+	if err != nil {
+		_ = remote.Close()
+		return
+	}
+
+	if _, err := local.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+		_ = local.Close()
+		_ = remote.Close()
+		return
+	}
+
+	// 客户端可能在收到响应前就已发送数据
+	if n := buf.Reader.Buffered(); n > 0 {
+		pending, _ := buf.Reader.Peek(n)
+		if _, err := remote.Write(pending); err != nil {
+			_ = local.Close()
+			_ = remote.Close()
+			return
+		}
+	}
+
+	go pipe(local, remote)
+}
+
+// authorized 校验代理认证信息，避免本机其他进程借用通道
+func (f *sshForwarder) authorized(r *http.Request) bool {
+	auth := r.Header.Get("Proxy-Authorization")
+	const prefix = "Basic "
+	if !strings.HasPrefix(auth, prefix) {
+		return false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(auth, prefix))
+	if err != nil {
+		return false
+	}
+	expected := proxyUser + ":" + f.password
+	return subtle.ConstantTimeCompare(decoded, []byte(expected)) == 1
+}
+
+// dial 经由跳板机连接目标地址，连接断开时自动重连一次
+func (f *sshForwarder) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, err := f.sshClient()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := client.DialContext(ctx, network, addr)
+	if err == nil {
+		return conn, nil
+	}
+	// 跳板机拒绝打开通道（例如目标不可达）时连接本身仍然可用
+	var openErr *ssh.OpenChannelError
+	if errors.As(err, &openErr) {
+		return nil, fmt.Errorf("failed to dial %s via ssh bastion %s: %w", addr, f.addr, err)
+	}
+
+	// 跳板机连接可能已失效，重建后再试一次
+	f.resetClient(client)
+	client, retryErr := f.sshClient()
+	if retryErr != nil {
+		return nil, retryErr
+	}
+	conn, retryErr = client.DialContext(ctx, network, addr)
+	if retryErr != nil {
+		return nil, fmt.Errorf("failed to dial %s via ssh bastion %s: %w", addr, f.addr, retryErr)
+	}
+	return conn, nil
+}
+
+// sshClient 返回跳板机连接，不存在时建立新连接
+func (f *sshForwarder) sshClient() (*ssh.Client, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return nil, fmt.Errorf("ssh tunnel is closed")
+	}
+	if f.client != nil {
+		return f.client, nil
+	}
+
+	client, err := ssh.Dial("tcp", f.addr, f.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ssh bastion %s: %w", f.addr, err)
+	}
+	klog.V(2).Infof("Connected to ssh bastion %s", f.addr)
+	f.client = client
+	return client, nil
+}
+
+// resetClient 关闭失效的跳板机连接
+func (f *sshForwarder) resetClient(client *ssh.Client) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.client == client {
+		f.client = nil
+	}
+	_ = client.Close()
+}
+
+// keepalive 定期向跳板机发送保活请求，及时发现断开的连接
+func (f *sshForwarder) keepalive() {
+	ticker := time.NewTicker(sshKeepaliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			f.mu.Lock()
+			client := f.client
+			f.mu.Unlock()
+			if client == nil {
+				continue
+			}
+			if _, _, err := client.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+				klog.V(2).Infof("SSH bastion %s keepalive failed: %v", f.addr, err)
+				f.resetClient(client)
+			}
+		case <-f.stopCh:
+			return
+		}
+	}
+}
+
+// Close 停止转发代理并断开跳板机连接
+func (f *sshForwarder) Close() {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return
+	}
+	f.closed = true
+	client := f.client
+	f.client = nil
+	close(f.stopCh)
+	f.mu.Unlock()
+
+	_ = f.server.Close()
+	if client != nil {
+		_ = client.Close()
+	}
+}
+
+// pipe 在两个连接之间双向复制数据，任一方向结束后关闭两端
+func pipe(a, b net.Conn) {
+	var once sync.Once
+	closeBoth := func() {
+		_ = a.Close()
+		_ = b.Close()
+	}
+
+	go func() {
+		_, _ = io.Copy(a, b)
+		once.Do(closeBoth)
+	}()
+	_, _ = io.Copy(b, a)
+	once.Do(closeBoth)
+}
+
+// randomToken 生成随机的代理认证密码
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate tunnel credentials: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
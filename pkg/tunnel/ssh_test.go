@@ -0,0 +1,238 @@
+package tunnel
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testSSHServer 进程内的 SSH 跳板机，只支持 direct-tcpip 端口转发
+type testSSHServer struct {
+	addr     string
+	hostKey  ssh.PublicKey
+	channels atomic.Int32
+}
+
+// newTestKey 生成 ed25519 密钥，返回签名器和 PEM 格式的私钥
+func newTestKey(t *testing.T) (ssh.Signer, string) {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(key, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer, string(pem.EncodeToMemory(block))
+}
+
+// startTestSSHServer 启动只接受 clientKey 认证的 SSH 服务
+func startTestSSHServer(t *testing.T, clientKey ssh.PublicKey) *testSSHServer {
+	t.Helper()
+	hostSigner, _ := newTestKey(t)
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(clientKey.Marshal()) {
+				return nil, fmt.Errorf("unknown public key for %s", conn.User())
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	server := &testSSHServer{addr: listener.Addr().String(), hostKey: hostSigner.PublicKey()}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn, config)
+		}
+	}()
+	return server
+}
+
+func (s *testSSHServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "only direct-tcpip is supported")
+			continue
+		}
+		var target struct {
+			Host     string
+			Port     uint32
+			OrigHost string
+			OrigPort uint32
+		}
+		if err := ssh.Unmarshal(newChannel.ExtraData(), &target); err != nil {
+			_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		remote, err := net.Dial("tcp", net.JoinHostPort(target.Host, fmt.Sprint(target.Port)))
+		if err != nil {
+			_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, channelReqs, err := newChannel.Accept()
+		if err != nil {
+			_ = remote.Close()
+			continue
+		}
+		s.channels.Add(1)
+		go ssh.DiscardRequests(channelReqs)
+		go func() {
+			_, _ = io.Copy(remote, channel)
+			_ = remote.Close()
+		}()
+		go func() {
+			_, _ = io.Copy(channel, remote)
+			_ = channel.Close()
+		}()
+	}
+}
+
+func authorizedKey(key ssh.PublicKey) string {
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+}
+
+func TestSSHTunnelForwardsRequests(t *testing.T) {
+	clientSigner, clientKey := newTestKey(t)
+	server := startTestSSHServer(t, clientSigner.PublicKey())
+
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer backend.Close()
+	plainBackend := httptest.NewServer(backend.Config.Handler)
+	defer plainBackend.Close()
+
+	tun, err := Open(&Config{Type: TypeSSH, SSH: &SSHConfig{
+		Host:       server.addr,
+		User:       "ops",
+		PrivateKey: clientKey,
+		HostKey:    authorizedKey(server.hostKey),
+	}})
+	if err != nil {
+		t.Fatalf("failed to open tunnel: %v", err)
+	}
+	defer tun.Close()
+
+	// HTTPS 经由 CONNECT 转发，与访问 API Server 的方式相同
+	transport := backend.Client().Transport.(*http.Transport).Clone()
+	transport.Proxy = tun.Proxy()
+	for _, tc := range []struct {
+		url    string
+		client *http.Client
+	}{
+		{url: backend.URL, client: &http.Client{Transport: transport}},
+		{url: plainBackend.URL, client: &http.Client{Transport: &http.Transport{Proxy: tun.Proxy()}}},
+	} {
+		resp, err := tc.client.Get(tc.url)
+		if err != nil {
+			t.Fatalf("request to %s through tunnel failed: %v", tc.url, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(body) != "ok" {
+			t.Fatalf("unexpected response from %s: %d %q", tc.url, resp.StatusCode, body)
+		}
+	}
+	if server.channels.Load() == 0 {
+		t.Fatal("requests were not forwarded through the ssh server")
+	}
+}
+
+func TestSSHTunnelRejectsWrongHostKey(t *testing.T) {
+	clientSigner, clientKey := newTestKey(t)
+	server := startTestSSHServer(t, clientSigner.PublicKey())
+	otherSigner, _ := newTestKey(t)
+
+	var hits atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer backend.Close()
+
+	tun, err := Open(&Config{Type: TypeSSH, SSH: &SSHConfig{
+		Host:       server.addr,
+		User:       "ops",
+		PrivateKey: clientKey,
+		HostKey:    authorizedKey(otherSigner.PublicKey()),
+	}})
+	if err != nil {
+		t.Fatalf("failed to open tunnel: %v", err)
+	}
+	defer tun.Close()
+
+	client := &http.Client{Transport: &http.Transport{Proxy: tun.Proxy()}}
+	resp, err := client.Get(backend.URL)
+	if err != nil {
+		t.Fatalf("request through tunnel failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || !strings.Contains(string(body), "host key mismatch") {
+		t.Fatalf("expected host key mismatch, got %d %q", resp.StatusCode, body)
+	}
+	if hits.Load() != 0 || server.channels.Load() != 0 {
+		t.Fatal("request was forwarded despite the wrong host key")
+	}
+}
+
+func TestSSHConfigRequiresHostKey(t *testing.T) {
+	_, clientKey := newTestKey(t)
+	hostSigner, _ := newTestKey(t)
+
+	for _, tc := range []struct {
+		name     string
+		hostKey  string
+		insecure bool
+		wantErr  bool
+	}{
+		{name: "host key", hostKey: authorizedKey(hostSigner.PublicKey())},
+		{name: "missing host key", wantErr: true},
+		{name: "explicitly insecure", insecure: true},
+		{name: "host key with insecure", hostKey: authorizedKey(hostSigner.PublicKey()), insecure: true, wantErr: true},
+	} {
+		config := &Config{Type: TypeSSH, SSH: &SSHConfig{
+			Host:                      "bastion.example.com",
+			User:                      "ops",
+			PrivateKey:                clientKey,
+			HostKey:                   tc.hostKey,
+			InsecureSkipHostKeyVerify: tc.insecure,
+		}}
+		if err := config.Validate(); (err != nil) != tc.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tc.name, err, tc.wantErr)
+		}
+	}
+}
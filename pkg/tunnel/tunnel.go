@@ -0,0 +1,249 @@
+package tunnel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"k8s.io/client-go/rest"
+)
+
+// Type 集群连接方式
+type Type string
+
+const (
+	// TypeDirect 直接连接
+	TypeDirect Type = "direct"
+	// TypeHTTP 通过 HTTP(S) 代理连接
+	TypeHTTP Type = "http"
+	// TypeSOCKS5 通过 SOCKS5 代理连接
+	TypeSOCKS5 Type = "socks5"
+	// TypeSSH 通过 SSH 跳板机转发
+	TypeSSH Type = "ssh"
+)
+
+// Config 集群连接方式配置
+type Config struct {
+	Type Type `json:"type"`
+	// ProxyURL HTTP(S) 或 SOCKS5 代理地址，例如 http://proxy:3128、socks5://proxy:1080
+	ProxyURL string `json:"proxyUrl,omitempty"`
+	// SSH 跳板机配置
+	SSH *SSHConfig `json:"ssh,omitempty"`
+}
+
+// SSHConfig SSH 跳板机配置，仅支持密钥认证
+type SSHConfig struct {
+	// Host 跳板机地址，格式为 host[:port]，默认端口 22
+	Host string `json:"host"`
+	// User 登录用户
+	User string `json:"user"`
+	// PrivateKey 私钥（PEM）
+	PrivateKey string `json:"privateKey"`
+	// Passphrase 私钥密码
+	Passphrase string `json:"passphrase,omitempty"`
+	// HostKey 跳板机公钥，authorized_keys 格式，必须设置，除非显式开启 InsecureSkipHostKeyVerify
+	HostKey string `json:"hostKey,omitempty"`
+	// InsecureSkipHostKeyVerify 不校验跳板机主机密钥，存在中间人攻击风险，仅用于测试环境
+	InsecureSkipHostKeyVerify bool `json:"insecureSkipHostKeyVerify,omitempty"`
+}
+
+// IsDirect 是否为直接连接
+func (c *Config) IsDirect() bool {
+	return c == nil || c.Type == "" || c.Type == TypeDirect
+}
+
+// Validate 校验连接方式配置
+func (c *Config) Validate() error {
+	if c.IsDirect() {
+		return nil
+	}
+
+	switch c.Type {
+	case TypeHTTP, TypeSOCKS5:
+		_, err := c.proxyURL()
+		return err
+	case TypeSSH:
+		if c.SSH == nil {
+			return fmt.Errorf("ssh config is required for ssh tunnel")
+		}
+		return c.SSH.Validate()
+	default:
+		return fmt.Errorf("unsupported connection type: %s", c.Type)
+	}
+}
+
+// proxyURL 解析代理地址，并校验协议与连接方式匹配
+func (c *Config) proxyURL() (*url.URL, error) {
+	if c.ProxyURL == "" {
+		return nil, fmt.Errorf("proxyUrl is required for %s proxy", c.Type)
+	}
+
+	u, err := url.Parse(c.ProxyURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy url: %s", c.ProxyURL)
+	}
+
+	switch c.Type {
+	case TypeHTTP:
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("http proxy url must use http or https scheme")
+		}
+	case TypeSOCKS5:
+		if u.Scheme != "socks5" {
+			return nil, fmt.Errorf("socks5 proxy url must use socks5 scheme")
+		}
+	}
+	return u, nil
+}
+
+// Validate 校验 SSH 跳板机配置
+func (c *SSHConfig) Validate() error {
+	if c.Host == "" {
+		return fmt.Errorf("ssh host is required")
+	}
+	if c.User == "" {
+		return fmt.Errorf("ssh user is required")
+	}
+	if c.PrivateKey == "" {
+		return fmt.Errorf("ssh private key is required")
+	}
+	if _, err := c.signer(); err != nil {
+		return err
+	}
+	if _, err := c.hostKeyCallback(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// address 跳板机地址，未指定端口时使用 22
+func (c *SSHConfig) address() string {
+	if _, _, err := net.SplitHostPort(c.Host); err != nil {
+		return net.JoinHostPort(c.Host, "22")
+	}
+	return c.Host
+}
+
+// Tunnel 已建立的集群连接通道
+//
+// 所有连接方式最终都表现为一个代理地址：HTTP/SOCKS5 直接使用配置的代理，
+// SSH 则在本地回环地址上启动一个经由跳板机转发的 HTTP 代理。
+// 这样 REST 客户端、exec/terminal 的 SPDY 连接和 Prometheus 客户端都可以通过
+// 标准的 Proxy 配置使用同一个通道。nil 表示直接连接。
+type Tunnel struct {
+	proxyURL *url.URL
+	ssh      *sshForwarder
+}
+
+// Open 根据配置建立连接通道，直接连接时返回 nil
+func Open(config *Config) (*Tunnel, error) {
+	if config.IsDirect() {
+		return nil, nil
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch config.Type {
+	case TypeHTTP, TypeSOCKS5:
+		u, err := config.proxyURL()
+		if err != nil {
+			return nil, err
+		}
+		return &Tunnel{proxyURL: u}, nil
+	case TypeSSH:
+		forwarder, err := newSSHForwarder(config.SSH)
+		if err != nil {
+			return nil, err
+		}
+		return &Tunnel{proxyURL: forwarder.proxyURL(), ssh: forwarder}, nil
+	default:
+		return nil, fmt.Errorf("unsupported connection type: %s", config.Type)
+	}
+}
+
+// Proxy 返回用于 http.Transport 和 rest.Config 的代理函数
+func (t *Tunnel) Proxy() func(*http.Request) (*url.URL, error) {
+	if t == nil {
+		return nil
+	}
+	return http.ProxyURL(t.proxyURL)
+}
+
+// RESTConfig 返回通过该通道访问 API Server 的 REST 配置副本
+func (t *Tunnel) RESTConfig(config *rest.Config) *rest.Config {
+	if t == nil {
+		return config
+	}
+	config = rest.CopyConfig(config)
+	config.Proxy = t.Proxy()
+	return config
+}
+
+// Transport 返回通过该通道访问外部服务（例如 Prometheus）的 HTTP Transport
+func (t *Tunnel) Transport() http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if t != nil {
+		transport.Proxy = t.Proxy()
+	}
+	return transport
+}
+
+// Close 关闭连接通道
+func (t *Tunnel) Close() {
+	if t == nil || t.ssh == nil {
+		return
+	}
+	t.ssh.Close()
+}
+
+// ParseConfig 解析以 JSON 保存的连接方式配置，空字符串表示直接连接
+func ParseConfig(data string) (*Config, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var config Config
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		return nil, fmt.Errorf("invalid tunnel config: %w", err)
+	}
+	if config.IsDirect() {
+		return nil, nil
+	}
+	return &config, nil
+}
+
+// Encode 将连接方式配置编码为 JSON 保存，直接连接时返回空字符串
+func (c *Config) Encode() (string, error) {
+	if c.IsDirect() {
+		return "", nil
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode tunnel config: %w", err)
+	}
+	return string(data), nil
+}
+
+// Summary 返回不含私钥、代理密码等敏感信息的连接方式摘要，直接连接时返回 nil
+func (c *Config) Summary() map[string]interface{} {
+	if c.IsDirect() {
+		return nil
+	}
+
+	summary := map[string]interface{}{
+		"type": c.Type,
+	}
+	if c.ProxyURL != "" {
+		if u, err := url.Parse(c.ProxyURL); err == nil {
+			summary["proxyUrl"] = u.Redacted()
+		}
+	}
+	if c.SSH != nil {
+		summary["sshHost"] = c.SSH.address()
+		summary["sshUser"] = c.SSH.User
+		summary["sshHostKeyVerified"] = c.SSH.HostKey != ""
+	}
+	return summary
+}
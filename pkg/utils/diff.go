@@ -0,0 +1,26 @@
+package utils
+
+import "github.com/pmezard/go-difflib/difflib"
+
+// UnifiedDiff returns a unified diff between two texts, or an empty string
+// when they are equal. An empty from or to is treated as a missing file.
+func UnifiedDiff(from, to, fromFile, toFile string) (string, error) {
+	if from == to {
+		return "", nil
+	}
+
+	var fromLines, toLines []string
+	if from != "" {
+		fromLines = difflib.SplitLines(from)
+	}
+	if to != "" {
+		toLines = difflib.SplitLines(to)
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        fromLines,
+		B:        toLines,
+		FromFile: fromFile,
+		ToFile:   toFile,
+		Context:  3,
+	})
+}
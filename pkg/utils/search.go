@@ -33,6 +33,8 @@ func GuessSearchResources(query string) (string, string) {
 			guessSearchResources = "jobs"
 		case "cronjob", "cronjobs":
 			guessSearchResources = "cronjobs"
+		case "cr", "crs", "customresource", "customresources":
+			guessSearchResources = "customresources"
 		default:
 			return "all", query
 		}
@@ -60,7 +60,9 @@ export function CreateResourceDialog({
     try {
       const result = await applyResource(yaml)
       toast.success(
-        `Resource ${result.kind}/${result.name} created successfully`
+        result.results.length === 1
+          ? `Resource ${result.kind}/${result.name} ${result.results[0].action}`
+          : result.message
       )
       setYaml('')
       onOpenChange(false)
@@ -2,9 +2,12 @@
 
 import { useCallback, useEffect, useRef, useState } from 'react'
 import { useQuery } from '@tanstack/react-query'
+import { Scale } from 'kubernetes-types/autoscaling/v1'
+import { EventList } from 'kubernetes-types/core/v1'
 
 import {
   clusterScopeResources,
+  CustomResource,
   DeploymentRelatedResource,
   OverviewData,
   PodMetrics,
@@ -157,24 +160,212 @@ export const restartDeployment = async (
   })
 }
 
+// Rollout APIs
+export type RolloutResource = 'deployments' | 'statefulsets' | 'daemonsets'
+
+export interface RolloutRevision {
+  revision: number
+  name: string
+  changeCause?: string
+  images: string[]
+  replicas: number
+  current: boolean
+  createdAt: string
+  template?: unknown
+  diff?: string
+}
+
+export interface RolloutStatus {
+  generation: number
+  observedGeneration: number
+  replicas: number
+  updatedReplicas: number
+  readyReplicas: number
+  availableReplicas: number
+  message: string
+  done: boolean
+  failed?: boolean
+}
+
+export const fetchRolloutHistory = async (
+  resource: RolloutResource,
+  namespace: string,
+  name: string
+): Promise<RolloutRevision[]> => {
+  const endpoint = `/${resource}/${namespace}/${name}/history`
+  const response = await fetchAPI<{ revisions: RolloutRevision[] }>(endpoint)
+  return response.revisions
+}
+
+// Fetch one revision with the diff from compareTo (default: the current template)
+export const fetchRolloutRevision = async (
+  resource: RolloutResource,
+  namespace: string,
+  name: string,
+  revision: number,
+  compareTo?: number
+): Promise<RolloutRevision> => {
+  const query = compareTo ? `?compareTo=${compareTo}` : ''
+  const endpoint = `/${resource}/${namespace}/${name}/history/${revision}${query}`
+  return fetchAPI<RolloutRevision>(endpoint)
+}
+
+// Roll back to a revision, or to the previous one when revision is omitted
+export const rollbackResource = async (
+  resource: RolloutResource,
+  namespace: string,
+  name: string,
+  revision = 0
+): Promise<{ message: string; revision: number }> => {
+  const endpoint = `/${resource}/${namespace}/${name}/rollback`
+  return apiClient.post<{ message: string; revision: number }>(endpoint, {
+    revision,
+  })
+}
+
+export const restartWorkload = async (
+  resource: RolloutResource,
+  namespace: string,
+  name: string
+): Promise<void> => {
+  await apiClient.post(`/${resource}/${namespace}/${name}/restart`)
+}
+
+// Pause a rollout; statefulsets pause by raising the partition to the replica count
+export const pauseRollout = async (
+  resource: Exclude<RolloutResource, 'daemonsets'>,
+  namespace: string,
+  name: string
+): Promise<void> => {
+  await apiClient.post(`/${resource}/${namespace}/${name}/pause`)
+}
+
+export const resumeRollout = async (
+  resource: Exclude<RolloutResource, 'daemonsets'>,
+  namespace: string,
+  name: string
+): Promise<void> => {
+  await apiClient.post(`/${resource}/${namespace}/${name}/resume`)
+}
+
+export interface RollingUpdateOptions {
+  partition?: number // statefulsets only
+  maxUnavailable?: number | string
+  maxSurge?: number | string // daemonsets only
+}
+
+export const updateRollingUpdate = async (
+  resource: Exclude<RolloutResource, 'deployments'>,
+  namespace: string,
+  name: string,
+  options: RollingUpdateOptions
+): Promise<void> => {
+  await apiClient.post(
+    `/${resource}/${namespace}/${name}/rolling-update`,
+    options
+  )
+}
+
+export const scaleStatefulSet = async (
+  namespace: string,
+  name: string,
+  replicas: number
+): Promise<{ message: string; replicas: number }> => {
+  return apiClient.post<{ message: string; replicas: number }>(
+    `/statefulsets/${namespace}/${name}/scale`,
+    { replicas }
+  )
+}
+
+// Stream rollout progress until the rollout completes or fails
+export const watchRolloutStatus = (
+  resource: RolloutResource,
+  namespace: string,
+  name: string,
+  onStatus: (status: RolloutStatus) => void,
+  onError?: (error: string) => void
+): EventSource => {
+  const endpoint = `${API_BASE_URL}/${resource}/${namespace}/${name}/rollout-status`
+  const eventSource = new EventSource(endpoint, {
+    withCredentials: true,
+  })
+  eventSource.addEventListener('status', (event: MessageEvent) => {
+    onStatus(JSON.parse(event.data))
+  })
+  eventSource.addEventListener('error', (event: MessageEvent) => {
+    try {
+      onError?.(JSON.parse(event.data).error)
+    } catch {
+      // Connection error without server data
+    }
+    eventSource.close()
+  })
+  eventSource.addEventListener('close', () => eventSource.close())
+  return eventSource
+}
+
 // Node operation APIs
+export interface DrainOptions {
+  force: boolean
+  gracePeriod?: number // seconds, negative uses each pod's own grace period
+  deleteLocalData: boolean
+  ignoreDaemonsets: boolean
+  timeoutSeconds?: number
+}
+
+export interface DrainEvent {
+  time: string
+  type: string
+  pod?: string
+  message: string
+}
+
+export interface DrainJob {
+  id: string
+  node: string
+  options: DrainOptions
+  status: 'running' | 'succeeded' | 'failed' | 'cancelled'
+  error?: string
+  startedAt: string
+  finishedAt?: string
+  events: DrainEvent[]
+}
+
+// Start draining a node; the drain runs in the background on the server
 export const drainNode = async (
   nodeName: string,
-  options: {
-    force: boolean
-    gracePeriod: number
-    deleteLocalData: boolean
-    ignoreDaemonsets: boolean
-  }
-): Promise<{ message: string; node: string; options: unknown }> => {
+  options: DrainOptions
+): Promise<{ message: string; job: DrainJob }> => {
   const endpoint = `/nodes/_all/${nodeName}/drain`
-  const response = await apiClient.post<{
-    message: string
-    node: string
-    options: unknown
-  }>(endpoint, options)
+  return apiClient.post<{ message: string; job: DrainJob }>(endpoint, options)
+}
 
-  return response
+export const fetchDrainJob = (nodeName: string): Promise<DrainJob> => {
+  return fetchAPI<DrainJob>(`/nodes/_all/${nodeName}/drain`)
+}
+
+export const cancelDrain = async (nodeName: string): Promise<void> => {
+  await apiClient.delete(`/nodes/_all/${nodeName}/drain`)
+}
+
+// Follow the progress of the latest drain of a node until it finishes
+export const watchDrain = (
+  nodeName: string,
+  onEvent: (event: DrainEvent) => void,
+  onFinish: (job: DrainJob) => void
+): EventSource => {
+  const endpoint = `${API_BASE_URL}/nodes/_all/${nodeName}/drain/events`
+  const eventSource = new EventSource(endpoint, {
+    withCredentials: true,
+  })
+  eventSource.addEventListener('progress', (event: MessageEvent) => {
+    onEvent(JSON.parse(event.data))
+  })
+  eventSource.addEventListener('close', (event: MessageEvent) => {
+    eventSource.close()
+    onFinish(JSON.parse(event.data))
+  })
+  return eventSource
 }
 
 export const cordonNode = async (
@@ -249,6 +440,29 @@ export const updateResource = async <T extends ResourceType>(
   })
 }
 
+export type PatchType = 'json' | 'merge' | 'strategic'
+
+const patchContentTypes: Record<PatchType, string> = {
+  json: 'application/json-patch+json',
+  merge: 'application/merge-patch+json',
+  strategic: 'application/strategic-merge-patch+json',
+}
+
+export const patchResource = async <T extends ResourceType>(
+  resource: T,
+  name: string,
+  namespace: string | undefined,
+  patch: unknown,
+  patchType: PatchType = 'merge'
+): Promise<ResourceTypeMap[T]> => {
+  const endpoint = `/${resource}/${namespace || '_all'}/${name}`
+  return await apiClient.patch<ResourceTypeMap[T]>(`${endpoint}`, patch, {
+    headers: {
+      'Content-Type': patchContentTypes[patchType],
+    },
+  })
+}
+
 export const createResource = async <T extends ResourceType>(
   resource: T,
   namespace: string | undefined,
@@ -276,19 +490,44 @@ export interface ApplyResourceRequest {
   yaml: string
 }
 
+export interface ApplyResult {
+  apiVersion: string
+  kind: string
+  name: string
+  namespace?: string
+  action?: 'created' | 'configured' | 'unchanged'
+  diff?: string
+  error?: string
+}
+
 export interface ApplyResourceResponse {
   message: string
+  dryRun: boolean
+  results: ApplyResult[]
   kind: string
   name: string
   namespace?: string
 }
 
+export interface ApplyResourceOptions {
+  dryRun?: boolean
+  diff?: boolean
+  force?: boolean
+}
+
 export const applyResource = async (
-  yaml: string
+  yaml: string,
+  options: ApplyResourceOptions = {}
 ): Promise<ApplyResourceResponse> => {
-  return await apiClient.post<ApplyResourceResponse>('/resources/apply', {
-    yaml,
-  })
+  const params = new URLSearchParams()
+  if (options.dryRun) params.append('dryRun', 'All')
+  if (options.diff) params.append('diff', 'true')
+  if (options.force) params.append('force', 'true')
+  const query = params.toString()
+  return await apiClient.post<ApplyResourceResponse>(
+    `/resources/apply${query ? `?${query}` : ''}`,
+    { yaml }
+  )
 }
 
 export const useResourcesEvents = <T extends ResourceType>(
@@ -359,6 +598,342 @@ export const useResources = <T extends ResourceType>(
   })
 }
 
+// Server-side filtering, sorting and paging of list endpoints
+export interface ListQuery {
+  name?: string
+  nameRegex?: string
+  status?: string[]
+  owner?: string
+  node?: string
+  sortBy?: 'name' | 'namespace' | 'creationTimestamp' | 'status' | 'node'
+  sortOrder?: 'asc' | 'desc'
+  page?: number
+  pageSize?: number
+  labelSelector?: string
+  fieldSelector?: string
+}
+
+export interface ListPage<T> {
+  items: T[]
+  metadata?: { resourceVersion?: string }
+  // Objects matching the filters across all pages
+  total: number
+  page?: number
+  pageSize?: number
+  totalPages?: number
+}
+
+export const fetchResourcePage = <T>(
+  resource: string,
+  namespace: string | undefined,
+  query: ListQuery,
+  clusterId?: string
+): Promise<ListPage<T>> => {
+  const endpoint = namespace ? `/${resource}/${namespace}` : `/${resource}`
+  const params = new URLSearchParams()
+  Object.entries(query).forEach(([key, value]) => {
+    if (value === undefined || value === '' || (Array.isArray(value) && value.length === 0)) {
+      return
+    }
+    params.append(key, Array.isArray(value) ? value.join(',') : String(value))
+  })
+  const search = params.toString()
+  return fetchAPI<ListPage<T>>(search ? `${endpoint}?${search}` : endpoint, clusterId)
+}
+
+// Relationship graph of an object, served by the /graph endpoints
+export type RelationType =
+  | 'owns'
+  | 'endpoints'
+  | 'targets'
+  | 'routes'
+  | 'mounts'
+  | 'references'
+  | 'serviceAccount'
+  | 'binds'
+  | 'storageClass'
+  | 'scales'
+
+export interface GraphNode {
+  id: string
+  apiVersion: string
+  kind: string
+  namespace?: string
+  name: string
+  uid?: string
+  status?: string
+  missing?: boolean
+  depth: number
+}
+
+export interface GraphEdge {
+  from: string
+  to: string
+  type: RelationType
+}
+
+export interface ResourceGraph {
+  root: string
+  depth: number
+  nodes: GraphNode[]
+  edges: GraphEdge[]
+  truncated?: boolean
+}
+
+// Fetch the objects related to an object, following up to depth relations
+export const fetchResourceGraph = (
+  resource: string,
+  name: string,
+  namespace?: string,
+  depth?: number,
+  clusterId?: string
+): Promise<ResourceGraph> => {
+  const endpoint = `/${resource}/${namespace || '_all'}/${name}/graph`
+  return fetchAPI<ResourceGraph>(
+    depth ? `${endpoint}?depth=${depth}` : endpoint,
+    clusterId
+  )
+}
+
+// Custom resource APIs. crd is the CRD name, e.g. widgets.example.com; the
+// version defaults to the storage version of the CRD.
+const customResourcePath = (
+  crd: string,
+  name: string,
+  namespace?: string,
+  subresource?: string
+) =>
+  `/${crd}/${namespace || '_all'}/${name}` +
+  (subresource ? `/${subresource}` : '')
+
+export const createCustomResource = async (
+  crd: string,
+  namespace: string | undefined,
+  body: CustomResource,
+  dryRun?: boolean
+): Promise<CustomResource> => {
+  const endpoint = `/${crd}/${namespace || '_all'}`
+  return await apiClient.post<CustomResource>(
+    dryRun ? `${endpoint}?dryRun=All` : endpoint,
+    body,
+    {
+      headers: {
+        'Content-Type': 'application/json',
+      },
+    }
+  )
+}
+
+export const fetchCustomResourceStatus = (
+  crd: string,
+  name: string,
+  namespace?: string,
+  clusterId?: string
+): Promise<CustomResource> => {
+  return fetchAPI<CustomResource>(
+    customResourcePath(crd, name, namespace, 'status'),
+    clusterId
+  )
+}
+
+export const fetchCustomResourceScale = (
+  crd: string,
+  name: string,
+  namespace?: string,
+  clusterId?: string
+): Promise<Scale> => {
+  return fetchAPI<Scale>(
+    customResourcePath(crd, name, namespace, 'scale'),
+    clusterId
+  )
+}
+
+export const scaleCustomResource = async (
+  crd: string,
+  name: string,
+  namespace: string | undefined,
+  replicas: number
+): Promise<{ message: string; scale: Scale; replicas: number }> => {
+  return await apiClient.post<{
+    message: string
+    scale: Scale
+    replicas: number
+  }>(customResourcePath(crd, name, namespace, 'scale'), {
+    replicas,
+  })
+}
+
+export const fetchCustomResourceEvents = (
+  crd: string,
+  name: string,
+  namespace?: string,
+  clusterId?: string
+): Promise<EventList> => {
+  return fetchAPI<EventList>(
+    customResourcePath(crd, name, namespace, 'events'),
+    clusterId
+  )
+}
+
+// Watch message sent by ?watch=true list endpoints
+export interface WatchMessage<T> {
+  type: 'INITIAL' | 'ADDED' | 'MODIFIED' | 'DELETED' | 'BOOKMARK' | 'ERROR'
+  items?: T[]
+  object?: T
+  resourceVersion?: string
+}
+
+// Open a watch stream of a resource list. The browser reconnects on its own and
+// resumes from the last resourceVersion it received.
+export const watchResources = <T>(
+  resource: string,
+  namespace: string | undefined,
+  onMessage: (message: WatchMessage<T>) => void,
+  clusterId?: string
+): EventSource => {
+  const params = new URLSearchParams({ watch: 'true' })
+  if (clusterId) {
+    params.append('cluster', clusterId)
+  }
+  const path = namespace ? `/${resource}/${namespace}` : `/${resource}`
+  const eventSource = new EventSource(
+    `${API_BASE_URL}${path}?${params.toString()}`,
+    { withCredentials: true }
+  )
+  eventSource.addEventListener('watch', (event: MessageEvent) => {
+    onMessage(JSON.parse(event.data))
+  })
+  return eventSource
+}
+
+type WatchedObject = { metadata?: { uid?: string } }
+
+// Keep a resource list up to date from a watch stream instead of polling
+export const useResourcesWatch = <T extends ResourceType>(
+  resource: T,
+  namespace?: string,
+  options?: { disable?: boolean }
+) => {
+  const { selectedCluster } = useCluster()
+  const [items, setItems] = useState<ResourcesItems<T> | undefined>()
+  const [error, setError] = useState<Error | null>(null)
+  const [generation, setGeneration] = useState(0)
+
+  useEffect(() => {
+    if (options?.disable || !selectedCluster) {
+      return
+    }
+    setItems(undefined)
+    setError(null)
+    const eventSource = watchResources<WatchedObject>(
+      resource,
+      namespace,
+      (message) => {
+        const uid = message.object?.metadata?.uid
+        switch (message.type) {
+          case 'INITIAL':
+            setItems(message.items as unknown as ResourcesItems<T>)
+            break
+          case 'ADDED':
+          case 'MODIFIED':
+            setItems((prev) => {
+              const list = (prev ?? []) as WatchedObject[]
+              const index = list.findIndex((o) => o.metadata?.uid === uid)
+              const next = [...list]
+              if (index >= 0) {
+                next[index] = message.object!
+              } else {
+                next.push(message.object!)
+              }
+              return next as unknown as ResourcesItems<T>
+            })
+            break
+          case 'DELETED':
+            setItems(
+              (prev) =>
+                ((prev ?? []) as WatchedObject[]).filter(
+                  (o) => o.metadata?.uid !== uid
+                ) as unknown as ResourcesItems<T>
+            )
+            break
+          case 'ERROR':
+            // The resourceVersion expired: start over with a fresh list
+            eventSource.close()
+            setGeneration((g) => g + 1)
+            break
+        }
+      },
+      selectedCluster
+    )
+    eventSource.onerror = () => {
+      if (eventSource.readyState === EventSource.CLOSED) {
+        setError(new Error('Watch connection closed'))
+      }
+    }
+    return () => eventSource.close()
+  }, [resource, namespace, selectedCluster, options?.disable, generation])
+
+  return { data: items, isLoading: items === undefined && !error, error }
+}
+
+// Table returned by ?format=table, with the columns kubectl get prints
+export interface TableColumn {
+  name: string
+  type: string
+  format: string
+  description: string
+  priority: number
+}
+
+export interface TableRow {
+  cells: unknown[]
+  object?: { metadata?: { name?: string; namespace?: string; uid?: string } }
+}
+
+export interface ResourceTable {
+  columnDefinitions: TableColumn[]
+  rows: TableRow[]
+  metadata?: { continue?: string; resourceVersion?: string }
+}
+
+// Fetch a resource list, or a single object when name is set, as a table.
+// wide adds the columns of kubectl get -o wide.
+export const fetchResourceTable = (
+  resource: string,
+  namespace?: string,
+  options?: {
+    name?: string
+    wide?: boolean
+    limit?: number
+    continueToken?: string
+    labelSelector?: string
+    fieldSelector?: string
+  },
+  clusterId?: string
+): Promise<ResourceTable> => {
+  let endpoint = namespace ? `/${resource}/${namespace}` : `/${resource}`
+  if (options?.name) {
+    endpoint += namespace ? `/${options.name}` : `/_all/${options.name}`
+  }
+  const params = new URLSearchParams({ format: 'table' })
+  if (options?.wide) {
+    params.append('wide', 'true')
+  }
+  if (options?.limit) {
+    params.append('limit', options.limit.toString())
+  }
+  if (options?.continueToken) {
+    params.append('continue', options.continueToken)
+  }
+  if (options?.labelSelector) {
+    params.append('labelSelector', options.labelSelector)
+  }
+  if (options?.fieldSelector) {
+    params.append('fieldSelector', options.fieldSelector)
+  }
+  return fetchAPI<ResourceTable>(`${endpoint}?${params.toString()}`, clusterId)
+}
+
 export const useResourcesV2 = <T extends ResourceType>(
   resource: T,
   namespace?: string,
@@ -22,6 +22,7 @@ import {
   updateResource,
   useResource,
   useResources,
+  watchDrain,
 } from '@/lib/api'
 import { formatCPU, formatDate, formatMemory } from '@/lib/utils'
 import { Badge } from '@/components/ui/badge'
@@ -121,9 +122,21 @@ export function NodeDetail(props: { name: string }) {
   const handleDrain = async () => {
     try {
       await drainNode(name, drainOptions)
-      toast.success(`Node ${name} drained successfully`)
+      toast.info(`Draining node ${name}...`)
       setIsDrainPopoverOpen(false)
-      handleRefresh()
+      watchDrain(
+        name,
+        () => refetchRelated(),
+        (job) => {
+          if (job.status === 'succeeded') {
+            toast.success(`Node ${name} drained successfully`)
+          } else {
+            toast.error(`Failed to drain node: ${job.error}`)
+          }
+          handleRefresh()
+          refetchRelated()
+        }
+      )
     } catch (error) {
       console.error('Failed to drain node:', error)
       toast.error(